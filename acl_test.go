@@ -0,0 +1,37 @@
+package windows2016fs_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// fileACLScript prints the access rules of the file passed as its argument
+// as a JSON array, even when there is a single rule.
+const fileACLScript = `
+param($path)
+$rules = (Get-Acl -LiteralPath $path).Access | ForEach-Object {
+    @{
+        Identity = $_.IdentityReference.Value
+        Rights   = $_.FileSystemRights.ToString()
+        Type     = $_.AccessControlType.ToString()
+    }
+}
+ConvertTo-Json -InputObject @($rules)
+`
+
+// fileACL returns the access rules of path inside a new container of image.
+func fileACL(image, path string) ([]validation.AccessRule, error) {
+	output, err := runInImage(image, "powershell", "-Command", "& {"+fileACLScript+"}", quotePowershell([]string{path})[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []validation.AccessRule
+	if err := json.Unmarshal([]byte(output), &rules); err != nil {
+		return nil, fmt.Errorf("parsing ACL of %s: %s", path, err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,44 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/upload"
+	. "github.com/onsi/ginkgo"
+)
+
+// uploadTimeout bounds uploading the artifacts of a run, which may include
+// an exported image of several gigabytes.
+const uploadTimeout = time.Hour
+
+// uploadArtifacts uploads the reports, SBOMs, build report and exported
+// tarballs in dirs to bucket, keyed by their digests.
+func uploadArtifacts(bucket string, dirs ...string) error {
+	target, err := upload.ParseTarget(bucket)
+	if err != nil {
+		return err
+	}
+
+	paths, err := upload.Collect(dirs...)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	uploader := upload.Uploader{Target: target, Stdout: GinkgoWriter, Stderr: os.Stderr}
+	artifacts, err := uploader.Upload(ctx, paths)
+	if err != nil {
+		return err
+	}
+
+	for _, artifact := range artifacts {
+		fmt.Fprintf(GinkgoWriter, "uploaded %s to %s\n", artifact.Path, artifact.URL)
+	}
+
+	return nil
+}
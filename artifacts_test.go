@@ -0,0 +1,130 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+)
+
+const (
+	captureTimeout = 2 * time.Minute
+	logTailLines   = "500"
+)
+
+var (
+	specContainers    = &containerTracker{}
+	containerSequence uint64
+
+	unsafeNameCharacters = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+)
+
+// containerTracker records the containers started by the current spec so
+// that they can be inspected and removed once it finishes.
+type containerTracker struct {
+	sync.Mutex
+	names []string
+}
+
+func (t *containerTracker) track(name string) {
+	t.Lock()
+	defer t.Unlock()
+	t.names = append(t.names, name)
+}
+
+func (t *containerTracker) drain() []string {
+	t.Lock()
+	defer t.Unlock()
+	names := t.names
+	t.names = nil
+	return names
+}
+
+// newContainerName returns a unique, deterministic name of the form
+// w2016fs-<spec>-<timestamp>-<sequence> and tracks it for the current spec.
+func newContainerName() string {
+	name := fmt.Sprintf(
+		"w2016fs-%s-%s-%d",
+		specSlug(),
+		time.Now().UTC().Format("20060102T150405"),
+		atomic.AddUint64(&containerSequence, 1),
+	)
+	specContainers.track(name)
+
+	return name
+}
+
+func specSlug() string {
+	text := CurrentGinkgoTestDescription().TestText
+	if text == "" {
+		text = "suite"
+	}
+
+	slug := strings.Trim(unsafeNameCharacters.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if len(slug) > 40 {
+		slug = slug[:40]
+	}
+
+	return slug
+}
+
+// captureContainerLogs writes the container's recent stdout and stderr plus
+// its System and Application event logs to destDir.
+func captureContainerLogs(name, destDir string) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), captureTimeout)
+	defer cancel()
+
+	logs, err := cmdlog.CombinedOutput(validation.Runtime.Command(ctx, "logs", "--tail", logTailLines, name))
+	if err != nil {
+		return fmt.Errorf("docker logs %s failed: %s: %s", name, err, logs)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(destDir, name+".log"), logs, 0644); err != nil {
+		return err
+	}
+
+	for _, eventLog := range []string{"System", "Application"} {
+		source := fmt.Sprintf(`%s:C:\Windows\System32\winevt\Logs\%s.evtx`, name, eventLog)
+		destination := filepath.Join(destDir, fmt.Sprintf("%s-%s.evtx", name, eventLog))
+
+		if output, err := cmdlog.CombinedOutput(validation.Runtime.Command(ctx, "cp", source, destination)); err != nil {
+			return fmt.Errorf("copying %s event log from %s failed: %s: %s", eventLog, name, err, output)
+		}
+	}
+
+	return nil
+}
+
+// collectSpecContainers captures logs for a failed spec into ARTIFACTS_DIR,
+// when set, and removes every container the spec started.
+func collectSpecContainers() {
+	names := specContainers.drain()
+
+	artifactsDir := os.Getenv("ARTIFACTS_DIR")
+	if artifactsDir != "" && CurrentGinkgoTestDescription().Failed {
+		destDir := filepath.Join(artifactsDir, specSlug())
+		for _, name := range names {
+			if err := captureContainerLogs(name, destDir); err != nil {
+				fmt.Fprintf(GinkgoWriter, "could not capture logs of %s: %s\n", name, err)
+			}
+		}
+	}
+
+	for _, name := range names {
+		cmdlog.Run(validation.Runtime.Command(context.Background(), "rm", "--force", name))
+	}
+}
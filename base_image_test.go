@@ -0,0 +1,123 @@
+package windows2016fs_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+const defaultMaxBaseImageAgeDays = 60
+
+// baseImage returns the image the Dockerfile of tag's variant under test is
+// built FROM. For multi-stage Dockerfiles that is the last stage's base.
+func baseImage(tag string) (string, error) {
+	dockerfile, err := ioutil.ReadFile(dockerfilePath(tag))
+	if err != nil {
+		return "", err
+	}
+
+	var base string
+	scanner := bufio.NewScanner(bytes.NewReader(dockerfile))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) > 0 {
+			base = args[0]
+		}
+	}
+
+	if base == "" {
+		return "", fmt.Errorf("%s has no FROM instruction", dockerfilePath(tag))
+	}
+
+	return base, nil
+}
+
+// baseImageCreated returns when image was created, pulling it first if it
+// isn't present locally.
+func baseImageCreated(image string) (time.Time, error) {
+	skipUnderCtr()
+
+	var inspection struct {
+		Created time.Time
+	}
+
+	if err := validation.InspectImage(image, &inspection); err != nil {
+		pullErr := timedCheck("pull", func(ctx context.Context) error {
+			return cmdlog.Run(validation.Runtime.Command(ctx, "pull", "--platform", targetPlatform, image))
+		})
+		if pullErr != nil {
+			return time.Time{}, fmt.Errorf("%s (pulling it failed too: %s)", err, pullErr)
+		}
+
+		if err := validation.InspectImage(image, &inspection); err != nil {
+			return time.Time{}, err
+		}
+	}
+
+	return inspection.Created, nil
+}
+
+// pinnedBaseLayers returns the diff IDs of the base image pinned in the
+// manifest of tag's variant under test, pulling it if it isn't present
+// locally, or nothing when no base image is pinned.
+func pinnedBaseLayers(tag string) (string, []string, error) {
+	skipUnderCtr()
+
+	manifest, err := builder.LoadManifest(filepath.Join(validation.VariantDir(tag, imageVariant), builder.ManifestName))
+	if err != nil || manifest.BaseImageDigest == "" {
+		return "", nil, err
+	}
+
+	base, err := baseImage(tag)
+	if err != nil {
+		return "", nil, err
+	}
+	pinned := builder.PinnedImage(base, manifest.BaseImageDigest)
+
+	layers, err := validation.ImageLayers(pinned)
+	if err != nil {
+		pullErr := timedCheck("pull", func(ctx context.Context) error {
+			return cmdlog.Run(validation.Runtime.Command(ctx, "pull", "--platform", targetPlatform, pinned))
+		})
+		if pullErr != nil {
+			return "", nil, fmt.Errorf("%s (pulling it failed too: %s)", err, pullErr)
+		}
+
+		layers, err = validation.ImageLayers(pinned)
+	}
+
+	return pinned, layers, err
+}
+
+// maxBaseImageAge returns MAX_BASE_IMAGE_AGE_DAYS as a duration.
+func maxBaseImageAge() (time.Duration, error) {
+	days := defaultMaxBaseImageAgeDays
+	if value := os.Getenv("MAX_BASE_IMAGE_AGE_DAYS"); value != "" {
+		var err error
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			return 0, fmt.Errorf("MAX_BASE_IMAGE_AGE_DAYS must be a positive number of days, got %q", value)
+		}
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
@@ -0,0 +1,180 @@
+package windows2016fs_test
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+)
+
+// buildFromTar builds tag from a tarball build context (for example the output
+// of `git archive`), streaming it to `docker build -` so that only committed
+// files end up in the image.
+func buildFromTar(tarPath, dockerfileRelPath, tag string) error {
+	if err := validateTarContext(tarPath, dockerfileRelPath); err != nil {
+		return err
+	}
+
+	tarFile, err := os.Open(tarPath)
+	if err != nil {
+		return err
+	}
+	defer tarFile.Close()
+
+	return timedCheck("build", func(ctx context.Context) error {
+		command := exec.CommandContext(
+			ctx,
+			validation.Runtime.Name(),
+			"build",
+			"-f", filepath.ToSlash(dockerfileRelPath),
+			"--tag", tag,
+			"--platform", targetPlatform,
+			"--pull",
+			"-",
+		)
+		command.Stdin = tarFile
+		command.Stdout = GinkgoWriter
+		command.Stderr = GinkgoWriter
+
+		if err := cmdlog.Run(command); err != nil {
+			return fmt.Errorf("docker build from %s failed: %s", tarPath, err)
+		}
+
+		return nil
+	})
+}
+
+// validateTarContext checks that the archive contains the Dockerfile and every
+// dependency the Dockerfile COPYs into the image.
+func validateTarContext(tarPath, dockerfileRelPath string) error {
+	entries, dockerfile, err := readTarContext(tarPath, dockerfileRelPath)
+	if err != nil {
+		return err
+	}
+
+	if dockerfile == nil {
+		return fmt.Errorf("%s does not contain %s", tarPath, dockerfileRelPath)
+	}
+
+	var missing []string
+	for _, source := range copySources(dockerfile) {
+		if !anyEntryMatches(entries, source) {
+			missing = append(missing, source)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is missing dependencies: %s", tarPath, strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+func readTarContext(tarPath, dockerfileRelPath string) ([]string, []byte, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var stream io.Reader = reader
+
+	magic, err := reader.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		stream = gz
+	}
+
+	var (
+		entries    []string
+		dockerfile []byte
+	)
+
+	wanted := path.Clean(filepath.ToSlash(dockerfileRelPath))
+	archive := tar.NewReader(stream)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %s", tarPath, err)
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		entries = append(entries, name)
+
+		if name == wanted && header.Typeflag == tar.TypeReg {
+			dockerfile, err = ioutil.ReadAll(archive)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return entries, dockerfile, nil
+}
+
+// copySources returns the source paths of every COPY instruction, ignoring
+// copies from other build stages.
+func copySources(dockerfile []byte) []string {
+	var sources []string
+
+	scanner := bufio.NewScanner(bytes.NewReader(dockerfile))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.EqualFold(fields[0], "COPY") {
+			continue
+		}
+
+		args := fields[1:]
+		fromStage := false
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			if strings.HasPrefix(args[0], "--from") {
+				fromStage = true
+			}
+			args = args[1:]
+		}
+
+		if fromStage || len(args) < 2 {
+			continue
+		}
+
+		for _, source := range args[:len(args)-1] {
+			sources = append(sources, path.Clean(strings.TrimPrefix(source, "/")))
+		}
+	}
+
+	return sources
+}
+
+func anyEntryMatches(entries []string, pattern string) bool {
+	for _, entry := range entries {
+		if matched, _ := path.Match(pattern, entry); matched {
+			return true
+		}
+		if strings.HasPrefix(entry, pattern+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// BaseImage returns the image the last stage of dockerfile is built FROM,
+// and the line of that FROM instruction.
+func BaseImage(dockerfile []byte) (string, int, error) {
+	instructions, err := ParseDockerfile(bytes.NewReader(dockerfile))
+	if err != nil {
+		return "", 0, err
+	}
+
+	for i := len(instructions) - 1; i >= 0; i-- {
+		instruction := instructions[i]
+		if instruction.Command != "FROM" {
+			continue
+		}
+
+		for _, arg := range instruction.Args {
+			if !strings.HasPrefix(arg, "--") {
+				return arg, instruction.Line, nil
+			}
+		}
+
+		return "", 0, fmt.Errorf("Dockerfile line %d: FROM names no image", instruction.Line)
+	}
+
+	return "", 0, fmt.Errorf("the Dockerfile has no FROM instruction")
+}
+
+// PinnedImage returns image, without its tag or digest, at digest, e.g.
+// mcr.microsoft.com/windows/servercore@sha256:... for
+// mcr.microsoft.com/windows/servercore:1809.
+func PinnedImage(image, digest string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	if colon := strings.LastIndex(image, ":"); colon > strings.LastIndex(image, "/") {
+		image = image[:colon]
+	}
+
+	return image + "@" + digest
+}
+
+// PinBaseImage rewrites the last FROM of dockerfile to build from its base
+// image at digest, so builds don't depend on where the tag points.
+func PinBaseImage(dockerfile []byte, digest string) ([]byte, error) {
+	image, line, err := BaseImage(dockerfile)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := bytes.Split(dockerfile, []byte("\n"))
+	i := bytes.Index(lines[line-1], []byte(image))
+	if i < 0 {
+		return nil, fmt.Errorf("Dockerfile line %d: FROM continues on the next line; keep it on one line to pin its base image", line)
+	}
+
+	pinned := append([]byte{}, lines[line-1][:i]...)
+	pinned = append(pinned, PinnedImage(image, digest)...)
+	lines[line-1] = append(pinned, lines[line-1][i+len(image):]...)
+
+	return bytes.Join(lines, []byte("\n")), nil
+}
+
+// ResolveBaseImage returns the digest the tag of image currently points
+// to. For multi-platform images that is the digest of the manifest list.
+func ResolveBaseImage(ctx context.Context, client *registry.Client, image string) (string, error) {
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+
+	_, _, descriptor, err := client.GetManifest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	return descriptor.Digest, nil
+}
+
+// PullBase pulls the base image of opts.Dockerfile, at opts.BaseImageDigest
+// when it is pinned, ahead of Build, so that the first pull of a large base
+// image can be given its own time.
+func PullBase(ctx context.Context, opts Options) error {
+	dockerfile, err := ioutil.ReadFile(opts.Dockerfile)
+	if err != nil {
+		return err
+	}
+
+	image, _, err := BaseImage(dockerfile)
+	if err != nil {
+		return err
+	}
+	if opts.BaseImageDigest != "" {
+		image = PinnedImage(image, opts.BaseImageDigest)
+	}
+
+	args := []string{"pull"}
+	if opts.Platform != "" {
+		args = append(args, "--platform", opts.Platform)
+	}
+
+	command := validation.Runtime.Command(ctx, append(args, image)...)
+	command.Stdout = opts.Stdout
+	command.Stderr = opts.Stderr
+
+	if err := cmdlog.Run(command); err != nil {
+		return fmt.Errorf("%s pull %s failed: %s", validation.Runtime.Name(), image, err)
+	}
+
+	return nil
+}
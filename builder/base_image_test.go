@@ -0,0 +1,49 @@
+package builder_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("base image pinning", func() {
+	const digest = "sha256:4f7d7dd3ed8c4c7f9e5d3f1b8a7c8ed7c0b7a0f7a8e4f2e1b6e4c5e1a0d9c8b7"
+
+	It("finds the base image of the last stage", func() {
+		image, line, err := builder.BaseImage([]byte("FROM golang:1.16 AS tools\nRUN go build\n\nFROM --platform=windows/amd64 mcr.microsoft.com/windows/servercore:1809\nCOPY --from=tools /go/bin /bin\n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(image).To(Equal("mcr.microsoft.com/windows/servercore:1809"))
+		Expect(line).To(Equal(4))
+	})
+
+	It("replaces the tag of the base image with the digest", func() {
+		pinned, err := builder.PinBaseImage([]byte("# escape=`\nFROM mcr.microsoft.com/windows/servercore:1809 AS base\n\nRUN net users\n"), digest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(pinned)).To(Equal("# escape=`\nFROM mcr.microsoft.com/windows/servercore@" + digest + " AS base\n\nRUN net users\n"))
+
+		Expect(builder.PinnedImage("localhost:5000/servercore@sha256:old", digest)).To(Equal("localhost:5000/servercore@" + digest))
+	})
+
+	It("pins the staged Dockerfile of a version with a pinned base image", func() {
+		contextDir, err := ioutil.TempDir("", "context")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(contextDir)
+
+		opts, err := builder.ForVariant(filepath.Join("..", "2019"), "nanoserver", "")
+		Expect(err).ToNot(HaveOccurred())
+		opts.ContextDir = contextDir
+		opts.BaseImageDigest = digest
+
+		Expect(builder.Stage(context.Background(), opts)).To(Succeed())
+
+		staged, err := ioutil.ReadFile(filepath.Join(contextDir, "Dockerfile"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(staged)).To(HavePrefix("FROM mcr.microsoft.com/windows/nanoserver@" + digest + "\n"))
+	})
+})
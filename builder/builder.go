@@ -0,0 +1,277 @@
+// Package builder builds windows2016fs candidate images from a version's
+// Dockerfile and a directory of dependencies, without the Ginkgo suite.
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/internal/staging"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Options describes a single candidate build.
+type Options struct {
+	// Dockerfile is the version's Dockerfile, e.g. 2019/Dockerfile.
+	Dockerfile string
+
+	// DependenciesDir holds the installers the Dockerfile copies into the
+	// image. Only the Dockerfile is staged when it is empty.
+	DependenciesDir string
+
+	// ContextDir is the directory the build context is staged in. Build
+	// stages into a temporary directory, removed afterwards, when empty.
+	ContextDir string
+
+	// Image is the reference the candidate is tagged with.
+	Image string
+
+	// Manifest, when set, lists the dependencies DependenciesDir must hold;
+	// they are verified before anything is staged.
+	Manifest *Manifest
+
+	// BaseImageDigest, when set, pins the base image of the staged
+	// Dockerfile to that digest.
+	BaseImageDigest string
+
+	// Labels are added to the candidate's configuration. When set, Build
+	// adds the build time and the base image's name and digest, pinning an
+	// unpinned base image to the digest it resolves to.
+	Labels map[string]string
+
+	// StrictDependencies fails the build when a dependency in Manifest isn't
+	// pinned.
+	StrictDependencies bool
+
+	// Platform is passed to docker build as --platform when set, and picks
+	// the base image the OCI backend pulls.
+	Platform string
+
+	// Backend is BackendDocker, the default, or BackendOCI.
+	Backend string
+
+	// LayoutDir is the OCI image layout the OCI backend writes the
+	// candidate to, as Image.
+	LayoutDir string
+
+	// Registry pulls the base image for the OCI backend; anonymous access
+	// is used when nil.
+	Registry *registry.Client
+
+	// Reuse skips the docker build when Image already exists and was built
+	// from the same inputs, as identified by CacheKey.
+	Reuse bool
+
+	// ReportPath, when set, is where a Report of the candidate is written
+	// once it is built.
+	ReportPath string
+
+	// Stdout and Stderr, when set, receive the output of staging and
+	// docker build.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// CandidateImage is the reference a tag's candidate is built as unless
+// another is given.
+func CandidateImage(tag string) string {
+	return fmt.Sprintf("windows2016fs-candidate:%s", tag)
+}
+
+// ForTag returns the options that build tag's Dockerfile as its candidate
+// image, verifying depDir against tag's manifest.
+func ForTag(tag, depDir string) (Options, error) {
+	return ForVariant(tag, validation.DefaultVariant, depDir)
+}
+
+// ForVariant returns the options that build the Dockerfile of variant for
+// tag as its candidate image, e.g. windows2016fs-candidate:2019-nanoserver.
+// Variants whose manifest lists no dependencies don't need depDir.
+func ForVariant(tag, variant, depDir string) (Options, error) {
+	if _, err := validation.VariantFor(variant); err != nil {
+		return Options{}, err
+	}
+
+	dir := validation.VariantDir(tag, variant)
+	manifest, err := LoadManifest(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return Options{}, err
+	}
+
+	labels, err := sourceLabels(dir, filepath.Join(dir, ManifestName))
+	if err != nil {
+		return Options{}, err
+	}
+
+	opts := Options{
+		Dockerfile: filepath.Join(dir, "Dockerfile"),
+		Image:      CandidateImage(validation.VariantTag(tag, variant)),
+		Labels:     labels,
+
+		BaseImageDigest: manifest.BaseImageDigest,
+	}
+	if len(manifest.Dependencies) > 0 {
+		if depDir == "" {
+			return Options{}, fmt.Errorf("%s needs a dependencies directory", dir)
+		}
+
+		opts.DependenciesDir = depDir
+		opts.Manifest = &manifest
+	}
+
+	return opts, nil
+}
+
+// Args returns the docker CLI arguments that build the staged context,
+// always pulling the base image so that candidates pick up its patches.
+func (o Options) Args() []string {
+	args := []string{"build", "-f", filepath.Join(o.ContextDir, "Dockerfile"), "--tag", o.Image}
+	if o.Platform != "" {
+		args = append(args, "--platform", o.Platform)
+	}
+	args = append(args, labelArgs(o.Labels)...)
+
+	return append(args, "--pull", o.ContextDir)
+}
+
+// Build verifies and stages the Dockerfile and dependencies and builds the
+// candidate image with opts.Backend.
+func Build(ctx context.Context, opts Options) error {
+	switch opts.Backend {
+	case "", BackendDocker, BackendOCI:
+	default:
+		return fmt.Errorf("unknown backend %q; supported backends are %s", opts.Backend, strings.Join(Backends, ", "))
+	}
+
+	if opts.Manifest != nil {
+		if err := opts.Manifest.Verify(opts.DependenciesDir, opts.StrictDependencies, opts.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if opts.Labels != nil {
+		if err := buildLabels(ctx, &opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Reuse && opts.Backend != BackendOCI {
+		key, err := CacheKey(opts)
+		if err != nil {
+			return err
+		}
+
+		if cached(opts.Image, key) {
+			if opts.Stdout != nil {
+				fmt.Fprintf(opts.Stdout, "reusing %s, built from the same inputs\n", opts.Image)
+			}
+			return writeReport(opts)
+		}
+
+		opts.Labels = withCacheKey(opts.Labels, key)
+	}
+
+	if opts.ContextDir == "" {
+		contextDir, err := ioutil.TempDir("", "build")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(contextDir)
+
+		opts.ContextDir = contextDir
+	}
+
+	if err := Stage(ctx, opts); err != nil {
+		return err
+	}
+
+	switch {
+	case opts.Backend == BackendOCI && cmdlog.DryRun != nil:
+		fmt.Fprintf(cmdlog.DryRun, "# build %s into the OCI image layout %s\n", opts.Image, opts.LayoutDir)
+	case opts.Backend == BackendOCI:
+		if err := buildLayout(ctx, opts); err != nil {
+			return err
+		}
+	default:
+		command := validation.Runtime.Command(ctx, opts.Args()...)
+		command.Stdout = opts.Stdout
+		command.Stderr = opts.Stderr
+
+		if err := cmdlog.Run(command); err != nil {
+			return fmt.Errorf("%s build of %s failed: %s", validation.Runtime.Name(), opts.Image, err)
+		}
+	}
+
+	return writeReport(opts)
+}
+
+// writeReport writes the report of the candidate opts built to
+// opts.ReportPath, when set.
+func writeReport(opts Options) error {
+	if opts.ReportPath == "" {
+		return nil
+	}
+	if cmdlog.DryRun != nil {
+		_, err := fmt.Fprintf(cmdlog.DryRun, "# write the report of %s to %s\n", opts.Image, opts.ReportPath)
+		return err
+	}
+
+	report, err := BuildReport(opts)
+	if err != nil {
+		return fmt.Errorf("reporting on %s: %s", opts.Image, err)
+	}
+
+	return report.Write(opts.ReportPath)
+}
+
+// Stage copies the Dockerfile and every dependency into opts.ContextDir,
+// reporting each staged file to opts.Stdout, and pins the base image of the
+// staged Dockerfile when opts.BaseImageDigest is set.
+func Stage(ctx context.Context, opts Options) error {
+	sources := []string{opts.Dockerfile}
+	if opts.DependenciesDir != "" {
+		if info, err := os.Stat(opts.DependenciesDir); err != nil {
+			return err
+		} else if !info.IsDir() {
+			return fmt.Errorf("%s is not a directory", opts.DependenciesDir)
+		}
+
+		sources = append(sources, opts.DependenciesDir)
+	}
+
+	if cmdlog.DryRun != nil {
+		fmt.Fprintf(cmdlog.DryRun, "# stage %s into %s\n", strings.Join(sources, " and "), opts.ContextDir)
+		if opts.BaseImageDigest != "" {
+			fmt.Fprintf(cmdlog.DryRun, "# pin the base image of %s to %s\n", filepath.Join(opts.ContextDir, "Dockerfile"), opts.BaseImageDigest)
+		}
+		return nil
+	}
+
+	if _, err := staging.Copy(ctx, opts.ContextDir, sources, opts.Stdout); err != nil {
+		return err
+	}
+
+	if opts.BaseImageDigest == "" {
+		return nil
+	}
+
+	staged := filepath.Join(opts.ContextDir, "Dockerfile")
+	dockerfile, err := ioutil.ReadFile(staged)
+	if err != nil {
+		return err
+	}
+
+	pinned, err := PinBaseImage(dockerfile, opts.BaseImageDigest)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(staged, pinned, 0644)
+}
@@ -0,0 +1,115 @@
+package builder_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Options", func() {
+	It("builds the staged Dockerfile with --pull", func() {
+		opts := builder.Options{
+			ContextDir: "context",
+			Image:      "windows2016fs-candidate:2019",
+			Platform:   "windows/amd64",
+		}
+
+		Expect(opts.Args()).To(Equal([]string{
+			"build",
+			"-f", filepath.Join("context", "Dockerfile"),
+			"--tag", "windows2016fs-candidate:2019",
+			"--platform", "windows/amd64",
+			"--pull",
+			"context",
+		}))
+	})
+
+	It("passes labels in a stable order", func() {
+		opts := builder.Options{ContextDir: "context", Image: "image", Labels: map[string]string{
+			builder.LabelRevision: "abc123",
+			builder.LabelCreated:  "2021-06-15T12:00:00Z",
+		}}
+
+		Expect(opts.Args()).To(Equal([]string{
+			"build",
+			"-f", filepath.Join("context", "Dockerfile"),
+			"--tag", "image",
+			"--label", "org.opencontainers.image.created=2021-06-15T12:00:00Z",
+			"--label", "org.opencontainers.image.revision=abc123",
+			"--pull",
+			"context",
+		}))
+	})
+
+	It("omits --platform when it isn't set", func() {
+		opts := builder.Options{ContextDir: "context", Image: "image"}
+
+		Expect(opts.Args()).ToNot(ContainElement("--platform"))
+	})
+})
+
+var _ = Describe("ForVariant", func() {
+	It("builds variants without dependencies from the Dockerfile alone", func() {
+		opts, err := builder.ForVariant(filepath.Join("..", "2019"), "nanoserver", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(opts.Dockerfile).To(Equal(filepath.Join("..", "2019", "nanoserver", "Dockerfile")))
+		Expect(opts.DependenciesDir).To(BeEmpty())
+		Expect(opts.Manifest).To(BeNil())
+	})
+
+	It("labels the candidate with the digest of its dependency manifest", func() {
+		opts, err := builder.ForVariant(filepath.Join("..", "2019"), "nanoserver", "")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(opts.Labels).To(HaveKeyWithValue(builder.LabelDependencies, MatchRegexp(`^sha256:[0-9a-f]{64}$`)))
+	})
+
+	It("needs a dependencies directory when the manifest lists dependencies", func() {
+		_, err := builder.ForTag(filepath.Join("..", "2019"), "")
+		Expect(err).To(MatchError(ContainSubstring("needs a dependencies directory")))
+	})
+})
+
+var _ = Describe("Build", func() {
+	It("prints the build without staging or running anything in a dry run", func() {
+		dir, err := ioutil.TempDir("", "dry-run")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		dockerfile := filepath.Join(dir, "Dockerfile")
+		Expect(ioutil.WriteFile(dockerfile, []byte("FROM mcr.microsoft.com/windows/servercore:ltsc2019\n"), 0644)).To(Succeed())
+		contextDir := filepath.Join(dir, "context")
+
+		var printed strings.Builder
+		cmdlog.DryRun = &printed
+		defer func() { cmdlog.DryRun = nil }()
+
+		opts := builder.Options{
+			Dockerfile:      dockerfile,
+			ContextDir:      contextDir,
+			Image:           "windows2016fs-candidate:2019",
+			BaseImageDigest: "sha256:1111",
+			ReportPath:      filepath.Join(dir, builder.ReportName),
+		}
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+
+		Expect(strings.Split(printed.String(), "\n")).To(Equal([]string{
+			"# stage " + dockerfile + " into " + contextDir,
+			"# pin the base image of " + filepath.Join(contextDir, "Dockerfile") + " to sha256:1111",
+			"docker " + strings.Join(opts.Args(), " "),
+			"# write the report of windows2016fs-candidate:2019 to " + opts.ReportPath,
+			"",
+		}))
+		Expect(contextDir).ToNot(BeADirectory())
+		Expect(opts.ReportPath).ToNot(BeAnExistingFile())
+	})
+})
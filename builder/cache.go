@@ -0,0 +1,97 @@
+package builder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// LabelCacheKey records the CacheKey of the inputs a candidate was built
+// from, so that a later build from the same inputs can reuse it.
+const LabelCacheKey = "org.cloudfoundry.windows2016fs.build-cache-key"
+
+// CacheKey identifies the inputs of a build: the Dockerfile, the dependency
+// manifest, the name and SHA256 of every file in the dependencies directory,
+// which are staged whether or not the manifest pins them, the base image
+// digest the build is pinned to and the platform. The base image only counts
+// when it is pinned or, with opts.Labels set, resolved.
+func CacheKey(opts Options) (string, error) {
+	dockerfile, err := ioutil.ReadFile(opts.Dockerfile)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := json.Marshal(opts.Manifest)
+	if err != nil {
+		return "", err
+	}
+
+	dependencies, err := dependencyHashes(opts.DependenciesDir)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, input := range [][]byte{dockerfile, manifest, dependencies, []byte(opts.BaseImageDigest), []byte(opts.Platform)} {
+		fmt.Fprintf(hash, "%x\n", sha256.Sum256(input))
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// dependencyHashes lists the SHA256 and slash-separated path of every file
+// under dir in sha256sum format, sorted by path. An empty dir has none.
+func dependencyHashes(dir string) ([]byte, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	var lines bytes.Buffer
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(&lines, "%s  %s\n", hash, filepath.ToSlash(name))
+		return nil
+	})
+
+	return lines.Bytes(), err
+}
+
+// cached reports whether image exists and was built from inputs matching
+// key.
+func cached(image, key string) bool {
+	labels, err := validation.ImageLabels(image)
+	if err != nil {
+		return false
+	}
+
+	return labels[LabelCacheKey] == key
+}
+
+// withCacheKey returns a copy of labels with key added as LabelCacheKey.
+func withCacheKey(labels map[string]string, key string) map[string]string {
+	keyed := map[string]string{LabelCacheKey: key}
+	for name, value := range labels {
+		keyed[name] = value
+	}
+
+	return keyed
+}
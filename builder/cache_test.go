@@ -0,0 +1,83 @@
+package builder_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CacheKey", func() {
+	var (
+		dir  string
+		opts builder.Options
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "cache")
+		Expect(err).ToNot(HaveOccurred())
+
+		opts = builder.Options{
+			Dockerfile:      filepath.Join(dir, "Dockerfile"),
+			DependenciesDir: filepath.Join(dir, "dependencies"),
+			Manifest: &builder.Manifest{Dependencies: []builder.Dependency{
+				{Name: "vc_redist.x64.exe", SHA256: "aaaa"},
+			}},
+			BaseImageDigest: "sha256:1111",
+			Platform:        "windows/amd64",
+		}
+		Expect(ioutil.WriteFile(opts.Dockerfile, []byte("FROM mcr.microsoft.com/windows/servercore:ltsc2019\n"), 0644)).To(Succeed())
+		Expect(os.Mkdir(opts.DependenciesDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(opts.DependenciesDir, "vc_redist.x64.exe"), []byte("vc_redist"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("is the same for the same inputs", func() {
+		key, err := builder.CacheKey(opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(key).To(MatchRegexp(`^[0-9a-f]{64}$`))
+
+		opts.Image = "another:tag"
+		opts.Labels = map[string]string{builder.LabelCreated: "2021-06-15T12:00:00Z"}
+		Expect(builder.CacheKey(opts)).To(Equal(key))
+	})
+
+	It("changes with the Dockerfile, the manifest, the dependencies and the base image", func() {
+		key, err := builder.CacheKey(opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		changes := []func(){
+			func() {
+				Expect(ioutil.WriteFile(opts.Dockerfile, []byte("FROM mcr.microsoft.com/windows/servercore:ltsc2019\nRUN echo\n"), 0644)).To(Succeed())
+			},
+			func() { opts.Manifest.Dependencies[0].SHA256 = "bbbb" },
+			func() {
+				Expect(ioutil.WriteFile(filepath.Join(opts.DependenciesDir, "vc_redist.x64.exe"), []byte("vc_redist, updated"), 0644)).To(Succeed())
+			},
+			func() {
+				Expect(ioutil.WriteFile(filepath.Join(opts.DependenciesDir, "Git-2.30.0-64-bit.exe"), []byte("git"), 0644)).To(Succeed())
+			},
+			func() {
+				Expect(os.Rename(filepath.Join(opts.DependenciesDir, "Git-2.30.0-64-bit.exe"), filepath.Join(opts.DependenciesDir, "Git-VERSION-64-bit.exe"))).To(Succeed())
+			},
+			func() { opts.BaseImageDigest = "sha256:2222" },
+			func() { opts.Platform = "windows/arm64" },
+		}
+		for _, change := range changes {
+			change()
+
+			changed, err := builder.CacheKey(opts)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changed).ToNot(Equal(key))
+			key = changed
+		}
+	})
+})
@@ -0,0 +1,64 @@
+package builder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Instruction is one instruction of a Dockerfile, with continuation lines
+// joined.
+type Instruction struct {
+	// Command is the upper-cased instruction, e.g. COPY.
+	Command string
+	Args    []string
+	Line    int
+}
+
+// ParseDockerfile returns the instructions of a Dockerfile, skipping
+// comments and blank lines. Lines ending in a backslash continue on the
+// next line.
+func ParseDockerfile(r io.Reader) ([]Instruction, error) {
+	var (
+		instructions []Instruction
+		current      strings.Builder
+		start        int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") || (text == "" && current.Len() == 0) {
+			continue
+		}
+
+		if current.Len() == 0 {
+			start = line
+		}
+
+		if strings.HasSuffix(text, `\`) {
+			current.WriteString(strings.TrimSuffix(text, `\`))
+			current.WriteString(" ")
+			continue
+		}
+		current.WriteString(text)
+
+		fields := strings.Fields(current.String())
+		current.Reset()
+		if len(fields) == 0 {
+			continue
+		}
+
+		instructions = append(instructions, Instruction{Command: strings.ToUpper(fields[0]), Args: fields[1:], Line: start})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if current.Len() > 0 {
+		return nil, fmt.Errorf("line %d continues past the end of the Dockerfile", start)
+	}
+
+	return instructions, nil
+}
@@ -0,0 +1,35 @@
+package builder_test
+
+import (
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseDockerfile", func() {
+	It("joins continuation lines and skips comments", func() {
+		instructions, err := builder.ParseDockerfile(strings.NewReader(`FROM mcr.microsoft.com/windows/servercore:1809
+
+# copy the installer
+copy Git-*-64-bit.exe /git-setup.exe
+RUN powershell.exe -Command \
+  $ErrorActionPreference = 'Stop'; \
+  Add-WindowsFeature Web-Webserver
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(instructions).To(Equal([]builder.Instruction{
+			{Command: "FROM", Args: []string{"mcr.microsoft.com/windows/servercore:1809"}, Line: 1},
+			{Command: "COPY", Args: []string{"Git-*-64-bit.exe", "/git-setup.exe"}, Line: 4},
+			{Command: "RUN", Args: []string{"powershell.exe", "-Command", "$ErrorActionPreference", "=", "'Stop';", "Add-WindowsFeature", "Web-Webserver"}, Line: 5},
+		}))
+	})
+
+	It("fails when the last line continues", func() {
+		_, err := builder.ParseDockerfile(strings.NewReader("FROM image\nRUN a \\\n"))
+		Expect(err).To(MatchError("line 2 continues past the end of the Dockerfile"))
+	})
+})
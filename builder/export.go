@@ -0,0 +1,265 @@
+package builder
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// maxArchiveMetadata bounds the size of the archive entries kept in memory
+// while verifying it: the indexes, manifests and configurations.
+const maxArchiveMetadata = 4 * validation.MiB
+
+// SaveArchive saves image from validation.Runtime to the tarball at path
+// with `docker save`: a Docker image archive, which Docker 25.0 and later
+// also lay out as an OCI image layout.
+func SaveArchive(ctx context.Context, image, path string) error {
+	save := validation.Runtime.Command(ctx, "save", "--output", path, image)
+	if output, err := cmdlog.CombinedOutput(save); err != nil {
+		return fmt.Errorf("%s save %s failed: %s: %s", validation.Runtime.Name(), image, err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// ArchiveSummary describes a verified image archive.
+type ArchiveSummary struct {
+	// Format is oci for an OCI image layout, which Docker 25.0 and later
+	// save too, and docker for an older Docker image archive.
+	Format string
+
+	Images int
+	Layers int
+
+	// Size is the size of the archive's files in bytes.
+	Size int64
+}
+
+// archiveEntry is a file of an archive with its digest.
+type archiveEntry struct {
+	digest  string
+	size    int64
+	content []byte
+}
+
+// VerifyArchive checks that every manifest, configuration and layer the
+// image archive at path refers to is in it, with the digest and size it is
+// referred to by. Layers that aren't distributable, such as the Windows base
+// layers, may be left out.
+func VerifyArchive(path string) (ArchiveSummary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ArchiveSummary{}, err
+	}
+	defer file.Close()
+
+	entries, err := readArchiveEntries(file)
+	if err != nil {
+		return ArchiveSummary{}, fmt.Errorf("reading %s: %s", path, err)
+	}
+
+	var summary ArchiveSummary
+	for _, entry := range entries {
+		summary.Size += entry.size
+	}
+
+	switch {
+	case entries["index.json"] != nil:
+		summary.Format = "oci"
+		err = verifyLayoutArchive(entries, &summary)
+	case entries["manifest.json"] != nil:
+		summary.Format = "docker"
+		err = verifyDockerArchive(entries, &summary)
+	default:
+		err = fmt.Errorf("neither an OCI image layout nor a Docker image archive")
+	}
+	if err != nil {
+		return ArchiveSummary{}, fmt.Errorf("%s: %s", path, err)
+	}
+
+	return summary, nil
+}
+
+// readArchiveEntries hashes every regular file of the tar archive r, keeping
+// the content of the small ones.
+func readArchiveEntries(r io.Reader) (map[string]*archiveEntry, error) {
+	entries := map[string]*archiveEntry{}
+
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		hash := sha256.New()
+		var kept []byte
+		if header.Size <= maxArchiveMetadata {
+			if kept, err = ioutil.ReadAll(archive); err != nil {
+				return nil, err
+			}
+			hash.Write(kept)
+		} else if _, err := io.Copy(hash, archive); err != nil {
+			return nil, err
+		}
+
+		entries[path.Clean(header.Name)] = &archiveEntry{
+			digest:  "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+			size:    header.Size,
+			content: kept,
+		}
+	}
+}
+
+func verifyLayoutArchive(entries map[string]*archiveEntry, summary *ArchiveSummary) error {
+	var index registry.Manifest
+	if err := json.Unmarshal(entries["index.json"].content, &index); err != nil {
+		return fmt.Errorf("parsing index.json: %s", err)
+	}
+
+	for _, descriptor := range index.Manifests {
+		manifest, err := layoutBlob(entries, descriptor)
+		if err != nil {
+			return err
+		}
+
+		var image registry.Manifest
+		if err := json.Unmarshal(manifest.content, &image); err != nil {
+			return fmt.Errorf("parsing manifest %s: %s", descriptor.Digest, err)
+		}
+		if image.Config == nil {
+			return fmt.Errorf("manifest %s has no configuration", descriptor.Digest)
+		}
+
+		if _, err := layoutBlob(entries, *image.Config); err != nil {
+			return err
+		}
+		for _, layer := range image.Layers {
+			if _, ok := entries[blobName(layer.Digest)]; !ok && len(layer.URLs) > 0 {
+				continue
+			}
+			if _, err := layoutBlob(entries, layer); err != nil {
+				return err
+			}
+			summary.Layers++
+		}
+
+		summary.Images++
+	}
+
+	return nil
+}
+
+// layoutBlob returns the blob of descriptor, failing unless it has the
+// digest and size descriptor gives.
+func layoutBlob(entries map[string]*archiveEntry, descriptor registry.Descriptor) (*archiveEntry, error) {
+	entry, ok := entries[blobName(descriptor.Digest)]
+	if !ok {
+		return nil, fmt.Errorf("blob %s is missing", descriptor.Digest)
+	}
+	if entry.digest != descriptor.Digest {
+		return nil, fmt.Errorf("blob %s has digest %s", descriptor.Digest, entry.digest)
+	}
+	if descriptor.Size != 0 && entry.size != descriptor.Size {
+		return nil, fmt.Errorf("blob %s has %d bytes, not %d", descriptor.Digest, entry.size, descriptor.Size)
+	}
+
+	return entry, nil
+}
+
+func blobName(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+// dockerArchiveImage is an image of a Docker image archive's manifest.json.
+type dockerArchiveImage struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+func verifyDockerArchive(entries map[string]*archiveEntry, summary *ArchiveSummary) error {
+	var images []dockerArchiveImage
+	if err := json.Unmarshal(entries["manifest.json"].content, &images); err != nil {
+		return fmt.Errorf("parsing manifest.json: %s", err)
+	}
+
+	for _, image := range images {
+		config, ok := entries[path.Clean(image.Config)]
+		if !ok {
+			return fmt.Errorf("configuration %s is missing", image.Config)
+		}
+
+		// The configuration is named after its digest.
+		name := strings.TrimSuffix(path.Base(image.Config), ".json")
+		if config.digest != "sha256:"+name {
+			return fmt.Errorf("configuration %s has digest %s", image.Config, config.digest)
+		}
+
+		var parsed struct {
+			RootFS struct {
+				DiffIDs []string `json:"diff_ids"`
+			} `json:"rootfs"`
+		}
+		if err := json.Unmarshal(config.content, &parsed); err != nil {
+			return fmt.Errorf("parsing configuration %s: %s", image.Config, err)
+		}
+		if len(parsed.RootFS.DiffIDs) != len(image.Layers) {
+			return fmt.Errorf("configuration %s has %d layers, the archive %d", image.Config, len(parsed.RootFS.DiffIDs), len(image.Layers))
+		}
+
+		// Layers are saved uncompressed, so their digests are the diff IDs.
+		for i, layer := range image.Layers {
+			entry, ok := entries[path.Clean(layer)]
+			if !ok {
+				return fmt.Errorf("layer %s is missing", layer)
+			}
+			if entry.digest != parsed.RootFS.DiffIDs[i] {
+				return fmt.Errorf("layer %s has digest %s, not %s", layer, entry.digest, parsed.RootFS.DiffIDs[i])
+			}
+			summary.Layers++
+		}
+
+		summary.Images++
+	}
+
+	return nil
+}
+
+// WriteChecksum writes the SHA-256 checksum of the file at path to
+// path.sha256, in the format of sha256sum, and returns that file's path.
+func WriteChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	checksumPath := path + ".sha256"
+	line := fmt.Sprintf("%s  %s\n", hex.EncodeToString(hash.Sum(nil)), filepath.Base(path))
+	return checksumPath, ioutil.WriteFile(checksumPath, []byte(line), 0644)
+}
@@ -0,0 +1,172 @@
+package builder_test
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/registry"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("VerifyArchive", func() {
+	var (
+		dir   string
+		files map[string][]byte
+	)
+
+	digestOf := func(content []byte) string {
+		return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+	}
+
+	descriptorOf := func(content []byte) registry.Descriptor {
+		return registry.Descriptor{Digest: digestOf(content), Size: int64(len(content))}
+	}
+
+	addBlob := func(content []byte) registry.Descriptor {
+		descriptor := descriptorOf(content)
+		files["blobs/sha256/"+strings.TrimPrefix(descriptor.Digest, "sha256:")] = content
+		return descriptor
+	}
+
+	addJSON := func(name string, v interface{}) []byte {
+		content, err := json.Marshal(v)
+		Expect(err).ToNot(HaveOccurred())
+		if name != "" {
+			files[name] = content
+		}
+		return content
+	}
+
+	writeArchive := func() string {
+		path := filepath.Join(dir, "image.tar")
+		file, err := os.Create(path)
+		Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+
+		archive := tar.NewWriter(file)
+		for name, content := range files {
+			Expect(archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := archive.Write(content)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		Expect(archive.Close()).To(Succeed())
+
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "verify-archive")
+		Expect(err).ToNot(HaveOccurred())
+
+		files = map[string][]byte{}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	Context("with an OCI image layout", func() {
+		var layer registry.Descriptor
+
+		BeforeEach(func() {
+			layer = addBlob([]byte("layer"))
+			foreign := descriptorOf([]byte("base layer"))
+			foreign.URLs = []string{"https://mcr.microsoft.com/base"}
+
+			manifest := addBlob(addJSON("", registry.Manifest{
+				SchemaVersion: 2,
+				Config:        &registry.Descriptor{Digest: addBlob([]byte("{}")).Digest, Size: 2},
+				Layers:        []registry.Descriptor{foreign, layer},
+			}))
+			files["oci-layout"] = []byte(`{"imageLayoutVersion":"1.0.0"}`)
+			addJSON("index.json", registry.Manifest{SchemaVersion: 2, Manifests: []registry.Descriptor{manifest}})
+		})
+
+		It("checks every blob, leaving out foreign layers", func() {
+			summary, err := builder.VerifyArchive(writeArchive())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Format).To(Equal("oci"))
+			Expect(summary.Images).To(Equal(1))
+			Expect(summary.Layers).To(Equal(1))
+		})
+
+		It("fails when a layer's digest is wrong", func() {
+			files["blobs/sha256/"+strings.TrimPrefix(layer.Digest, "sha256:")] = []byte("tampered")
+
+			_, err := builder.VerifyArchive(writeArchive())
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("blob %s has digest %s", layer.Digest, digestOf([]byte("tampered"))))))
+		})
+
+		It("fails when a layer is missing", func() {
+			delete(files, "blobs/sha256/"+strings.TrimPrefix(layer.Digest, "sha256:"))
+
+			_, err := builder.VerifyArchive(writeArchive())
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("blob %s is missing", layer.Digest))))
+		})
+	})
+
+	Context("with a Docker image archive", func() {
+		BeforeEach(func() {
+			config := addJSON("", map[string]interface{}{
+				"rootfs": map[string]interface{}{"type": "layers", "diff_ids": []string{digestOf([]byte("layer"))}},
+			})
+			configName := strings.TrimPrefix(digestOf(config), "sha256:") + ".json"
+			files[configName] = config
+			files["abc/layer.tar"] = []byte("layer")
+			addJSON("manifest.json", []map[string]interface{}{
+				{"Config": configName, "RepoTags": []string{"windows2016fs-candidate:2019"}, "Layers": []string{"abc/layer.tar"}},
+			})
+		})
+
+		It("checks the configuration and layers against their digests", func() {
+			summary, err := builder.VerifyArchive(writeArchive())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(summary.Format).To(Equal("docker"))
+			Expect(summary.Images).To(Equal(1))
+			Expect(summary.Layers).To(Equal(1))
+		})
+
+		It("fails when a layer doesn't match its diff ID", func() {
+			files["abc/layer.tar"] = []byte("tampered")
+
+			_, err := builder.VerifyArchive(writeArchive())
+			Expect(err).To(MatchError(ContainSubstring("layer abc/layer.tar has digest " + digestOf([]byte("tampered")))))
+		})
+	})
+
+	It("fails on an archive that isn't an image", func() {
+		files["readme.txt"] = []byte("hello")
+
+		_, err := builder.VerifyArchive(writeArchive())
+		Expect(err).To(MatchError(ContainSubstring("neither an OCI image layout nor a Docker image archive")))
+	})
+})
+
+var _ = Describe("WriteChecksum", func() {
+	It("writes the file's checksum in the format of sha256sum", func() {
+		dir, err := ioutil.TempDir("", "checksum")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "image.tar")
+		Expect(ioutil.WriteFile(path, []byte("image"), 0644)).To(Succeed())
+
+		checksumPath, err := builder.WriteChecksum(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checksumPath).To(Equal(path + ".sha256"))
+
+		content, err := ioutil.ReadFile(checksumPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal(fmt.Sprintf("%x  image.tar\n", sha256.Sum256([]byte("image")))))
+	})
+})
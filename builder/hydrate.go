@@ -0,0 +1,298 @@
+package builder
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// DownloadRetry fills in the attempts and backoff that HydrateOptions.Retry
+// leaves unset.
+var DownloadRetry = validation.RetryPolicy{Attempts: 5, Backoff: 5 * time.Second}
+
+// HydrateOptions configures how Hydrate downloads dependencies.
+type HydrateOptions struct {
+	// Workers is the number of concurrent downloads, at least one.
+	Workers int
+
+	// Retry is how downloads that fail transiently are retried. Its
+	// Transient is ignored: every failure but a permanent one, such as a
+	// 404, is retried.
+	Retry validation.RetryPolicy
+
+	// Mirrors rewrite dependency URLs before they are downloaded.
+	Mirrors []Mirror
+
+	// Log receives progress when it is non-nil.
+	Log io.Writer
+}
+
+// downloadClient fetches dependencies, honouring HTTP_PROXY, HTTPS_PROXY and
+// NO_PROXY.
+var downloadClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSHandshakeTimeout: 30 * time.Second,
+	},
+}
+
+// partialSuffix marks an incomplete download, which the next attempt resumes
+// with an HTTP range request. Downloads that Hydrate gives up on are removed,
+// but one interrupted by the context is kept for the next run to resume.
+const partialSuffix = ".partial"
+
+// Hydrate downloads every dependency in the manifest into dir. Pinned
+// dependencies are verified as they are downloaded and skipped when dir
+// already holds a matching copy; unpinned ones are skipped whenever dir holds
+// them. Interrupted downloads are resumed and retried with exponential
+// backoff.
+func Hydrate(ctx context.Context, manifest Manifest, dir string, opts HydrateOptions) error {
+	if opts.Log == nil {
+		opts.Log = ioutil.Discard
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.Retry.Attempts < 1 {
+		opts.Retry.Attempts = DownloadRetry.Attempts
+	}
+	if opts.Retry.Backoff <= 0 {
+		opts.Retry.Backoff = DownloadRetry.Backoff
+	}
+	opts.Retry.Transient = func(err error) bool {
+		var permanent permanentError
+		return !errors.As(err, &permanent)
+	}
+
+	var (
+		queue    = make(chan Dependency)
+		mutex    sync.Mutex
+		problems []string
+		wait     sync.WaitGroup
+	)
+
+	opts.Log = &lockedWriter{w: opts.Log, mutex: &mutex}
+
+	for i := 0; i < opts.Workers; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+
+			for dependency := range queue {
+				message, err := hydrate(ctx, dependency, dir, opts)
+				if err != nil {
+					mutex.Lock()
+					problems = append(problems, fmt.Sprintf("%s: %s", dependency.Name, err))
+					mutex.Unlock()
+					continue
+				}
+
+				fmt.Fprintf(opts.Log, "%s: %s\n", dependency.Name, message)
+			}
+		}()
+	}
+
+	for _, dependency := range manifest.Dependencies {
+		queue <- dependency
+	}
+	close(queue)
+	wait.Wait()
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("hydrating %s failed:\n%s", dir, strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// hydrate brings a single dependency into dir, returning what it did.
+func hydrate(ctx context.Context, dependency Dependency, dir string, opts HydrateOptions) (string, error) {
+	path := filepath.Join(dir, dependency.Name)
+
+	if info, err := os.Stat(path); err == nil {
+		if !dependency.pinned() {
+			return "present, not pinned", nil
+		}
+		if info.Size() == dependency.Size {
+			if hash, err := hashFile(path); err == nil && hash == strings.ToLower(dependency.SHA256) {
+				return "present", nil
+			}
+		}
+	}
+
+	if dependency.URL == "" {
+		return "", fmt.Errorf("no url to download from")
+	}
+
+	if url := mirrorURL(opts.Mirrors, dependency.URL); url != dependency.URL {
+		fmt.Fprintf(opts.Log, "%s: downloading from mirror %s\n", dependency.Name, url)
+		dependency.URL = url
+	}
+
+	var size int64
+	var digest string
+	err := opts.Retry.Do(ctx, opts.Log, dependency.Name, func() error {
+		var err error
+		size, digest, err = download(ctx, dependency, path+partialSuffix, opts.Log)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			os.Remove(path + partialSuffix)
+		}
+		return "", err
+	}
+
+	if err := os.Rename(path+partialSuffix, path); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("downloaded %d bytes, SHA256 %s", size, digest), nil
+}
+
+// permanentError is a download failure that retrying won't fix, such as a
+// 404.
+type permanentError struct {
+	error
+}
+
+// download fetches dependency into partialPath, resuming from whatever an
+// earlier attempt left there, and verifies the complete file when the
+// dependency is pinned. A download that fails verification is discarded.
+func download(ctx context.Context, dependency Dependency, partialPath string, log io.Writer) (int64, string, error) {
+	var offset int64
+	if info, err := os.Stat(partialPath); err == nil {
+		offset = info.Size()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, dependency.URL, nil)
+	if err != nil {
+		return 0, "", permanentError{err}
+	}
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	response, err := downloadClient.Do(request)
+	if err != nil {
+		return 0, "", err
+	}
+	defer response.Body.Close()
+
+	hash := sha256.New()
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	switch {
+	case response.StatusCode == http.StatusPartialContent && offset > 0:
+		if err := hashInto(hash, partialPath); err != nil {
+			return 0, "", err
+		}
+		flags = os.O_WRONLY | os.O_APPEND
+	case response.StatusCode == http.StatusOK:
+		offset = 0
+	case response.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		os.Remove(partialPath)
+		return 0, "", fmt.Errorf("GET %s refused to resume at byte %d", dependency.URL, offset)
+	case response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests:
+		return 0, "", fmt.Errorf("GET %s returned %s", dependency.URL, response.Status)
+	default:
+		return 0, "", permanentError{fmt.Errorf("GET %s returned %s", dependency.URL, response.Status)}
+	}
+
+	file, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return 0, "", err
+	}
+
+	total := dependency.Size
+	if response.ContentLength >= 0 {
+		total = offset + response.ContentLength
+	}
+	progress := &progressWriter{log: log, name: dependency.Name, written: offset, total: total}
+
+	_, err = io.Copy(io.MultiWriter(file, hash, progress), response.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("downloading %s failed after %d bytes: %s", dependency.URL, progress.written, err)
+	}
+
+	size := progress.written
+	digest := fmt.Sprintf("%x", hash.Sum(nil))
+	if dependency.pinned() && (size != dependency.Size || digest != strings.ToLower(dependency.SHA256)) {
+		os.Remove(partialPath)
+		return 0, "", fmt.Errorf("download from %s is %d bytes with SHA256 %s, expected %d bytes with SHA256 %s", dependency.URL, size, digest, dependency.Size, dependency.SHA256)
+	}
+
+	return size, digest, nil
+}
+
+func hashInto(hash hash.Hash, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(hash, file)
+	return err
+}
+
+// progressWriter logs each tenth of a download of known size, or each
+// progressInterval bytes otherwise.
+type progressWriter struct {
+	log      io.Writer
+	name     string
+	written  int64
+	total    int64
+	reported int64
+}
+
+const progressInterval = 10 << 20
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	p.written += int64(len(data))
+
+	step := int64(progressInterval)
+	if p.total > 0 {
+		step = p.total / 10
+	}
+
+	if step > 0 && p.written/step > p.reported/step {
+		p.reported = p.written
+		if p.total > 0 {
+			fmt.Fprintf(p.log, "%s: %d of %d bytes\n", p.name, p.written, p.total)
+		} else {
+			fmt.Fprintf(p.log, "%s: %d bytes\n", p.name, p.written)
+		}
+	}
+
+	return len(data), nil
+}
+
+type lockedWriter struct {
+	w     io.Writer
+	mutex *sync.Mutex
+}
+
+func (l *lockedWriter) Write(data []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.w.Write(data)
+}
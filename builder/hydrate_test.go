@@ -0,0 +1,177 @@
+package builder_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hydrate", func() {
+	var (
+		depDir   string
+		server   *httptest.Server
+		requests int32
+		opts     builder.HydrateOptions
+	)
+
+	BeforeEach(func() {
+		var err error
+		depDir, err = ioutil.TempDir("", "hydrate")
+		Expect(err).ToNot(HaveOccurred())
+
+		opts = builder.HydrateOptions{Workers: 1, Retry: validation.RetryPolicy{Attempts: 5, Backoff: time.Millisecond}}
+
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count := atomic.AddInt32(&requests, 1)
+			switch r.URL.Path {
+			case "/tar.exe":
+				w.Write([]byte("tar"))
+			case "/rewrite.msi":
+				w.Write([]byte("rewrite"))
+			case "/flaky.msi":
+				if count < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Write([]byte("rewrite"))
+			case "/truncated.msi":
+				if r.Header.Get("Range") == "" {
+					w.Header().Set("Content-Length", "7")
+					w.Write([]byte("rew"))
+					return
+				}
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader([]byte("rewrite")))
+			case "/withdrawn.msi":
+				if r.Header.Get("Range") == "" {
+					w.Header().Set("Content-Length", "7")
+					w.Write([]byte("rew"))
+					return
+				}
+				http.NotFound(w, r)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(depDir)).To(Succeed())
+	})
+
+	It("downloads and verifies every dependency", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "tar-VERSION.exe", URL: server.URL + "/tar.exe", Size: 3, SHA256: "90aebae315675cbf04612de4f7d5874850f48e0b8dd82becbeaa47ca93f5ebfb"},
+			{Name: "rewrite.msi", URL: server.URL + "/rewrite.msi"},
+		}}
+
+		opts.Workers = 2
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(Succeed())
+		Expect(manifest.Verify(depDir, false, nil)).To(Succeed())
+
+		contents, err := ioutil.ReadFile(filepath.Join(depDir, "rewrite.msi"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("rewrite"))
+	})
+
+	It("skips dependencies that are already present", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "tar-VERSION.exe", URL: server.URL + "/tar.exe", Size: 3, SHA256: "90aebae315675cbf04612de4f7d5874850f48e0b8dd82becbeaa47ca93f5ebfb"},
+		}}
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "tar-VERSION.exe"), []byte("tar"), 0644)).To(Succeed())
+
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(Succeed())
+		Expect(atomic.LoadInt32(&requests)).To(BeZero())
+	})
+
+	It("leaves nothing behind for downloads that fail verification", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "tar-VERSION.exe", URL: server.URL + "/tar.exe", Size: 3, SHA256: "0000000000000000000000000000000000000000000000000000000000000000"},
+			{Name: "missing.exe", URL: server.URL + "/missing.exe"},
+		}}
+
+		opts.Workers = 2
+		err := builder.Hydrate(context.Background(), manifest, depDir, opts)
+		Expect(err).To(MatchError(ContainSubstring("tar-VERSION.exe: download from")))
+		Expect(err).To(MatchError(ContainSubstring("missing.exe: GET")))
+
+		entries, err := ioutil.ReadDir(depDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("removes the partial download of a dependency it gives up on", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "rewrite.msi", URL: server.URL + "/withdrawn.msi"},
+		}}
+
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(MatchError(ContainSubstring("rewrite.msi: GET")))
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(2))
+
+		entries, err := ioutil.ReadDir(depDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("gives up after the configured number of attempts", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "rewrite.msi", URL: server.URL + "/flaky.msi"},
+		}}
+
+		opts.Retry.Attempts = 2
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(MatchError(ContainSubstring("503")))
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(2))
+	})
+
+	It("resumes interrupted downloads", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "rewrite.msi", URL: server.URL + "/truncated.msi", Size: 7, SHA256: "ac92c1ce78bc97a7cece787c097bba16839f44163fc2dd7f61a9fbb3c7053ede"},
+		}}
+
+		var log bytes.Buffer
+		opts.Log = &log
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(Succeed())
+		Expect(log.String()).To(ContainSubstring("rewrite.msi: attempt 1 of 5 failed"))
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(2))
+		Expect(manifest.Verify(depDir, true, nil)).To(Succeed())
+	})
+
+	It("retries transient failures and gives up on permanent ones", func() {
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "rewrite.msi", URL: server.URL + "/flaky.msi"},
+		}}
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(Succeed())
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(3))
+
+		requests = 0
+		manifest = builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "missing.exe", URL: server.URL + "/missing.exe"},
+		}}
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).ToNot(Succeed())
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(1))
+	})
+
+	It("downloads from mirrors while verifying the published checksums", func() {
+		opts.Mirrors = []builder.Mirror{{Prefix: "https://download.example/", Replacement: server.URL + "/"}}
+
+		manifest := builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "tar-VERSION.exe", URL: "https://download.example/tar.exe", Size: 3, SHA256: "90aebae315675cbf04612de4f7d5874850f48e0b8dd82becbeaa47ca93f5ebfb"},
+		}}
+
+		Expect(builder.Hydrate(context.Background(), manifest, depDir, opts)).To(Succeed())
+		Expect(manifest.Verify(depDir, true, nil)).To(Succeed())
+	})
+})
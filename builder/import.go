@@ -0,0 +1,256 @@
+package builder
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// ExportLayout writes image, named by the org.opencontainers.image.ref.name
+// annotation of its manifest in the OCI image layout at dir, to w as an OCI
+// image archive holding only that image.
+func ExportLayout(dir, image string, w io.Writer) error {
+	var index registry.Manifest
+	if err := readLayoutJSON(filepath.Join(dir, "index.json"), &index); err != nil {
+		return err
+	}
+
+	var descriptor *registry.Descriptor
+	for i := range index.Manifests {
+		if index.Manifests[i].Annotations["org.opencontainers.image.ref.name"] == image {
+			descriptor = &index.Manifests[i]
+		}
+	}
+	if descriptor == nil {
+		return fmt.Errorf("%s has no image %s", dir, image)
+	}
+
+	var manifest registry.Manifest
+	if err := readBlob(dir, descriptor.Digest, &manifest); err != nil {
+		return err
+	}
+	if manifest.Config == nil {
+		return fmt.Errorf("manifest %s has no configuration", descriptor.Digest)
+	}
+
+	blobs := []string{descriptor.Digest, manifest.Config.Digest}
+	for _, layer := range manifest.Layers {
+		blobs = append(blobs, layer.Digest)
+	}
+
+	exported, err := json.Marshal(registry.Manifest{SchemaVersion: 2, MediaType: registry.MediaTypeOCIIndex, Manifests: []registry.Descriptor{*descriptor}})
+	if err != nil {
+		return err
+	}
+
+	archive := tar.NewWriter(w)
+	if err := addArchiveContent(archive, "oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)); err != nil {
+		return err
+	}
+	if err := addArchiveContent(archive, "index.json", exported); err != nil {
+		return err
+	}
+	for _, digest := range blobs {
+		name := "blobs/sha256/" + strings.TrimPrefix(digest, "sha256:")
+		if err := addArchiveFile(archive, filepath.Join(dir, filepath.FromSlash(name)), name); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// ImportLayout imports image from the OCI image layout at dir into
+// validation.Runtime, such as containerd through ctr on workers without a
+// Docker daemon, so that its containers can run there.
+func ImportLayout(ctx context.Context, dir, image string) error {
+	file, err := ioutil.TempFile("", "windows2016fs-import-*.tar")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = ExportLayout(dir, image, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	args := validation.Runtime.ImportArgs(file.Name(), image)
+	if output, err := cmdlog.CombinedOutput(validation.Runtime.Command(ctx, args...)); err != nil {
+		return fmt.Errorf("%s %s failed: %s: %s", validation.Runtime.Name(), strings.Join(args[:len(args)-1], " "), err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// SaveLayout saves image from validation.Runtime into dir as an OCI image
+// layout, for tools that read one, such as groot-windows. It relies on
+// docker save writing an OCI image layout, which Docker does since 25.0.
+func SaveLayout(ctx context.Context, image, dir string) error {
+	file, err := ioutil.TempFile("", "windows2016fs-save-*.tar")
+	if err != nil {
+		return err
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	if err := SaveArchive(ctx, image, file.Name()); err != nil {
+		return err
+	}
+
+	archive, err := os.Open(file.Name())
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	if err := extractArchive(archive, dir); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"oci-layout", "index.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("%s save %s didn't write an OCI image layout, which needs Docker 25.0 or later: no %s", validation.Runtime.Name(), image, name)
+		}
+	}
+
+	return nil
+}
+
+// extractArchive writes the directories and regular files of the tar
+// archive r to dir, refusing entries that would land outside it.
+func extractArchive(r io.Reader, dir string) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+		if path != filepath.Clean(dir) && !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s is outside of %s", header.Name, dir)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			if err := writeArchiveFile(archive, path); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeArchiveFile(r io.Reader, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func readLayoutJSON(path string, v interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return nil
+}
+
+func readBlob(dir, digest string, v interface{}) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")))
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("parsing blob %s: %s", digest, err)
+	}
+
+	return nil
+}
+
+func addArchiveContent(archive *tar.Writer, name string, content []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(content)),
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := archive.Write(content)
+	return err
+}
+
+// addArchiveFile writes file to archive as name, with a fixed modification
+// time so that the same layout produces the same archive.
+func addArchiveFile(archive *tar.Writer, file, name string) error {
+	source, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	info, err := source.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", file)
+	}
+
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     info.Size(),
+		ModTime:  time.Unix(0, 0),
+		Format:   tar.FormatPAX,
+	}
+	if err := archive.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(archive, source)
+	return err
+}
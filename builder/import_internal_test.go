@@ -0,0 +1,50 @@
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("extractArchive", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "extract")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	archiveOf := func(names ...string) *bytes.Buffer {
+		var buf bytes.Buffer
+		archive := tar.NewWriter(&buf)
+		for _, name := range names {
+			Expect(addArchiveContent(archive, name, []byte(name))).To(Succeed())
+		}
+		Expect(archive.Close()).To(Succeed())
+		return &buf
+	}
+
+	It("writes the files of an archive under dir", func() {
+		Expect(extractArchive(archiveOf("oci-layout", "blobs/sha256/abc"), dir)).To(Succeed())
+
+		content, err := ioutil.ReadFile(filepath.Join(dir, "blobs", "sha256", "abc"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("blobs/sha256/abc"))
+		Expect(filepath.Join(dir, "oci-layout")).To(BeARegularFile())
+	})
+
+	It("refuses entries outside of dir", func() {
+		Expect(extractArchive(archiveOf("../escaped"), dir)).To(MatchError(ContainSubstring("archive entry ../escaped is outside of")))
+		Expect(filepath.Join(filepath.Dir(dir), "escaped")).ToNot(BeAnExistingFile())
+	})
+})
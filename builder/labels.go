@@ -0,0 +1,113 @@
+package builder
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// Labels describing where a candidate came from.
+const (
+	LabelCreated    = "org.opencontainers.image.created"
+	LabelRevision   = "org.opencontainers.image.revision"
+	LabelBaseName   = "org.opencontainers.image.base.name"
+	LabelBaseDigest = "org.opencontainers.image.base.digest"
+
+	// LabelDependencies is the SHA256 of the dependency manifest the
+	// candidate's dependencies were verified against.
+	LabelDependencies = "org.cloudfoundry.windows2016fs.dependencies.digest"
+)
+
+// Revision returns the git commit the repository at dir is checked out at,
+// or $GIT_COMMIT when it is set, for checkouts without git metadata.
+func Revision(dir string) (string, error) {
+	if commit := os.Getenv("GIT_COMMIT"); commit != "" {
+		return commit, nil
+	}
+
+	output, err := cmdlog.Output(exec.Command("git", "-C", dir, "rev-parse", "HEAD"))
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse HEAD in %s failed: %s", dir, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// sourceLabels returns the labels of a build from dir, with the dependency
+// manifest at manifestPath. The revision is left out when dir isn't a git
+// checkout.
+func sourceLabels(dir, manifestPath string) (map[string]string, error) {
+	hash, err := hashFile(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := map[string]string{LabelDependencies: "sha256:" + hash}
+	if revision, err := Revision(dir); err == nil && revision != "" {
+		labels[LabelRevision] = revision
+	}
+
+	return labels, nil
+}
+
+// buildLabels adds the build time and the base image's name and digest to
+// opts.Labels. An unpinned base image is resolved, and the build
+// pinned to it, so the digest is the one built from.
+func buildLabels(ctx context.Context, opts *Options) error {
+	dockerfile, err := ioutil.ReadFile(opts.Dockerfile)
+	if err != nil {
+		return err
+	}
+
+	base, _, err := BaseImage(dockerfile)
+	if err != nil {
+		return err
+	}
+
+	if opts.BaseImageDigest == "" {
+		client := opts.Registry
+		if client == nil {
+			client = &registry.Client{}
+		}
+
+		if opts.BaseImageDigest, err = ResolveBaseImage(ctx, client, base); err != nil {
+			return fmt.Errorf("resolving the base image %s: %s", base, err)
+		}
+	}
+
+	labels := map[string]string{}
+	for name, value := range opts.Labels {
+		labels[name] = value
+	}
+	labels[LabelCreated] = time.Now().UTC().Format(time.RFC3339)
+	labels[LabelBaseName] = base
+	labels[LabelBaseDigest] = opts.BaseImageDigest
+
+	opts.Labels = labels
+	return nil
+}
+
+// labelArgs returns the --label arguments of docker build for labels, in a
+// stable order.
+func labelArgs(labels map[string]string) []string {
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var args []string
+	for _, name := range names {
+		args = append(args, "--label", name+"="+labels[name])
+	}
+
+	return args
+}
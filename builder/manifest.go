@@ -0,0 +1,241 @@
+package builder
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestName is the dependency manifest kept in each version directory,
+// e.g. 2019/deps.json.
+const ManifestName = "deps.json"
+
+// ChecksumsName is the sha256sum-style file, one "<sha256>  <file name>" line
+// per dependency, that a dependencies directory may hold. It pins the
+// dependencies the manifest doesn't.
+const ChecksumsName = "dependencies.sha256"
+
+// Dependency is an artifact expected in the dependencies directory. A
+// dependency is pinned once its SHA256 and size are recorded.
+type Dependency struct {
+	Name   string `json:"name"`
+	URL    string `json:"url,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+func (d Dependency) pinned() bool {
+	return d.SHA256 != "" && d.Size > 0
+}
+
+// Manifest lists every dependency of a version.
+type Manifest struct {
+	// BaseImageDigest, when set, pins the base image the Dockerfile is
+	// built FROM to a digest instead of its tag.
+	BaseImageDigest string `json:"base_image_digest,omitempty"`
+
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// ManifestPath returns the path of tag's dependency manifest.
+func ManifestPath(tag string) string {
+	return filepath.Join(tag, ManifestName)
+}
+
+// LoadManifest reads the manifest at path.
+func LoadManifest(path string) (Manifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return Manifest{}, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	for _, dependency := range manifest.Dependencies {
+		if dependency.Name == "" || filepath.Base(dependency.Name) != dependency.Name {
+			return Manifest{}, fmt.Errorf("%s: invalid dependency name %q", path, dependency.Name)
+		}
+	}
+
+	return manifest, nil
+}
+
+// Write records the manifest at path.
+func (m Manifest) Write(path string) error {
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Pin records the size and SHA256 of every dependency as found in dir.
+func (m Manifest) Pin(dir string) (Manifest, error) {
+	pinned := Manifest{BaseImageDigest: m.BaseImageDigest}
+	for _, dependency := range m.Dependencies {
+		path := filepath.Join(dir, dependency.Name)
+		info, err := os.Stat(path)
+		if err != nil {
+			return Manifest{}, err
+		}
+
+		hash, err := hashFile(path)
+		if err != nil {
+			return Manifest{}, err
+		}
+
+		dependency.Size = info.Size()
+		dependency.SHA256 = hash
+		pinned.Dependencies = append(pinned.Dependencies, dependency)
+	}
+
+	return pinned, nil
+}
+
+// Verify checks that dir holds exactly the manifest's dependencies and that
+// each pinned dependency has its recorded size and SHA256, logging every hash
+// to log in sha256sum format. A ChecksumsName file in dir pins the
+// dependencies the manifest doesn't, and downloads Hydrate left unfinished
+// are ignored. With strict, unpinned dependencies fail too.
+func (m Manifest) Verify(dir string, strict bool, log io.Writer) error {
+	if log == nil {
+		log = ioutil.Discard
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	present := map[string]os.FileInfo{}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), partialSuffix) {
+			continue
+		}
+		present[entry.Name()] = entry
+	}
+
+	var problems []string
+	checksums := map[string]string{}
+	if _, ok := present[ChecksumsName]; ok {
+		delete(present, ChecksumsName)
+
+		checksums, err = readChecksums(filepath.Join(dir, ChecksumsName))
+		if err != nil {
+			return err
+		}
+	}
+
+	listed := map[string]bool{}
+	for _, dependency := range m.Dependencies {
+		listed[dependency.Name] = true
+
+		expected := strings.ToLower(dependency.SHA256)
+		if checksum, ok := checksums[dependency.Name]; ok {
+			if expected != "" && checksum != expected {
+				problems = append(problems, fmt.Sprintf("%s has SHA256 %s in %s, but %s in the manifest", dependency.Name, checksum, ChecksumsName, expected))
+			}
+			if expected == "" {
+				expected = checksum
+			}
+		}
+
+		info, ok := present[dependency.Name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is missing", dependency.Name))
+			continue
+		case !info.Mode().IsRegular():
+			problems = append(problems, fmt.Sprintf("%s is not a regular file", dependency.Name))
+			continue
+		case dependency.Size > 0 && info.Size() != dependency.Size:
+			problems = append(problems, fmt.Sprintf("%s is %d bytes, expected %d", dependency.Name, info.Size(), dependency.Size))
+			continue
+		}
+
+		hash, err := hashFile(filepath.Join(dir, dependency.Name))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(log, "%s  %s\n", hash, dependency.Name)
+
+		switch {
+		case expected != "" && hash != expected:
+			problems = append(problems, fmt.Sprintf("%s has SHA256 %s, expected %s", dependency.Name, hash, expected))
+		case expected == "" && strict:
+			problems = append(problems, fmt.Sprintf("%s is not pinned", dependency.Name))
+		}
+	}
+
+	for name := range present {
+		if !listed[name] {
+			problems = append(problems, fmt.Sprintf("%s is not in the manifest", name))
+		}
+	}
+	for name := range checksums {
+		if !listed[name] {
+			problems = append(problems, fmt.Sprintf("%s is in %s but not in the manifest", name, ChecksumsName))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("dependencies in %s failed verification:\n%s", dir, strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
+
+// readChecksums parses a sha256sum-style file into the SHA256 of each file
+// name, ignoring blank lines and # comments.
+func readChecksums(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	checksums := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: malformed line %q", path, line)
+		}
+
+		checksums[strings.TrimPrefix(fields[1], "*")] = strings.ToLower(fields[0])
+	}
+
+	return checksums, scanner.Err()
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
@@ -0,0 +1,126 @@
+package builder_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	var (
+		depDir   string
+		manifest builder.Manifest
+	)
+
+	BeforeEach(func() {
+		var err error
+		depDir, err = ioutil.TempDir("", "deps")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "rewrite.msi"), []byte("rewrite"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "tar-VERSION.exe"), []byte("tar"), 0644)).To(Succeed())
+
+		manifest = builder.Manifest{Dependencies: []builder.Dependency{
+			{Name: "rewrite.msi", URL: "https://example.com/rewrite.msi"},
+			{Name: "tar-VERSION.exe"},
+		}}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(depDir)).To(Succeed())
+	})
+
+	It("pins the size and SHA256 of every dependency", func() {
+		pinned, err := manifest.Pin(depDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(pinned.Dependencies).To(Equal([]builder.Dependency{
+			{Name: "rewrite.msi", URL: "https://example.com/rewrite.msi", Size: 7, SHA256: "ac92c1ce78bc97a7cece787c097bba16839f44163fc2dd7f61a9fbb3c7053ede"},
+			{Name: "tar-VERSION.exe", Size: 3, SHA256: "90aebae315675cbf04612de4f7d5874850f48e0b8dd82becbeaa47ca93f5ebfb"},
+		}))
+		Expect(pinned.Verify(depDir, true, nil)).To(Succeed())
+	})
+
+	It("only checks that unpinned dependencies are present unless strict", func() {
+		Expect(manifest.Verify(depDir, false, nil)).To(Succeed())
+		Expect(manifest.Verify(depDir, true, nil)).To(MatchError(ContainSubstring("rewrite.msi is not pinned")))
+	})
+
+	It("reports missing, extra, truncated and corrupted dependencies", func() {
+		pinned, err := manifest.Pin(depDir)
+		Expect(err).ToNot(HaveOccurred())
+		pinned.Dependencies = append(pinned.Dependencies, builder.Dependency{Name: "vcredist-2010.x64.exe"})
+
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "rewrite.msi"), []byte("rewr"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "tar-VERSION.exe"), []byte("TAR"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "notes.txt"), nil, 0644)).To(Succeed())
+
+		err = pinned.Verify(depDir, false, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("notes.txt is not in the manifest"))
+		Expect(err.Error()).To(ContainSubstring("rewrite.msi is 4 bytes, expected 7"))
+		Expect(err.Error()).To(ContainSubstring("tar-VERSION.exe has SHA256"))
+		Expect(err.Error()).To(ContainSubstring("vcredist-2010.x64.exe is missing"))
+	})
+
+	It("ignores downloads hydrate left unfinished", func() {
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "vcredist-2010.x64.exe.partial"), []byte("vc"), 0644)).To(Succeed())
+
+		Expect(manifest.Verify(depDir, false, nil)).To(Succeed())
+	})
+
+	It("pins the dependencies the manifest doesn't from a dependencies.sha256 file", func() {
+		checksums := "ac92c1ce78bc97a7cece787c097bba16839f44163fc2dd7f61a9fbb3c7053ede  rewrite.msi\n" +
+			"90aebae315675cbf04612de4f7d5874850f48e0b8dd82becbeaa47ca93f5ebfb *tar-VERSION.exe\n"
+		Expect(ioutil.WriteFile(filepath.Join(depDir, builder.ChecksumsName), []byte(checksums), 0644)).To(Succeed())
+		Expect(manifest.Verify(depDir, true, nil)).To(Succeed())
+
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "rewrite.msi"), []byte("rewr"), 0644)).To(Succeed())
+		Expect(manifest.Verify(depDir, false, nil)).To(MatchError(ContainSubstring("rewrite.msi has SHA256")))
+	})
+
+	It("reports dependencies.sha256 entries that disagree with or are missing from the manifest", func() {
+		pinned, err := manifest.Pin(depDir)
+		Expect(err).ToNot(HaveOccurred())
+
+		checksums := "0000000000000000000000000000000000000000000000000000000000000000  rewrite.msi\n" +
+			"1111111111111111111111111111111111111111111111111111111111111111  vcredist-2010.x64.exe\n"
+		Expect(ioutil.WriteFile(filepath.Join(depDir, builder.ChecksumsName), []byte(checksums), 0644)).To(Succeed())
+
+		err = pinned.Verify(depDir, false, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("rewrite.msi has SHA256 0000000000000000000000000000000000000000000000000000000000000000 in dependencies.sha256, but ac92c1ce78bc97a7cece787c097bba16839f44163fc2dd7f61a9fbb3c7053ede in the manifest"))
+		Expect(err.Error()).To(ContainSubstring("vcredist-2010.x64.exe is in dependencies.sha256 but not in the manifest"))
+		Expect(err.Error()).ToNot(ContainSubstring("dependencies.sha256 is not in the manifest"))
+	})
+
+	It("round-trips through a file and rejects names that aren't plain file names", func() {
+		path := filepath.Join(depDir, "deps.json")
+		Expect(manifest.Write(path)).To(Succeed())
+
+		loaded, err := builder.LoadManifest(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(loaded).To(Equal(manifest))
+
+		Expect(ioutil.WriteFile(path, []byte(`{"dependencies": [{"name": "../escape.exe"}]}`), 0644)).To(Succeed())
+		_, err = builder.LoadManifest(path)
+		Expect(err).To(MatchError(ContainSubstring(`invalid dependency name "../escape.exe"`)))
+	})
+
+	It("loads the committed manifest of every known tag and variant", func() {
+		for _, tag := range validation.KnownTags() {
+			for variant := range validation.Variants {
+				dir := filepath.Join("..", validation.VariantDir(tag, variant))
+				_, err := builder.LoadManifest(filepath.Join(dir, builder.ManifestName))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(filepath.Join(dir, "Dockerfile")).To(BeARegularFile())
+			}
+		}
+	})
+})
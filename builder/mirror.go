@@ -0,0 +1,51 @@
+package builder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mirror replaces the Prefix of a URL with Replacement, so that Hydrate
+// fetches dependencies from an internal mirror, e.g. for foundations that
+// can't reach download.microsoft.com. Downloads are still verified against
+// the manifest's checksums.
+type Mirror struct {
+	Prefix      string
+	Replacement string
+}
+
+// ParseMirrors parses comma-separated prefix=replacement rules, e.g.
+// "https://download.microsoft.com/=https://artifactory.internal/microsoft/".
+func ParseMirrors(rules string) ([]Mirror, error) {
+	var mirrors []Mirror
+	for _, rule := range strings.Split(rules, ",") {
+		if rule = strings.TrimSpace(rule); rule == "" {
+			continue
+		}
+
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid mirror rule %q; expected prefix=replacement", rule)
+		}
+
+		mirrors = append(mirrors, Mirror{Prefix: parts[0], Replacement: parts[1]})
+	}
+
+	return mirrors, nil
+}
+
+// mirrorURL applies the rule with the longest matching prefix to url.
+func mirrorURL(mirrors []Mirror, url string) string {
+	var best *Mirror
+	for i, mirror := range mirrors {
+		if strings.HasPrefix(url, mirror.Prefix) && (best == nil || len(mirror.Prefix) > len(best.Prefix)) {
+			best = &mirrors[i]
+		}
+	}
+
+	if best == nil {
+		return url
+	}
+
+	return best.Replacement + strings.TrimPrefix(url, best.Prefix)
+}
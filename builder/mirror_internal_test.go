@@ -0,0 +1,36 @@
+package builder
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("mirrorURL", func() {
+	mirrors := []Mirror{
+		{Prefix: "https://download.microsoft.com/", Replacement: "https://mirror.internal/microsoft/"},
+		{Prefix: "https://download.microsoft.com/download/1/", Replacement: "https://mirror.internal/vcredist/"},
+	}
+
+	It("applies the rule with the longest matching prefix", func() {
+		Expect(mirrorURL(mirrors, "https://download.microsoft.com/download/C/rewrite_amd64.msi")).To(Equal("https://mirror.internal/microsoft/download/C/rewrite_amd64.msi"))
+		Expect(mirrorURL(mirrors, "https://download.microsoft.com/download/1/vcredist_x64.exe")).To(Equal("https://mirror.internal/vcredist/vcredist_x64.exe"))
+	})
+
+	It("leaves URLs without a matching rule alone", func() {
+		Expect(mirrorURL(mirrors, "https://aka.ms/vs/16/release/vc_redist.x64.exe")).To(Equal("https://aka.ms/vs/16/release/vc_redist.x64.exe"))
+	})
+})
+
+var _ = Describe("ParseMirrors", func() {
+	It("parses comma-separated rules", func() {
+		Expect(ParseMirrors("https://a/=https://m/a/, https://b/=https://m/b/")).To(Equal([]Mirror{
+			{Prefix: "https://a/", Replacement: "https://m/a/"},
+			{Prefix: "https://b/", Replacement: "https://m/b/"},
+		}))
+	})
+
+	It("rejects rules without a replacement", func() {
+		_, err := ParseMirrors("https://a/")
+		Expect(err).To(MatchError(ContainSubstring(`invalid mirror rule "https://a/"`)))
+	})
+})
@@ -0,0 +1,484 @@
+package builder
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// Build backends.
+const (
+	// BackendDocker builds with docker build and needs a daemon with
+	// Windows containers enabled.
+	BackendDocker = "docker"
+
+	// BackendOCI assembles an OCI image layout in Go, without a daemon. It
+	// pulls the base image's layers from its registry and adds a layer for
+	// each COPY, so it only builds Dockerfiles that don't RUN anything.
+	BackendOCI = "oci"
+)
+
+// Backends lists the supported build backends.
+var Backends = []string{BackendDocker, BackendOCI}
+
+// Media types of the content the OCI backend writes.
+const (
+	mediaTypeOCIConfig = "application/vnd.oci.image.config.v1+json"
+	mediaTypeOCILayer  = "application/vnd.oci.image.layer.v1.tar"
+)
+
+// ociLayerMediaTypes maps the layer media types of Docker manifests to
+// their OCI equivalents.
+var ociLayerMediaTypes = map[string]string{
+	"application/vnd.docker.image.rootfs.diff.tar.gzip":         "application/vnd.oci.image.layer.v1.tar+gzip",
+	"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip": "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip",
+}
+
+// ociInstructions are the Dockerfile instructions the OCI backend applies.
+var ociInstructions = map[string]bool{"FROM": true, "COPY": true, "USER": true, "ENV": true}
+
+// ociBuild is the state of an image being assembled by the OCI backend.
+type ociBuild struct {
+	opts     Options
+	client   *registry.Client
+	base     registry.Reference
+	manifest registry.Manifest
+	config   map[string]interface{}
+	log      io.Writer
+}
+
+// buildLayout assembles the candidate from the Dockerfile staged in
+// opts.ContextDir into the OCI image layout at opts.LayoutDir, tagged
+// opts.Image. Blobs already in the layout aren't downloaded again.
+func buildLayout(ctx context.Context, opts Options) error {
+	if opts.LayoutDir == "" {
+		return fmt.Errorf("the %s backend needs a layout directory", BackendOCI)
+	}
+
+	dockerfile, err := os.Open(filepath.Join(opts.ContextDir, "Dockerfile"))
+	if err != nil {
+		return err
+	}
+	instructions, err := ParseDockerfile(dockerfile)
+	dockerfile.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := checkOCIInstructions(instructions); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Join(opts.LayoutDir, "blobs", "sha256"), 0755); err != nil {
+		return err
+	}
+
+	b := &ociBuild{opts: opts, client: opts.Registry, log: opts.Stdout}
+	if b.client == nil {
+		b.client = &registry.Client{}
+	}
+	if b.log == nil {
+		b.log = ioutil.Discard
+	}
+
+	for _, instruction := range instructions {
+		if err := b.apply(ctx, instruction); err != nil {
+			return fmt.Errorf("Dockerfile line %d: %s", instruction.Line, err)
+		}
+	}
+
+	return b.write()
+}
+
+// checkOCIInstructions fails on Dockerfiles the OCI backend can't build:
+// anything but a single FROM followed by COPY, USER and ENV.
+func checkOCIInstructions(instructions []Instruction) error {
+	if len(instructions) == 0 || instructions[0].Command != "FROM" {
+		return fmt.Errorf("the Dockerfile doesn't start with FROM")
+	}
+	if len(instructions[0].Args) != 1 {
+		return fmt.Errorf("Dockerfile line %d: the %s backend only supports FROM <image>", instructions[0].Line, BackendOCI)
+	}
+
+	for _, instruction := range instructions[1:] {
+		if !ociInstructions[instruction.Command] || instruction.Command == "FROM" {
+			return fmt.Errorf("Dockerfile line %d: the %s backend can't build %s instructions; build with the %s backend", instruction.Line, BackendOCI, instruction.Command, BackendDocker)
+		}
+	}
+
+	return nil
+}
+
+func (b *ociBuild) apply(ctx context.Context, instruction Instruction) error {
+	switch instruction.Command {
+	case "FROM":
+		return b.pullBase(ctx, instruction.Args[0])
+	case "COPY":
+		return b.copyLayer(instruction)
+	case "USER":
+		if len(instruction.Args) != 1 {
+			return fmt.Errorf("USER takes one user")
+		}
+		b.containerConfig()["User"] = instruction.Args[0]
+		b.history(instruction, true)
+		return nil
+	case "ENV":
+		return b.env(instruction)
+	}
+
+	return fmt.Errorf("unsupported instruction %s", instruction.Command)
+}
+
+// pullBase fetches the manifest, configuration and layers of the base image
+// for opts.Platform (windows/amd64 by default) into the layout.
+func (b *ociBuild) pullBase(ctx context.Context, image string) error {
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		return err
+	}
+	b.base = ref
+
+	manifest, _, _, err := b.client.GetManifest(ctx, ref)
+	if err != nil {
+		return err
+	}
+
+	if manifest.IsList() {
+		descriptor, err := selectPlatform(manifest, b.opts.Platform)
+		if err != nil {
+			return fmt.Errorf("%s: %s", ref, err)
+		}
+
+		if manifest, _, _, err = b.client.GetManifest(ctx, ref.WithDigest(descriptor.Digest)); err != nil {
+			return err
+		}
+	}
+
+	if manifest.Config == nil {
+		return fmt.Errorf("%s isn't an image manifest (%s)", ref, manifest.MediaType)
+	}
+
+	content, err := b.client.GetBlob(ctx, ref, manifest.Config.Digest)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(content, &b.config); err != nil {
+		return fmt.Errorf("parsing the configuration of %s: %s", ref, err)
+	}
+
+	for i, layer := range manifest.Layers {
+		if mediaType, ok := ociLayerMediaTypes[layer.MediaType]; ok {
+			manifest.Layers[i].MediaType = mediaType
+		}
+
+		if err := b.fetchBlob(ctx, layer); err != nil {
+			return err
+		}
+		fmt.Fprintf(b.log, "pulled layer %s (%d bytes) [%d/%d]\n", layer.Digest, layer.Size, i+1, len(manifest.Layers))
+	}
+
+	b.manifest = registry.Manifest{SchemaVersion: 2, MediaType: registry.MediaTypeOCIManifest, Layers: manifest.Layers}
+	return nil
+}
+
+// selectPlatform returns the entry of list for platform, given as os/arch.
+func selectPlatform(list registry.Manifest, platform string) (registry.Descriptor, error) {
+	if platform == "" {
+		platform = "windows/amd64"
+	}
+
+	for _, descriptor := range list.Manifests {
+		if descriptor.Platform != nil && descriptor.Platform.OS+"/"+descriptor.Platform.Architecture == platform {
+			return descriptor, nil
+		}
+	}
+
+	return registry.Descriptor{}, fmt.Errorf("no image for %s", platform)
+}
+
+// fetchBlob downloads a blob into the layout unless it's already there.
+func (b *ociBuild) fetchBlob(ctx context.Context, descriptor registry.Descriptor) error {
+	path := b.blobPath(descriptor.Digest)
+	if info, err := os.Stat(path); err == nil && info.Size() == descriptor.Size {
+		return nil
+	}
+
+	partial := path + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return err
+	}
+
+	err = b.client.FetchBlob(ctx, b.base, descriptor.Digest, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+
+	return os.Rename(partial, path)
+}
+
+// copyLayer adds a layer holding the sources of a COPY instruction, matched
+// as globs in the build context, at its destination. Windows layers keep
+// the container's C: drive under Files/.
+func (b *ociBuild) copyLayer(instruction Instruction) error {
+	if len(instruction.Args) < 2 || strings.HasPrefix(instruction.Args[0], "--") {
+		return fmt.Errorf("the %s backend only supports COPY <source>... <destination>", BackendOCI)
+	}
+
+	sources := instruction.Args[:len(instruction.Args)-1]
+	destination := containerPath(instruction.Args[len(instruction.Args)-1])
+	toDirectory := len(sources) > 1 || strings.HasSuffix(destination, "/")
+
+	var files []string
+	for _, source := range sources {
+		matches, err := filepath.Glob(filepath.Join(b.opts.ContextDir, filepath.FromSlash(source)))
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no files in the build context match %s", source)
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	if len(files) > 1 {
+		toDirectory = true
+	}
+
+	layer, err := ioutil.TempFile(filepath.Join(b.opts.LayoutDir, "blobs", "sha256"), "layer")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(layer.Name())
+	defer layer.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(layer, hash)}
+	archive := tar.NewWriter(counter)
+
+	for _, file := range files {
+		target := destination
+		if toDirectory {
+			target = path.Join(destination, filepath.Base(file))
+		}
+
+		if err := addArchiveFile(archive, file, "Files"+target); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	if err := layer.Close(); err != nil {
+		return err
+	}
+
+	digest := fmt.Sprintf("sha256:%x", hash.Sum(nil))
+	if err := os.Rename(layer.Name(), b.blobPath(digest)); err != nil {
+		return err
+	}
+
+	b.manifest.Layers = append(b.manifest.Layers, registry.Descriptor{MediaType: mediaTypeOCILayer, Digest: digest, Size: counter.n})
+	b.addDiffID(digest)
+	b.history(instruction, false)
+	fmt.Fprintf(b.log, "added layer %s (%d bytes) for COPY %s\n", digest, counter.n, strings.Join(instruction.Args, " "))
+
+	return nil
+}
+
+// containerPath converts a destination such as C:\Windows\ or /Windows/ to
+// a slash-separated path from the root of C:, keeping a trailing slash.
+func containerPath(destination string) string {
+	destination = strings.ReplaceAll(destination, `\`, "/")
+	if len(destination) >= 2 && destination[1] == ':' {
+		destination = destination[2:]
+	}
+
+	cleaned := path.Clean("/" + destination)
+	if strings.HasSuffix(destination, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// env sets the environment variables of an ENV instruction, in either the
+// ENV key=value... or ENV key value form.
+func (b *ociBuild) env(instruction Instruction) error {
+	pairs := map[string]string{}
+	var keys []string
+
+	switch {
+	case len(instruction.Args) == 0:
+		return fmt.Errorf("ENV needs a variable")
+	case !strings.Contains(instruction.Args[0], "="):
+		keys = []string{instruction.Args[0]}
+		pairs[instruction.Args[0]] = strings.Join(instruction.Args[1:], " ")
+	default:
+		for _, arg := range instruction.Args {
+			i := strings.Index(arg, "=")
+			if i <= 0 {
+				return fmt.Errorf("invalid ENV argument %q", arg)
+			}
+			keys = append(keys, arg[:i])
+			pairs[arg[:i]] = strings.Trim(arg[i+1:], `"`)
+		}
+	}
+
+	config := b.containerConfig()
+	existing, _ := config["Env"].([]interface{})
+
+	var env []interface{}
+	for _, variable := range existing {
+		name := strings.SplitN(fmt.Sprint(variable), "=", 2)[0]
+		if _, ok := pairs[name]; !ok {
+			env = append(env, variable)
+		}
+	}
+	for _, key := range keys {
+		env = append(env, key+"="+pairs[key])
+	}
+	config["Env"] = env
+
+	b.history(instruction, true)
+	return nil
+}
+
+// containerConfig returns the config section of the image configuration.
+func (b *ociBuild) containerConfig() map[string]interface{} {
+	config, ok := b.config["config"].(map[string]interface{})
+	if !ok {
+		config = map[string]interface{}{}
+		b.config["config"] = config
+	}
+
+	return config
+}
+
+func (b *ociBuild) addDiffID(digest string) {
+	rootfs, ok := b.config["rootfs"].(map[string]interface{})
+	if !ok {
+		rootfs = map[string]interface{}{"type": "layers"}
+		b.config["rootfs"] = rootfs
+	}
+
+	diffIDs, _ := rootfs["diff_ids"].([]interface{})
+	rootfs["diff_ids"] = append(diffIDs, digest)
+}
+
+func (b *ociBuild) history(instruction Instruction, empty bool) {
+	entry := map[string]interface{}{
+		"created_by": instruction.Command + " " + strings.Join(instruction.Args, " "),
+		"comment":    "assembled by the " + BackendOCI + " backend",
+	}
+	if empty {
+		entry["empty_layer"] = true
+	}
+
+	history, _ := b.config["history"].([]interface{})
+	b.config["history"] = append(history, entry)
+}
+
+// write stores the configuration and manifest and records the manifest in
+// the layout's index as opts.Image, replacing an earlier build of it.
+func (b *ociBuild) write() error {
+	if len(b.opts.Labels) > 0 {
+		labels, _ := b.containerConfig()["Labels"].(map[string]interface{})
+		if labels == nil {
+			labels = map[string]interface{}{}
+		}
+		for name, value := range b.opts.Labels {
+			labels[name] = value
+		}
+		b.containerConfig()["Labels"] = labels
+	}
+
+	config, err := json.Marshal(b.config)
+	if err != nil {
+		return err
+	}
+	configDescriptor, err := b.writeBlob(mediaTypeOCIConfig, config)
+	if err != nil {
+		return err
+	}
+	b.manifest.Config = &configDescriptor
+
+	manifest, err := json.Marshal(b.manifest)
+	if err != nil {
+		return err
+	}
+	manifestDescriptor, err := b.writeBlob(registry.MediaTypeOCIManifest, manifest)
+	if err != nil {
+		return err
+	}
+	manifestDescriptor.Annotations = map[string]string{"org.opencontainers.image.ref.name": b.opts.Image}
+
+	index := registry.Manifest{SchemaVersion: 2, MediaType: registry.MediaTypeOCIIndex}
+	indexPath := filepath.Join(b.opts.LayoutDir, "index.json")
+	if content, err := ioutil.ReadFile(indexPath); err == nil {
+		var existing registry.Manifest
+		if err := json.Unmarshal(content, &existing); err != nil {
+			return fmt.Errorf("parsing %s: %s", indexPath, err)
+		}
+
+		for _, descriptor := range existing.Manifests {
+			if descriptor.Annotations["org.opencontainers.image.ref.name"] != b.opts.Image {
+				index.Manifests = append(index.Manifests, descriptor)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	index.Manifests = append(index.Manifests, manifestDescriptor)
+
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(indexPath, content, 0644); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(b.opts.LayoutDir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(b.log, "wrote %s as %s to %s\n", manifestDescriptor.Digest, b.opts.Image, b.opts.LayoutDir)
+	return nil
+}
+
+func (b *ociBuild) writeBlob(mediaType string, content []byte) (registry.Descriptor, error) {
+	descriptor := registry.Descriptor{MediaType: mediaType, Digest: registry.Digest(content), Size: int64(len(content))}
+	return descriptor, ioutil.WriteFile(b.blobPath(descriptor.Digest), content, 0644)
+}
+
+func (b *ociBuild) blobPath(digest string) string {
+	return filepath.Join(b.opts.LayoutDir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
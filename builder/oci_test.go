@@ -0,0 +1,253 @@
+package builder_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("the OCI backend", func() {
+	var (
+		server     *registrytest.Server
+		dir        string
+		opts       builder.Options
+		baseLayer  string
+		listDigest string
+		dockerfile string
+	)
+
+	readBlob := func(digest string, v interface{}) []byte {
+		content, err := ioutil.ReadFile(filepath.Join(opts.LayoutDir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")))
+		Expect(err).ToNot(HaveOccurred())
+		if v != nil {
+			Expect(json.Unmarshal(content, v)).To(Succeed())
+		}
+
+		return content
+	}
+
+	BeforeEach(func() {
+		server = registrytest.NewServer()
+
+		var err error
+		dir, err = ioutil.TempDir("", "oci")
+		Expect(err).ToNot(HaveOccurred())
+
+		baseLayer = server.PutBlob([]byte("base layer"))
+		config := server.PutBlob([]byte(`{"architecture":"amd64","os":"windows","os.version":"10.0.17763.5122","config":{"Env":["PATH=C:\\Windows"]},"rootfs":{"type":"layers","diff_ids":["sha256:base"]}}`))
+		image := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":%q,"size":1},"layers":[{"mediaType":"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip","digest":%q,"size":10,"urls":["https://mcr.microsoft.com/layer"]}]}`, registry.MediaTypeDockerManifest, config, baseLayer)
+		imageDigest := server.PutManifest("windows/servercore", "1809-amd64", registry.MediaTypeDockerManifest, []byte(image))
+		list := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"manifests":[{"mediaType":%q,"digest":%q,"size":%d,"platform":{"architecture":"amd64","os":"windows","os.version":"10.0.17763.5122"}}]}`, registry.MediaTypeDockerManifestList, registry.MediaTypeDockerManifest, imageDigest, len(image))
+		listDigest = server.PutManifest("windows/servercore", "1809", registry.MediaTypeDockerManifestList, []byte(list))
+
+		depDir := filepath.Join(dir, "dependencies")
+		Expect(os.Mkdir(depDir, 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(depDir, "rewrite_amd64.msi"), []byte("msi"), 0644)).To(Succeed())
+
+		dockerfile = filepath.Join(dir, "Dockerfile")
+		Expect(ioutil.WriteFile(dockerfile, []byte(fmt.Sprintf(`FROM %s/windows/servercore:1809
+COPY rewrite*.msi /Windows/rewrite.msi
+ENV GIT_VERSION=2.43.0
+USER vcap
+`, server.Host())), 0644)).To(Succeed())
+
+		opts = builder.Options{
+			Dockerfile:      dockerfile,
+			DependenciesDir: depDir,
+			Image:           "windows2016fs-candidate:2019",
+			Backend:         builder.BackendOCI,
+			LayoutDir:       filepath.Join(dir, "layout"),
+			Registry:        &registry.Client{PlainHTTP: true},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("resolves the base image's tag to the digest of its manifest list", func() {
+		digest, err := builder.ResolveBaseImage(context.Background(), opts.Registry, server.Host()+"/windows/servercore:1809")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(digest).To(Equal(listDigest))
+	})
+
+	It("builds from the pinned base image", func() {
+		opts.BaseImageDigest = listDigest
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+
+		opts.BaseImageDigest = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+		Expect(builder.Build(context.Background(), opts)).To(MatchError(ContainSubstring("404")))
+	})
+
+	It("labels the candidate with the build time and the base image it was built from", func() {
+		opts.Labels = map[string]string{builder.LabelRevision: "abc123"}
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+
+		var index, manifest registry.Manifest
+		Expect(json.Unmarshal(readFile(filepath.Join(opts.LayoutDir, "index.json")), &index)).To(Succeed())
+		readBlob(index.Manifests[0].Digest, &manifest)
+
+		var config struct {
+			Config struct {
+				Env    []string
+				Labels map[string]string
+			}
+		}
+		readBlob(manifest.Config.Digest, &config)
+		Expect(config.Config.Env).To(ContainElement(`PATH=C:\Windows`))
+		Expect(config.Config.Labels).To(HaveKeyWithValue(builder.LabelRevision, "abc123"))
+		Expect(config.Config.Labels).To(HaveKeyWithValue(builder.LabelBaseName, server.Host()+"/windows/servercore:1809"))
+		Expect(config.Config.Labels).To(HaveKeyWithValue(builder.LabelBaseDigest, listDigest))
+		Expect(config.Config.Labels).To(HaveKey(builder.LabelCreated))
+	})
+
+	It("assembles the base image's layers and a layer for each COPY into an OCI layout", func() {
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+
+		var index registry.Manifest
+		Expect(json.Unmarshal(readFile(filepath.Join(opts.LayoutDir, "index.json")), &index)).To(Succeed())
+		Expect(index.Manifests).To(HaveLen(1))
+		Expect(index.Manifests[0].Annotations).To(HaveKeyWithValue("org.opencontainers.image.ref.name", "windows2016fs-candidate:2019"))
+		Expect(string(readFile(filepath.Join(opts.LayoutDir, "oci-layout")))).To(ContainSubstring(`"imageLayoutVersion":"1.0.0"`))
+
+		var manifest registry.Manifest
+		readBlob(index.Manifests[0].Digest, &manifest)
+		Expect(manifest.MediaType).To(Equal(registry.MediaTypeOCIManifest))
+		Expect(manifest.Layers).To(HaveLen(2))
+		Expect(manifest.Layers[0].Digest).To(Equal(baseLayer))
+		Expect(manifest.Layers[0].MediaType).To(Equal("application/vnd.oci.image.layer.nondistributable.v1.tar+gzip"))
+		Expect(manifest.Layers[0].URLs).To(Equal([]string{"https://mcr.microsoft.com/layer"}))
+		Expect(string(readBlob(baseLayer, nil))).To(Equal("base layer"))
+
+		layer := tar.NewReader(strings.NewReader(string(readBlob(manifest.Layers[1].Digest, nil))))
+		header, err := layer.Next()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(header.Name).To(Equal("Files/Windows/rewrite.msi"))
+		content, err := ioutil.ReadAll(layer)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("msi"))
+
+		var config struct {
+			OSVersion string `json:"os.version"`
+			Config    struct {
+				Env  []string
+				User string
+			}
+			RootFS struct {
+				DiffIDs []string `json:"diff_ids"`
+			} `json:"rootfs"`
+			History []interface{}
+		}
+		readBlob(manifest.Config.Digest, &config)
+		Expect(config.OSVersion).To(Equal("10.0.17763.5122"))
+		Expect(config.Config.User).To(Equal("vcap"))
+		Expect(config.Config.Env).To(Equal([]string{`PATH=C:\Windows`, "GIT_VERSION=2.43.0"}))
+		Expect(config.RootFS.DiffIDs).To(Equal([]string{"sha256:base", manifest.Layers[1].Digest}))
+		Expect(config.History).To(HaveLen(3))
+	})
+
+	It("writes a report of the candidate", func() {
+		opts.BaseImageDigest = listDigest
+		dependency := builder.Dependency{Name: "rewrite_amd64.msi", SHA256: "7f1d49243ee662770bbdff7da2cec54eb382cd9d76dfa7b049a620ec457db57b", Size: 3}
+		opts.Manifest = &builder.Manifest{Dependencies: []builder.Dependency{dependency}}
+		opts.ReportPath = filepath.Join(dir, "reports", builder.ReportName)
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+
+		var index, manifest registry.Manifest
+		Expect(json.Unmarshal(readFile(filepath.Join(opts.LayoutDir, "index.json")), &index)).To(Succeed())
+		readBlob(index.Manifests[0].Digest, &manifest)
+
+		var report builder.Report
+		Expect(json.Unmarshal(readFile(opts.ReportPath), &report)).To(Succeed())
+		Expect(report.Image).To(Equal("windows2016fs-candidate:2019"))
+		Expect(report.Digest).To(Equal(index.Manifests[0].Digest))
+		Expect(report.ImageID).To(Equal(manifest.Config.Digest))
+		Expect(report.BaseImage).To(Equal(server.Host() + "/windows/servercore:1809"))
+		Expect(report.BaseImageDigest).To(Equal(listDigest))
+		Expect(report.Layers).To(HaveLen(2))
+		Expect(report.Layers[0]).To(Equal(builder.ReportLayer{DiffID: "sha256:base", Size: 10}))
+		Expect(report.Layers[1].DiffID).To(Equal(manifest.Layers[1].Digest))
+		Expect(report.Size).To(Equal(10 + manifest.Layers[1].Size))
+		Expect(report.Dependencies).To(Equal([]builder.Dependency{dependency}))
+	})
+
+	It("produces the same image from the same sources", func() {
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+		first := readFile(filepath.Join(opts.LayoutDir, "index.json"))
+
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+		Expect(readFile(filepath.Join(opts.LayoutDir, "index.json"))).To(Equal(first))
+	})
+
+	It("exports one image of the layout as an OCI image archive", func() {
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+		opts.Image = "windows2016fs-candidate:2019-rc"
+		Expect(builder.Build(context.Background(), opts)).To(Succeed())
+
+		var archive bytes.Buffer
+		Expect(builder.ExportLayout(opts.LayoutDir, "windows2016fs-candidate:2019", &archive)).To(Succeed())
+
+		entries := map[string][]byte{}
+		reader := tar.NewReader(&archive)
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			Expect(err).ToNot(HaveOccurred())
+			entries[header.Name], err = ioutil.ReadAll(reader)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		var index registry.Manifest
+		Expect(json.Unmarshal(entries["index.json"], &index)).To(Succeed())
+		Expect(index.Manifests).To(HaveLen(1))
+		Expect(index.Manifests[0].Annotations).To(HaveKeyWithValue("org.opencontainers.image.ref.name", "windows2016fs-candidate:2019"))
+		Expect(entries).To(HaveKey("oci-layout"))
+		Expect(entries).To(HaveKeyWithValue("blobs/sha256/"+strings.TrimPrefix(baseLayer, "sha256:"), []byte("base layer")))
+		Expect(entries).To(HaveLen(2 + 4))
+
+		Expect(builder.ExportLayout(opts.LayoutDir, "missing:tag", ioutil.Discard)).To(MatchError(ContainSubstring("has no image missing:tag")))
+	})
+
+	It("refuses Dockerfiles that RUN commands", func() {
+		Expect(ioutil.WriteFile(dockerfile, []byte("FROM image\nRUN cmd.exe /C net accounts\n"), 0644)).To(Succeed())
+
+		err := builder.Build(context.Background(), opts)
+		Expect(err).To(MatchError("Dockerfile line 2: the oci backend can't build RUN instructions; build with the docker backend"))
+	})
+
+	It("needs a layout directory", func() {
+		opts.LayoutDir = ""
+
+		Expect(builder.Build(context.Background(), opts)).To(MatchError("the oci backend needs a layout directory"))
+	})
+
+	It("rejects unknown backends", func() {
+		opts.Backend = "buildah"
+
+		Expect(builder.Build(context.Background(), opts)).To(MatchError(ContainSubstring(`unknown backend "buildah"`)))
+	})
+})
+
+func readFile(path string) []byte {
+	content, err := ioutil.ReadFile(path)
+	Expect(err).ToNot(HaveOccurred())
+
+	return content
+}
@@ -0,0 +1,251 @@
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// ReportName is the file a build's report is written to by default.
+const ReportName = "build-report.json"
+
+// Report describes a candidate that was built, for pipelines promoting it
+// without inspecting the daemon again.
+type Report struct {
+	Image string `json:"image"`
+
+	// ImageID is the digest of the candidate's configuration and Digest of
+	// its manifest, which is only known for the OCI backend and for images
+	// that were pushed.
+	ImageID string `json:"image_id"`
+	Digest  string `json:"digest,omitempty"`
+
+	// Size is the size of the candidate's layers in bytes: as stored by the
+	// daemon for the docker backend, and compressed for the OCI backend.
+	Size   int64         `json:"size"`
+	Layers []ReportLayer `json:"layers"`
+
+	BaseImage       string `json:"base_image"`
+	BaseImageDigest string `json:"base_image_digest,omitempty"`
+
+	Dependencies []Dependency `json:"dependencies"`
+
+	Created time.Time `json:"created"`
+}
+
+// ReportLayer is a layer of a candidate, base first, with the instruction
+// that created it. DiffID is left out when the history doesn't account for
+// every layer.
+type ReportLayer struct {
+	DiffID    string `json:"diff_id,omitempty"`
+	Size      int64  `json:"size"`
+	CreatedBy string `json:"created_by,omitempty"`
+}
+
+// Write stores the report at path as indented JSON.
+func (r Report) Write(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+// BuildReport describes the candidate opts built, reading it from the
+// daemon, or from opts.LayoutDir for the OCI backend.
+func BuildReport(opts Options) (Report, error) {
+	dockerfile, err := ioutil.ReadFile(opts.Dockerfile)
+	if err != nil {
+		return Report{}, err
+	}
+	base, _, err := BaseImage(dockerfile)
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := Report{
+		Image:           opts.Image,
+		BaseImage:       base,
+		BaseImageDigest: opts.BaseImageDigest,
+		Dependencies:    []Dependency{},
+		Created:         time.Now().UTC(),
+	}
+	if r.BaseImageDigest == "" {
+		r.BaseImageDigest = opts.Labels[LabelBaseDigest]
+	}
+	if opts.Manifest != nil {
+		r.Dependencies = append(r.Dependencies, opts.Manifest.Dependencies...)
+	}
+
+	if opts.Backend == BackendOCI {
+		err = r.readLayout(opts.LayoutDir)
+	} else {
+		err = r.readDaemon()
+	}
+
+	return r, err
+}
+
+// readDaemon fills in the image ID, repository digest, size and layers of
+// the candidate from docker.
+func (r *Report) readDaemon() error {
+	var err error
+	if r.ImageID, err = validation.ImageID(r.Image); err != nil {
+		return err
+	}
+	if r.Size, err = validation.ImageSize(r.Image); err != nil {
+		return err
+	}
+
+	digests, err := validation.ImageRepoDigests(r.Image)
+	if err != nil {
+		return err
+	}
+	if len(digests) > 0 {
+		r.Digest = digests[0][strings.Index(digests[0], "@")+1:]
+	}
+
+	diffIDs, err := validation.ImageLayers(r.Image)
+	if err != nil {
+		return err
+	}
+	history, err := validation.ImageHistory(r.Image)
+	if err != nil {
+		return err
+	}
+	r.Layers = historyLayers(history, diffIDs)
+
+	return nil
+}
+
+// metadataInstructions are the Dockerfile instructions that don't add a
+// layer.
+var metadataInstructions = []string{
+	"ARG", "CMD", "ENTRYPOINT", "ENV", "EXPOSE", "HEALTHCHECK", "LABEL",
+	"MAINTAINER", "ONBUILD", "SHELL", "STOPSIGNAL", "USER", "VOLUME", "WORKDIR",
+}
+
+// historyLayers returns the steps of history that added a layer, with the
+// diff IDs of the layers when their number lines up.
+func historyLayers(history []validation.HistoryEntry, diffIDs []string) []ReportLayer {
+	layers := []ReportLayer{}
+	for _, entry := range history {
+		if entry.Size == 0 && isMetadataStep(entry.CreatedBy) {
+			continue
+		}
+
+		layers = append(layers, ReportLayer{Size: entry.Size, CreatedBy: entry.CreatedBy})
+	}
+
+	if len(layers) == len(diffIDs) {
+		for i := range layers {
+			layers[i].DiffID = diffIDs[i]
+		}
+	}
+
+	return layers
+}
+
+// isMetadataStep reports whether a history step, such as
+// `cmd /S /C #(nop)  ENV FOO=bar`, was created by an instruction that only
+// changes the configuration. Steps without #(nop) are RUN instructions.
+func isMetadataStep(createdBy string) bool {
+	i := strings.Index(createdBy, "#(nop)")
+	if i < 0 {
+		return false
+	}
+
+	fields := strings.Fields(createdBy[i+len("#(nop)"):])
+	if len(fields) == 0 {
+		return true
+	}
+
+	for _, instruction := range metadataInstructions {
+		if strings.EqualFold(fields[0], instruction) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// readLayout fills in the image ID, digest, size and layers of the
+// candidate from the OCI image layout at dir.
+func (r *Report) readLayout(dir string) error {
+	content, err := ioutil.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return err
+	}
+	var index registry.Manifest
+	if err := json.Unmarshal(content, &index); err != nil {
+		return fmt.Errorf("parsing the index of %s: %s", dir, err)
+	}
+
+	for _, descriptor := range index.Manifests {
+		if descriptor.Annotations["org.opencontainers.image.ref.name"] == r.Image {
+			r.Digest = descriptor.Digest
+		}
+	}
+	if r.Digest == "" {
+		return fmt.Errorf("%s has no image %s", dir, r.Image)
+	}
+
+	var manifest registry.Manifest
+	if err := readBlob(dir, r.Digest, &manifest); err != nil {
+		return err
+	}
+	if manifest.Config == nil {
+		return fmt.Errorf("manifest %s has no configuration", r.Digest)
+	}
+	r.ImageID = manifest.Config.Digest
+
+	var config struct {
+		RootFS struct {
+			DiffIDs []string `json:"diff_ids"`
+		} `json:"rootfs"`
+		History []struct {
+			CreatedBy  string `json:"created_by"`
+			EmptyLayer bool   `json:"empty_layer"`
+		} `json:"history"`
+	}
+	if err := readBlob(dir, r.ImageID, &config); err != nil {
+		return err
+	}
+
+	var createdBy []string
+	for _, step := range config.History {
+		if !step.EmptyLayer {
+			createdBy = append(createdBy, step.CreatedBy)
+		}
+	}
+
+	r.Layers = []ReportLayer{}
+	for i, layer := range manifest.Layers {
+		reportLayer := ReportLayer{Size: layer.Size}
+		if len(config.RootFS.DiffIDs) == len(manifest.Layers) {
+			reportLayer.DiffID = config.RootFS.DiffIDs[i]
+		}
+		if len(createdBy) == len(manifest.Layers) {
+			reportLayer.CreatedBy = createdBy[i]
+		}
+
+		r.Layers = append(r.Layers, reportLayer)
+		r.Size += layer.Size
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package builder
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("historyLayers", func() {
+	history := []validation.HistoryEntry{
+		{CreatedBy: "Apply image 10.0.17763.1", Size: 4000},
+		{CreatedBy: "Install update 10.0.17763.5122", Size: 1500},
+		{CreatedBy: `cmd /S /C #(nop)  ENV GIT_VERSION=2.43.0`},
+		{CreatedBy: `cmd /S /C #(nop) COPY file:abc in C:\Windows\rewrite.msi `, Size: 300},
+		{CreatedBy: `cmd /S /C powershell -Command Remove-Item C:\Windows\rewrite.msi`},
+		{CreatedBy: `cmd /S /C #(nop)  USER vcap`},
+	}
+
+	It("keeps the steps that added a layer, with their diff IDs", func() {
+		layers := historyLayers(history, []string{"sha256:a", "sha256:b", "sha256:c", "sha256:d"})
+		Expect(layers).To(Equal([]ReportLayer{
+			{DiffID: "sha256:a", Size: 4000, CreatedBy: "Apply image 10.0.17763.1"},
+			{DiffID: "sha256:b", Size: 1500, CreatedBy: "Install update 10.0.17763.5122"},
+			{DiffID: "sha256:c", Size: 300, CreatedBy: `cmd /S /C #(nop) COPY file:abc in C:\Windows\rewrite.msi `},
+			{DiffID: "sha256:d", CreatedBy: `cmd /S /C powershell -Command Remove-Item C:\Windows\rewrite.msi`},
+		}))
+	})
+
+	It("leaves out the diff IDs when the layers don't line up with the history", func() {
+		layers := historyLayers(history, []string{"sha256:a"})
+		Expect(layers).To(HaveLen(4))
+		Expect(layers[0].DiffID).To(BeEmpty())
+	})
+})
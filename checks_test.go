@@ -0,0 +1,19 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/gomega"
+)
+
+// expectCheckToPass runs check against candidate and asserts that it passed,
+// reporting the check's message and measurements otherwise.
+func expectCheckToPass(check validation.Check, candidate validation.Candidate) {
+	result, err := check(candidate)
+	Expect(err).ToNot(HaveOccurred())
+
+	suiteResults.annotate(result.Metadata)
+	Expect(result.Passed).To(BeTrue(), fmt.Sprintf("%s: %s %v", result.Name, result.Message, result.Metadata))
+}
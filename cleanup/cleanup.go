@@ -0,0 +1,191 @@
+// Package cleanup removes what suite runs and builds leave on a worker: the
+// images they built, dangling layers and temporary build directories.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// TempDirPrefix starts the names of the temporary directories the suite
+// creates, so that Worker can find the ones a run didn't remove.
+const TempDirPrefix = "windows2016fs-"
+
+// Repositories are the local repositories candidates and test images are
+// tagged in.
+var Repositories = []string{"windows2016fs-candidate", "windows2016fs-test"}
+
+// OperationTimeout bounds each docker invocation.
+var OperationTimeout = 10 * time.Minute
+
+// Manager collects the images and directories a run creates and removes
+// them once it is over.
+type Manager struct {
+	mu     sync.Mutex
+	images []string
+	dirs   []string
+}
+
+// Image registers image for removal.
+func (m *Manager) Image(image string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.images = append(m.images, image)
+}
+
+// Dir registers dir, and everything in it, for removal.
+func (m *Manager) Dir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dirs = append(m.dirs, dir)
+}
+
+// Images returns the images registered for removal.
+func (m *Manager) Images() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.images...)
+}
+
+// Dirs returns the directories registered for removal.
+func (m *Manager) Dirs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string{}, m.dirs...)
+}
+
+// Run removes the registered images and directories, writing each to w as
+// it goes. Images that no longer exist are skipped, and the other failures
+// are returned together once everything was attempted.
+func (m *Manager) Run(w io.Writer) error {
+	var problems []string
+
+	for _, image := range m.Images() {
+		if err := removeImage(image); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		fmt.Fprintf(w, "removed image %s\n", image)
+	}
+
+	for _, dir := range m.Dirs() {
+		if err := os.RemoveAll(dir); err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		fmt.Fprintf(w, "removed %s\n", dir)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("cleanup failed:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func removeImage(image string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	output, err := cmdlog.CombinedOutput(validation.Runtime.Command(ctx, "image", "rm", "--force", image))
+	if err != nil && !strings.Contains(string(output), "No such image") {
+		return fmt.Errorf("%s image rm %s failed: %s", validation.Runtime.Name(), image, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// PruneDangling removes the dangling images, the layers left untagged when
+// a candidate or test image is rebuilt under the same tag, and writes what
+// docker reclaimed to w.
+func PruneDangling(w io.Writer) error {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	output, err := cmdlog.CombinedOutput(validation.Runtime.Command(ctx, "image", "prune", "--force"))
+	if err != nil {
+		return fmt.Errorf("%s image prune failed: %s", validation.Runtime.Name(), strings.TrimSpace(string(output)))
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.HasPrefix(line, "Total reclaimed space") {
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	return nil
+}
+
+// Worker returns a manager holding every image in Repositories and every
+// directory in tempDir starting with TempDirPrefix, for cleaning up after
+// runs that didn't.
+func Worker(tempDir string) (*Manager, error) {
+	m := &Manager{}
+
+	for _, repository := range Repositories {
+		images, err := repositoryImages(repository)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range images {
+			m.Image(image)
+		}
+	}
+
+	dirs, err := TempDirs(tempDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		m.Dir(dir)
+	}
+
+	return m, nil
+}
+
+// TempDirs returns the directories in tempDir starting with TempDirPrefix.
+func TempDirs(tempDir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(tempDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for _, info := range infos {
+		if info.IsDir() && strings.HasPrefix(info.Name(), TempDirPrefix) {
+			dirs = append(dirs, filepath.Join(tempDir, info.Name()))
+		}
+	}
+
+	return dirs, nil
+}
+
+// repositoryImages returns the tagged images of repository.
+func repositoryImages(repository string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	output, err := cmdlog.Output(validation.Runtime.Command(ctx, "image", "ls", "--format", "{{.Repository}}:{{.Tag}}", repository))
+	if err != nil {
+		return nil, fmt.Errorf("%s image ls %s failed: %s", validation.Runtime.Name(), repository, err)
+	}
+
+	var images []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasSuffix(line, ":<none>") {
+			images = append(images, line)
+		}
+	}
+
+	return images, nil
+}
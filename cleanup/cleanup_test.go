@@ -0,0 +1,56 @@
+package cleanup_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/cleanup"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "cleanup")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("removes the registered directories", func() {
+		build := filepath.Join(dir, cleanup.TempDirPrefix+"build123")
+		Expect(os.MkdirAll(filepath.Join(build, "2019"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(build, "2019", "Dockerfile"), []byte("FROM scratch\n"), 0644)).To(Succeed())
+
+		m := &cleanup.Manager{}
+		m.Dir(build)
+		m.Dir(filepath.Join(dir, "already-gone"))
+
+		var out bytes.Buffer
+		Expect(m.Run(&out)).To(Succeed())
+		Expect(build).ToNot(BeADirectory())
+		Expect(out.String()).To(ContainSubstring("removed " + build + "\n"))
+	})
+
+	It("finds the temporary directories runs left behind", func() {
+		for _, name := range []string{cleanup.TempDirPrefix + "build1", cleanup.TempDirPrefix + "build2", "build3"} {
+			Expect(os.Mkdir(filepath.Join(dir, name), 0755)).To(Succeed())
+		}
+		Expect(ioutil.WriteFile(filepath.Join(dir, cleanup.TempDirPrefix+"file"), nil, 0644)).To(Succeed())
+
+		dirs, err := cleanup.TempDirs(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dirs).To(Equal([]string{
+			filepath.Join(dir, cleanup.TempDirPrefix+"build1"),
+			filepath.Join(dir, cleanup.TempDirPrefix+"build2"),
+		}))
+	})
+})
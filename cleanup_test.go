@@ -0,0 +1,35 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/cleanup"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// cleanups holds the images and directories the suite created, which
+// AfterSuite removes.
+var cleanups = &cleanup.Manager{}
+
+// cleanUpSuite removes what the suite created, and the dangling layers of
+// rebuilt images, unless the configuration keeps them. Failures are
+// reported without failing the run; the cleanup command catches up later.
+func cleanUpSuite() {
+	switch {
+	case suiteConfig.Cleanup.Skip:
+		return
+	case suiteConfig.Cleanup.KeepOnFailure && !suiteResults.allPassed():
+		kept := append(cleanups.Images(), cleanups.Dirs()...)
+		fmt.Fprintf(GinkgoWriter, "not all specs passed, keeping %s\n", strings.Join(kept, ", "))
+		return
+	}
+
+	if err := cleanups.Run(GinkgoWriter); err != nil {
+		fmt.Fprintln(GinkgoWriter, err)
+	}
+	if err := cleanup.PruneDangling(GinkgoWriter); err != nil {
+		fmt.Fprintln(GinkgoWriter, err)
+	}
+}
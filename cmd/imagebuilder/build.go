@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// build verifies and stages a version's dependencies and builds and tags its
+// candidate image. It exits 1 if the build fails and 2 on invalid flags.
+func build(args []string) int {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version to build, e.g. 2019 (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant to build, e.g. nanoserver")
+	depDir := flags.String("dependencies-dir", os.Getenv("DEPENDENCIES_DIR"), "directory of dependencies (default $DEPENDENCIES_DIR)")
+	image := flags.String("image", "", "reference to tag the candidate as (default windows2016fs-candidate:<tag>[-<variant>])")
+	dockerfile := flags.String("dockerfile", "", "Dockerfile to build (default <tag>[/<variant>]/Dockerfile)")
+	manifestPath := flags.String("manifest", "", "dependency manifest (default <tag>[/<variant>]/"+builder.ManifestName+")")
+	strict := flags.Bool("strict", os.Getenv("VERIFY_DEPENDENCIES") != "", "fail on dependencies that aren't pinned (default $VERIFY_DEPENDENCIES)")
+	platform := flags.String("platform", "", "platform passed to docker build, or of the base image the oci backend pulls")
+	backend := flags.String("backend", builder.BackendDocker, "build backend: "+strings.Join(builder.Backends, " or "))
+	layoutDir := flags.String("layout", "", "OCI image layout the oci backend writes the candidate to")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for staging and building")
+	reportPath := flags.String("report", builder.ReportName, "where to write the report of the candidate once it is built; empty for none")
+	reuse := flags.Bool("reuse", false, "keep the existing image when it was built from the same Dockerfile, manifest and base image")
+	dryRun := flags.Bool("dry-run", false, "print the commands the build would run instead of running them")
+	pullTimeout := flags.Duration("pull-timeout", 30*time.Minute, "time allowed for pulling the base image before the docker backend builds")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *dryRun {
+		cmdlog.DryRun = os.Stdout
+	}
+
+	if *backend == builder.BackendOCI && *layoutDir == "" {
+		fmt.Fprintln(os.Stderr, "build: -layout is required with -backend oci")
+		return 2
+	}
+
+	if _, err := validation.ProfileFor(*tag); err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 2
+	}
+
+	opts, err := builder.ForVariant(*tag, *variant, *depDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 2
+	}
+
+	if *manifestPath != "" {
+		manifest, err := builder.LoadManifest(*manifestPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "build: %s\n", err)
+			return 2
+		}
+		opts.Manifest = &manifest
+	}
+
+	if *dockerfile != "" {
+		opts.Dockerfile = *dockerfile
+	}
+
+	if *image != "" {
+		opts.Image = *image
+	}
+
+	opts.StrictDependencies = *strict
+	opts.Platform = *platform
+	opts.Backend = *backend
+	opts.LayoutDir = *layoutDir
+	opts.ReportPath = *reportPath
+	opts.Reuse = *reuse
+	opts.Registry = &registry.Client{Credentials: environmentCredentials}
+	opts.Stdout = os.Stdout
+	opts.Stderr = os.Stderr
+
+	if opts.Backend != builder.BackendOCI {
+		pullCtx, cancel := context.WithTimeout(context.Background(), *pullTimeout)
+		err := builder.PullBase(pullCtx, opts)
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "build: %s\n", err)
+			return 1
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := builder.Build(ctx, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "build: %s\n", err)
+		return 1
+	}
+
+	fmt.Println(opts.Image)
+	return 0
+}
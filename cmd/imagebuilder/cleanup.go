@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/cleanup"
+	"github.com/cloudfoundry/windows2016fs/report"
+)
+
+// cleanupCommand removes the candidate and test images, dangling layers and
+// temporary build directories runs left on the worker. It exits 1 if any of
+// them couldn't be removed and 2 on invalid flags.
+func cleanupCommand(args []string) int {
+	flags := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	keepOnFailure := flags.Bool("keep-on-failure", false, "remove nothing when the reports in -report-dir record a failed spec")
+	reportDir := flags.String("report-dir", defaultReportDir(), "directory of the last run's reports (default $REPORT_DIR, or $ARTIFACTS_DIR)")
+	tempDir := flags.String("temp-dir", os.TempDir(), "directory the suite creates its build directories in")
+	dryRun := flags.Bool("dry-run", false, "print what would be removed without removing it")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *keepOnFailure {
+		if *reportDir == "" {
+			fmt.Fprintln(os.Stderr, "cleanup: -keep-on-failure needs -report-dir")
+			return 2
+		}
+
+		failed, err := report.Failures(*reportDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cleanup: %s\n", err)
+			return 1
+		}
+		if failed > 0 {
+			fmt.Printf("%d specs failed, keeping everything for debugging\n", failed)
+			return 0
+		}
+	}
+
+	m, err := cleanup.Worker(*tempDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "cleanup: %s\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		for _, item := range append(m.Images(), m.Dirs()...) {
+			fmt.Printf("would remove %s\n", item)
+		}
+		return 0
+	}
+
+	var problems []string
+	if err := m.Run(os.Stdout); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if err := cleanup.PruneDangling(os.Stdout); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "cleanup: %s\n", strings.Join(problems, "\n"))
+		return 1
+	}
+
+	return 0
+}
+
+func defaultReportDir() string {
+	if dir := os.Getenv("REPORT_DIR"); dir != "" {
+		return dir
+	}
+
+	return os.Getenv("ARTIFACTS_DIR")
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// credentialFlags are the flags of commands that authenticate with a
+// registry.
+type credentialFlags struct {
+	username     *string
+	password     *string
+	dockerConfig *string
+}
+
+func addCredentialFlags(flags *flag.FlagSet) credentialFlags {
+	return credentialFlags{
+		username:     flags.String("username", os.Getenv("REGISTRY_USERNAME"), "registry username (default $REGISTRY_USERNAME)"),
+		password:     flags.String("password", os.Getenv("REGISTRY_PASSWORD"), "registry password (default $REGISTRY_PASSWORD)"),
+		dockerConfig: flags.String("docker-config", registry.DockerConfigPath(), "docker CLI configuration to read credentials from when -username isn't set"),
+	}
+}
+
+// credentials returns the credentials for host: -username and -password
+// when set, or those docker login stored for it.
+func (f credentialFlags) credentials(host string) (registry.Credentials, error) {
+	if *f.username != "" {
+		return registry.Credentials{Username: *f.username, Password: *f.password}, nil
+	}
+
+	if *f.dockerConfig == "" {
+		return registry.Credentials{}, nil
+	}
+
+	config, err := registry.LoadDockerConfig(*f.dockerConfig)
+	if err != nil {
+		return registry.Credentials{}, err
+	}
+
+	return config.Credentials(host)
+}
+
+// client returns a registry client that authenticates with host using the
+// credentials the flags select.
+func (f credentialFlags) client(host string, plainHTTP bool) (*registry.Client, error) {
+	credentials, err := f.credentials(host)
+	if err != nil {
+		return nil, err
+	}
+
+	return &registry.Client{
+		Credentials: func(string) registry.Credentials { return credentials },
+		PlainHTTP:   plainHTTP,
+	}, nil
+}
+
+// environmentCredentials returns REGISTRY_USERNAME and REGISTRY_PASSWORD for
+// every registry, the credentials the suite pushes with.
+func environmentCredentials(string) registry.Credentials {
+	return registry.Credentials{Username: os.Getenv("REGISTRY_USERNAME"), Password: os.Getenv("REGISTRY_PASSWORD")}
+}
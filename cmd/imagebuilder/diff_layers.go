@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// diffLayers compares the layer sizes of a candidate with those of the last
+// released image, read from their manifests. It exits 1 if a layer grew by
+// more than -max-growth and 2 on invalid flags.
+func diffLayers(args []string) int {
+	flags := flag.NewFlagSet("diff-layers", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version of the candidate (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the candidate, e.g. nanoserver")
+	candidate := flags.String("candidate", "", "pushed candidate to compare, e.g. registry.example.com/windows2016fs:2019-rc")
+	layoutDir := flags.String("layout", "", "OCI image layout holding the candidate, named by its org.opencontainers.image.ref.name annotation, instead of -candidate")
+	image := flags.String("image", "", "name of the candidate in -layout (default windows2016fs-candidate:<tag>[-<variant>])")
+	previous := flags.String("previous", "", "released image to compare with (default cloudfoundry/windows2016fs:<tag>[-<variant>])")
+	platform := flags.String("platform", "", "platform to compare when an image is a manifest list (default windows/amd64)")
+	maxGrowth := flags.Float64("max-growth", 10, "percentage a layer may grow by")
+	minSize := flags.String("min-size", "10MiB", "size below which layers aren't checked")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http")
+	timeout := flags.Duration("timeout", 5*time.Minute, "time allowed for reading the manifests")
+	credentials := addCredentialFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *tag == "" {
+		fmt.Fprintln(os.Stderr, "diff-layers: -tag is required")
+		return 2
+	}
+	if (*candidate == "") == (*layoutDir == "") {
+		fmt.Fprintln(os.Stderr, "diff-layers: give either -candidate or -layout")
+		return 2
+	}
+	if *image == "" {
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+	if *previous == "" {
+		*previous = "cloudfoundry/windows2016fs:" + validation.VariantTag(*tag, *variant)
+	}
+
+	minBytes, err := validation.ParseSize(*minSize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-layers: -min-size: %s\n", err)
+		return 2
+	}
+
+	previousRef, err := registry.ParseReference(*previous)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-layers: %s\n", err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	released, err := readLayers(ctx, credentials, previousRef, *platform, *plainHTTP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-layers: %s\n", err)
+		return 1
+	}
+
+	var current []publish.Layer
+	if *layoutDir != "" {
+		current, err = publish.LayoutLayers(*layoutDir, *image)
+	} else {
+		var candidateRef registry.Reference
+		if candidateRef, err = registry.ParseReference(*candidate); err != nil {
+			fmt.Fprintf(os.Stderr, "diff-layers: %s\n", err)
+			return 2
+		}
+		current, err = readLayers(ctx, credentials, candidateRef, *platform, *plainHTTP)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-layers: %s\n", err)
+		return 1
+	}
+
+	comparison := publish.CompareLayers(released, current, *maxGrowth, minBytes)
+	fmt.Printf("layer sizes from %s to the candidate:\n%s", previousRef, comparison)
+
+	if len(comparison.Grown) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// readLayers reads the layers of ref with the credentials for its registry.
+func readLayers(ctx context.Context, credentials credentialFlags, ref registry.Reference, platform string, plainHTTP bool) ([]publish.Layer, error) {
+	client, err := credentials.client(ref.Host, plainHTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	return publish.ImageLayers(ctx, client, ref, platform)
+}
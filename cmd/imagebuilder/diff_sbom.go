@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/sbom"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// diffSBOM prints the components added, removed and upgraded between the
+// SBOM attached to the last released image and the candidate's.
+func diffSBOM(args []string) int {
+	flags := flag.NewFlagSet("diff-sbom", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version of the candidate (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the candidate, e.g. nanoserver")
+	image := flags.String("image", "", "local image to describe (default windows2016fs-candidate:<tag>[-<variant>])")
+	candidateSBOM := flags.String("candidate-sbom", "", "CycloneDX or SPDX document of the candidate, written by the sbom command, instead of describing the local image")
+	previous := flags.String("previous", "", "released image whose attached SBOM to compare with (default cloudfoundry/windows2016fs:<tag>[-<variant>])")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for collecting and fetching")
+	credentials := addCredentialFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *tag == "" {
+		fmt.Fprintln(os.Stderr, "diff-sbom: -tag is required")
+		return 2
+	}
+	if *image == "" {
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+	if *previous == "" {
+		*previous = "cloudfoundry/windows2016fs:" + validation.VariantTag(*tag, *variant)
+	}
+
+	ref, err := registry.ParseReference(*previous)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-sbom: %s\n", err)
+		return 2
+	}
+
+	var candidate sbom.Document
+	if *candidateSBOM != "" {
+		candidate, err = readSBOM(*candidateSBOM)
+	} else {
+		candidate, err = collectSBOM(*image, *tag, *variant)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-sbom: %s\n", err)
+		return 1
+	}
+
+	client, err := credentials.client(ref.Host, *plainHTTP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-sbom: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	released, err := sbom.Fetch(ctx, client, ref, sbom.Formats[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff-sbom: %s\n", err)
+		return 1
+	}
+
+	fmt.Print(sbom.Compare(released, candidate))
+
+	return 0
+}
+
+// collectSBOM describes a local candidate the way the sbom command does.
+func collectSBOM(image, tag, variant string) (sbom.Document, error) {
+	dir := validation.VariantDir(tag, variant)
+	manifest, err := builder.LoadManifest(filepath.Join(dir, builder.ManifestName))
+	if err != nil {
+		return sbom.Document{}, err
+	}
+
+	return sbom.Collect(image, validation.VariantTag(tag, variant), filepath.Join(dir, "Dockerfile"), manifest)
+}
+
+// readSBOM decodes a document in the format its extension names.
+func readSBOM(path string) (sbom.Document, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return sbom.Document{}, err
+	}
+
+	for _, format := range sbom.Formats {
+		if strings.HasSuffix(path, format.Extension) {
+			return format.Decode(content)
+		}
+	}
+
+	return sbom.Document{}, fmt.Errorf("%s isn't a .cdx.json or .spdx.json document", path)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// exportCommand saves a candidate to a tarball in an output directory, with
+// a checksum file next to it, for foundations that can't pull from a
+// registry. The tarball's manifests and layers are checked against their
+// digests before it is kept. It exits 1 if saving or checking fails and 2
+// on invalid flags.
+func exportCommand(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version of the candidate (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the candidate, e.g. nanoserver")
+	image := flags.String("image", "", "candidate to export (default windows2016fs-candidate:<tag>[-<variant>])")
+	layoutDir := flags.String("layout", "", "OCI image layout holding the candidate, named by its org.opencontainers.image.ref.name annotation, instead of the container runtime")
+	output := flags.String("output", "", "directory to write the tarball and its checksum file to (required)")
+	name := flags.String("name", "", "file name of the tarball (default windows2016fs-<tag>[-<variant>].tar)")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for saving the candidate")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *output == "" {
+		fmt.Fprintln(os.Stderr, "export: -output is required")
+		return 2
+	}
+	if *tag == "" && (*image == "" || *name == "") {
+		fmt.Fprintln(os.Stderr, "export: -tag, or -image and -name, is required")
+		return 2
+	}
+	if *image == "" {
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+	if *name == "" {
+		*name = "windows2016fs-" + validation.VariantTag(*tag, *variant) + ".tar"
+	}
+
+	if err := os.MkdirAll(*output, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+	path := filepath.Join(*output, *name)
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := saveCandidate(ctx, *layoutDir, *image, path); err != nil {
+		os.Remove(path)
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+
+	summary, err := builder.VerifyArchive(path)
+	if err != nil {
+		os.Remove(path)
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+
+	checksumPath, err := builder.WriteChecksum(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("exported %s as a %s archive of %d layers, %s\n", *image, summary.Format, summary.Layers, validation.FormatSize(summary.Size))
+	fmt.Printf("wrote %s\n", strings.Join([]string{path, checksumPath}, ", "))
+	return 0
+}
+
+// saveCandidate writes image to path, from the OCI image layout at
+// layoutDir when it is set and from validation.Runtime otherwise.
+func saveCandidate(ctx context.Context, layoutDir, image, path string) error {
+	if layoutDir == "" {
+		return builder.SaveArchive(ctx, image, path)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = builder.ExportLayout(layoutDir, image, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
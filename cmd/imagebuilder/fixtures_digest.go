@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// fixturesDigestCommand prints the digest of a fixtures directory and, with
+// -write, records it as the reviewed digest.
+func fixturesDigestCommand(args []string) int {
+	flags := flag.NewFlagSet("fixtures-digest", flag.ContinueOnError)
+	dir := flags.String("dir", "fixtures", "fixtures directory")
+	write := flags.Bool("write", false, "record the digest in the directory's "+validation.FixturesDigestFile)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	digest, err := validation.FixturesDigest(*dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fixtures-digest: %s\n", err)
+		return 1
+	}
+
+	if *write {
+		if err := ioutil.WriteFile(filepath.Join(*dir, validation.FixturesDigestFile), []byte(digest+"\n"), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "fixtures-digest: %s\n", err)
+			return 1
+		}
+	}
+
+	fmt.Println(digest)
+	return 0
+}
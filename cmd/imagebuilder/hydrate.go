@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+)
+
+// hydrateCommand downloads the dependencies in a version's manifest into the
+// dependencies directory.
+func hydrateCommand(args []string) int {
+	flags := flag.NewFlagSet("hydrate", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose dependencies to download (default $VERSION_TAG)")
+	depDir := flags.String("dependencies-dir", os.Getenv("DEPENDENCIES_DIR"), "directory to download into (default $DEPENDENCIES_DIR)")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for all downloads")
+	mirrors := flags.String("mirrors", os.Getenv("DEPENDENCY_MIRRORS"), "comma-separated prefix=replacement URL rewrites (default $DEPENDENCY_MIRRORS)")
+
+	opts := builder.HydrateOptions{Retry: builder.DownloadRetry, Log: os.Stdout}
+	flags.IntVar(&opts.Workers, "workers", 4, "concurrent downloads")
+	flags.IntVar(&opts.Retry.Attempts, "attempts", opts.Retry.Attempts, "attempts at each download")
+	flags.DurationVar(&opts.Retry.Backoff, "backoff", opts.Retry.Backoff, "delay before retrying a download, doubling after each retry")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *tag == "" || *depDir == "" {
+		fmt.Fprintln(os.Stderr, "hydrate: -tag and -dependencies-dir are required")
+		return 2
+	}
+
+	var err error
+	opts.Mirrors, err = builder.ParseMirrors(*mirrors)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hydrate: %s\n", err)
+		return 2
+	}
+
+	manifest, err := builder.LoadManifest(builder.ManifestPath(*tag))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "hydrate: %s\n", err)
+		return 2
+	}
+
+	if err := os.MkdirAll(*depDir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "hydrate: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := builder.Hydrate(ctx, manifest, *depDir, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "hydrate: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
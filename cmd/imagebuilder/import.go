@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// importCommand imports a candidate from an OCI image layout into the
+// container runtime, e.g. containerd with CONTAINER_RUNTIME=ctr, so that
+// verify can run its checks on workers without a Docker daemon. It exits 1
+// if the import fails and 2 on invalid flags.
+func importCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version of the candidate (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the candidate, e.g. nanoserver")
+	layoutDir := flags.String("layout", "", "OCI image layout holding the candidate, named by its org.opencontainers.image.ref.name annotation (required)")
+	image := flags.String("image", "", "name of the candidate in -layout, and in the runtime (default windows2016fs-candidate:<tag>[-<variant>])")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for the import")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *layoutDir == "" {
+		fmt.Fprintln(os.Stderr, "import: -layout is required")
+		return 2
+	}
+	if *image == "" {
+		if *tag == "" {
+			fmt.Fprintln(os.Stderr, "import: -tag or -image is required")
+			return 2
+		}
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := builder.ImportLayout(ctx, *layoutDir, *image); err != nil {
+		fmt.Fprintf(os.Stderr, "import: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("imported %s into %s\n", *image, validation.Runtime.Name())
+	return 0
+}
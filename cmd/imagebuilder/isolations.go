@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/report"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// isolationReportName is the combined report isolations writes to its
+// report directory.
+const isolationReportName = "isolation-report.json"
+
+// isolations runs the suite once per isolation mode, each writing its
+// reports to <report-dir>/<mode>, and reports the specs whose outcome
+// differs between the modes. It exits 1 if any run or spec failed and 2 on
+// invalid flags.
+func isolations(args []string) int {
+	flags := flag.NewFlagSet("isolations", flag.ContinueOnError)
+	command := flags.String("command", "ginkgo", "command that runs the suite, split on spaces")
+	modes := flags.String("modes", strings.Join(validation.IsolationModes, ","), "comma-separated isolation modes to run the suite with")
+	reportDir := flags.String("report-dir", defaultReportDir(), "directory the runs' reports and the combined report are written to (default $REPORT_DIR, or $ARTIFACTS_DIR)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *reportDir == "" {
+		fmt.Fprintln(os.Stderr, "isolations: -report-dir is required")
+		return 2
+	}
+
+	commandLine := strings.Fields(*command)
+	if len(commandLine) == 0 {
+		fmt.Fprintln(os.Stderr, "isolations: -command is required")
+		return 2
+	}
+
+	order := strings.Split(*modes, ",")
+	for _, mode := range order {
+		err := validation.ValidateIsolation(mode)
+		if mode == "" {
+			err = errors.New("-modes lists an empty mode")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "isolations: %s\n", err)
+			return 2
+		}
+	}
+
+	var runs []report.Run
+	for i, mode := range order {
+		fmt.Printf("running the suite with %s isolation\n", mode)
+
+		// Every run but the last keeps the candidate, so that the next one
+		// reuses it rather than building it again.
+		run, err := isolationRun(commandLine, mode, filepath.Join(*reportDir, mode), i < len(order)-1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "isolations: %s\n", err)
+			return 1
+		}
+
+		runs = append(runs, run)
+	}
+
+	var combined bytes.Buffer
+	if err := report.WriteComparison(runs, &combined); err != nil {
+		fmt.Fprintf(os.Stderr, "isolations: %s\n", err)
+		return 1
+	}
+	path := filepath.Join(*reportDir, isolationReportName)
+	if err := ioutil.WriteFile(path, combined.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "isolations: %s\n", err)
+		return 1
+	}
+
+	if err := report.PrintComparison(runs, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "isolations: %s\n", err)
+		return 1
+	}
+	fmt.Printf("wrote %s\n", path)
+
+	for _, run := range runs {
+		if run.Error != "" || run.Report.Failed() > 0 {
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// isolationRun runs the suite with mode and reads back the report it wrote
+// to dir. The run never pushes the candidate and, with keepCandidate,
+// leaves the images it built behind.
+func isolationRun(commandLine []string, mode, dir string, keepCandidate bool) (report.Run, error) {
+	run := report.Run{Name: mode}
+
+	// Reports of an earlier comparison would otherwise be read back.
+	if err := os.RemoveAll(dir); err != nil {
+		return report.Run{}, err
+	}
+
+	command := exec.Command(commandLine[0], commandLine[1:]...)
+	command.Env = append(os.Environ(), "DOCKER_ISOLATION="+mode, "REPORT_DIR="+dir, "PUSH_ON_SUCCESS=")
+	if keepCandidate {
+		command.Env = append(command.Env, "SKIP_CLEANUP=true")
+	}
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	if err := cmdlog.Run(command); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return report.Run{}, err
+		}
+
+		run.Error = err.Error()
+	}
+
+	reports, err := report.Read(dir)
+	if err != nil {
+		return report.Run{}, err
+	}
+	if len(reports) == 0 && run.Error == "" {
+		run.Error = "wrote no report"
+	}
+
+	for i, r := range reports {
+		if i == 0 {
+			run.Report = r
+			continue
+		}
+
+		run.Report.Results = append(run.Report.Results, r.Results...)
+	}
+
+	return run, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/k8s"
+	"github.com/cloudfoundry/windows2016fs/report"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// k8sSmoke runs the container test of a pushed candidate as a pod on a
+// Windows node of a Kubernetes cluster and reports whether it started and
+// mounted the share at \\SHARE_IP\SHARE_NAME. It exits 1 if either failed and
+// 2 on invalid flags or settings.
+func k8sSmoke(args []string) int {
+	flags := flag.NewFlagSet("k8s-smoke", flag.ContinueOnError)
+	image := flags.String("image", "", "candidate the cluster's nodes can pull (required)")
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version of the candidate, which picks nodes of its Windows build (default $VERSION_TAG, any Windows node when unset)")
+	kubeconfig := flags.String("kubeconfig", os.Getenv("KUBECONFIG"), "kubeconfig of the cluster (default $KUBECONFIG)")
+	namespace := flags.String("namespace", "", "namespace the pod runs in (default the kubeconfig's)")
+	reportDir := flags.String("report-dir", "", "directory to write JUnit XML and JSON reports of the results to")
+	keep := flags.Bool("keep", false, "leave the pod in place after it ran, for debugging")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for pulling the image and running the pod")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *image == "" {
+		fmt.Fprintln(os.Stderr, "k8s-smoke: -image is required")
+		return 2
+	}
+
+	var missing []string
+	for _, name := range []string{"SHARE_IP", "SHARE_NAME", "SHARE_USERNAME", "SHARE_PASSWORD"} {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "k8s-smoke: %s must be set\n", strings.Join(missing, ", "))
+		return 2
+	}
+
+	script, err := ioutil.ReadFile(filepath.Join(validation.FixturesDir, "container-test.ps1"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "k8s-smoke: %s\n", err)
+		return 2
+	}
+
+	smoke := k8s.Smoke{
+		Image:      *image,
+		Kubeconfig: *kubeconfig,
+		Namespace:  *namespace,
+		ShareUNC:   fmt.Sprintf(`\\%s\%s`, os.Getenv("SHARE_IP"), os.Getenv("SHARE_NAME")),
+		Username:   os.Getenv("SHARE_USERNAME"),
+		Password:   os.Getenv("SHARE_PASSWORD"),
+		Script:     script,
+		Keep:       *keep,
+	}
+
+	if *tag != "" {
+		profile, err := validation.ProfileFor(*tag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "k8s-smoke: %s\n", err)
+			return 2
+		}
+
+		// Process isolated containers need a node of the image's build.
+		smoke.NodeSelector = map[string]string{"node.kubernetes.io/windows-build": fmt.Sprintf("10.0.%d", profile.MinOSBuild)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	started := time.Now()
+	results, err := smoke.Run(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "k8s-smoke: %s\n", err)
+		return 1
+	}
+
+	if err := validation.PrintSummary(results, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "k8s-smoke: %s\n", err)
+		return 1
+	}
+
+	if *reportDir != "" {
+		run := report.Report{Suite: "k8s-smoke", Image: *image, Tag: *tag, Started: started, Duration: time.Since(started), Results: results}
+		paths, err := run.Write(*reportDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "k8s-smoke: %s\n", err)
+			return 1
+		}
+		fmt.Printf("wrote %s\n", strings.Join(paths, ", "))
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if !result.Passed {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Name, result.Message)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
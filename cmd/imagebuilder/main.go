@@ -0,0 +1,79 @@
+// Command imagebuilder builds and validates windows2016fs images outside of
+// the Ginkgo suite.
+//
+// Usage:
+//
+//	imagebuilder <command> [flags]
+//
+// Run `imagebuilder <command> -h` for the flags of a command. Commands
+// drive the container runtime named by $CONTAINER_RUNTIME, docker by
+// default.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// commands maps each subcommand to its implementation, which receives the
+// arguments following the subcommand name and returns the process exit code.
+var commands = map[string]func(args []string) int{
+	"build":            build,
+	"cleanup":          cleanupCommand,
+	"diff-layers":      diffLayers,
+	"diff-sbom":        diffSBOM,
+	"export":           exportCommand,
+	"fixtures-digest":  fixturesDigestCommand,
+	"hydrate":          hydrateCommand,
+	"import":           importCommand,
+	"isolations":       isolations,
+	"k8s-smoke":        k8sSmoke,
+	"manifest-list":    manifestList,
+	"matrix":           matrix,
+	"pin-base-image":   pinBaseImage,
+	"pin-dependencies": pinDependencies,
+	"preflight":        preflight,
+	"promote":          promote,
+	"publish":          publishCommand,
+	"sbom":             sbomCommand,
+	"snapshot":         snapshotCommand,
+	"upload":           uploadCommand,
+	"verify":           verify,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	runtime, err := validation.RuntimeFor(os.Getenv("CONTAINER_RUNTIME"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	validation.Runtime = runtime
+
+	os.Exit(command(os.Args[2:]))
+}
+
+func usage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "usage: imagebuilder <command> [flags]\n\ncommands: %s\n", strings.Join(names, ", "))
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// manifestList publishes a manifest list combining pushed images of several
+// versions under one tag.
+func manifestList(args []string) int {
+	flags := flag.NewFlagSet("manifest-list", flag.ContinueOnError)
+	target := flags.String("target", "", "reference to publish the list as, e.g. cloudfoundry/windows2016fs:latest")
+	images := flags.String("images", "", "comma-separated pushed images to list, e.g. cloudfoundry/windows2016fs:2019.12,cloudfoundry/windows2016fs:2022.1")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http")
+	credentials := addCredentialFlags(flags)
+	timeout := flags.Duration("timeout", 10*time.Minute, "time allowed for publishing")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *target == "" || *images == "" {
+		fmt.Fprintln(os.Stderr, "manifest-list: -target and -images are required")
+		return 2
+	}
+
+	targetRef, err := registry.ParseReference(*target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "manifest-list: %s\n", err)
+		return 2
+	}
+
+	var sources []registry.Reference
+	for _, image := range strings.Split(*images, ",") {
+		source, err := registry.ParseReference(strings.TrimSpace(image))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "manifest-list: %s\n", err)
+			return 2
+		}
+		sources = append(sources, source)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client, err := credentials.client(targetRef.Host, *plainHTTP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "manifest-list: %s\n", err)
+		return 1
+	}
+
+	digest, err := publish.ManifestList(ctx, client, targetRef, sources)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "manifest-list: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("%s@%s\n", targetRef, digest)
+	return 0
+}
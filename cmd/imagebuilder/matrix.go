@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// matrix builds and verifies several version tags in turn and prints one
+// combined summary. Each tag is built from <dependencies-dir>/<tag> into its
+// own context directory and candidate image, so the builds don't collide. It
+// exits 1 if any build or check fails and 2 on invalid flags.
+func matrix(args []string) int {
+	flags := flag.NewFlagSet("matrix", flag.ContinueOnError)
+	tags := flags.String("tags", strings.Join(validation.KnownTags(), ","), "comma-separated versions to build")
+	variant := flags.String("variant", validation.DefaultVariant, "variant to build, e.g. nanoserver")
+	depDir := flags.String("dependencies-dir", os.Getenv("DEPENDENCIES_DIR"), "directory with a dependencies directory per tag (default $DEPENDENCIES_DIR)")
+	checks := flags.String("checks", "", fmt.Sprintf("comma-separated checks to run, from %s (default all that apply to the variant)", strings.Join(validation.CheckNames(), ", ")))
+	strict := flags.Bool("strict", os.Getenv("VERIFY_DEPENDENCIES") != "", "fail on dependencies that aren't pinned (default $VERIFY_DEPENDENCIES)")
+	platform := flags.String("platform", "", "platform passed to docker build and run")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for staging and building each tag")
+	dryRun := flags.Bool("dry-run", false, "print the commands the builds and checks would run instead of running them")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	selected, err := validation.VariantFor(*variant)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "matrix: %s\n", err)
+		return 2
+	}
+
+	names := selected.Checks
+	if *checks != "" {
+		names = strings.Split(*checks, ",")
+	}
+
+	builds := map[string]builder.Options{}
+	order := strings.Split(*tags, ",")
+	for _, tag := range order {
+		if _, err := validation.ProfileFor(tag); err != nil {
+			fmt.Fprintf(os.Stderr, "matrix: %s\n", err)
+			return 2
+		}
+
+		tagDepDir := ""
+		if *depDir != "" {
+			tagDepDir = filepath.Join(*depDir, tag)
+		}
+
+		opts, err := builder.ForVariant(tag, *variant, tagDepDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "matrix: %s\n", err)
+			return 2
+		}
+
+		opts.StrictDependencies = *strict
+		opts.Platform = *platform
+		opts.Stdout = os.Stdout
+		opts.Stderr = os.Stderr
+		builds[tag] = opts
+	}
+
+	validation.Platform = *platform
+
+	if *dryRun {
+		cmdlog.DryRun = os.Stdout
+		for _, tag := range order {
+			ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+			err := builder.Build(ctx, builds[tag])
+			cancel()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "matrix: %s\n", err)
+				return 1
+			}
+
+			if err := dryRunChecks(validation.Candidate{Image: builds[tag].Image, Tag: tag, Variant: *variant}, names); err != nil {
+				fmt.Fprintf(os.Stderr, "matrix: %s\n", err)
+				return 2
+			}
+		}
+
+		return 0
+	}
+
+	var results []validation.CheckResult
+	for _, tag := range order {
+		candidate := validation.Candidate{Image: builds[tag].Image, Tag: tag, Variant: *variant}
+		results = append(results, matrixTag(candidate, builds[tag], names, *timeout)...)
+	}
+
+	if err := validation.PrintSummary(results, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "matrix: %s\n", err)
+		return 2
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if !result.Passed {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Name, result.Message)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+// matrixTag builds candidate with opts and verifies it, naming each result
+// <tag>[-<variant>]/<check>. A failed build is reported as a failed "build"
+// result and skips the checks.
+func matrixTag(candidate validation.Candidate, opts builder.Options, names []string, timeout time.Duration) []validation.CheckResult {
+	name := candidate.VariantTag()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := builder.Build(ctx, opts)
+	build := validation.CheckResult{
+		Name:     name + "/build",
+		Passed:   err == nil,
+		Duration: time.Since(start),
+		Metadata: map[string]string{"image": opts.Image},
+	}
+	if err != nil {
+		build.Message = err.Error()
+		return []validation.CheckResult{build}
+	}
+
+	results, err := validation.RunChecks(candidate, names)
+	if err != nil {
+		return []validation.CheckResult{build, {Name: name + "/checks", Message: err.Error()}}
+	}
+
+	for i := range results {
+		results[i].Name = name + "/" + results[i].Name
+	}
+
+	return append([]validation.CheckResult{build}, results...)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// pinBaseImage records the digest the base image tag of a version's
+// Dockerfile points to in its manifest, so later builds use that image.
+func pinBaseImage(args []string) int {
+	flags := flag.NewFlagSet("pin-base-image", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose base image to pin (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant whose base image to pin, e.g. nanoserver")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http")
+	timeout := flags.Duration("timeout", 5*time.Minute, "time allowed for resolving the digest")
+	credentials := addCredentialFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *tag == "" {
+		fmt.Fprintln(os.Stderr, "pin-base-image: -tag is required")
+		return 2
+	}
+
+	dir := validation.VariantDir(*tag, *variant)
+	dockerfile, err := ioutil.ReadFile(filepath.Join(dir, "Dockerfile"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-base-image: %s\n", err)
+		return 1
+	}
+
+	image, _, err := builder.BaseImage(dockerfile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-base-image: %s\n", err)
+		return 1
+	}
+
+	ref, err := registry.ParseReference(image)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-base-image: %s\n", err)
+		return 1
+	}
+
+	path := filepath.Join(dir, builder.ManifestName)
+	manifest, err := builder.LoadManifest(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-base-image: %s\n", err)
+		return 1
+	}
+
+	client, err := credentials.client(ref.Host, *plainHTTP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-base-image: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	manifest.BaseImageDigest, err = builder.ResolveBaseImage(ctx, client, image)
+	if err == nil {
+		err = manifest.Write(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-base-image: %s\n", err)
+		return 1
+	}
+
+	fmt.Println(builder.PinnedImage(image, manifest.BaseImageDigest))
+	return 0
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+)
+
+// pinDependencies records the size and SHA256 of every dependency in a
+// version's manifest from a reviewed dependencies directory.
+func pinDependencies(args []string) int {
+	flags := flag.NewFlagSet("pin-dependencies", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose manifest to pin (default $VERSION_TAG)")
+	depDir := flags.String("dependencies-dir", os.Getenv("DEPENDENCIES_DIR"), "directory of reviewed dependencies (default $DEPENDENCIES_DIR)")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *tag == "" || *depDir == "" {
+		fmt.Fprintln(os.Stderr, "pin-dependencies: -tag and -dependencies-dir are required")
+		return 2
+	}
+
+	path := builder.ManifestPath(*tag)
+	manifest, err := builder.LoadManifest(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-dependencies: %s\n", err)
+		return 1
+	}
+
+	pinned, err := manifest.Pin(*depDir)
+	if err == nil {
+		err = pinned.Write(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pin-dependencies: %s\n", err)
+		return 1
+	}
+
+	for _, dependency := range pinned.Dependencies {
+		fmt.Printf("%s  %s\n", dependency.SHA256, dependency.Name)
+	}
+	return 0
+}
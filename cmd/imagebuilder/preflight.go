@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// preflight checks that the host can build and run the candidate of a tag
+// before anything is built: the daemon runs Windows containers, the host's
+// build and isolation can run the image, and there is enough free space. It
+// exits 1 if the suite can't run and 2 on invalid flags.
+func preflight(args []string) int {
+	flags := flag.NewFlagSet("preflight", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose expected Windows build the host must run (default $VERSION_TAG)")
+	image := flags.String("image", "", "candidate whose Windows build to check instead of the tag's, when it was already built")
+	isolation := flags.String("isolation", os.Getenv("DOCKER_ISOLATION"), "isolation the suite will run with: process or hyperv (default $DOCKER_ISOLATION, or the daemon's)")
+	minFreeSpace := flags.String("min-free-space", "25GiB", "free space the daemon's root directory needs for a build and run")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	profile, err := validation.ProfileFor(*tag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "preflight: %s\n", err)
+		return 2
+	}
+
+	if err := validation.ValidateIsolation(*isolation); err != nil {
+		fmt.Fprintf(os.Stderr, "preflight: %s\n", err)
+		return 2
+	}
+
+	opts := validation.PreflightOptions{ImageBuild: profile.MinOSBuild, Isolation: *isolation}
+	if opts.MinFreeSpace, err = validation.ParseSize(*minFreeSpace); err != nil {
+		fmt.Fprintf(os.Stderr, "preflight: -min-free-space: %s\n", err)
+		return 2
+	}
+
+	if *image != "" {
+		if opts.ImageBuild, err = validation.ImageOSBuild(*image); err != nil {
+			fmt.Fprintf(os.Stderr, "preflight: %s\n", err)
+			return 1
+		}
+	}
+
+	host, err := validation.InspectHost()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "preflight: %s\n", err)
+		return 1
+	}
+
+	results := validation.CheckHost(host, opts)
+	if err := validation.PrintSummary(results, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "preflight: %s\n", err)
+		return 1
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if !result.Passed {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", result.Name, result.Message)
+			exitCode = 1
+		}
+	}
+
+	if exitCode == 0 {
+		fmt.Printf("the suite can run %s images of build %d on this host\n", *tag, opts.ImageBuild)
+	}
+
+	return exitCode
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// promote pushes a validated candidate as an immutable version tag, points
+// the release tag at it and writes a JSON record of the promotion.
+func promote(args []string) int {
+	flags := flag.NewFlagSet("promote", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose candidate to promote (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant whose candidate to promote, e.g. nanoserver")
+	image := flags.String("image", "", "validated candidate (default windows2016fs-candidate:<tag>[-<variant>])")
+	repository := flags.String("repository", "cloudfoundry/windows2016fs", "repository to promote to")
+	version := flags.String("version", "", "immutable version tag, e.g. 2019.12")
+	releaseTag := flags.String("release-tag", "", "release tag (default <tag>[-<variant>])")
+	record := flags.String("record", "", "file to write the promotion record to (default promotion-<version>.json)")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http")
+	timeout := flags.Duration("timeout", time.Hour, "time allowed for promoting")
+	credentials := addCredentialFlags(flags)
+	signing := addSigningFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *version == "" || *tag == "" {
+		fmt.Fprintln(os.Stderr, "promote: -tag and -version are required")
+		return 2
+	}
+
+	if *image == "" {
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+	if *releaseTag == "" {
+		*releaseTag = validation.VariantTag(*tag, *variant)
+	}
+	if *record == "" {
+		*record = fmt.Sprintf("promotion-%s.json", *version)
+	}
+
+	versionRef, err := registry.ParseReference(*repository + ":" + *version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "promote: %s\n", err)
+		return 2
+	}
+	releaseRef := versionRef.WithTag(*releaseTag)
+
+	if _, err := os.Stat(*record); err == nil {
+		fmt.Fprintf(os.Stderr, "promote: %s already exists\n", *record)
+		return 1
+	}
+
+	creds, err := credentials.credentials(versionRef.Host)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "promote: %s\n", err)
+		return 1
+	}
+
+	imageID, err := validation.ImageID(*image)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "promote: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	client := &registry.Client{Credentials: func(string) registry.Credentials { return creds }, PlainHTTP: *plainHTTP}
+	promotion, err := publish.Promote(ctx, client, publish.PromoteOptions{
+		Source:        *image,
+		SourceImageID: imageID,
+		Version:       versionRef,
+		Release:       releaseRef,
+		Push: func(local, remote string) (string, error) {
+			return validation.PushImageWithAuth(ctx, local, remote, validation.RegistryAuth{Username: creds.Username, Password: creds.Password}, validation.PushRetry)
+		},
+		Retry: validation.PushRetry,
+	})
+	if err == nil {
+		err = signing.signDigest(ctx, versionRef, promotion.Digest)
+		promotion.Signed = err == nil && *signing.sign
+	}
+	if err == nil {
+		err = promotion.Write(*record)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "promote: %s\n", err)
+		return 1
+	}
+
+	for _, promoted := range promotion.Tags {
+		fmt.Printf("%s@%s\n", promoted, promotion.Digest)
+	}
+	return 0
+}
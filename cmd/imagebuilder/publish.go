@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// publishCommand pushes a candidate image to a registry, from the Docker
+// daemon or from an OCI image layout, and prints its digest.
+func publishCommand(args []string) int {
+	flags := flag.NewFlagSet("publish", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose candidate to push (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant whose candidate to push, e.g. nanoserver")
+	image := flags.String("image", "", "image to push (default windows2016fs-candidate:<tag>[-<variant>])")
+	target := flags.String("target", "", "reference to push to, e.g. registry.example.com/cloudfoundry/windows2016fs:2019.12")
+	layoutDir := flags.String("layout", "", "push the image from this OCI image layout instead of the Docker daemon")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http (with -layout)")
+	retry := validation.PushRetry
+	flags.IntVar(&retry.Attempts, "attempts", retry.Attempts, "tries for each upload that fails transiently")
+	flags.DurationVar(&retry.Backoff, "backoff", retry.Backoff, "delay before the first retry, doubling after each")
+	timeout := flags.Duration("timeout", time.Hour, "time allowed for pushing")
+	credentials := addCredentialFlags(flags)
+	signing := addSigningFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *target == "" {
+		fmt.Fprintln(os.Stderr, "publish: -target is required")
+		return 2
+	}
+
+	if *image == "" {
+		if *tag == "" {
+			fmt.Fprintln(os.Stderr, "publish: -image or -tag is required")
+			return 2
+		}
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+
+	targetRef, err := registry.ParseReference(*target)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "publish: %s\n", err)
+		return 2
+	}
+	if targetRef.Digest != "" {
+		fmt.Fprintln(os.Stderr, "publish: -target must be a tag, not a digest")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var digest string
+	if *layoutDir != "" {
+		client, err := credentials.client(targetRef.Host, *plainHTTP)
+		if err == nil {
+			digest, err = publish.Layout(ctx, client, *layoutDir, *image, targetRef, retry, os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: %s\n", err)
+			return 1
+		}
+	} else {
+		creds, err := credentials.credentials(targetRef.Host)
+		if err == nil {
+			digest, err = validation.PushImageWithAuth(ctx, *image, *target, validation.RegistryAuth{Username: creds.Username, Password: creds.Password}, retry)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "publish: %s\n", err)
+			return 1
+		}
+	}
+
+	if err := signing.signDigest(ctx, targetRef, digest); err != nil {
+		fmt.Fprintf(os.Stderr, "publish: %s\n", err)
+		return 1
+	}
+
+	fmt.Printf("%s@%s\n", targetRef, digest)
+	return 0
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/sbom"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// sbomCommand writes the CycloneDX and SPDX documents of a candidate and
+// optionally attaches them to its pushed image.
+func sbomCommand(args []string) int {
+	flags := flag.NewFlagSet("sbom", flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version of the candidate (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the candidate, e.g. nanoserver")
+	image := flags.String("image", "", "local image to describe (default windows2016fs-candidate:<tag>[-<variant>])")
+	outputDir := flags.String("output-dir", os.Getenv("ARTIFACTS_DIR"), "directory to write the documents to (default $ARTIFACTS_DIR, or the working directory)")
+	attach := flags.String("attach", "", "pushed image to attach the documents to, e.g. cloudfoundry/windows2016fs:2019.12")
+	plainHTTP := flags.Bool("plain-http", false, "talk to the registry over http")
+	timeout := flags.Duration("timeout", 30*time.Minute, "time allowed for collecting and attaching")
+	credentials := addCredentialFlags(flags)
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *tag == "" {
+		fmt.Fprintln(os.Stderr, "sbom: -tag is required")
+		return 2
+	}
+	if *image == "" {
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+	if *outputDir == "" {
+		*outputDir = "."
+	}
+
+	dir := validation.VariantDir(*tag, *variant)
+	manifest, err := builder.LoadManifest(filepath.Join(dir, builder.ManifestName))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sbom: %s\n", err)
+		return 2
+	}
+
+	doc, err := sbom.Collect(*image, validation.VariantTag(*tag, *variant), filepath.Join(dir, "Dockerfile"), manifest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sbom: %s\n", err)
+		return 1
+	}
+
+	paths, err := doc.Write(*outputDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sbom: %s\n", err)
+		return 1
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+
+	if *attach == "" {
+		return 0
+	}
+
+	ref, err := registry.ParseReference(*attach)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sbom: %s\n", err)
+		return 2
+	}
+
+	client, err := credentials.client(ref.Host, *plainHTTP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sbom: %s\n", err)
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	digests, err := sbom.Attach(ctx, client, ref, doc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sbom: %s\n", err)
+		return 1
+	}
+	for _, digest := range digests {
+		fmt.Println(ref.WithDigest(digest))
+	}
+
+	return 0
+}
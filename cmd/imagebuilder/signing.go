@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// signingFlags are the flags of commands that sign what they push.
+type signingFlags struct {
+	sign *bool
+	key  *string
+}
+
+func addSigningFlags(flags *flag.FlagSet) signingFlags {
+	return signingFlags{
+		sign: flags.Bool("sign", os.Getenv("SIGN_IMAGES") != "", "sign the pushed digest with cosign (default $SIGN_IMAGES)"),
+		key:  flags.String("sign-key", os.Getenv("COSIGN_KEY"), "cosign key to sign with; keyless when empty (default $COSIGN_KEY)"),
+	}
+}
+
+// signDigest signs repository@digest when signing is enabled.
+func (f signingFlags) signDigest(ctx context.Context, ref registry.Reference, digest string) error {
+	if !*f.sign {
+		return nil
+	}
+
+	signer := publish.Signer{Key: *f.key, Stdout: os.Stdout, Stderr: os.Stderr}
+	return signer.Sign(ctx, ref.WithDigest(digest))
+}
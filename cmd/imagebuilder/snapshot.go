@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// snapshot is a kind of baseline fixture: where it is kept for a tag and how
+// it is captured from an image, given the path of the current fixture.
+type snapshot struct {
+	path  func(tag string) string
+	write func(image, path string, w io.Writer) error
+}
+
+// snapshots are the baselines snapshot writes, by the name it is given.
+var snapshots = map[string]snapshot{
+	"certificates": {path: validation.CertificatesBaselinePath, write: snapshotCertificates},
+	"env":          {path: validation.EnvBaselinePath, write: snapshotEnv},
+	"features":     {path: validation.FeaturesBaselinePath, write: snapshotFeatures},
+	"registry":     {path: validation.RegistryBaselinePath, write: snapshotRegistry},
+	"services":     {path: validation.ServicesBaselinePath, write: snapshotServices},
+	"tasks":        {path: validation.TasksBaselinePath, write: snapshotTasks},
+}
+
+// snapshotCommand regenerates a baseline fixture from a running image, so
+// that updating one after an intended change is a single reproducible
+// command. It exits 1 if the image can't be inspected and 2 on invalid
+// flags.
+func snapshotCommand(args []string) int {
+	var kinds []string
+	for kind := range snapshots {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	if len(args) == 0 || snapshots[args[0]].write == nil {
+		fmt.Fprintf(os.Stderr, "usage: imagebuilder snapshot <%s> [flags]\n", strings.Join(kinds, "|"))
+		return 2
+	}
+	kind := args[0]
+
+	flags := flag.NewFlagSet("snapshot "+kind, flag.ContinueOnError)
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose baseline to write (default $VERSION_TAG)")
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the image, e.g. nanoserver")
+	image := flags.String("image", "", "known-good image to snapshot (default windows2016fs-candidate:<tag>[-<variant>])")
+	fixturesDir := flags.String("fixtures-dir", validation.FixturesDir, "directory the baseline is written to")
+	timeout := flags.Duration("timeout", validation.OperationTimeout, "time allowed for running the image")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	if *tag == "" {
+		fmt.Fprintf(os.Stderr, "snapshot %s: -tag is required\n", kind)
+		return 2
+	}
+	if *image == "" {
+		*image = builder.CandidateImage(validation.VariantTag(*tag, *variant))
+	}
+
+	validation.FixturesDir = *fixturesDir
+	validation.OperationTimeout = *timeout
+
+	path := snapshots[kind].path(validation.VariantTag(*tag, *variant))
+
+	var buf bytes.Buffer
+	if err := snapshots[kind].write(*image, path, &buf); err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot %s: %s\n", kind, err)
+		return 1
+	}
+
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "snapshot %s: %s\n", kind, err)
+		return 1
+	}
+
+	fmt.Printf("wrote %s from %s\n", path, *image)
+	return 0
+}
+
+func snapshotServices(image, _ string, w io.Writer) error {
+	services, err := validation.Services(image)
+	if err != nil {
+		return err
+	}
+
+	return validation.WriteServicesBaseline(services, w)
+}
+
+func snapshotTasks(image, _ string, w io.Writer) error {
+	tasks, err := validation.ScheduledTasks(image)
+	if err != nil {
+		return err
+	}
+
+	return validation.WriteTasksBaseline(tasks, w)
+}
+
+// snapshotRegistry records the values under the keys of the current
+// baseline, with its ignore patterns, or under DefaultRegistryKeys when there
+// is none yet.
+func snapshotRegistry(image, path string, w io.Writer) error {
+	baseline, err := validation.ReadRegistryBaseline(path)
+	if os.IsNotExist(err) {
+		baseline, err = validation.RegistryBaseline{Keys: validation.DefaultRegistryKeys, Ignore: validation.DefaultRegistryIgnore}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	values, err := validation.RegistryValues(image, baseline.Keys)
+	if err != nil {
+		return err
+	}
+
+	if baseline, err = baseline.Snapshot(values); err != nil {
+		return err
+	}
+
+	return validation.WriteRegistryBaseline(baseline, w)
+}
+
+// snapshotEnv records the environment of a new container of image, keeping
+// the variables the current baseline lets vary, or DefaultEnvVary.
+func snapshotEnv(image, path string, w io.Writer) error {
+	baseline, err := validation.ReadEnvBaseline(path)
+	if os.IsNotExist(err) {
+		baseline, err = validation.EnvBaseline{Vary: validation.DefaultEnvVary}, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if baseline.Env, err = validation.ContainerEnv(image); err != nil {
+		return err
+	}
+
+	return validation.WriteEnvBaseline(baseline, w)
+}
+
+func snapshotFeatures(image, _ string, w io.Writer) error {
+	features, err := validation.WindowsFeatures(image)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(validation.EnabledFeatures(features), "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+func snapshotCertificates(image, _ string, w io.Writer) error {
+	certificates, err := validation.Certificates(image)
+	if err != nil {
+		return err
+	}
+
+	return validation.WriteCertificatesBaseline(certificates, w)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/upload"
+)
+
+// uploadCommand uploads the artifacts of a run, the files given as
+// arguments and those in -dirs, to a bucket under keys derived from their
+// digests, and prints where each went as JSON. It exits 1 if an upload
+// fails and 2 on invalid flags.
+func uploadCommand(args []string) int {
+	flags := flag.NewFlagSet("upload", flag.ContinueOnError)
+	bucket := flags.String("bucket", os.Getenv("ARTIFACTS_BUCKET"), "s3://<bucket>[/<prefix>], gs://<bucket>[/<prefix>] or az://<account>/<container>[/<prefix>] (default $ARTIFACTS_BUCKET)")
+	dirs := flags.String("dirs", defaultReportDir(), "comma-separated directories whose reports, SBOMs, build report and tarballs to upload (default $REPORT_DIR, or $ARTIFACTS_DIR)")
+	timeout := flags.Duration("timeout", time.Hour, "time allowed for uploading")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *bucket == "" {
+		fmt.Fprintln(os.Stderr, "upload: -bucket is required")
+		return 2
+	}
+	target, err := upload.ParseTarget(*bucket)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "upload: -bucket: %s\n", err)
+		return 2
+	}
+
+	var collected []string
+	if *dirs != "" {
+		if collected, err = upload.Collect(strings.Split(*dirs, ",")...); err != nil {
+			fmt.Fprintf(os.Stderr, "upload: %s\n", err)
+			return 1
+		}
+	}
+	paths := append(flags.Args(), collected...)
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "upload: there are no artifacts to upload")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	uploader := upload.Uploader{Target: target, Stdout: os.Stderr, Stderr: os.Stderr}
+	artifacts, err := uploader.Upload(ctx, paths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "upload: %s\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(artifacts); err != nil {
+		fmt.Fprintf(os.Stderr, "upload: %s\n", err)
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/report"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// verify runs validation checks against an image and reports the results.
+// It exits 1 if any check fails and 2 if the checks could not be run.
+func verify(args []string) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	image := flags.String("image", "", "image reference to verify (required)")
+	tag := flags.String("tag", os.Getenv("VERSION_TAG"), "version whose expectations apply to the image (default $VERSION_TAG, else the tag of -image)")
+	checks := flags.String("checks", "", fmt.Sprintf("comma-separated checks to run, from %s (default all that apply to the variant)", strings.Join(validation.CheckNames(), ", ")))
+	variant := flags.String("variant", validation.DefaultVariant, "variant of the image, e.g. nanoserver")
+	output := flags.String("output", "text", "result format: text, tap, junit or json")
+	platform := flags.String("platform", "", "platform passed to docker run")
+	isolation := flags.String("isolation", "", "isolation passed to docker run: process or hyperv (default the daemon's)")
+	dryRun := flags.Bool("dry-run", false, "print the commands the checks would run instead of running them")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *image == "" {
+		fmt.Fprintln(os.Stderr, "verify: -image is required")
+		return 2
+	}
+
+	switch *output {
+	case "text", "tap", "junit", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "verify: unknown output %q; use text, tap, junit or json\n", *output)
+		return 2
+	}
+
+	selected, err := validation.VariantFor(*variant)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %s\n", err)
+		return 2
+	}
+
+	names := selected.Checks
+	if *checks != "" {
+		names = strings.Split(*checks, ",")
+	}
+
+	if err := validation.ValidateIsolation(*isolation); err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %s\n", err)
+		return 2
+	}
+
+	candidate := validation.NewCandidate(*image, *tag, *variant)
+
+	validation.Platform = *platform
+	validation.Isolation = *isolation
+
+	if *dryRun {
+		cmdlog.DryRun = os.Stdout
+		if err := dryRunChecks(candidate, names); err != nil {
+			fmt.Fprintf(os.Stderr, "verify: %s\n", err)
+			return 2
+		}
+		return 0
+	}
+
+	started := time.Now()
+	results, err := validation.RunChecks(candidate, names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %s\n", err)
+		return 2
+	}
+	run := report.Report{Suite: "verify", Image: *image, Started: started, Duration: time.Since(started), Results: results}
+
+	switch *output {
+	case "tap":
+		err = validation.WriteTAP(results, os.Stdout)
+	case "junit":
+		err = report.WriteJUnit(run, os.Stdout)
+	case "json":
+		err = report.WriteJSON(run, os.Stdout)
+	default:
+		err = validation.PrintSummary(results, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "verify: %s\n", err)
+		return 2
+	}
+
+	exitCode := 0
+	for _, result := range results {
+		if !result.Passed {
+			if *output == "text" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", result.Name, result.Message)
+			}
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+// dryRunChecks prints the commands of each of the named checks in turn,
+// with cmdlog.DryRun set. A check whose commands depend on an earlier one's
+// output stops there; its result is meaningless and isn't reported.
+func dryRunChecks(candidate validation.Candidate, names []string) error {
+	known := map[string]bool{}
+	for _, name := range validation.CheckNames() {
+		known[name] = true
+	}
+
+	for _, name := range names {
+		if !known[name] {
+			return fmt.Errorf("unknown check %q; known checks are %s", name, strings.Join(validation.CheckNames(), ", "))
+		}
+
+		fmt.Printf("# check %s\n", name)
+		validation.RunChecks(candidate, []string{name})
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gexec"
+)
+
+// openCommandLog creates the log every command of the run is recorded in:
+// path, or commands-<timestamp>.jsonl in ARTIFACTS_DIR. There is none when
+// neither is set.
+func openCommandLog(path string) (*cmdlog.Log, error) {
+	if path == "" {
+		artifactsDir := os.Getenv("ARTIFACTS_DIR")
+		if artifactsDir == "" {
+			return nil, nil
+		}
+
+		path = filepath.Join(artifactsDir, fmt.Sprintf("commands-%s.jsonl", time.Now().UTC().Format("20060102T150405")))
+	}
+
+	log, err := cmdlog.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(GinkgoWriter, "recording commands in %s\n", path)
+
+	return log, nil
+}
+
+// runSession starts command as a session streaming to GinkgoWriter, waits
+// up to timeout for it to exit and records it in the command log. A session
+// that is still running has exit code -1.
+func runSession(command *exec.Cmd, timeout time.Duration) *Session {
+	start := time.Now()
+	session, err := Start(command, GinkgoWriter, GinkgoWriter)
+	Expect(err).ToNot(HaveOccurred())
+
+	select {
+	case <-session.Exited:
+	case <-time.After(timeout):
+	}
+	cmdlog.Finished(command, start, session.ExitCode(), session.Out.Contents(), session.Err.Contents())
+
+	return session
+}
@@ -0,0 +1,32 @@
+package windows2016fs_test
+
+import (
+	"context"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/gomega"
+)
+
+// mountSMBImage runs the mount container described by spec and returns its
+// output. It reports failures as errors instead of asserting so that it can
+// be called from the goroutines of validation.MountConcurrently.
+func mountSMBImage(spec validation.ContainerSpec) (string, error) {
+	var output string
+
+	err := timedCheck("mount", func(ctx context.Context) error {
+		var err error
+		output, err = validation.MountSMB(ctx, spec)
+		return err
+	})
+
+	return output, err
+}
+
+// expectMountResults asserts, on the spec goroutine, that every worker
+// mapped shareUnc.
+func expectMountResults(attempts []validation.MountAttempt, shareUnc string) {
+	for _, attempt := range attempts {
+		Expect(attempt.Problem(shareUnc)).To(BeEmpty())
+	}
+}
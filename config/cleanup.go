@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Cleanup configures what the suite removes from the worker once it is
+// over: the images it built and its temporary build directory.
+type Cleanup struct {
+	// Skip leaves them in place, for pipelines that use the candidate after
+	// the suite.
+	Skip bool `yaml:"skip" json:"skip"`
+
+	// KeepOnFailure leaves them in place when a spec failed, for
+	// debugging.
+	KeepOnFailure bool `yaml:"keep_on_failure" json:"keep_on_failure"`
+}
+
+// applyEnv overrides skip and keep_on_failure with SKIP_CLEANUP and
+// KEEP_ON_FAILURE and returns the overrides that don't parse.
+func (c *Cleanup) applyEnv() []string {
+	var problems []string
+
+	for _, override := range []struct {
+		key, env string
+		value    *bool
+	}{
+		{"cleanup.skip", "SKIP_CLEANUP", &c.Skip},
+		{"cleanup.keep_on_failure", "KEEP_ON_FAILURE", &c.KeepOnFailure},
+	} {
+		value, ok := os.LookupEnv(override.env)
+		if !ok {
+			continue
+		}
+
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s (%s) is invalid: %q is not true or false", override.key, override.env, value))
+			continue
+		}
+		*override.value = parsed
+	}
+
+	return problems
+}
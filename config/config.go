@@ -0,0 +1,218 @@
+// Package config loads the settings of the windows2016fs suite from a YAML
+// or JSON file, with environment variables overriding the file, and
+// validates them before anything runs.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/upload"
+	"github.com/cloudfoundry/windows2016fs/validation"
+	"gopkg.in/yaml.v2"
+)
+
+// FileEnv names the environment variable holding the path of the
+// configuration file.
+const FileEnv = "SUITE_CONFIG"
+
+// Config holds the settings of a suite run.
+type Config struct {
+	// VersionTag is the version under test, e.g. 2019.
+	VersionTag string `yaml:"version_tag" json:"version_tag"`
+
+	// DependenciesDir holds the installers the candidate is built with.
+	DependenciesDir string `yaml:"dependencies_dir" json:"dependencies_dir"`
+
+	// CandidateImage, when set, is validated instead of building one.
+	CandidateImage string `yaml:"candidate_image" json:"candidate_image"`
+
+	// ContainerRuntime is the CLI images are built and containers run
+	// with: docker, the default, podman, nerdctl or ctr.
+	ContainerRuntime string `yaml:"container_runtime" json:"container_runtime"`
+
+	// Isolation, process or hyperv, is the isolation of every container the
+	// suite runs. The daemon's default applies when it is empty.
+	Isolation string `yaml:"isolation" json:"isolation"`
+
+	Share Share `yaml:"share" json:"share"`
+
+	Timeouts Timeouts `yaml:"timeouts" json:"timeouts"`
+
+	Retry Retry `yaml:"retry" json:"retry"`
+
+	Cleanup Cleanup `yaml:"cleanup" json:"cleanup"`
+
+	// CommandLog is the JSON lines file every command the suite runs is
+	// recorded in. It defaults to a file in ARTIFACTS_DIR, when that is set.
+	CommandLog string `yaml:"command_log" json:"command_log"`
+
+	// ReportDir is where the JUnit XML and JSON reports of the run are
+	// written. It defaults to ARTIFACTS_DIR.
+	ReportDir string `yaml:"report_dir" json:"report_dir"`
+
+	// SizeBudget, such as 6.5GiB, overrides the size budget of the
+	// candidate's tag and variant.
+	SizeBudget string `yaml:"size_budget" json:"size_budget"`
+
+	// ArtifactsBucket, such as s3://releases/windows2016fs, is where the
+	// reports and other artifacts of a run are uploaded when all its specs
+	// pass.
+	ArtifactsBucket string `yaml:"artifacts_bucket" json:"artifacts_bucket"`
+}
+
+// Share is the SMB share the mount specs write to.
+type Share struct {
+	Name     string `yaml:"name" json:"name"`
+	Username string `yaml:"username" json:"username"`
+	Password string `yaml:"password" json:"password"`
+	FQDN     string `yaml:"fqdn" json:"fqdn"`
+	IP       string `yaml:"ip" json:"ip"`
+
+	// GMSACredentialSpec, e.g. file://webapp.json, makes the mount specs
+	// authenticate as the gMSA it names instead of with the username and
+	// password.
+	GMSACredentialSpec string `yaml:"gmsa_credspec" json:"gmsa_credspec"`
+}
+
+// setting is a configuration field with the key it has in the file and the
+// environment variable overriding it.
+type setting struct {
+	key      string
+	env      string
+	value    *string
+	required bool
+	validate func(string) error
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "version_tag", env: "VERSION_TAG", value: &c.VersionTag, required: true, validate: isKnownTag},
+		{key: "dependencies_dir", env: "DEPENDENCIES_DIR", value: &c.DependenciesDir},
+		{key: "candidate_image", env: "TEST_CANDIDATE_IMAGE", value: &c.CandidateImage},
+		{key: "container_runtime", env: "CONTAINER_RUNTIME", value: &c.ContainerRuntime, validate: isRuntime},
+		{key: "isolation", env: "DOCKER_ISOLATION", value: &c.Isolation, validate: validation.ValidateIsolation},
+		{key: "share.name", env: "SHARE_NAME", value: &c.Share.Name, required: true},
+		{key: "share.username", env: "SHARE_USERNAME", value: &c.Share.Username, required: true},
+		{key: "share.password", env: "SHARE_PASSWORD", value: &c.Share.Password, required: true},
+		{key: "share.fqdn", env: "SHARE_FQDN", value: &c.Share.FQDN, required: true, validate: isFQDN},
+		{key: "share.ip", env: "SHARE_IP", value: &c.Share.IP, required: true, validate: isIP},
+		{key: "share.gmsa_credspec", env: "GMSA_CREDSPEC", value: &c.Share.GMSACredentialSpec, validate: isCredentialSpec},
+		{key: "command_log", env: "COMMAND_LOG", value: &c.CommandLog},
+		{key: "report_dir", env: "REPORT_DIR", value: &c.ReportDir},
+		{key: "size_budget", env: "IMAGE_SIZE_BUDGET", value: &c.SizeBudget, validate: isSize},
+		{key: "artifacts_bucket", env: "ARTIFACTS_BUCKET", value: &c.ArtifactsBucket, validate: isBucket},
+	}
+}
+
+// Load reads the configuration file at path, when path isn't empty,
+// applies the environment variables that are set and validates the result.
+// YAML files may also be written as JSON.
+func Load(path string) (Config, error) {
+	c := Config{Timeouts: DefaultTimeouts, Retry: DefaultRetry}
+
+	if path != "" {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+
+		if err := yaml.UnmarshalStrict(content, &c); err != nil {
+			return Config{}, fmt.Errorf("parsing %s: %s", path, err)
+		}
+	}
+
+	for _, s := range c.settings() {
+		if value, ok := os.LookupEnv(s.env); ok {
+			*s.value = value
+		}
+	}
+
+	problems := append(c.Timeouts.applyEnv(), c.Retry.applyEnv()...)
+	problems = append(problems, c.Cleanup.applyEnv()...)
+
+	return c, c.validate(problems)
+}
+
+// Validate checks every setting and returns a single error listing all the
+// missing and invalid ones.
+func (c Config) Validate() error {
+	return c.validate(nil)
+}
+
+func (c Config) validate(problems []string) error {
+	for _, s := range c.settings() {
+		switch {
+		case *s.value == "" && s.required:
+			problems = append(problems, fmt.Sprintf("%s (%s) is missing", s.key, s.env))
+		case *s.value != "" && s.validate != nil:
+			if err := s.validate(*s.value); err != nil {
+				problems = append(problems, fmt.Sprintf("%s (%s) is invalid: %s", s.key, s.env, err))
+			}
+		}
+	}
+
+	if c.ContainerRuntime == "ctr" && c.CandidateImage == "" {
+		problems = append(problems, "container_runtime (CONTAINER_RUNTIME) ctr can't build the candidate, so candidate_image (TEST_CANDIDATE_IMAGE) must be set")
+	}
+
+	problems = append(problems, c.Timeouts.problems()...)
+	problems = append(problems, c.Retry.problems()...)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+func isIP(value string) error {
+	if net.ParseIP(value) == nil {
+		return fmt.Errorf("%q is not an IP address", value)
+	}
+
+	return nil
+}
+
+func isFQDN(value string) error {
+	if !strings.Contains(strings.Trim(value, "."), ".") {
+		return fmt.Errorf("%q is not a fully qualified domain name", value)
+	}
+
+	return nil
+}
+
+// isCredentialSpec accepts the credential spec references docker's
+// credentialspec security option takes.
+func isCredentialSpec(value string) error {
+	for _, scheme := range []string{"file://", "registry://", "config://"} {
+		if strings.HasPrefix(value, scheme) && len(value) > len(scheme) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%q is not a file://, registry:// or config:// credential spec", value)
+}
+
+func isKnownTag(value string) error {
+	_, err := validation.ProfileFor(value)
+	return err
+}
+
+func isRuntime(value string) error {
+	_, err := validation.RuntimeFor(value)
+	return err
+}
+
+func isSize(value string) error {
+	_, err := validation.ParseSize(value)
+	return err
+}
+
+func isBucket(value string) error {
+	_, err := upload.ParseTarget(value)
+	return err
+}
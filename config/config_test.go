@@ -0,0 +1,199 @@
+package config_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Load", func() {
+	var (
+		dir   string
+		saved map[string]string
+	)
+
+	envs := []string{
+		"VERSION_TAG", "DEPENDENCIES_DIR", "TEST_CANDIDATE_IMAGE", "CONTAINER_RUNTIME", "DOCKER_ISOLATION",
+		"SHARE_NAME", "SHARE_USERNAME", "SHARE_PASSWORD", "SHARE_FQDN", "SHARE_IP", "GMSA_CREDSPEC",
+		"TIMEOUT_BUILD", "TIMEOUT_PULL", "TIMEOUT_RUN", "TIMEOUT_MOUNT", "TIMEOUT_COMMAND", "TIMEOUT_INSPECT", "TIMEOUT_HOST",
+		"DOCKER_RETRY_ATTEMPTS", "DOCKER_RETRY_BACKOFF", "COMMAND_LOG", "REPORT_DIR", "IMAGE_SIZE_BUDGET",
+		"SKIP_CLEANUP", "KEEP_ON_FAILURE", "ARTIFACTS_BUCKET",
+	}
+
+	validShare := "share:\n  name: s\n  username: u\n  password: p\n  fqdn: share.example.com\n  ip: 10.0.0.5\n"
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		return path
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config")
+		Expect(err).ToNot(HaveOccurred())
+
+		saved = map[string]string{}
+		for _, env := range envs {
+			if value, ok := os.LookupEnv(env); ok {
+				saved[env] = value
+			}
+			os.Unsetenv(env)
+		}
+	})
+
+	AfterEach(func() {
+		for _, env := range envs {
+			os.Unsetenv(env)
+			if value, ok := saved[env]; ok {
+				os.Setenv(env, value)
+			}
+		}
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("reads a YAML file, with environment variables overriding it", func() {
+		path := writeConfig("suite.yml", `
+version_tag: "2019"
+dependencies_dir: C:\dependencies
+share:
+  name: windows2016fs
+  username: smbuser
+  password: from-the-file
+  fqdn: share.example.com
+  ip: 10.0.0.5
+`)
+		os.Setenv("SHARE_PASSWORD", "from-the-environment")
+
+		c, err := config.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c).To(Equal(config.Config{
+			VersionTag:      "2019",
+			DependenciesDir: `C:\dependencies`,
+			Share: config.Share{
+				Name:     "windows2016fs",
+				Username: "smbuser",
+				Password: "from-the-environment",
+				FQDN:     "share.example.com",
+				IP:       "10.0.0.5",
+			},
+			Timeouts: config.DefaultTimeouts,
+			Retry:    config.DefaultRetry,
+		}))
+	})
+
+	It("reads JSON files", func() {
+		path := writeConfig("suite.json", `{"version_tag": "2022", "candidate_image": "windows2016fs-candidate:2022", "share": {"name": "s", "username": "u", "password": "p", "fqdn": "share.example.com", "ip": "10.0.0.5"}}`)
+
+		c, err := config.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.CandidateImage).To(Equal("windows2016fs-candidate:2022"))
+	})
+
+	It("loads from the environment alone", func() {
+		for env, value := range map[string]string{"VERSION_TAG": "2019", "SHARE_NAME": "s", "SHARE_USERNAME": "u", "SHARE_PASSWORD": "p", "SHARE_FQDN": "share.example.com", "SHARE_IP": "10.0.0.5"} {
+			os.Setenv(env, value)
+		}
+
+		c, err := config.Load("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Share.Name).To(Equal("s"))
+	})
+
+	It("lists every missing and invalid setting in one error", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2016\"\ncontainer_runtime: lxc\nisolation: vm\nshare:\n  name: s\n  ip: share.example.com\n  gmsa_credspec: webapp.json\nsize_budget: huge\nartifacts_bucket: https://releases.example.com\n")
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(`invalid configuration:
+  version_tag (VERSION_TAG) is invalid: unknown tag "2016"; known tags are 2019, 2022
+  container_runtime (CONTAINER_RUNTIME) is invalid: unknown container runtime "lxc"; supported runtimes are ctr, docker, nerdctl, podman
+  isolation (DOCKER_ISOLATION) is invalid: unsupported isolation "vm"; supported isolation modes are process, hyperv
+  share.username (SHARE_USERNAME) is missing
+  share.password (SHARE_PASSWORD) is missing
+  share.fqdn (SHARE_FQDN) is missing
+  share.ip (SHARE_IP) is invalid: "share.example.com" is not an IP address
+  share.gmsa_credspec (GMSA_CREDSPEC) is invalid: "webapp.json" is not a file://, registry:// or config:// credential spec
+  size_budget (IMAGE_SIZE_BUDGET) is invalid: "huge" is not a size such as 6.5GiB
+  artifacts_bucket (ARTIFACTS_BUCKET) is invalid: "https://releases.example.com" is not an s3://, gs:// or az:// bucket`))
+	})
+
+	It("requires a candidate image with ctr, which can't build one", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\ncontainer_runtime: ctr\n"+validShare)
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("container_runtime (CONTAINER_RUNTIME) ctr can't build the candidate, so candidate_image (TEST_CANDIDATE_IMAGE) must be set")))
+
+		path = writeConfig("suite.yml", "version_tag: \"2019\"\ncontainer_runtime: ctr\ncandidate_image: docker.io/cloudfoundry/windows2016fs:2019.12\n"+validShare)
+		_, err = config.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("rejects unknown keys", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\nshare_name: s\n")
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("field share_name not found")))
+	})
+
+	It("reads timeouts, defaulting those it doesn't set", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\n"+validShare+"timeouts:\n  pull: 1h\n  mount: 90s\n")
+		os.Setenv("TIMEOUT_MOUNT", "2m")
+
+		c, err := config.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Timeouts.Pull).To(Equal(time.Hour))
+		Expect(c.Timeouts.Mount).To(Equal(2 * time.Minute))
+		Expect(c.Timeouts.Build).To(Equal(config.DefaultTimeouts.Build))
+		Expect(c.Timeouts.ByCheck()).To(HaveKeyWithValue("pull", time.Hour))
+	})
+
+	It("rejects timeouts that aren't positive durations", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\n"+validShare+"timeouts:\n  run: 0s\n")
+		os.Setenv("TIMEOUT_BUILD", "forever")
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(`invalid configuration:
+  timeouts.build (TIMEOUT_BUILD) is invalid: "forever" is not a duration such as 10m
+  timeouts.run (TIMEOUT_RUN) must be positive, got 0s`))
+	})
+
+	It("configures the retry policy of docker operations", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\n"+validShare+"retry:\n  attempts: 5\n  retryable_errors:\n    - 'The RPC server is unavailable'\n")
+		os.Setenv("DOCKER_RETRY_BACKOFF", "30s")
+
+		c, err := config.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		policy := c.Retry.Policy()
+		Expect(policy.Attempts).To(Equal(5))
+		Expect(policy.Backoff).To(Equal(30 * time.Second))
+		Expect(policy.ShouldRetry(1, "hcsshim::CreateComputeSystem: The RPC server is unavailable.")).To(BeTrue())
+		Expect(policy.ShouldRetry(1, "TLS handshake timeout")).To(BeTrue())
+	})
+
+	It("rejects invalid retry settings", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\n"+validShare+"retry:\n  attempts: 0\n  retryable_errors: ['(']\n")
+
+		_, err := config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring("retry.attempts (DOCKER_RETRY_ATTEMPTS) must be at least 1, got 0")))
+		Expect(err).To(MatchError(ContainSubstring(`retry.retryable_errors is invalid: invalid retryable error "("`)))
+	})
+
+	It("keeps what a failed run created when asked to", func() {
+		path := writeConfig("suite.yml", "version_tag: \"2019\"\n"+validShare+"cleanup:\n  keep_on_failure: true\n")
+
+		c, err := config.Load(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.Cleanup).To(Equal(config.Cleanup{KeepOnFailure: true}))
+
+		os.Setenv("KEEP_ON_FAILURE", "maybe")
+		_, err = config.Load(path)
+		Expect(err).To(MatchError(ContainSubstring(`cleanup.keep_on_failure (KEEP_ON_FAILURE) is invalid: "maybe" is not true or false`)))
+	})
+})
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Retry configures how docker operations failing with transient errors,
+// such as a registry hiccup, are retried.
+type Retry struct {
+	// Attempts is how many times an operation runs at most.
+	Attempts int `yaml:"attempts" json:"attempts"`
+
+	// Backoff is the policy's validation.RetryPolicy.Backoff.
+	Backoff time.Duration `yaml:"backoff" json:"backoff"`
+
+	// RetryableErrors are regular expressions matching the output of
+	// further failures to retry, besides validation.RetryableDockerErrors.
+	RetryableErrors []string `yaml:"retryable_errors" json:"retryable_errors"`
+}
+
+// DefaultRetry applies when the configuration doesn't set attempts or a
+// backoff.
+var DefaultRetry = Retry{Attempts: 3, Backoff: 10 * time.Second}
+
+// Policy returns the retry policy of a validated configuration.
+func (r Retry) Policy() validation.RetryPolicy {
+	policy, _ := r.policy()
+	return policy
+}
+
+func (r Retry) policy() (validation.RetryPolicy, error) {
+	patterns := append(append([]string{}, validation.RetryableDockerErrors...), r.RetryableErrors...)
+	return validation.NewRetryPolicy(r.Attempts, r.Backoff, patterns)
+}
+
+// applyEnv overrides the attempts and backoff with DOCKER_RETRY_ATTEMPTS
+// and DOCKER_RETRY_BACKOFF and returns the overrides that don't parse.
+func (r *Retry) applyEnv() []string {
+	var problems []string
+
+	if value, ok := os.LookupEnv("DOCKER_RETRY_ATTEMPTS"); ok {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("retry.attempts (DOCKER_RETRY_ATTEMPTS) is invalid: %q is not a number", value))
+		} else {
+			r.Attempts = attempts
+		}
+	}
+
+	if value, ok := os.LookupEnv("DOCKER_RETRY_BACKOFF"); ok {
+		backoff, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("retry.backoff (DOCKER_RETRY_BACKOFF) is invalid: %q is not a duration such as 10s", value))
+		} else {
+			r.Backoff = backoff
+		}
+	}
+
+	return problems
+}
+
+func (r Retry) problems() []string {
+	var problems []string
+	if r.Attempts < 1 {
+		problems = append(problems, fmt.Sprintf("retry.attempts (DOCKER_RETRY_ATTEMPTS) must be at least 1, got %d", r.Attempts))
+	}
+	if r.Backoff < 0 {
+		problems = append(problems, fmt.Sprintf("retry.backoff (DOCKER_RETRY_BACKOFF) can't be negative, got %s", r.Backoff))
+	}
+	if _, err := r.policy(); err != nil {
+		problems = append(problems, fmt.Sprintf("retry.retryable_errors is invalid: %s", err))
+	}
+
+	return problems
+}
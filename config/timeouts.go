@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Timeouts bound each kind of operation the suite runs. Builds install
+// every dependency and take far longer than a container run or an image
+// inspection; the first pull of servercore can take longer still.
+type Timeouts struct {
+	Build   time.Duration `yaml:"build" json:"build"`
+	Pull    time.Duration `yaml:"pull" json:"pull"`
+	Run     time.Duration `yaml:"run" json:"run"`
+	Mount   time.Duration `yaml:"mount" json:"mount"`
+	Command time.Duration `yaml:"command" json:"command"`
+	Inspect time.Duration `yaml:"inspect" json:"inspect"`
+	Host    time.Duration `yaml:"host" json:"host"`
+}
+
+// DefaultTimeouts apply to the operations the configuration doesn't set.
+var DefaultTimeouts = Timeouts{
+	Build:   30 * time.Minute,
+	Pull:    30 * time.Minute,
+	Run:     5 * time.Minute,
+	Mount:   5 * time.Minute,
+	Command: 10 * time.Minute,
+	Inspect: 2 * time.Minute,
+	Host:    time.Minute,
+}
+
+// ByCheck returns the timeouts keyed by the name of the check they bound,
+// e.g. "build".
+func (t Timeouts) ByCheck() map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+	for _, field := range t.fields() {
+		timeouts[field.name] = *field.timeout
+	}
+
+	return timeouts
+}
+
+type timeoutField struct {
+	name    string
+	timeout *time.Duration
+}
+
+func (t *Timeouts) fields() []timeoutField {
+	return []timeoutField{
+		{"build", &t.Build},
+		{"pull", &t.Pull},
+		{"run", &t.Run},
+		{"mount", &t.Mount},
+		{"command", &t.Command},
+		{"inspect", &t.Inspect},
+		{"host", &t.Host},
+	}
+}
+
+// applyEnv overrides each timeout given as TIMEOUT_<CHECK>, e.g.
+// TIMEOUT_BUILD=45m, and returns the overrides that aren't durations.
+func (t *Timeouts) applyEnv() []string {
+	var problems []string
+	for _, field := range t.fields() {
+		envName := "TIMEOUT_" + strings.ToUpper(field.name)
+		value, ok := os.LookupEnv(envName)
+		if !ok {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("timeouts.%s (%s) is invalid: %q is not a duration such as 10m", field.name, envName, value))
+			continue
+		}
+		*field.timeout = parsed
+	}
+
+	return problems
+}
+
+// problems lists the timeouts that aren't positive.
+func (t Timeouts) problems() []string {
+	var problems []string
+	for _, field := range t.fields() {
+		if *field.timeout <= 0 {
+			problems = append(problems, fmt.Sprintf("timeouts.%s (TIMEOUT_%s) must be positive, got %s", field.name, strings.ToUpper(field.name), *field.timeout))
+		}
+	}
+
+	return problems
+}
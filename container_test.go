@@ -0,0 +1,46 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+)
+
+// skipUnderCtr skips specs that use docker CLI commands, such as build,
+// create, cp or image inspect, which ctr doesn't have.
+func skipUnderCtr() {
+	if _, ok := validation.Runtime.(validation.Ctr); ok {
+		Skip("the spec needs the docker CLI, and container_runtime is ctr")
+	}
+}
+
+// runInImage runs params in image, or in its live container when
+// LIVE_CONTAINER is set, and returns the command's stdout. Unlike
+// expectCommand it reports failures as errors so that helpers can be
+// composed before asserting.
+func runInImage(image string, params ...string) (string, error) {
+	var run validation.ContainerRun
+
+	err := timedCheck("run", func(ctx context.Context) error {
+		var err error
+		run, err = validation.RunIn(ctx, image, params)
+		if err == nil && run.ExitCode != 0 {
+			err = fmt.Errorf("exit status %d", run.ExitCode)
+		}
+		if err != nil {
+			return fmt.Errorf("running %s in %s failed: %s: %s", strings.Join(params, " "), image, err, strings.TrimSpace(run.Stderr))
+		}
+
+		return nil
+	})
+
+	return run.Stdout, err
+}
+
+// powershellIn runs script with powershell in image, as runInImage does.
+func powershellIn(image, script string) (string, error) {
+	return runInImage(image, "powershell", "-Command", script)
+}
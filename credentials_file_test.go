@@ -0,0 +1,47 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+const (
+	containerCredentialsDir  = `C:\share-credentials`
+	credentialsFileName      = "credentials"
+	containerCredentialsFile = containerCredentialsDir + `\` + credentialsFileName
+)
+
+// withCredentialsFile moves the share credentials of spec out of its
+// environment into a file in a bind-mounted temporary directory, since
+// Windows can only bind mount directories. The returned cleanup removes the
+// directory.
+func withCredentialsFile(spec validation.ContainerSpec, shareUsername, sharePassword string) (validation.ContainerSpec, func(), error) {
+	dir, err := ioutil.TempDir("", "share-credentials")
+	if err != nil {
+		return spec, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	contents := fmt.Sprintf("%s\r\n%s\r\n", shareUsername, sharePassword)
+	if err := ioutil.WriteFile(filepath.Join(dir, credentialsFileName), []byte(contents), 0600); err != nil {
+		cleanup()
+		return spec, nil, err
+	}
+
+	env := map[string]string{}
+	for key, value := range spec.Env {
+		if key != "SHARE_USERNAME" && key != "SHARE_PASSWORD" {
+			env[key] = value
+		}
+	}
+	env["SHARE_CREDS_FILE"] = containerCredentialsFile
+
+	spec.Env = env
+	spec.Volumes = append(append([]string{}, spec.Volumes...), fmt.Sprintf("%s:%s", dir, containerCredentialsDir))
+
+	return spec, cleanup, nil
+}
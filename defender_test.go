@@ -0,0 +1,70 @@
+package windows2016fs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// errDefenderUnavailable is returned by defenderConfig when the image has no
+// Defender cmdlets.
+var errDefenderUnavailable = errors.New("Defender cmdlets are not available in the image")
+
+// defenderScript prints the Defender exclusions as JSON, or nothing when
+// Get-MpPreference doesn't exist.
+const defenderScript = `
+if (Get-Command Get-MpPreference -ErrorAction SilentlyContinue) {
+    $preference = Get-MpPreference
+    ConvertTo-Json -InputObject @{
+        ExclusionPath = @($preference.ExclusionPath | Where-Object { $_ })
+        ExclusionProcess = @($preference.ExclusionProcess | Where-Object { $_ })
+    }
+}
+`
+
+// DefenderPrefs holds the Windows Defender exclusions configured in an image.
+type DefenderPrefs struct {
+	ExclusionPath    []string
+	ExclusionProcess []string
+}
+
+// defenderConfig returns the Defender exclusions of image, or
+// errDefenderUnavailable if the image doesn't ship Defender.
+func defenderConfig(image string) (DefenderPrefs, error) {
+	output, err := powershellIn(image, defenderScript)
+	if err != nil {
+		return DefenderPrefs{}, err
+	}
+
+	if strings.TrimSpace(output) == "" {
+		return DefenderPrefs{}, errDefenderUnavailable
+	}
+
+	var prefs DefenderPrefs
+	if err := json.Unmarshal([]byte(output), &prefs); err != nil {
+		return DefenderPrefs{}, fmt.Errorf("parsing Get-MpPreference output %q: %s", output, err)
+	}
+
+	return prefs, nil
+}
+
+// missingExclusions lists the exclusions in expected that aren't configured
+// in actual. Paths and process names compare case-insensitively.
+func (expected DefenderPrefs) missingExclusions(actual DefenderPrefs) []string {
+	var missing []string
+	for _, path := range expected.ExclusionPath {
+		if !validation.ContainsFold(actual.ExclusionPath, path) {
+			missing = append(missing, "path "+path)
+		}
+	}
+	for _, process := range expected.ExclusionProcess {
+		if !validation.ContainsFold(actual.ExclusionProcess, process) {
+			missing = append(missing, "process "+process)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,38 @@
+package windows2016fs_test
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// dnsScript prints the DNS suffix search list and the active hosts file
+// entries, without comments or blank lines, as JSON.
+const dnsScript = `
+ConvertTo-Json -InputObject @{
+    SuffixSearchList = @((Get-DnsClientGlobalSetting).SuffixSearchList | Where-Object { $_ })
+    HostsEntries = @(Get-Content "$env:SystemRoot\System32\drivers\etc\hosts" |
+        ForEach-Object { ($_ -replace '#.*', '').Trim() -replace '\s+', ' ' } |
+        Where-Object { $_ })
+}
+`
+
+// DNSConfig is the name resolution configuration of a container.
+type DNSConfig struct {
+	SuffixSearchList []string
+	HostsEntries     []string
+}
+
+// dnsConfig returns the DNS configuration of a new container of image.
+func dnsConfig(image string) (DNSConfig, error) {
+	output, err := powershellIn(image, dnsScript)
+	if err != nil {
+		return DNSConfig{}, err
+	}
+
+	var config DNSConfig
+	if err := json.Unmarshal([]byte(output), &config); err != nil {
+		return DNSConfig{}, fmt.Errorf("parsing DNS configuration %q: %s", output, err)
+	}
+
+	return config, nil
+}
@@ -0,0 +1,71 @@
+package windows2016fs_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+)
+
+// timedDockerCheck runs a docker operation as a timedCheck, running it again
+// per validation.DockerRetry when it fails with a transient error. outputs
+// are where the operation writes docker's output, which is matched against
+// the retryable errors; the attempts share the check's timeout.
+func timedDockerCheck(name string, operation func(ctx context.Context) error, outputs ...*io.Writer) error {
+	return timedCheck(name, func(ctx context.Context) error {
+		streams := make([]io.Writer, len(outputs))
+		for i, output := range outputs {
+			streams[i] = *output
+		}
+		defer func() {
+			for i, output := range outputs {
+				*output = streams[i]
+			}
+		}()
+
+		for attempt := 1; ; attempt++ {
+			var captured bytes.Buffer
+			for i, output := range outputs {
+				*output = teeWriter(streams[i], &captured)
+			}
+
+			err := operation(ctx)
+			if err == nil || ctx.Err() != nil || !validation.DockerRetry.ShouldRetry(attempt, captured.String()+err.Error()) {
+				return err
+			}
+
+			fmt.Fprintf(GinkgoWriter, "%s check failed with a transient error, retrying (attempt %d of %d): %s\n", name, attempt+1, validation.DockerRetry.Attempts, err)
+			if err := validation.DockerRetry.Wait(ctx, attempt); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// teeWriter writes to stream, when set, and to captured.
+func teeWriter(stream io.Writer, captured io.Writer) io.Writer {
+	if stream == nil {
+		return captured
+	}
+
+	return io.MultiWriter(stream, captured)
+}
+
+// pullAndBuild pulls the base image of opts and builds it, retrying either
+// step on transient docker errors.
+func pullAndBuild(opts *builder.Options) error {
+	err := timedDockerCheck("pull", func(ctx context.Context) error {
+		return builder.PullBase(ctx, *opts)
+	}, &opts.Stdout, &opts.Stderr)
+	if err != nil {
+		return err
+	}
+
+	return timedDockerCheck("build", func(ctx context.Context) error {
+		return builder.Build(ctx, *opts)
+	}, &opts.Stdout, &opts.Stderr)
+}
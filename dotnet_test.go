@@ -0,0 +1,71 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"strings"
+)
+
+const netFrameworkRuntime = "Microsoft.NETFramework"
+
+// listRuntimesScript prints one "<name> <version>" line per runtime, in the
+// format of `dotnet --list-runtimes`. The .NET Framework is always reported;
+// .NET Core runtimes only when the dotnet CLI is installed.
+const listRuntimesScript = `
+$version = Get-ItemPropertyValue 'HKLM:\SOFTWARE\Microsoft\NET Framework Setup\NDP\v4\Full\' -Name Version
+Write-Output "` + netFrameworkRuntime + ` $version"
+if (Get-Command dotnet -ErrorAction SilentlyContinue) {
+    dotnet --list-runtimes
+}
+`
+
+// Runtime is an installed .NET runtime.
+type Runtime struct {
+	Name    string
+	Version string
+}
+
+// installedDotNetRuntimes returns the .NET Framework and, when present, the
+// .NET Core runtimes installed in image.
+func installedDotNetRuntimes(image string) ([]Runtime, error) {
+	output, err := powershellIn(image, listRuntimesScript)
+	if err != nil {
+		return nil, err
+	}
+
+	var runtimes []Runtime
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("unexpected runtime line %q", line)
+		}
+
+		runtimes = append(runtimes, Runtime{Name: fields[0], Version: fields[1]})
+	}
+
+	return runtimes, nil
+}
+
+// missingRuntimes returns every expected runtime that has no installed
+// runtime of the same name with at least the expected version.
+func missingRuntimes(expected, installed []Runtime) []Runtime {
+	var missing []Runtime
+
+	for _, want := range expected {
+		found := false
+		for _, have := range installed {
+			if have.Name == want.Name && compareVersions(have.Version, want.Version) >= 0 {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,52 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// consoleEncodingScript prints the active code page reported by chcp, then
+// the code page and name of the console output encoding.
+const consoleEncodingScript = `
+(chcp) -replace '\D', ''
+[Console]::OutputEncoding.CodePage
+[Console]::OutputEncoding.WebName
+`
+
+// EncodingInfo describes the console encoding of a container.
+type EncodingInfo struct {
+	ActiveCodePage int
+	OutputCodePage int
+	OutputEncoding string
+}
+
+// consoleEncoding returns the console encoding of a new container of image.
+func consoleEncoding(image string) (EncodingInfo, error) {
+	output, err := powershellIn(image, consoleEncodingScript)
+	if err != nil {
+		return EncodingInfo{}, err
+	}
+
+	lines := validation.NonEmptyLines(output)
+	if len(lines) != 3 {
+		return EncodingInfo{}, fmt.Errorf("unexpected console encoding output %q", output)
+	}
+
+	activeCodePage, err := strconv.Atoi(lines[0])
+	if err != nil {
+		return EncodingInfo{}, fmt.Errorf("unexpected chcp output %q", lines[0])
+	}
+
+	outputCodePage, err := strconv.Atoi(lines[1])
+	if err != nil {
+		return EncodingInfo{}, fmt.Errorf("unexpected output code page %q", lines[1])
+	}
+
+	return EncodingInfo{
+		ActiveCodePage: activeCodePage,
+		OutputCodePage: outputCodePage,
+		OutputEncoding: lines[2],
+	}, nil
+}
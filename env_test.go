@@ -0,0 +1,31 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// envDifferences lists every variable in required that is missing from
+// actual or set to a different value. Windows variable names are case
+// insensitive.
+func envDifferences(required, actual map[string]string) []string {
+	folded := map[string]string{}
+	for key, value := range actual {
+		folded[strings.ToUpper(key)] = value
+	}
+
+	var differences []string
+	for key, value := range required {
+		actualValue, ok := folded[strings.ToUpper(key)]
+		switch {
+		case !ok:
+			differences = append(differences, fmt.Sprintf("%s: missing, expected %q", key, value))
+		case actualValue != value:
+			differences = append(differences, fmt.Sprintf("%s: expected %q, got %q", key, value, actualValue))
+		}
+	}
+	sort.Strings(differences)
+
+	return differences
+}
@@ -0,0 +1,34 @@
+package windows2016fs_test
+
+import (
+	"sort"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// eventLogSources returns the names of the sources registered under every
+// event log of image.
+func eventLogSources(image string) ([]string, error) {
+	output, err := powershellIn(image, `Get-ChildItem HKLM:\SYSTEM\CurrentControlSet\Services\EventLog | Get-ChildItem | ForEach-Object { $_.PSChildName }`)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := validation.NonEmptyLines(output)
+	sort.Strings(sources)
+
+	return sources, nil
+}
+
+// missingSources returns the required sources that aren't registered.
+// Windows treats source names case-insensitively.
+func missingSources(required, registered []string) []string {
+	var missing []string
+	for _, source := range required {
+		if !validation.ContainsFold(registered, source) {
+			missing = append(missing, source)
+		}
+	}
+
+	return missing
+}
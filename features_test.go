@@ -0,0 +1,13 @@
+package windows2016fs_test
+
+// disabledFeatures returns the required features that aren't enabled.
+func disabledFeatures(required []string, features map[string]bool) []string {
+	var disabled []string
+	for _, feature := range required {
+		if !features[feature] {
+			disabled = append(disabled, feature)
+		}
+	}
+
+	return disabled
+}
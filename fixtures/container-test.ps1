@@ -3,12 +3,74 @@ trap {
     $host.SetShouldExit(1) 
 }
 
-net use t: $env:SHARE_UNC $env:SHARE_PASSWORD /user:$env:SHARE_USERNAME
-if ($LASTEXITCODE -ne 0) {
-    echo "ERROR: could not create smb mapping"
-    Get-EventLog -LogName System -Newest 3 | format-list -Property Message
+# Credentials come from SHARE_CREDS_FILE (username on the first line, password
+# on the second) when set, so they needn't be passed in the environment.
+$shareUsername = $env:SHARE_USERNAME
+$sharePassword = $env:SHARE_PASSWORD
+if ($env:SHARE_CREDS_FILE) {
+    $credentials = @(Get-Content -LiteralPath $env:SHARE_CREDS_FILE)
+    if ($credentials.Count -lt 2) {
+        echo "ERROR: $env:SHARE_CREDS_FILE must contain a username and a password line"
+        exit 1
+    }
 
-    exit $LASTEXITCODE
+    $shareUsername = $credentials[0]
+    $sharePassword = $credentials[1]
+}
+
+# Resolve the share host before mounting so that slow or broken DNS is
+# reported separately from SMB failures.
+$shareHost = $env:SHARE_UNC.TrimStart("\").Split("\")[0]
+$shareAddress = $null
+if (-not [System.Net.IPAddress]::TryParse($shareHost, [ref]$shareAddress)) {
+    $attempts = 10
+    if ($env:SHARE_RESOLVE_ATTEMPTS) {
+        $attempts = [int]$env:SHARE_RESOLVE_ATTEMPTS
+    }
+
+    $resolved = $false
+    for ($i = 1; $i -le $attempts; $i++) {
+        if (Resolve-DnsName -Name $shareHost -ErrorAction SilentlyContinue) {
+            $resolved = $true
+            break
+        }
+        Start-Sleep 3
+    }
+
+    if (-not $resolved) {
+        echo "ERROR: could not resolve FQDN $shareHost after $attempts attempts"
+        exit 3
+    }
+}
+
+if ($env:SHARE_VIA_PROXY) {
+    $newSmbMapping = Get-Command New-SmbMapping -ErrorAction SilentlyContinue
+    if (-not $newSmbMapping -or -not $newSmbMapping.Parameters.ContainsKey("TransportType")) {
+        echo "SKIP: SMB over QUIC is not supported on Windows build $([Environment]::OSVersion.Version)"
+        exit 0
+    }
+
+    New-SmbMapping -LocalPath t: -RemotePath $env:SHARE_UNC -UserName $shareUsername -Password $sharePassword -TransportType QUIC | Out-Null
+} else {
+    # cmd merges net's stderr so that PowerShell doesn't turn it into a terminating error
+    if ($env:SHARE_GMSA) {
+        # Without credentials net use authenticates as the container's
+        # identity, the gMSA of its credential spec.
+        $output = cmd /c "net use t: `"$env:SHARE_UNC`" 2>&1"
+    } else {
+        $output = cmd /c "net use t: `"$env:SHARE_UNC`" `"$sharePassword`" /user:`"$shareUsername`" 2>&1"
+    }
+    $exitCode = $LASTEXITCODE
+    $output
+    if ($exitCode -ne 0) {
+        echo "ERROR: could not create smb mapping"
+        if ($output -match "System error (5|86|1326) ") {
+            [Console]::Error.WriteLine("ERROR: access denied to $env:SHARE_UNC")
+        }
+        Get-EventLog -LogName System -Newest 3 | format-list -Property Message
+
+        exit $exitCode
+    }
 }
 
 Start-Sleep 1
@@ -22,3 +84,29 @@ if ($LASTEXITCODE -ne 0) {
 }
 
 Start-Sleep 1
+
+# In read-only mode the share must be readable while writes are refused with
+# access denied; any other write failure points at the network instead.
+if ($env:SHARE_READONLY) {
+    try {
+        Get-ChildItem t:\ -ErrorAction Stop | Out-Null
+    } catch {
+        echo "ERROR: could not read from read-only share: $($_.Exception.Message)"
+        exit 4
+    }
+
+    $probe = "t:\readonly-probe-$([guid]::NewGuid()).txt"
+    try {
+        Set-Content -LiteralPath $probe -Value "probe" -ErrorAction Stop
+        Remove-Item -LiteralPath $probe -ErrorAction SilentlyContinue
+        echo "ERROR: write to read-only share succeeded"
+        exit 5
+    } catch {
+        if ($_.Exception -is [System.UnauthorizedAccessException] -or $_.Exception.HResult -eq -2147024891) {
+            echo "READONLY: write denied"
+        } else {
+            echo "ERROR: write failed for a reason other than access denied: $($_.Exception.Message)"
+            exit 6
+        }
+    }
+}
@@ -0,0 +1,14 @@
+$ErrorActionPreference = "Stop";
+
+$listener = New-Object System.Net.HttpListener
+$listener.Prefixes.Add("http://+:8080/")
+$listener.Start()
+
+while ($listener.IsListening) {
+    $context = $listener.GetContext()
+    $body = [System.Text.Encoding]::UTF8.GetBytes("ok")
+
+    $context.Response.StatusCode = 200
+    $context.Response.OutputStream.Write($body, 0, $body.Length)
+    $context.Response.Close()
+}
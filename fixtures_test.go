@@ -0,0 +1,33 @@
+package windows2016fs_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+)
+
+// loadTagFixture unmarshals fixtures/<name>-<tag>.json into v, or
+// fixtures/<name>-<tag>-<variant>.json for variants other than servercore.
+func loadTagFixture(name, tag string, v interface{}) error {
+	fixtureTag := validation.VariantTag(tag, imageVariant)
+	jsonData, err := ioutil.ReadFile(filepath.Join("fixtures", fmt.Sprintf("%s-%s.json", name, fixtureTag)))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(jsonData, v)
+}
+
+// skipWithoutServicesBaseline skips the services specs when tag, in the
+// variant under test, has no services baseline yet, as 2022 doesn't.
+func skipWithoutServicesBaseline(tag string) {
+	fixture := validation.ServicesBaselinePath(validation.VariantTag(tag, imageVariant))
+	if _, err := os.Stat(fixture); os.IsNotExist(err) {
+		Skip(fmt.Sprintf("%s does not exist", fixture))
+	}
+}
@@ -0,0 +1,92 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// fontsScript lists every font registered under the Fonts key as
+// "font<TAB>name<TAB>file", and every file in C:\Windows\Fonts as
+// "file<TAB>name".
+const fontsScript = `
+$tab = [char]9
+$key = Get-Item 'HKLM:\SOFTWARE\Microsoft\Windows NT\CurrentVersion\Fonts'
+foreach ($name in $key.GetValueNames()) {
+    Write-Output ('font', $name, $key.GetValue($name) -join $tab)
+}
+Get-ChildItem C:\Windows\Fonts -File | ForEach-Object { 'file', $_.Name -join $tab }
+`
+
+// installedFonts returns the fonts of image that are both registered and
+// present in C:\Windows\Fonts, by family name (e.g. "Arial Bold") and by file
+// name (e.g. "arialbd.ttf"). A registration whose file is missing doesn't
+// count as installed.
+func installedFonts(image string) ([]string, error) {
+	output, err := powershellIn(image, fontsScript)
+	if err != nil {
+		return nil, err
+	}
+
+	type registration struct{ names, file string }
+
+	var registrations []registration
+	files := map[string]bool{}
+	for _, line := range validation.NonEmptyLines(output) {
+		fields := strings.Split(line, "\t")
+		switch {
+		case fields[0] == "font" && len(fields) == 3:
+			registrations = append(registrations, registration{names: fields[1], file: fields[2]})
+		case fields[0] == "file" && len(fields) == 2:
+			files[strings.ToLower(fields[1])] = true
+		default:
+			return nil, fmt.Errorf("unexpected font listing line %q", line)
+		}
+	}
+
+	var fonts []string
+	for _, r := range registrations {
+		// Registrations hold either a path or a name relative to C:\Windows\Fonts.
+		file := path.Base(strings.ReplaceAll(r.file, `\`, "/"))
+		if !files[strings.ToLower(file)] {
+			continue
+		}
+
+		fonts = append(fonts, file)
+		fonts = append(fonts, fontFamilies(r.names)...)
+	}
+	sort.Strings(fonts)
+
+	return fonts, nil
+}
+
+// fontFamilies returns the family names of a Fonts registration value name,
+// e.g. "Cambria & Cambria Math (TrueType)" holds "Cambria" and "Cambria Math".
+func fontFamilies(registration string) []string {
+	if i := strings.LastIndex(registration, " ("); i >= 0 && strings.HasSuffix(registration, ")") {
+		registration = registration[:i]
+	}
+
+	var families []string
+	for _, family := range strings.Split(registration, " & ") {
+		families = append(families, strings.TrimSpace(family))
+	}
+
+	return families
+}
+
+// missingFonts returns the required fonts, given by family or file name, that
+// aren't installed. Windows treats both case-insensitively.
+func missingFonts(required, installed []string) []string {
+	var missing []string
+	for _, font := range required {
+		if !validation.ContainsFold(installed, font) {
+			missing = append(missing, font)
+		}
+	}
+
+	return missing
+}
@@ -0,0 +1,19 @@
+package windows2016fs_test
+
+import (
+	. "github.com/onsi/ginkgo"
+)
+
+// gmsaCredentialSpec is the configuration's share.gmsa_credspec
+// (GMSA_CREDSPEC), resolved in BeforeSuite. When set, the mount specs run
+// their containers with it and authenticate to the share as its gMSA
+// instead of with the share's username and password.
+var gmsaCredentialSpec string
+
+// skipWithGMSA skips specs that exercise the share's username and password,
+// which the mount containers don't get when they authenticate as a gMSA.
+func skipWithGMSA() {
+	if gmsaCredentialSpec != "" {
+		Skip("the mount specs authenticate as a gMSA (GMSA_CREDSPEC)")
+	}
+}
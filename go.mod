@@ -3,6 +3,8 @@ module github.com/cloudfoundry/windows2016fs
 go 1.16
 
 require (
+	github.com/Microsoft/go-winio v0.5.2
 	github.com/onsi/ginkgo v1.16.2
 	github.com/onsi/gomega v1.12.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,92 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// Mapping is an SMB mapping on the host running the suite.
+type Mapping struct {
+	LocalPath  string
+	RemotePath string
+}
+
+// snapshotHostSMB returns the host's current SMB mappings. The result is
+// never nil, so that callers can tell an empty snapshot from a missing one.
+func snapshotHostSMB() ([]Mapping, error) {
+	output, err := hostPowershell("ConvertTo-Json -InputObject @(Get-SmbMapping | Select-Object LocalPath, RemotePath)")
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := []Mapping{}
+	if err := json.Unmarshal([]byte(output), &mappings); err != nil {
+		return nil, fmt.Errorf("parsing Get-SmbMapping output %q: %s", output, err)
+	}
+
+	return mappings, nil
+}
+
+// restoreHostSMB removes every host SMB mapping that isn't in snapshot.
+// Mappings that existed before the snapshot are left untouched.
+func restoreHostSMB(snapshot []Mapping) error {
+	current, err := snapshotHostSMB()
+	if err != nil {
+		return err
+	}
+
+	var failures []string
+	for _, mapping := range current {
+		if containsMapping(snapshot, mapping) {
+			continue
+		}
+
+		script := fmt.Sprintf("Remove-SmbMapping -RemotePath %s -Force -UpdateProfile", quotePowershell([]string{mapping.RemotePath})[0])
+		if mapping.LocalPath != "" {
+			script = fmt.Sprintf("Remove-SmbMapping -LocalPath %s -Force -UpdateProfile", quotePowershell([]string{mapping.LocalPath})[0])
+		}
+
+		if _, err := hostPowershell(script); err != nil {
+			failures = append(failures, fmt.Sprintf("%s %s: %s", mapping.LocalPath, mapping.RemotePath, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to remove host SMB mappings:\n%s", strings.Join(failures, "\n"))
+	}
+
+	return nil
+}
+
+func containsMapping(mappings []Mapping, mapping Mapping) bool {
+	for _, m := range mappings {
+		if strings.EqualFold(m.LocalPath, mapping.LocalPath) && strings.EqualFold(m.RemotePath, mapping.RemotePath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hostPowershell runs script on the host running the suite and returns its
+// stdout.
+func hostPowershell(script string) (string, error) {
+	var output []byte
+
+	err := timedCheck("host", func(ctx context.Context) error {
+		var err error
+		output, err = cmdlog.Output(exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", script))
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return fmt.Errorf("powershell %q failed: %s: %s", script, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return err
+	})
+
+	return string(output), err
+}
@@ -0,0 +1,52 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// ImageConfig is the part of the image's runtime configuration that
+// downstream buildpacks depend on.
+type ImageConfig struct {
+	WorkingDir string
+	Entrypoint []string
+	User       string
+}
+
+// imageConfig returns the runtime configuration recorded in image.
+func imageConfig(image string) (ImageConfig, error) {
+	skipUnderCtr()
+
+	var inspection struct {
+		Config ImageConfig
+	}
+
+	if err := validation.InspectImage(image, &inspection); err != nil {
+		return ImageConfig{}, err
+	}
+
+	return inspection.Config, nil
+}
+
+// diff lists every field that differs between c and actual.
+func (c ImageConfig) diff(actual ImageConfig) []string {
+	var differences []string
+
+	if c.WorkingDir != actual.WorkingDir {
+		differences = append(differences, fmt.Sprintf("WorkingDir: expected %q, got %q", c.WorkingDir, actual.WorkingDir))
+	}
+
+	if len(c.Entrypoint) != 0 || len(actual.Entrypoint) != 0 {
+		if !reflect.DeepEqual(c.Entrypoint, actual.Entrypoint) {
+			differences = append(differences, fmt.Sprintf("Entrypoint: expected %q, got %q", c.Entrypoint, actual.Entrypoint))
+		}
+	}
+
+	if c.User != actual.User {
+		differences = append(differences, fmt.Sprintf("User: expected %q, got %q", c.User, actual.User))
+	}
+
+	return differences
+}
@@ -0,0 +1,293 @@
+// Package cmdlog records the commands a run executes, with their arguments,
+// duration, exit code and output, as JSON lines, so that a failed CI run can
+// be diagnosed after the fact.
+package cmdlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxOutput is how much of each of a command's stdout and stderr is kept.
+// Longer output keeps its end, where failures are usually reported.
+const MaxOutput = 64 * 1024
+
+// Entry is an executed command.
+type Entry struct {
+	Command  string    `json:"command"`
+	Args     []string  `json:"args"`
+	Started  time.Time `json:"started"`
+	Duration float64   `json:"duration_seconds"`
+
+	// ExitCode is -1 when the command didn't exit on its own, e.g. it
+	// couldn't be started or was killed when its context was done.
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated,omitempty"`
+	StderrTruncated bool   `json:"stderr_truncated,omitempty"`
+}
+
+// Log is a JSON lines file of entries.
+type Log struct {
+	mu      sync.Mutex
+	file    *os.File
+	secrets []string
+}
+
+// Default is the log commands run with Run, Output and CombinedOutput are
+// recorded in. Nothing is recorded when it is nil.
+var Default *Log
+
+// DryRun, when set, receives the commands Run, Output and CombinedOutput
+// would execute, one per line, instead of them being run. They succeed
+// without output, so commands that depend on an earlier one's output may
+// not be reached.
+var DryRun io.Writer
+
+// Create creates the log file at path, and its directory.
+func Create(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Log{file: file}, nil
+}
+
+// Redact masks secret wherever it appears in the arguments and output of
+// later entries, e.g. a share password passed to docker run.
+func (l *Log) Redact(secret string) {
+	if l == nil || secret == "" {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.secrets = append(l.secrets, secret)
+}
+
+// Record appends entry to the log.
+func (l *Log) Record(entry Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Command = l.redact(entry.Command)
+	args := make([]string, len(entry.Args))
+	for i, arg := range entry.Args {
+		args[i] = l.redact(arg)
+	}
+	entry.Args = args
+	entry.Stdout = l.redact(entry.Stdout)
+	entry.Stderr = l.redact(entry.Stderr)
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.file.Write(append(line, '\n'))
+	return err
+}
+
+func (l *Log) redact(s string) string {
+	for _, secret := range l.secrets {
+		s = strings.ReplaceAll(s, secret, "[REDACTED]")
+	}
+
+	return s
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	return l.file.Close()
+}
+
+// Run runs command like command.Run, recording it in Default. Its output
+// still goes to command.Stdout and command.Stderr.
+func Run(command *exec.Cmd) error {
+	if DryRun != nil {
+		line := Format(command)
+		if Default != nil {
+			Default.mu.Lock()
+			line = Default.redact(line)
+			Default.mu.Unlock()
+		}
+
+		_, err := fmt.Fprintln(DryRun, line)
+		return err
+	}
+
+	if Default == nil {
+		return command.Run()
+	}
+
+	stdout, stderr := &tail{}, &tail{}
+	command.Stdout = tee(command.Stdout, stdout)
+	command.Stderr = tee(command.Stderr, stderr)
+
+	start := time.Now()
+	err := command.Run()
+	Default.Record(newEntry(command, start, err, stdout, stderr))
+
+	return err
+}
+
+// Output runs command like command.Output, recording it in Default.
+func Output(command *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	err := Run(command)
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+
+	return stdout.Bytes(), err
+}
+
+// CombinedOutput runs command like command.CombinedOutput, recording it in
+// Default.
+func CombinedOutput(command *exec.Cmd) ([]byte, error) {
+	// Run tees stdout and stderr separately, so they are copied by
+	// different goroutines.
+	output := &lockedBuffer{}
+	command.Stdout = output
+	command.Stderr = output
+
+	err := Run(command)
+
+	return output.buf.Bytes(), err
+}
+
+// Finished records a command that was started elsewhere, such as with
+// gexec, and has exited with exitCode.
+func Finished(command *exec.Cmd, start time.Time, exitCode int, stdout, stderr []byte) {
+	entry := started(command, start)
+	entry.ExitCode = exitCode
+	entry.Stdout, entry.StdoutTruncated = truncate(stdout)
+	entry.Stderr, entry.StderrTruncated = truncate(stderr)
+
+	Default.Record(entry)
+}
+
+// Format returns command as it was invoked, with the arguments that contain
+// spaces or quotes quoted, e.g. for printing it.
+func Format(command *exec.Cmd) string {
+	args := command.Args
+	if len(args) == 0 {
+		args = []string{command.Path}
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = arg
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			quoted[i] = strconv.Quote(arg)
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func newEntry(command *exec.Cmd, start time.Time, err error, stdout, stderr *tail) Entry {
+	entry := started(command, start)
+	entry.ExitCode = -1
+	if command.ProcessState != nil {
+		entry.ExitCode = command.ProcessState.ExitCode()
+	}
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		entry.Error = err.Error()
+	}
+
+	entry.Stdout, entry.StdoutTruncated = stdout.String(), stdout.truncated
+	entry.Stderr, entry.StderrTruncated = stderr.String(), stderr.truncated
+
+	return entry
+}
+
+// started returns the entry of command, named as it was invoked rather than
+// by its resolved path, with its duration so far.
+func started(command *exec.Cmd, start time.Time) Entry {
+	entry := Entry{Command: command.Path, Started: start.UTC(), Duration: time.Since(start).Seconds()}
+	if len(command.Args) > 0 {
+		entry.Command, entry.Args = command.Args[0], command.Args[1:]
+	}
+
+	return entry
+}
+
+// tail keeps the last MaxOutput bytes written to it.
+type tail struct {
+	buf       []byte
+	truncated bool
+}
+
+func (t *tail) Write(p []byte) (int, error) {
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > MaxOutput {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-MaxOutput:]...)
+		t.truncated = true
+	}
+
+	return len(p), nil
+}
+
+func (t *tail) String() string {
+	return string(t.buf)
+}
+
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func truncate(output []byte) (string, bool) {
+	if len(output) > MaxOutput {
+		return string(output[len(output)-MaxOutput:]), true
+	}
+
+	return string(output), false
+}
+
+func tee(w io.Writer, t *tail) io.Writer {
+	if w == nil {
+		return t
+	}
+
+	return io.MultiWriter(w, t)
+}
@@ -0,0 +1,13 @@
+package cmdlog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestCmdlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Cmdlog Suite")
+}
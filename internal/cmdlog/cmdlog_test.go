@@ -0,0 +1,122 @@
+package cmdlog_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+	var (
+		dir  string
+		path string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "cmdlog")
+		Expect(err).ToNot(HaveOccurred())
+
+		path = filepath.Join(dir, "logs", "commands.jsonl")
+		cmdlog.Default, err = cmdlog.Create(path)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(cmdlog.Default.Close()).To(Succeed())
+		cmdlog.Default = nil
+		os.RemoveAll(dir)
+	})
+
+	entries := func() []cmdlog.Entry {
+		file, err := os.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		defer file.Close()
+
+		var entries []cmdlog.Entry
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(nil, 4*cmdlog.MaxOutput)
+		for scanner.Scan() {
+			var entry cmdlog.Entry
+			Expect(json.Unmarshal(scanner.Bytes(), &entry)).To(Succeed())
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+
+	It("records each command with its exit code and output", func() {
+		output, err := cmdlog.Output(exec.Command("sh", "-c", "echo out; echo err >&2"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(output)).To(Equal("out\n"))
+
+		err = cmdlog.Run(exec.Command("sh", "-c", "exit 3"))
+		Expect(err).To(HaveOccurred())
+
+		logged := entries()
+		Expect(logged).To(HaveLen(2))
+		Expect(logged[0].Command).To(Equal("sh"))
+		Expect(logged[0].Args).To(Equal([]string{"-c", "echo out; echo err >&2"}))
+		Expect(logged[0].ExitCode).To(Equal(0))
+		Expect(logged[0].Stdout).To(Equal("out\n"))
+		Expect(logged[0].Stderr).To(Equal("err\n"))
+		Expect(logged[0].Started.IsZero()).To(BeFalse())
+		Expect(logged[1].ExitCode).To(Equal(3))
+		Expect(logged[1].Error).To(BeEmpty())
+	})
+
+	It("records commands that can't be started", func() {
+		err := cmdlog.Run(exec.Command("cmdlog-no-such-command"))
+		Expect(err).To(HaveOccurred())
+
+		logged := entries()
+		Expect(logged).To(HaveLen(1))
+		Expect(logged[0].ExitCode).To(Equal(-1))
+		Expect(logged[0].Error).ToNot(BeEmpty())
+	})
+
+	It("keeps the end of long output", func() {
+		_, err := cmdlog.CombinedOutput(exec.Command("sh", "-c", "head -c 70000 /dev/zero | tr '\\0' a; echo end"))
+		Expect(err).ToNot(HaveOccurred())
+
+		logged := entries()
+		Expect(logged[0].StdoutTruncated).To(BeTrue())
+		Expect(logged[0].Stdout).To(HaveLen(cmdlog.MaxOutput))
+		Expect(strings.HasSuffix(logged[0].Stdout, "end\n")).To(BeTrue())
+	})
+
+	It("redacts secrets", func() {
+		cmdlog.Default.Redact("hunter2")
+		_, err := cmdlog.Output(exec.Command("echo", "SHARE_PASSWORD=hunter2"))
+		Expect(err).ToNot(HaveOccurred())
+
+		logged := entries()
+		Expect(logged[0].Args).To(Equal([]string{"SHARE_PASSWORD=[REDACTED]"}))
+		Expect(logged[0].Stdout).To(Equal("SHARE_PASSWORD=[REDACTED]\n"))
+	})
+
+	It("prints commands instead of running them in a dry run", func() {
+		var printed strings.Builder
+		cmdlog.DryRun = &printed
+		defer func() { cmdlog.DryRun = nil }()
+		cmdlog.Default.Redact("hunter2")
+
+		marker := filepath.Join(dir, "marker")
+		output, err := cmdlog.Output(exec.Command("sh", "-c", "touch "+marker))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(BeEmpty())
+		Expect(cmdlog.Run(exec.Command("docker", "run", "--env", "SHARE_PASSWORD=hunter2", "image"))).To(Succeed())
+
+		Expect(marker).ToNot(BeAnExistingFile())
+		Expect(printed.String()).To(Equal(`sh -c "touch ` + marker + `"` + "\n" + "docker run --env SHARE_PASSWORD=[REDACTED] image\n"))
+		Expect(entries()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,140 @@
+// Package staging copies build inputs into a build context, verifying each
+// copy against the checksum of its source.
+package staging
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// File is a file that was staged.
+type File struct {
+	Name   string
+	Size   int64
+	SHA256 string
+}
+
+// Copy copies each source into dir and returns the staged files in the order
+// they were copied. A source is either a file or a directory, of which the
+// regular files at the top level are copied. A line is written to progress,
+// when it is non-nil, as each file is staged.
+func Copy(ctx context.Context, dir string, sources []string, progress io.Writer) ([]File, error) {
+	if progress == nil {
+		progress = ioutil.Discard
+	}
+
+	paths, err := expand(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var staged []File
+	for i, path := range paths {
+		file, err := copyFile(ctx, path, filepath.Join(dir, filepath.Base(path)))
+		if err != nil {
+			return staged, err
+		}
+
+		staged = append(staged, file)
+		fmt.Fprintf(progress, "staged %s (%d bytes) [%d/%d]\n", file.Name, file.Size, i+1, len(paths))
+	}
+
+	return staged, nil
+}
+
+func expand(sources []string) ([]string, error) {
+	var paths []string
+	for _, source := range sources {
+		info, err := os.Stat(source)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			paths = append(paths, source)
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(source)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.Mode().IsRegular() {
+				paths = append(paths, filepath.Join(source, entry.Name()))
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// copyFile copies source to destination, hashing the source as it is read,
+// and then re-reads the destination to check that it came out identical.
+func copyFile(ctx context.Context, source, destination string) (File, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return File{}, err
+	}
+	defer in.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return File{}, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), contextReader{ctx, in})
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("copying %s failed: %s", source, err)
+	}
+
+	expected := fmt.Sprintf("%x", hash.Sum(nil))
+	actual, err := hashFile(ctx, destination)
+	if err != nil {
+		return File{}, err
+	}
+	if actual != expected {
+		return File{}, fmt.Errorf("staged copy of %s has SHA256 %s, expected %s", source, actual, expected)
+	}
+
+	return File{Name: filepath.Base(destination), Size: size, SHA256: expected}, nil
+}
+
+func hashFile(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, contextReader{ctx, file}); err != nil {
+		return "", fmt.Errorf("hashing %s failed: %s", path, err)
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// contextReader stops a copy once its context is done, so that staging
+// multi-gigabyte installers honours the build timeout.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
@@ -0,0 +1,69 @@
+package staging_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/internal/staging"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Copy", func() {
+	var sourceDir, destinationDir string
+
+	BeforeEach(func() {
+		var err error
+		sourceDir, err = ioutil.TempDir("", "staging-source")
+		Expect(err).ToNot(HaveOccurred())
+		destinationDir, err = ioutil.TempDir("", "staging-destination")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(filepath.Join(sourceDir, "Dockerfile"), []byte("FROM scratch\n"), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(sourceDir, "deps"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(sourceDir, "deps", "tar.exe"), []byte("tar"), 0644)).To(Succeed())
+		Expect(os.Mkdir(filepath.Join(sourceDir, "deps", "nested"), 0755)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(sourceDir)).To(Succeed())
+		Expect(os.RemoveAll(destinationDir)).To(Succeed())
+	})
+
+	It("copies files and the top-level files of directories with their checksums", func() {
+		var progress bytes.Buffer
+		staged, err := staging.Copy(context.Background(), destinationDir, []string{
+			filepath.Join(sourceDir, "Dockerfile"),
+			filepath.Join(sourceDir, "deps"),
+		}, &progress)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(staged).To(Equal([]staging.File{
+			{Name: "Dockerfile", Size: 13, SHA256: "bb57c7da220a8753d7bdabac0d3afdb6efa742e4c736c5bc93ab40dfd5e23b9b"},
+			{Name: "tar.exe", Size: 3, SHA256: "90aebae315675cbf04612de4f7d5874850f48e0b8dd82becbeaa47ca93f5ebfb"},
+		}))
+		Expect(progress.String()).To(Equal("staged Dockerfile (13 bytes) [1/2]\nstaged tar.exe (3 bytes) [2/2]\n"))
+
+		contents, err := ioutil.ReadFile(filepath.Join(destinationDir, "tar.exe"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal("tar"))
+		Expect(filepath.Join(destinationDir, "nested")).ToNot(BeADirectory())
+	})
+
+	It("fails on a missing source", func() {
+		_, err := staging.Copy(context.Background(), destinationDir, []string{filepath.Join(sourceDir, "missing")}, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("stops once the context is done", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := staging.Copy(ctx, destinationDir, []string{filepath.Join(sourceDir, "Dockerfile")}, nil)
+		Expect(err).To(MatchError(ContainSubstring(context.Canceled.Error())))
+	})
+})
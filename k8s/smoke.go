@@ -0,0 +1,304 @@
+// Package k8s runs a candidate image as a pod on the Windows nodes of a
+// Kubernetes cluster, through kubectl, to catch kubelet and containerd
+// incompatibilities that a local docker run never sees.
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// PollInterval is how often the pod's status is read while it runs.
+var PollInterval = 5 * time.Second
+
+// scriptDir is where the validation script is mounted in the pod.
+const scriptDir = `C:\validation`
+
+// startFailures are the reasons a waiting container won't start on its own.
+var startFailures = []string{
+	"ErrImagePull", "ImagePullBackOff", "InvalidImageName",
+	"CreateContainerError", "CreateContainerConfigError", "RunContainerError",
+}
+
+// Smoke is a pod that runs container-test.ps1 from a candidate image on a
+// Windows node, mounting a share like the suite's mount specs do.
+type Smoke struct {
+	// Image must be pullable by the cluster's nodes.
+	Image string
+
+	// Kubeconfig and Namespace are passed to kubectl when set.
+	Kubeconfig string
+	Namespace  string
+
+	// NodeSelector narrows down the Windows nodes the pod may run on, e.g.
+	// to those of the image's build with node.kubernetes.io/windows-build.
+	NodeSelector map[string]string
+
+	ShareUNC string
+	Username string
+	Password string
+
+	// Script is the content of container-test.ps1.
+	Script []byte
+
+	// Name names the pod and the secret and config map it uses. It
+	// defaults to a random w2016fs-smoke-<hex> name.
+	Name string
+
+	// Keep leaves the pod in place after it ran, for debugging.
+	Keep bool
+}
+
+// Objects returns the secret holding the share credentials, the config map
+// holding the script and the pod, as a kubectl List.
+func (s Smoke) Objects() ([]byte, error) {
+	labels := map[string]string{"app.kubernetes.io/name": "windows2016fs-smoke", "app.kubernetes.io/instance": s.Name}
+	metadata := map[string]interface{}{"name": s.Name, "labels": labels}
+
+	nodeSelector := map[string]string{"kubernetes.io/os": "windows"}
+	for key, value := range s.NodeSelector {
+		nodeSelector[key] = value
+	}
+
+	list := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items": []interface{}{
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   metadata,
+				"stringData": map[string]string{"SHARE_USERNAME": s.Username, "SHARE_PASSWORD": s.Password},
+			},
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   metadata,
+				"data":       map[string]string{"container-test.ps1": string(s.Script)},
+			},
+			map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Pod",
+				"metadata":   metadata,
+				"spec": map[string]interface{}{
+					"restartPolicy": "Never",
+					"nodeSelector":  nodeSelector,
+					"tolerations": []interface{}{
+						map[string]string{"key": "os", "operator": "Equal", "value": "windows", "effect": "NoSchedule"},
+					},
+					"securityContext": map[string]interface{}{
+						"windowsOptions": map[string]string{"runAsUserName": "vcap"},
+					},
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":    "smoke",
+							"image":   s.Image,
+							"command": []string{"powershell", "-File", scriptDir + `\container-test.ps1`},
+							"env":     []interface{}{map[string]string{"name": "SHARE_UNC", "value": s.ShareUNC}},
+							"envFrom": []interface{}{
+								map[string]interface{}{"secretRef": map[string]string{"name": s.Name}},
+							},
+							"volumeMounts": []interface{}{
+								map[string]string{"name": "validation", "mountPath": scriptDir},
+							},
+						},
+					},
+					"volumes": []interface{}{
+						map[string]interface{}{"name": "validation", "configMap": map[string]string{"name": s.Name}},
+					},
+				},
+			},
+		},
+	}
+
+	return json.MarshalIndent(list, "", "  ")
+}
+
+// pod holds the fields of a pod's status that Run reads.
+type pod struct {
+	Spec struct {
+		NodeName string `json:"nodeName"`
+	} `json:"spec"`
+	Status struct {
+		Phase      string `json:"phase"`
+		Conditions []struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"conditions"`
+		ContainerStatuses []struct {
+			State struct {
+				Waiting *struct {
+					Reason  string `json:"reason"`
+					Message string `json:"message"`
+				} `json:"waiting"`
+				Terminated *struct {
+					ExitCode int `json:"exitCode"`
+				} `json:"terminated"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// startProblem returns why the pod can't start, or "" while it may still.
+func (p pod) startProblem() string {
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == "PodScheduled" && condition.Status == "False" && condition.Reason == "Unschedulable" {
+			return fmt.Sprintf("the pod can't be scheduled: %s", condition.Message)
+		}
+	}
+
+	for _, status := range p.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil {
+			for _, reason := range startFailures {
+				if waiting.Reason == reason {
+					return fmt.Sprintf("the container can't start: %s: %s", waiting.Reason, waiting.Message)
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func (p pod) finished() bool {
+	return p.Status.Phase == "Succeeded" || p.Status.Phase == "Failed"
+}
+
+func (p pod) exitCode() int {
+	for _, status := range p.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil {
+			return terminated.ExitCode
+		}
+	}
+
+	return -1
+}
+
+// node holds the versions a node reports.
+type node struct {
+	Status struct {
+		NodeInfo struct {
+			KernelVersion           string `json:"kernelVersion"`
+			KubeletVersion          string `json:"kubeletVersion"`
+			ContainerRuntimeVersion string `json:"containerRuntimeVersion"`
+		} `json:"nodeInfo"`
+	} `json:"status"`
+}
+
+// Run creates the pod, waits for it to finish and returns whether it
+// started on a Windows node, "k8s-start", and whether it mounted the share,
+// "k8s-smb". Unless Keep is set the pod and its objects are deleted.
+func (s Smoke) Run(ctx context.Context) ([]validation.CheckResult, error) {
+	if s.Name == "" {
+		suffix := make([]byte, 4)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		s.Name = "w2016fs-smoke-" + hex.EncodeToString(suffix)
+	}
+
+	objects, err := s.Objects()
+	if err != nil {
+		return nil, err
+	}
+
+	create := s.kubectl(ctx, "create", "--filename", "-")
+	create.Stdin = bytes.NewReader(objects)
+	if output, err := cmdlog.CombinedOutput(create); err != nil {
+		return nil, fmt.Errorf("kubectl create failed: %s: %s", err, strings.TrimSpace(string(output)))
+	}
+	if !s.Keep {
+		defer s.delete()
+	}
+
+	start := time.Now()
+	var p pod
+	for {
+		if err := s.get(ctx, &p, "pod", s.Name); err != nil {
+			return nil, err
+		}
+
+		if problem := p.startProblem(); problem != "" {
+			return []validation.CheckResult{{Name: "k8s-start", Message: problem, Duration: time.Since(start)}}, nil
+		}
+		if p.finished() {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("pod %s didn't finish, it is %s: %s", s.Name, p.Status.Phase, ctx.Err())
+		case <-time.After(PollInterval):
+		}
+	}
+
+	var n node
+	if err := s.get(ctx, &n, "node", p.Spec.NodeName); err != nil {
+		return nil, err
+	}
+	started := validation.CheckResult{
+		Name:     "k8s-start",
+		Passed:   true,
+		Duration: time.Since(start),
+		Metadata: map[string]string{
+			"node":    p.Spec.NodeName,
+			"kernel":  n.Status.NodeInfo.KernelVersion,
+			"kubelet": n.Status.NodeInfo.KubeletVersion,
+			"runtime": n.Status.NodeInfo.ContainerRuntimeVersion,
+		},
+	}
+
+	logs, err := cmdlog.Output(s.kubectl(ctx, "logs", s.Name))
+	if err != nil {
+		return nil, fmt.Errorf("kubectl logs %s failed: %s", s.Name, err)
+	}
+
+	run := validation.ContainerRun{Name: s.Name, Stdout: string(logs), ExitCode: p.exitCode()}
+	return []validation.CheckResult{started, validation.MountResult("k8s-smb", run, s.ShareUNC)}, nil
+}
+
+func (s Smoke) kubectl(ctx context.Context, args ...string) *exec.Cmd {
+	var global []string
+	if s.Kubeconfig != "" {
+		global = append(global, "--kubeconfig", s.Kubeconfig)
+	}
+	if s.Namespace != "" {
+		global = append(global, "--namespace", s.Namespace)
+	}
+
+	return exec.CommandContext(ctx, "kubectl", append(global, args...)...)
+}
+
+func (s Smoke) get(ctx context.Context, v interface{}, kind, name string) error {
+	output, err := cmdlog.Output(s.kubectl(ctx, "get", kind, name, "--output", "json"))
+	if err != nil {
+		return fmt.Errorf("kubectl get %s %s failed: %s", kind, name, err)
+	}
+
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("parsing %s %s: %s", kind, name, err)
+	}
+
+	return nil
+}
+
+// delete removes the pod and its objects without waiting for them to go, even
+// once Run's context is done.
+func (s Smoke) delete() {
+	ctx, cancel := context.WithTimeout(context.Background(), validation.OperationTimeout)
+	defer cancel()
+
+	cmdlog.Run(s.kubectl(ctx, "delete", "pod,secret,configmap", "--selector", "app.kubernetes.io/instance="+s.Name, "--wait=false"))
+}
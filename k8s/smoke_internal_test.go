@@ -0,0 +1,39 @@
+package k8s
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("pod", func() {
+	parse := func(status string) pod {
+		var p pod
+		Expect(json.Unmarshal([]byte(status), &p)).To(Succeed())
+		return p
+	}
+
+	DescribeTable("tells why it can't start",
+		func(status, problem string) {
+			Expect(parse(status).startProblem()).To(Equal(problem))
+		},
+		Entry("while it is being scheduled", `{"status": {"phase": "Pending"}}`, ""),
+		Entry("while its image is pulled", `{"status": {"phase": "Pending", "containerStatuses": [{"state": {"waiting": {"reason": "ContainerCreating"}}}]}}`, ""),
+		Entry("when no node fits",
+			`{"status": {"phase": "Pending", "conditions": [{"type": "PodScheduled", "status": "False", "reason": "Unschedulable", "message": "0/3 nodes are available"}]}}`,
+			"the pod can't be scheduled: 0/3 nodes are available"),
+		Entry("when its image can't be pulled",
+			`{"status": {"phase": "Pending", "containerStatuses": [{"state": {"waiting": {"reason": "ImagePullBackOff", "message": "manifest unknown"}}}]}}`,
+			"the container can't start: ImagePullBackOff: manifest unknown"),
+	)
+
+	It("reads the exit code of a finished container", func() {
+		p := parse(`{"status": {"phase": "Failed", "containerStatuses": [{"state": {"terminated": {"exitCode": 2}}}]}}`)
+		Expect(p.finished()).To(BeTrue())
+		Expect(p.exitCode()).To(Equal(2))
+
+		Expect(parse(`{"status": {"phase": "Running"}}`).exitCode()).To(Equal(-1))
+	})
+})
@@ -0,0 +1,71 @@
+package k8s_test
+
+import (
+	"encoding/json"
+
+	"github.com/cloudfoundry/windows2016fs/k8s"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Smoke", func() {
+	It("describes a pod that runs the script from a config map with the credentials from a secret", func() {
+		smoke := k8s.Smoke{
+			Image:        "registry.example.com/windows2016fs:2019",
+			NodeSelector: map[string]string{"node.kubernetes.io/windows-build": "10.0.17763"},
+			ShareUNC:     `\\share.example.com\share`,
+			Username:     "smbuser",
+			Password:     "secret",
+			Script:       []byte("net use\n"),
+			Name:         "w2016fs-smoke-1",
+		}
+
+		content, err := smoke.Objects()
+		Expect(err).ToNot(HaveOccurred())
+
+		var list struct {
+			Items []struct {
+				Kind     string
+				Metadata struct {
+					Name   string
+					Labels map[string]string
+				}
+				StringData map[string]string `json:"stringData"`
+				Data       map[string]string
+				Spec       struct {
+					NodeSelector map[string]string `json:"nodeSelector"`
+					Containers   []struct {
+						Image   string
+						Command []string
+						Env     []map[string]string
+					}
+				}
+			}
+		}
+		Expect(json.Unmarshal(content, &list)).To(Succeed())
+
+		Expect(list.Items).To(HaveLen(3))
+		for _, item := range list.Items {
+			Expect(item.Metadata.Name).To(Equal("w2016fs-smoke-1"))
+			Expect(item.Metadata.Labels).To(HaveKeyWithValue("app.kubernetes.io/instance", "w2016fs-smoke-1"))
+		}
+
+		secret, configMap, pod := list.Items[0], list.Items[1], list.Items[2]
+		Expect(secret.Kind).To(Equal("Secret"))
+		Expect(secret.StringData).To(Equal(map[string]string{"SHARE_USERNAME": "smbuser", "SHARE_PASSWORD": "secret"}))
+		Expect(configMap.Kind).To(Equal("ConfigMap"))
+		Expect(configMap.Data).To(Equal(map[string]string{"container-test.ps1": "net use\n"}))
+
+		Expect(pod.Kind).To(Equal("Pod"))
+		Expect(pod.Spec.NodeSelector).To(Equal(map[string]string{
+			"kubernetes.io/os":                 "windows",
+			"node.kubernetes.io/windows-build": "10.0.17763",
+		}))
+		Expect(pod.Spec.Containers).To(HaveLen(1))
+		Expect(pod.Spec.Containers[0].Image).To(Equal("registry.example.com/windows2016fs:2019"))
+		Expect(pod.Spec.Containers[0].Command).To(Equal([]string{"powershell", "-File", `C:\validation\container-test.ps1`}))
+		Expect(pod.Spec.Containers[0].Env).To(Equal([]map[string]string{{"name": "SHARE_UNC", "value": `\\share.example.com\share`}}))
+		Expect(string(content)).ToNot(ContainSubstring(`"value": "secret"`))
+	})
+})
@@ -0,0 +1,53 @@
+package windows2016fs_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+var (
+	revisionPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	digestPattern   = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// buildLabelProblems returns what is missing or malformed in the build
+// metadata labels of a candidate of tag.
+func buildLabelProblems(tag string, labels map[string]string) ([]string, error) {
+	var problems []string
+	check := func(name string, valid bool, expected string) {
+		value, ok := labels[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s is missing", name))
+		case !valid:
+			problems = append(problems, fmt.Sprintf("%s is %q, expected %s", name, value, expected))
+		}
+	}
+
+	_, err := time.Parse(time.RFC3339, labels[builder.LabelCreated])
+	check(builder.LabelCreated, err == nil, "an RFC 3339 time")
+	check(builder.LabelRevision, revisionPattern.MatchString(labels[builder.LabelRevision]), "a git commit")
+	check(builder.LabelBaseDigest, digestPattern.MatchString(labels[builder.LabelBaseDigest]), "a sha256 digest")
+
+	base, err := baseImage(tag)
+	if err != nil {
+		return nil, err
+	}
+	check(builder.LabelBaseName, labels[builder.LabelBaseName] == base, base)
+
+	manifest, err := ioutil.ReadFile(filepath.Join(validation.VariantDir(tag, imageVariant), builder.ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	manifestDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(manifest))
+	check(builder.LabelDependencies, labels[builder.LabelDependencies] == manifestDigest, manifestDigest+", the digest of the dependency manifest")
+
+	return problems, nil
+}
@@ -0,0 +1,22 @@
+package windows2016fs_test
+
+import "fmt"
+
+// layerDifferences describes every layer index at which actual differs from
+// golden, including layers present in only one of them.
+func layerDifferences(golden, actual []string) []string {
+	var differences []string
+
+	for i := 0; i < len(golden) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			differences = append(differences, fmt.Sprintf("layer %d: expected %s, missing", i, golden[i]))
+		case i >= len(golden):
+			differences = append(differences, fmt.Sprintf("layer %d: unexpected %s", i, actual[i]))
+		case golden[i] != actual[i]:
+			differences = append(differences, fmt.Sprintf("layer %d: expected %s, got %s", i, golden[i], actual[i]))
+		}
+	}
+
+	return differences
+}
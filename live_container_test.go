@@ -0,0 +1,34 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// useLiveContainer checks that container is running and returns its image,
+// against which every inspection will then exec into container rather than
+// starting new containers.
+func useLiveContainer(container string) (string, error) {
+	output, err := cmdlog.Output(validation.Runtime.Command(context.Background(), "container", "inspect", "--format", "{{.State.Running}} {{.Config.Image}}", container))
+	if err != nil {
+		return "", fmt.Errorf("docker container inspect %s failed: %s", container, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return "", fmt.Errorf("unexpected state of container %s: %q", container, output)
+	}
+
+	if fields[0] != "true" {
+		return "", fmt.Errorf("LIVE_CONTAINER %s is not running", container)
+	}
+
+	image := fields[1]
+	validation.UseLiveContainer(image, container)
+
+	return image, nil
+}
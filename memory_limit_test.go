@@ -0,0 +1,77 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+const defaultMemoryLimit = "512m"
+
+// Exit codes of Windows processes that failed to allocate memory.
+const (
+	statusNoMemory        = 0xC0000017
+	statusCommitmentLimit = 0xC000012D
+)
+
+// memoryLimit returns the --memory value used by the memory limit spec.
+func memoryLimit() string {
+	if limit := os.Getenv("MEMORY_LIMIT"); limit != "" {
+		return limit
+	}
+
+	return defaultMemoryLimit
+}
+
+// withMemoryLimit returns a copy of runArgs that limits the container's
+// memory to limit.
+func withMemoryLimit(runArgs []string, limit string) []string {
+	return append(append([]string{}, runArgs...), "--memory", limit)
+}
+
+// memoryExhausted reports whether the stopped container ran out of memory,
+// either because docker killed it or because its process exited with an
+// allocation failure.
+func memoryExhausted(container string) (bool, error) {
+	output, err := cmdlog.Output(validation.Runtime.Command(context.Background(), "container", "inspect", "--format", "{{.State.OOMKilled}} {{.State.ExitCode}}", container))
+	if err != nil {
+		return false, fmt.Errorf("docker container inspect %s failed: %s", container, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return false, fmt.Errorf("unexpected container state %q", output)
+	}
+
+	exitCode, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("unexpected exit code %q", fields[1])
+	}
+
+	switch uint32(exitCode) {
+	case statusNoMemory, statusCommitmentLimit:
+		return true, nil
+	}
+
+	return fields[0] == "true", nil
+}
+
+// limitedRunError explains why a container run under a memory limit failed,
+// telling running out of memory apart from unrelated failures.
+func limitedRunError(container, limit string, err error) error {
+	exhausted, inspectErr := memoryExhausted(container)
+	if inspectErr != nil {
+		return fmt.Errorf("%s (could not check for memory exhaustion: %s)", err, inspectErr)
+	}
+
+	if exhausted {
+		return fmt.Errorf("container %s ran out of memory under --memory %s: %s", container, limit, err)
+	}
+
+	return fmt.Errorf("container %s failed under --memory %s for a reason other than memory: %s", container, limit, err)
+}
@@ -0,0 +1,82 @@
+package windows2016fs_test
+
+import (
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// odbcDriverPathsScript imports odbc.reg when it is present in the working
+// directory, as it is in the test image, and prints the Driver and Setup
+// paths of every registered ODBC driver with environment variables expanded.
+const odbcDriverPathsScript = `
+if (Test-Path odbc.reg) {
+    reg import odbc.reg 2>&1 | Out-Null
+}
+Get-ChildItem 'HKLM:\SOFTWARE\ODBC\ODBCINST.INI' |
+    ForEach-Object { Get-ItemProperty $_.PSPath } |
+    ForEach-Object { $_.Driver; $_.Setup } |
+    Where-Object { $_ } |
+    Sort-Object -Unique
+`
+
+// missingFilesScript prints each of its arguments that doesn't resolve to a
+// file.
+const missingFilesScript = `
+foreach ($path in $args) {
+    if (-not (Get-Item -LiteralPath $path -ErrorAction SilentlyContinue)) {
+        Write-Output $path
+    }
+}
+`
+
+// odbcDriverPaths returns the Driver and Setup DLL paths of the ODBC drivers
+// registered in image, including those imported from odbc.reg.
+func odbcDriverPaths(image string) ([]string, error) {
+	output, err := powershellIn(image, odbcDriverPathsScript)
+	if err != nil {
+		return nil, err
+	}
+
+	return validation.NonEmptyLines(output), nil
+}
+
+// missingFiles returns the paths that don't exist inside image.
+func missingFiles(image string, paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	params := append([]string{"powershell", "-Command", "& {" + missingFilesScript + "}"}, quotePowershell(paths)...)
+	output, err := runInImage(image, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	return validation.NonEmptyLines(output), nil
+}
+
+func quotePowershell(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+
+	return quoted
+}
+
+func withoutValues(values, excluded []string) []string {
+	skip := map[string]bool{}
+	for _, value := range excluded {
+		skip[strings.ToLower(value)] = true
+	}
+
+	var remaining []string
+	for _, value := range values {
+		if !skip[strings.ToLower(value)] {
+			remaining = append(remaining, value)
+		}
+	}
+
+	return remaining
+}
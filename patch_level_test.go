@@ -0,0 +1,25 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const defaultMaxPatchMonthsBehind = 2
+
+// maxPatchMonthsBehind returns how many monthly cumulative updates the image
+// may lack, from MAX_PATCH_MONTHS_BEHIND.
+func maxPatchMonthsBehind() (int, error) {
+	value := os.Getenv("MAX_PATCH_MONTHS_BEHIND")
+	if value == "" {
+		return defaultMaxPatchMonthsBehind, nil
+	}
+
+	months, err := strconv.Atoi(value)
+	if err != nil || months < 0 {
+		return 0, fmt.Errorf("MAX_PATCH_MONTHS_BEHIND must be a number of months, got %q", value)
+	}
+
+	return months, nil
+}
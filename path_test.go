@@ -0,0 +1,70 @@
+package windows2016fs_test
+
+import "strings"
+
+// pathExpectations is the shape of fixtures/expected-path-<tag>.json. Entries
+// in neither list are reported as warnings rather than failures.
+type pathExpectations struct {
+	Required []string `json:"required"`
+	Optional []string `json:"optional"`
+}
+
+// containerPath returns the entries of PATH inside a new container of image.
+func containerPath(image string) ([]string, error) {
+	output, err := powershellIn(image, "$env:PATH")
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	for _, entry := range strings.Split(strings.TrimSpace(output), ";") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// missingPathEntries returns the required directories absent from path.
+func (e pathExpectations) missingPathEntries(path []string) []string {
+	var missing []string
+	for _, dir := range e.Required {
+		if !containsDir(path, dir) {
+			missing = append(missing, dir)
+		}
+	}
+
+	return missing
+}
+
+// unexpectedPathEntries returns the entries of path that are neither
+// required nor optional.
+func (e pathExpectations) unexpectedPathEntries(path []string) []string {
+	known := append(append([]string{}, e.Required...), e.Optional...)
+
+	var unexpected []string
+	for _, entry := range path {
+		if !containsDir(known, entry) {
+			unexpected = append(unexpected, entry)
+		}
+	}
+
+	return unexpected
+}
+
+func containsDir(dirs []string, dir string) bool {
+	for _, candidate := range dirs {
+		if normalizeDir(candidate) == normalizeDir(dir) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normalizeDir makes Windows directory comparisons case and trailing
+// separator insensitive.
+func normalizeDir(dir string) string {
+	return strings.ToLower(strings.TrimRight(dir, `\/`))
+}
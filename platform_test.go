@@ -0,0 +1,49 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+var supportedPlatforms = []string{"windows/amd64", "windows/arm64"}
+
+// targetPlatform is the platform images are built and run for, resolved from
+// TARGET_PLATFORM in BeforeSuite.
+var targetPlatform string
+
+// resolveTargetPlatform returns TARGET_PLATFORM, or the host platform when it
+// is unset, failing on anything outside supportedPlatforms.
+func resolveTargetPlatform() (string, error) {
+	platform := os.Getenv("TARGET_PLATFORM")
+	if platform == "" {
+		platform = "windows/" + runtime.GOARCH
+	}
+
+	for _, supported := range supportedPlatforms {
+		if platform == supported {
+			return platform, nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported platform %q; supported platforms are %s", platform, strings.Join(supportedPlatforms, ", "))
+}
+
+// isolation is passed to docker run as --isolation when set, from the
+// configuration's isolation (DOCKER_ISOLATION). When empty the daemon's
+// default applies.
+var isolation string
+
+// runFlags returns the docker run flags that select the platform and
+// isolation containers run with.
+func runFlags() []string {
+	flags := []string{"--platform", targetPlatform}
+	if isolation != "" {
+		flags = append(flags, validation.Runtime.IsolationArgs(isolation)...)
+	}
+
+	return flags
+}
@@ -0,0 +1,78 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type portRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (r portRange) contains(port int) bool {
+	return port >= r.From && port <= r.To
+}
+
+// listeningPortsAllowlist is the shape of fixtures/expected-listening-ports-<tag>.json.
+// ExcludedRanges covers ports, such as the dynamic RPC range, that are assigned
+// at runtime and can't be listed individually.
+type listeningPortsAllowlist struct {
+	Allowed        []int       `json:"allowed"`
+	ExcludedRanges []portRange `json:"excludedRanges"`
+}
+
+func (a listeningPortsAllowlist) unexpected(ports []int) []int {
+	allowed := map[int]bool{}
+	for _, port := range a.Allowed {
+		allowed[port] = true
+	}
+
+	var unexpected []int
+	for _, port := range ports {
+		if allowed[port] || a.excluded(port) {
+			continue
+		}
+		unexpected = append(unexpected, port)
+	}
+
+	return unexpected
+}
+
+func (a listeningPortsAllowlist) excluded(port int) bool {
+	for _, r := range a.ExcludedRanges {
+		if r.contains(port) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listeningPorts returns the sorted, de-duplicated TCP ports listening inside a
+// freshly started container of image.
+func listeningPorts(image string) ([]int, error) {
+	output, err := powershellIn(image, "Get-NetTCPConnection -State Listen | Select-Object -ExpandProperty LocalPort")
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[int]bool{}
+	var ports []int
+	for _, line := range strings.Fields(output) {
+		port, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected port %q in Get-NetTCPConnection output", line)
+		}
+
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+
+	sort.Ints(ports)
+	return ports, nil
+}
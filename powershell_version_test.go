@@ -0,0 +1,28 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PowerShellVersionExpectation is the minimum PowerShell version a tag must
+// ship.
+type PowerShellVersionExpectation struct {
+	Minimum string
+}
+
+// powerShellVersion returns $PSVersionTable.PSVersion of image, e.g.
+// "5.1.17763.1971".
+func powerShellVersion(image string) (string, error) {
+	output, err := powershellIn(image, "$PSVersionTable.PSVersion.ToString()")
+	if err != nil {
+		return "", err
+	}
+
+	version := strings.TrimSpace(output)
+	if len(versionParts(version)) < 2 {
+		return "", fmt.Errorf("unexpected PowerShell version %q", version)
+	}
+
+	return version, nil
+}
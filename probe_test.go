@@ -0,0 +1,87 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/onsi/ginkgo/config"
+)
+
+const defaultShareProbeTimeout = 10 * time.Second
+
+// smbSpecTexts are the full texts of the specs that talk to the file server.
+// The reachability probe only runs when at least one of them is selected.
+var smbSpecTexts = []string{
+	"Windows2016fs can write to an IP-based smb share",
+	"Windows2016fs can write to an FQDN-based smb share",
+	"Windows2016fs can write to an smb share over QUIC when a proxy is required",
+	"Windows2016fs can write to an smb share using a credential file",
+	"Windows2016fs fails to mount an smb share with a bad password",
+	"Windows2016fs can access one share multiple times on the same VM",
+	"Windows2016fs functions under a memory limit",
+	"Windows2016fs handles a read-only share correctly",
+	"Windows2016fs can write to an IP-based smb share with process isolation",
+	"Windows2016fs can write to an IP-based smb share with hyperv isolation",
+}
+
+// probeShareReachable checks that host accepts TCP connections on the SMB
+// port within timeout.
+func probeShareReachable(host string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, "445"), timeout)
+	if err != nil {
+		return fmt.Errorf("file server %s is not reachable on port 445 within %s: %s", host, timeout, err)
+	}
+
+	return conn.Close()
+}
+
+// shareProbeTimeout reads SHARE_PROBE_TIMEOUT (e.g. "30s"), falling back to
+// defaultShareProbeTimeout.
+func shareProbeTimeout() (time.Duration, error) {
+	value := os.Getenv("SHARE_PROBE_TIMEOUT")
+	if value == "" {
+		return defaultShareProbeTimeout, nil
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("SHARE_PROBE_TIMEOUT is invalid: %s", err)
+	}
+
+	return timeout, nil
+}
+
+// smbSpecsSelected reports whether the -focus and -skip flags leave any SMB
+// spec to run.
+func smbSpecsSelected() bool {
+	for _, text := range smbSpecTexts {
+		if specSelected(text) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func specSelected(text string) bool {
+	for _, skip := range config.GinkgoConfig.SkipStrings {
+		if regexp.MustCompile(skip).MatchString(text) {
+			return false
+		}
+	}
+
+	if len(config.GinkgoConfig.FocusStrings) == 0 {
+		return true
+	}
+
+	for _, focus := range config.GinkgoConfig.FocusStrings {
+		if regexp.MustCompile(focus).MatchString(text) {
+			return true
+		}
+	}
+
+	return false
+}
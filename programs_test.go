@@ -0,0 +1,35 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// unapprovedPrograms returns the programs whose name matches none of the
+// allowlist patterns. Patterns use path.Match syntax, so that entries such as
+// "Git version *" survive version bumps, and match case-insensitively.
+func unapprovedPrograms(allowlist []string, programs []validation.Program) ([]string, error) {
+	var unapproved []string
+	for _, program := range programs {
+		approved := false
+		for _, pattern := range allowlist {
+			matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(program.Name))
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist pattern %q: %s", pattern, err)
+			}
+			if matched {
+				approved = true
+				break
+			}
+		}
+
+		if !approved {
+			unapproved = append(unapproved, program.String())
+		}
+	}
+
+	return unapproved, nil
+}
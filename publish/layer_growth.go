@@ -0,0 +1,304 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Layer is a layer of an image, with its compressed size as its manifest
+// records it and the instruction that created it, when the image's history
+// accounts for every layer.
+type Layer struct {
+	Digest    string
+	Size      int64
+	CreatedBy string
+}
+
+// layerConfig holds the history of an image configuration.
+type layerConfig struct {
+	History []struct {
+		CreatedBy  string `json:"created_by"`
+		EmptyLayer bool   `json:"empty_layer"`
+	} `json:"history"`
+}
+
+// ImageLayers reads the layers of the image ref points to from its
+// manifest and configuration. For a manifest list it reads the image for
+// platform, given as os/arch (windows/amd64 by default).
+func ImageLayers(ctx context.Context, client *registry.Client, ref registry.Reference, platform string) ([]Layer, error) {
+	manifest, _, _, err := client.GetManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.IsList() {
+		digest, err := platformImage(manifest, platform)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", ref, err)
+		}
+
+		if manifest, _, _, err = client.GetManifest(ctx, ref.WithDigest(digest)); err != nil {
+			return nil, err
+		}
+	}
+
+	if manifest.Config == nil {
+		return nil, fmt.Errorf("%s isn't an image manifest (%s)", ref, manifest.MediaType)
+	}
+
+	content, err := client.GetBlob(ctx, ref, manifest.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	var config layerConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("parsing the configuration of %s: %s", ref, err)
+	}
+
+	return manifestLayers(manifest, config), nil
+}
+
+// LayoutLayers reads the layers of image, named by its
+// org.opencontainers.image.ref.name annotation, from the OCI image layout at
+// dir.
+func LayoutLayers(dir, image string) ([]Layer, error) {
+	var index registry.Manifest
+	if err := readLayoutFile(filepath.Join(dir, "index.json"), &index); err != nil {
+		return nil, err
+	}
+
+	for _, descriptor := range index.Manifests {
+		if descriptor.Annotations["org.opencontainers.image.ref.name"] != image {
+			continue
+		}
+
+		var manifest registry.Manifest
+		if err := readLayoutFile(blobPath(dir, descriptor.Digest), &manifest); err != nil {
+			return nil, err
+		}
+		if manifest.Config == nil {
+			return nil, fmt.Errorf("manifest %s has no configuration", descriptor.Digest)
+		}
+
+		var config layerConfig
+		if err := readLayoutFile(blobPath(dir, manifest.Config.Digest), &config); err != nil {
+			return nil, err
+		}
+
+		return manifestLayers(manifest, config), nil
+	}
+
+	return nil, fmt.Errorf("%s has no image %s", dir, image)
+}
+
+func readLayoutFile(path string, v interface{}) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return nil
+}
+
+// platformImage returns the digest of the single image list holds for
+// platform.
+func platformImage(list registry.Manifest, platform string) (string, error) {
+	if platform == "" {
+		platform = "windows/amd64"
+	}
+
+	var digests []string
+	for _, descriptor := range list.Manifests {
+		if descriptor.Platform != nil && descriptor.Platform.OS+"/"+descriptor.Platform.Architecture == platform {
+			digests = append(digests, descriptor.Digest)
+		}
+	}
+
+	switch len(digests) {
+	case 0:
+		return "", fmt.Errorf("no image for %s", platform)
+	case 1:
+		return digests[0], nil
+	default:
+		return "", fmt.Errorf("%d images for %s; give the digest of one", len(digests), platform)
+	}
+}
+
+func manifestLayers(manifest registry.Manifest, config layerConfig) []Layer {
+	var createdBy []string
+	for _, step := range config.History {
+		if !step.EmptyLayer {
+			createdBy = append(createdBy, step.CreatedBy)
+		}
+	}
+
+	layers := make([]Layer, len(manifest.Layers))
+	for i, descriptor := range manifest.Layers {
+		layers[i] = Layer{Digest: descriptor.Digest, Size: descriptor.Size}
+		if len(createdBy) == len(manifest.Layers) {
+			layers[i].CreatedBy = createdBy[i]
+		}
+	}
+
+	return layers
+}
+
+// Growth is a layer that grew from one image to the next.
+type Growth struct {
+	Previous, Current Layer
+
+	// Percent is how much larger the current layer is.
+	Percent float64
+}
+
+// LayerComparison lists how the layers of an image changed since the
+// previous one.
+type LayerComparison struct {
+	// Grown are the layers that grew by more than the allowed percentage.
+	Grown []Growth
+
+	// Added and Removed are the layers of one image that have no
+	// counterpart in the other, such as a base image's update layer after
+	// a new cumulative update.
+	Added, Removed []Layer
+}
+
+// CompareLayers pairs each layer of current with a layer of previous and
+// returns the ones that grew by more than maxPercent. Layers are paired by
+// the instruction that created them when both histories account for every
+// layer, and by position otherwise. Layers smaller than minSize are too
+// small to fail on and are left out.
+func CompareLayers(previous, current []Layer, maxPercent float64, minSize int64) LayerComparison {
+	var comparison LayerComparison
+
+	pairs := pairLayers(previous, current)
+	paired := map[int]bool{}
+	for i, layer := range current {
+		j, ok := pairs[i]
+		if !ok {
+			comparison.Added = append(comparison.Added, layer)
+			continue
+		}
+		paired[j] = true
+
+		before := previous[j]
+		if layer.Digest == before.Digest || layer.Size < minSize || layer.Size <= before.Size {
+			continue
+		}
+
+		percent := math.Inf(1)
+		if before.Size > 0 {
+			percent = float64(layer.Size-before.Size) * 100 / float64(before.Size)
+		}
+		if percent > maxPercent {
+			comparison.Grown = append(comparison.Grown, Growth{Previous: before, Current: layer, Percent: percent})
+		}
+	}
+
+	for j, layer := range previous {
+		if !paired[j] {
+			comparison.Removed = append(comparison.Removed, layer)
+		}
+	}
+
+	return comparison
+}
+
+// pairLayers maps the index of each layer of current to the index of its
+// counterpart in previous.
+func pairLayers(previous, current []Layer) map[int]int {
+	pairs := map[int]int{}
+
+	if hasHistory(previous) && hasHistory(current) {
+		used := map[int]bool{}
+		for i, layer := range current {
+			for j, candidate := range previous {
+				if !used[j] && candidate.CreatedBy == layer.CreatedBy {
+					pairs[i] = j
+					used[j] = true
+					break
+				}
+			}
+		}
+
+		return pairs
+	}
+
+	for i := range current {
+		if i < len(previous) {
+			pairs[i] = i
+		}
+	}
+
+	return pairs
+}
+
+func hasHistory(layers []Layer) bool {
+	for _, layer := range layers {
+		if layer.CreatedBy == "" {
+			return false
+		}
+	}
+
+	return len(layers) > 0
+}
+
+// String describes the comparison, e.g. for a pipeline log.
+func (c LayerComparison) String() string {
+	var b strings.Builder
+
+	if len(c.Grown) == 0 {
+		b.WriteString("no layer grew beyond the limit\n")
+	} else {
+		b.WriteString("layers that grew beyond the limit:\n")
+		for _, growth := range c.Grown {
+			fmt.Fprintf(&b, "  %s: %s -> %s (+%.1f%%)\n", describeLayer(growth.Current), validation.FormatSize(growth.Previous.Size), validation.FormatSize(growth.Current.Size), growth.Percent)
+		}
+	}
+
+	for _, list := range []struct {
+		heading string
+		layers  []Layer
+	}{
+		{"added layers", c.Added},
+		{"removed layers", c.Removed},
+	} {
+		if len(list.layers) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:\n", list.heading)
+		for _, layer := range list.layers {
+			fmt.Fprintf(&b, "  %s: %s\n", describeLayer(layer), validation.FormatSize(layer.Size))
+		}
+	}
+
+	return b.String()
+}
+
+// describeLayer names a layer by its instruction, shortened, or its digest.
+func describeLayer(layer Layer) string {
+	if layer.CreatedBy == "" {
+		return layer.Digest
+	}
+
+	description := strings.Join(strings.Fields(layer.CreatedBy), " ")
+	if len(description) > 80 {
+		description = description[:77] + "..."
+	}
+
+	return description
+}
@@ -0,0 +1,99 @@
+package publish_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("layer growth", func() {
+	Describe("ImageLayers", func() {
+		var (
+			server *registrytest.Server
+			client *registry.Client
+		)
+
+		BeforeEach(func() {
+			server = registrytest.NewServer()
+			client = &registry.Client{PlainHTTP: true}
+
+			config := server.PutBlob([]byte(`{"history":[{"created_by":"Apply image 10.0.17763.1"},{"created_by":"cmd /S /C #(nop)  ENV A=b","empty_layer":true},{"created_by":"cmd /S /C powershell ./install.ps1"}]}`))
+			image := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":%q,"size":1},"layers":[{"mediaType":"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip","digest":"sha256:base","size":1000},{"mediaType":"application/vnd.docker.image.rootfs.diff.tar.gzip","digest":"sha256:install","size":200}]}`, registry.MediaTypeDockerManifest, config)
+			imageDigest := server.PutManifest("cloudfoundry/windows2016fs", "2019.12", registry.MediaTypeDockerManifest, []byte(image))
+			list := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"manifests":[{"mediaType":%q,"digest":%q,"size":%d,"platform":{"architecture":"amd64","os":"windows"}}]}`, registry.MediaTypeOCIIndex, registry.MediaTypeDockerManifest, imageDigest, len(image))
+			server.PutManifest("cloudfoundry/windows2016fs", "2019", registry.MediaTypeOCIIndex, []byte(list))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("reads the sizes of the layers and the instructions that created them", func() {
+			layers, err := publish.ImageLayers(context.Background(), client, reference(server.Host()+"/cloudfoundry/windows2016fs:2019"), "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(layers).To(Equal([]publish.Layer{
+				{Digest: "sha256:base", Size: 1000, CreatedBy: "Apply image 10.0.17763.1"},
+				{Digest: "sha256:install", Size: 200, CreatedBy: "cmd /S /C powershell ./install.ps1"},
+			}))
+		})
+
+		It("fails for platforms the list has no image for", func() {
+			_, err := publish.ImageLayers(context.Background(), client, reference(server.Host()+"/cloudfoundry/windows2016fs:2019"), "linux/amd64")
+			Expect(err).To(MatchError(ContainSubstring("no image for linux/amd64")))
+		})
+	})
+
+	Describe("CompareLayers", func() {
+		previous := []publish.Layer{
+			{Digest: "sha256:base", Size: 4000, CreatedBy: "Apply image 10.0.17763.1"},
+			{Digest: "sha256:update-5122", Size: 1500, CreatedBy: "Install update 10.0.17763.5122"},
+			{Digest: "sha256:install-1", Size: 200, CreatedBy: "cmd /S /C powershell ./install.ps1"},
+			{Digest: "sha256:fonts-1", Size: 100, CreatedBy: "cmd /S /C powershell ./fonts.ps1"},
+		}
+
+		It("reports the layers that grew by more than the limit", func() {
+			current := []publish.Layer{
+				{Digest: "sha256:base", Size: 4000, CreatedBy: "Apply image 10.0.17763.1"},
+				{Digest: "sha256:update-5206", Size: 1600, CreatedBy: "Install update 10.0.17763.5206"},
+				{Digest: "sha256:install-2", Size: 300, CreatedBy: "cmd /S /C powershell ./install.ps1"},
+				{Digest: "sha256:fonts-2", Size: 105, CreatedBy: "cmd /S /C powershell ./fonts.ps1"},
+			}
+
+			comparison := publish.CompareLayers(previous, current, 10, 0)
+			Expect(comparison.Grown).To(Equal([]publish.Growth{{Previous: previous[2], Current: current[2], Percent: 50}}))
+			Expect(comparison.Added).To(Equal([]publish.Layer{current[1]}))
+			Expect(comparison.Removed).To(Equal([]publish.Layer{previous[1]}))
+
+			Expect(comparison.String()).To(Equal(`layers that grew beyond the limit:
+  cmd /S /C powershell ./install.ps1: 200B -> 300B (+50.0%)
+added layers:
+  Install update 10.0.17763.5206: 1.56KiB
+removed layers:
+  Install update 10.0.17763.5122: 1.46KiB
+`))
+		})
+
+		It("pairs layers by position without history", func() {
+			current := []publish.Layer{{Digest: "sha256:a", Size: 4000}, {Digest: "sha256:b", Size: 3000}}
+			comparison := publish.CompareLayers(
+				[]publish.Layer{{Digest: "sha256:a", Size: 4000}, {Digest: "sha256:c", Size: 1500}},
+				current, 10, 0)
+
+			Expect(comparison.Grown).To(HaveLen(1))
+			Expect(comparison.Grown[0].Current).To(Equal(current[1]))
+			Expect(comparison.Added).To(BeEmpty())
+		})
+
+		It("leaves out layers smaller than the minimum size", func() {
+			current := []publish.Layer{{Digest: "sha256:fonts-2", Size: 150, CreatedBy: "cmd /S /C powershell ./fonts.ps1"}}
+			Expect(publish.CompareLayers(previous[3:], current, 10, 1000).Grown).To(BeEmpty())
+			Expect(publish.CompareLayers(previous[3:], current, 10, 0).String()).To(ContainSubstring("+50.0%"))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+// Package publish assembles and publishes the images built from this repo.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// imageConfig holds the fields of an image configuration that make up its
+// platform.
+type imageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	OSVersion    string `json:"os.version"`
+}
+
+// ManifestList publishes an OCI image index at target listing the images of
+// sources, each with the platform recorded in its configuration, and returns
+// the index's digest. Windows hosts pick the entry whose os.version matches
+// their build, so one tag serves every version. Sources must be images, not
+// lists, in target's repository.
+func ManifestList(ctx context.Context, client *registry.Client, target registry.Reference, sources []registry.Reference) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("no images to publish as %s", target)
+	}
+
+	index := registry.Manifest{SchemaVersion: 2, MediaType: registry.MediaTypeOCIIndex}
+	for _, source := range sources {
+		if !source.SameRepository(target) {
+			return "", fmt.Errorf("%s isn't in the repository of %s", source, target)
+		}
+
+		descriptor, err := platformDescriptor(ctx, client, source)
+		if err != nil {
+			return "", err
+		}
+
+		for _, listed := range index.Manifests {
+			if *listed.Platform == *descriptor.Platform {
+				return "", fmt.Errorf("%s has the same platform as %s: %s", source, listed.Digest, platformString(*listed.Platform))
+			}
+		}
+
+		index.Manifests = append(index.Manifests, descriptor)
+	}
+
+	sort.SliceStable(index.Manifests, func(i, j int) bool {
+		return index.Manifests[i].Platform.OSVersion < index.Manifests[j].Platform.OSVersion
+	})
+
+	content, err := json.Marshal(index)
+	if err != nil {
+		return "", err
+	}
+
+	return client.PutManifest(ctx, target, registry.MediaTypeOCIIndex, content)
+}
+
+// platformDescriptor returns the descriptor of the image ref points to,
+// with the platform read from its configuration.
+func platformDescriptor(ctx context.Context, client *registry.Client, ref registry.Reference) (registry.Descriptor, error) {
+	manifest, _, descriptor, err := client.GetManifest(ctx, ref)
+	if err != nil {
+		return registry.Descriptor{}, err
+	}
+
+	if manifest.IsList() || manifest.Config == nil {
+		return registry.Descriptor{}, fmt.Errorf("%s isn't an image manifest (%s)", ref, manifest.MediaType)
+	}
+
+	content, err := client.GetBlob(ctx, ref, manifest.Config.Digest)
+	if err != nil {
+		return registry.Descriptor{}, err
+	}
+
+	var config imageConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return registry.Descriptor{}, fmt.Errorf("parsing the configuration of %s: %s", ref, err)
+	}
+
+	if config.OS == "" || config.Architecture == "" {
+		return registry.Descriptor{}, fmt.Errorf("the configuration of %s doesn't record its platform", ref)
+	}
+	if config.OS == "windows" && config.OSVersion == "" {
+		return registry.Descriptor{}, fmt.Errorf("the configuration of %s doesn't record its os.version", ref)
+	}
+
+	descriptor.Platform = &registry.Platform{Architecture: config.Architecture, OS: config.OS, OSVersion: config.OSVersion}
+	descriptor.Annotations = map[string]string{"org.opencontainers.image.ref.name": ref.String()}
+
+	return descriptor, nil
+}
+
+func platformString(p registry.Platform) string {
+	if p.OSVersion == "" {
+		return p.OS + "/" + p.Architecture
+	}
+
+	return fmt.Sprintf("%s/%s (%s)", p.OS, p.Architecture, p.OSVersion)
+}
@@ -0,0 +1,97 @@
+package publish_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ManifestList", func() {
+	var (
+		server *registrytest.Server
+		client *registry.Client
+	)
+
+	// pushImage stores an image whose configuration records osVersion under
+	// tag and returns its reference.
+	pushImage := func(tag, osVersion string) registry.Reference {
+		config := server.PutBlob([]byte(fmt.Sprintf(`{"architecture":"amd64","os":"windows","os.version":%q}`, osVersion)))
+		manifest := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":%q,"size":1}}`, registry.MediaTypeDockerManifest, config)
+		server.PutManifest("cloudfoundry/windows2016fs", tag, registry.MediaTypeDockerManifest, []byte(manifest))
+
+		return reference(server.Host() + "/cloudfoundry/windows2016fs:" + tag)
+	}
+
+	BeforeEach(func() {
+		server = registrytest.NewServer()
+		client = &registry.Client{PlainHTTP: true}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("publishes an index listing each image by platform", func() {
+		ltsc2022 := pushImage("2022.1", "10.0.20348.2113")
+		ltsc2019 := pushImage("2019.12", "10.0.17763.5122")
+		target := reference(server.Host() + "/cloudfoundry/windows2016fs:latest")
+
+		digest, err := publish.ManifestList(context.Background(), client, target, []registry.Reference{ltsc2022, ltsc2019})
+		Expect(err).ToNot(HaveOccurred())
+
+		mediaType, content, ok := server.Manifest("cloudfoundry/windows2016fs", "latest")
+		Expect(ok).To(BeTrue())
+		Expect(mediaType).To(Equal(registry.MediaTypeOCIIndex))
+		Expect(registry.Digest(content)).To(Equal(digest))
+
+		var index registry.Manifest
+		Expect(json.Unmarshal(content, &index)).To(Succeed())
+		Expect(index.MediaType).To(Equal(registry.MediaTypeOCIIndex))
+		Expect(index.Manifests).To(HaveLen(2))
+		Expect(*index.Manifests[0].Platform).To(Equal(registry.Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.17763.5122"}))
+		Expect(*index.Manifests[1].Platform).To(Equal(registry.Platform{Architecture: "amd64", OS: "windows", OSVersion: "10.0.20348.2113"}))
+
+		_, ltsc2019Manifest, _ := server.Manifest("cloudfoundry/windows2016fs", "2019.12")
+		Expect(index.Manifests[0].Digest).To(Equal(registry.Digest(ltsc2019Manifest)))
+		Expect(index.Manifests[0].MediaType).To(Equal(registry.MediaTypeDockerManifest))
+	})
+
+	It("rejects images from another repository", func() {
+		source := pushImage("2019.12", "10.0.17763.5122")
+		target := reference(server.Host() + "/cloudfoundry/other:latest")
+
+		_, err := publish.ManifestList(context.Background(), client, target, []registry.Reference{source})
+		Expect(err).To(MatchError(ContainSubstring("isn't in the repository of")))
+	})
+
+	It("rejects two images for the same platform", func() {
+		first := pushImage("2019.11", "10.0.17763.5122")
+		second := pushImage("2019.12", "10.0.17763.5122")
+		target := reference(server.Host() + "/cloudfoundry/windows2016fs:latest")
+
+		_, err := publish.ManifestList(context.Background(), client, target, []registry.Reference{first, second})
+		Expect(err).To(MatchError(ContainSubstring("has the same platform as")))
+	})
+
+	It("rejects images without an os.version", func() {
+		source := pushImage("2019.12", "")
+		target := reference(server.Host() + "/cloudfoundry/windows2016fs:latest")
+
+		_, err := publish.ManifestList(context.Background(), client, target, []registry.Reference{source})
+		Expect(err).To(MatchError(ContainSubstring("doesn't record its os.version")))
+	})
+})
+
+func reference(ref string) registry.Reference {
+	parsed, err := registry.ParseReference(ref)
+	Expect(err).ToNot(HaveOccurred())
+
+	return parsed
+}
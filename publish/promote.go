@@ -0,0 +1,118 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Promotion records the promotion of a validated candidate to release, for
+// auditing.
+type Promotion struct {
+	Source        string    `json:"source"`
+	SourceImageID string    `json:"source_image_id,omitempty"`
+	Digest        string    `json:"digest"`
+	Tags          []string  `json:"tags"`
+	Signed        bool      `json:"signed"`
+	PromotedAt    time.Time `json:"promoted_at"`
+}
+
+// PushFunc pushes a local image to a remote reference and returns the
+// digest of the pushed manifest, e.g. validation.PushImage.
+type PushFunc func(local, remote string) (string, error)
+
+// PromoteOptions describes a promotion.
+type PromoteOptions struct {
+	// Source is the validated candidate, e.g. windows2016fs-candidate:2019,
+	// and SourceImageID its image ID.
+	Source        string
+	SourceImageID string
+
+	// Version is the immutable version tag, e.g.
+	// cloudfoundry/windows2016fs:2019.12; promotion fails if it exists.
+	Version registry.Reference
+
+	// Release is the moving release tag, e.g. cloudfoundry/windows2016fs:2019,
+	// pointed at the version's manifest.
+	Release registry.Reference
+
+	Push PushFunc
+
+	// Retry is the policy for pointing the release tag at the version.
+	Retry validation.RetryPolicy
+}
+
+// Promote pushes the candidate as the version tag and points the release
+// tag at the same manifest, so both tags resolve to the digest that was
+// validated.
+func Promote(ctx context.Context, client *registry.Client, opts PromoteOptions) (Promotion, error) {
+	if !opts.Version.SameRepository(opts.Release) {
+		return Promotion{}, fmt.Errorf("%s and %s are in different repositories", opts.Version, opts.Release)
+	}
+	if opts.Version.Tag == "" || opts.Release.Tag == "" {
+		return Promotion{}, errors.New("promotion needs a version tag and a release tag, not digests")
+	}
+
+	_, _, existing, err := client.GetManifest(ctx, opts.Version)
+	if err == nil {
+		return Promotion{}, fmt.Errorf("%s already exists as %s; version tags are immutable", opts.Version, existing.Digest)
+	}
+	var registryErr *registry.Error
+	if !errors.As(err, &registryErr) || registryErr.StatusCode != http.StatusNotFound {
+		return Promotion{}, fmt.Errorf("checking whether %s exists: %s", opts.Version, err)
+	}
+
+	digest, err := opts.Push(opts.Source, opts.Version.String())
+	if err != nil {
+		return Promotion{}, err
+	}
+
+	_, content, descriptor, err := client.GetManifest(ctx, opts.Version.WithDigest(digest))
+	if err != nil {
+		return Promotion{}, err
+	}
+
+	err = opts.Retry.Do(ctx, nil, "tagging "+opts.Release.String(), func() error {
+		_, err := client.PutManifest(ctx, opts.Release, descriptor.MediaType, content)
+		return err
+	})
+	if err != nil {
+		return Promotion{}, fmt.Errorf("tagging %s: %s", opts.Release, err)
+	}
+
+	return Promotion{
+		Source:        opts.Source,
+		SourceImageID: opts.SourceImageID,
+		Digest:        digest,
+		Tags:          []string{opts.Version.String(), opts.Release.String()},
+		PromotedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// Write stores the promotion as JSON at path, refusing to overwrite an
+// earlier record.
+func (p Promotion) Write(path string) error {
+	content, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(content, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,119 @@
+package publish_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Promote", func() {
+	var (
+		server   *registrytest.Server
+		client   *registry.Client
+		opts     publish.PromoteOptions
+		manifest []byte
+		pushed   []string
+	)
+
+	BeforeEach(func() {
+		server = registrytest.NewServer()
+		client = &registry.Client{PlainHTTP: true}
+		manifest = []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"sha256:config","size":1}}`, registry.MediaTypeDockerManifest))
+		pushed = nil
+
+		opts = publish.PromoteOptions{
+			Source:        "windows2016fs-candidate:2019",
+			SourceImageID: "sha256:image",
+			Version:       reference(server.Host() + "/cloudfoundry/windows2016fs:2019.12"),
+			Release:       reference(server.Host() + "/cloudfoundry/windows2016fs:2019"),
+			Push: func(local, remote string) (string, error) {
+				pushed = append(pushed, local+" "+remote)
+				return server.PutManifest("cloudfoundry/windows2016fs", "2019.12", registry.MediaTypeDockerManifest, manifest), nil
+			},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("pushes the version tag and points the release tag at the same manifest", func() {
+		promotion, err := publish.Promote(context.Background(), client, opts)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(pushed).To(Equal([]string{"windows2016fs-candidate:2019 " + opts.Version.String()}))
+
+		_, release, ok := server.Manifest("cloudfoundry/windows2016fs", "2019")
+		Expect(ok).To(BeTrue())
+		Expect(release).To(Equal(manifest))
+
+		Expect(promotion.Source).To(Equal("windows2016fs-candidate:2019"))
+		Expect(promotion.SourceImageID).To(Equal("sha256:image"))
+		Expect(promotion.Digest).To(Equal(registry.Digest(manifest)))
+		Expect(promotion.Tags).To(Equal([]string{opts.Version.String(), opts.Release.String()}))
+		Expect(promotion.PromotedAt).To(BeTemporally("~", time.Now(), time.Minute))
+	})
+
+	It("refuses to move an existing version tag", func() {
+		server.PutManifest("cloudfoundry/windows2016fs", "2019.12", registry.MediaTypeDockerManifest, manifest)
+
+		_, err := publish.Promote(context.Background(), client, opts)
+		Expect(err).To(MatchError(ContainSubstring("version tags are immutable")))
+		Expect(pushed).To(BeEmpty())
+	})
+
+	It("leaves the release tag alone when the push fails", func() {
+		opts.Push = func(string, string) (string, error) { return "", errors.New("push failed") }
+
+		_, err := publish.Promote(context.Background(), client, opts)
+		Expect(err).To(MatchError("push failed"))
+
+		_, _, ok := server.Manifest("cloudfoundry/windows2016fs", "2019")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("requires both tags in one repository", func() {
+		opts.Release = reference(server.Host() + "/cloudfoundry/other:2019")
+
+		_, err := publish.Promote(context.Background(), client, opts)
+		Expect(err).To(MatchError(ContainSubstring("are in different repositories")))
+	})
+})
+
+var _ = Describe("Promotion", func() {
+	It("writes a JSON record and never overwrites one", func() {
+		dir, err := ioutil.TempDir("", "promotion")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "2019.12.json")
+		promotion := publish.Promotion{
+			Source:     "windows2016fs-candidate:2019",
+			Digest:     "sha256:abc",
+			Tags:       []string{"cloudfoundry/windows2016fs:2019.12", "cloudfoundry/windows2016fs:2019"},
+			PromotedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		Expect(promotion.Write(path)).To(Succeed())
+
+		var record map[string]interface{}
+		content, err := ioutil.ReadFile(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(json.Unmarshal(content, &record)).To(Succeed())
+		Expect(record).To(HaveKeyWithValue("digest", "sha256:abc"))
+		Expect(record).To(HaveKeyWithValue("promoted_at", "2024-01-02T03:04:05Z"))
+
+		Expect(promotion.Write(path)).ToNot(Succeed())
+	})
+})
@@ -0,0 +1,101 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Layout pushes the image named image, by its org.opencontainers.image.ref.name
+// annotation, in the OCI image layout at dir to target and returns the digest
+// of its manifest. Blobs the registry already has aren't uploaded, and
+// nondistributable layers are left to be fetched from their URLs. Uploads
+// are retried as retry allows.
+func Layout(ctx context.Context, client *registry.Client, dir, image string, target registry.Reference, retry validation.RetryPolicy, log io.Writer) (string, error) {
+	if log == nil {
+		log = ioutil.Discard
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return "", err
+	}
+
+	var index registry.Manifest
+	if err := json.Unmarshal(content, &index); err != nil {
+		return "", fmt.Errorf("parsing the index of %s: %s", dir, err)
+	}
+
+	var descriptor *registry.Descriptor
+	for i, candidate := range index.Manifests {
+		if candidate.Annotations["org.opencontainers.image.ref.name"] == image {
+			descriptor = &index.Manifests[i]
+		}
+	}
+	if descriptor == nil {
+		return "", fmt.Errorf("%s has no image %s", dir, image)
+	}
+
+	manifestContent, err := ioutil.ReadFile(blobPath(dir, descriptor.Digest))
+	if err != nil {
+		return "", err
+	}
+
+	var manifest registry.Manifest
+	if err := json.Unmarshal(manifestContent, &manifest); err != nil {
+		return "", fmt.Errorf("parsing the manifest of %s: %s", image, err)
+	}
+	if manifest.Config == nil {
+		return "", fmt.Errorf("%s in %s isn't an image manifest", image, dir)
+	}
+
+	blobs := append([]registry.Descriptor{*manifest.Config}, manifest.Layers...)
+	for i, blob := range blobs {
+		if strings.Contains(blob.MediaType, "nondistributable") && len(blob.URLs) > 0 {
+			fmt.Fprintf(log, "skipped nondistributable blob %s [%d/%d]\n", blob.Digest, i+1, len(blobs))
+			continue
+		}
+
+		err := retry.Do(ctx, log, "uploading "+blob.Digest, func() error {
+			return uploadFile(ctx, client, target, blob, blobPath(dir, blob.Digest))
+		})
+		if err != nil {
+			return "", fmt.Errorf("uploading %s: %s", blob.Digest, err)
+		}
+		fmt.Fprintf(log, "pushed blob %s (%d bytes) [%d/%d]\n", blob.Digest, blob.Size, i+1, len(blobs))
+	}
+
+	var digest string
+	err = retry.Do(ctx, log, "uploading the manifest", func() error {
+		var err error
+		digest, err = client.PutManifest(ctx, target, manifest.MediaType, manifestContent)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("uploading the manifest: %s", err)
+	}
+
+	return digest, nil
+}
+
+func uploadFile(ctx context.Context, client *registry.Client, target registry.Reference, blob registry.Descriptor, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return client.UploadBlob(ctx, target, blob, file)
+}
+
+func blobPath(dir, digest string) string {
+	return filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+}
@@ -0,0 +1,103 @@
+package publish_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Layout", func() {
+	var (
+		server   *registrytest.Server
+		client   *registry.Client
+		dir      string
+		manifest []byte
+		config   string
+		layer    string
+		retry    validation.RetryPolicy
+	)
+
+	writeBlob := func(content []byte) string {
+		digest := registry.Digest(content)
+		Expect(ioutil.WriteFile(filepath.Join(dir, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")), content, 0644)).To(Succeed())
+
+		return digest
+	}
+
+	BeforeEach(func() {
+		server = registrytest.NewServer()
+		client = &registry.Client{PlainHTTP: true}
+
+		var err error
+		dir, err = ioutil.TempDir("", "layout")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755)).To(Succeed())
+
+		config = writeBlob([]byte(`{"os":"windows"}`))
+		layer = writeBlob([]byte("layer"))
+		manifest = []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":%q,"size":16},"layers":[{"mediaType":"application/vnd.oci.image.layer.nondistributable.v1.tar+gzip","digest":"sha256:foreign","size":1,"urls":["https://mcr.microsoft.com/foreign"]},{"mediaType":"application/vnd.oci.image.layer.v1.tar","digest":%q,"size":5}]}`, registry.MediaTypeOCIManifest, config, layer))
+		index := fmt.Sprintf(`{"schemaVersion":2,"manifests":[{"mediaType":%q,"digest":%q,"size":%d,"annotations":{"org.opencontainers.image.ref.name":"windows2016fs-candidate:2019"}}]}`, registry.MediaTypeOCIManifest, writeBlob(manifest), len(manifest))
+		Expect(ioutil.WriteFile(filepath.Join(dir, "index.json"), []byte(index), 0644)).To(Succeed())
+
+		retry = validation.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	target := func() registry.Reference {
+		return reference(server.Host() + "/cloudfoundry/windows2016fs:2019.12")
+	}
+
+	It("pushes the image's blobs and manifest, skipping nondistributable layers", func() {
+		digest, err := publish.Layout(context.Background(), client, dir, "windows2016fs-candidate:2019", target(), retry, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(digest).To(Equal(registry.Digest(manifest)))
+
+		_, content, ok := server.Manifest("cloudfoundry/windows2016fs", "2019.12")
+		Expect(ok).To(BeTrue())
+		Expect(content).To(Equal(manifest))
+
+		for _, blob := range []string{config, layer} {
+			_, ok := server.Blob(blob)
+			Expect(ok).To(BeTrue(), blob)
+		}
+		_, ok = server.Blob("sha256:foreign")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("retries uploads that fail transiently", func() {
+		server.FailUploads = 2
+		var log strings.Builder
+
+		_, err := publish.Layout(context.Background(), client, dir, "windows2016fs-candidate:2019", target(), retry, &log)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(strings.Count(log.String(), "retrying")).To(Equal(2))
+	})
+
+	It("gives up after the configured attempts", func() {
+		server.FailUploads = 3
+
+		_, err := publish.Layout(context.Background(), client, dir, "windows2016fs-candidate:2019", target(), retry, nil)
+		Expect(err).To(MatchError(ContainSubstring("returned 503")))
+	})
+
+	It("fails on images the layout doesn't have", func() {
+		_, err := publish.Layout(context.Background(), client, dir, "windows2016fs-candidate:2022", target(), retry, nil)
+		Expect(err).To(MatchError(ContainSubstring("has no image windows2016fs-candidate:2022")))
+	})
+})
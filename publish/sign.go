@@ -0,0 +1,91 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// Cosign is the cosign binary images are signed and verified with.
+var Cosign = "cosign"
+
+// Signer signs pushed images with cosign, with a key or, when Key is empty,
+// keylessly with the ambient OIDC identity.
+type Signer struct {
+	// Key is a cosign private key file or KMS URI. cosign reads its
+	// password from COSIGN_PASSWORD.
+	Key string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Args returns the cosign arguments that sign ref.
+func (s Signer) Args(ref registry.Reference) []string {
+	args := []string{"sign", "--yes"}
+	if s.Key != "" {
+		args = append(args, "--key", s.Key)
+	}
+
+	return append(args, ref.String())
+}
+
+// Sign signs the manifest ref points to. Only digest references are signed,
+// so that the signature covers exactly what was pushed.
+func (s Signer) Sign(ctx context.Context, ref registry.Reference) error {
+	if ref.Digest == "" {
+		return fmt.Errorf("%s: only digest references can be signed", ref)
+	}
+
+	return runCosign(ctx, s.Args(ref), s.Stdout, s.Stderr)
+}
+
+// Verifier verifies the cosign signatures of published images, with a public
+// key or, for keyless signatures, the expected signer identity.
+type Verifier struct {
+	Key string
+
+	CertificateIdentity   string
+	CertificateOIDCIssuer string
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Args returns the cosign arguments that verify ref.
+func (v Verifier) Args(ref registry.Reference) []string {
+	args := []string{"verify"}
+	if v.Key != "" {
+		args = append(args, "--key", v.Key)
+	} else {
+		args = append(args, "--certificate-identity", v.CertificateIdentity, "--certificate-oidc-issuer", v.CertificateOIDCIssuer)
+	}
+
+	return append(args, ref.String())
+}
+
+// Verify fails unless ref carries a valid signature.
+func (v Verifier) Verify(ctx context.Context, ref registry.Reference) error {
+	if v.Key == "" && (v.CertificateIdentity == "" || v.CertificateOIDCIssuer == "") {
+		return errors.New("verifying signatures needs a key, or a certificate identity and OIDC issuer")
+	}
+
+	return runCosign(ctx, v.Args(ref), v.Stdout, v.Stderr)
+}
+
+func runCosign(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	command := exec.CommandContext(ctx, Cosign, args...)
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	if err := cmdlog.Run(command); err != nil {
+		return fmt.Errorf("cosign %s %s failed: %s", args[0], args[len(args)-1], err)
+	}
+
+	return nil
+}
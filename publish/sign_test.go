@@ -0,0 +1,55 @@
+package publish_test
+
+import (
+	"context"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Signer", func() {
+	ref := reference("cloudfoundry/windows2016fs@sha256:abc")
+
+	It("signs with a key", func() {
+		Expect(publish.Signer{Key: "cosign.key"}.Args(ref)).To(Equal([]string{
+			"sign", "--yes", "--key", "cosign.key", "docker.io/cloudfoundry/windows2016fs@sha256:abc",
+		}))
+	})
+
+	It("signs keylessly without a key", func() {
+		Expect(publish.Signer{}.Args(ref)).To(Equal([]string{"sign", "--yes", "docker.io/cloudfoundry/windows2016fs@sha256:abc"}))
+	})
+
+	It("only signs digests", func() {
+		err := publish.Signer{}.Sign(context.Background(), reference("cloudfoundry/windows2016fs:2019"))
+		Expect(err).To(MatchError(ContainSubstring("only digest references can be signed")))
+	})
+})
+
+var _ = Describe("Verifier", func() {
+	ref := reference("cloudfoundry/windows2016fs:2019")
+
+	It("verifies with a public key", func() {
+		Expect(publish.Verifier{Key: "cosign.pub"}.Args(ref)).To(Equal([]string{
+			"verify", "--key", "cosign.pub", "docker.io/cloudfoundry/windows2016fs:2019",
+		}))
+	})
+
+	It("verifies keyless signatures against the signer's identity", func() {
+		verifier := publish.Verifier{CertificateIdentity: "release@example.com", CertificateOIDCIssuer: "https://accounts.example.com"}
+
+		Expect(verifier.Args(ref)).To(Equal([]string{
+			"verify",
+			"--certificate-identity", "release@example.com",
+			"--certificate-oidc-issuer", "https://accounts.example.com",
+			"docker.io/cloudfoundry/windows2016fs:2019",
+		}))
+	})
+
+	It("needs a key or an identity", func() {
+		err := publish.Verifier{CertificateIdentity: "release@example.com"}.Verify(context.Background(), ref)
+		Expect(err).To(MatchError(ContainSubstring("needs a key, or a certificate identity")))
+	})
+})
@@ -0,0 +1,449 @@
+// Package registry is a minimal client of the Docker Registry HTTP API V2,
+// covering the manifest and blob operations needed to publish images
+// without the docker CLI.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Media types of the manifests the client reads and writes.
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+var manifestMediaTypes = []string{MediaTypeOCIIndex, MediaTypeOCIManifest, MediaTypeDockerManifestList, MediaTypeDockerManifest}
+
+// Credentials authenticate with a registry; empty credentials request
+// anonymous access.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Client talks to registries, authenticating with basic auth or bearer
+// tokens as each registry demands.
+type Client struct {
+	// Credentials returns the credentials for a registry host; nil means
+	// anonymous access everywhere.
+	Credentials func(host string) Credentials
+
+	// PlainHTTP talks to registries over http instead of https, for local
+	// test registries.
+	PlainHTTP bool
+
+	HTTP *http.Client
+
+	mutex  sync.Mutex
+	tokens map[string]string
+}
+
+// Descriptor identifies content by its media type, digest and size.
+type Descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *Platform `json:"platform,omitempty"`
+
+	// URLs are where foreign layers, such as Windows base layers, can be
+	// downloaded from.
+	URLs []string `json:"urls,omitempty"`
+
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Platform is the platform of an image in a manifest list.
+type Platform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	OSVersion    string `json:"os.version,omitempty"`
+}
+
+// Manifest is an image manifest or manifest list; Config and Layers are set
+// for images and Manifests for lists.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	ArtifactType  string       `json:"artifactType,omitempty"`
+	Config        *Descriptor  `json:"config,omitempty"`
+	Layers        []Descriptor `json:"layers,omitempty"`
+	Manifests     []Descriptor `json:"manifests,omitempty"`
+
+	// Subject is the manifest an artifact, such as a signature or SBOM,
+	// refers to.
+	Subject *Descriptor `json:"subject,omitempty"`
+
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// IsList reports whether the manifest lists other manifests.
+func (m Manifest) IsList() bool {
+	return m.MediaType == MediaTypeOCIIndex || m.MediaType == MediaTypeDockerManifestList
+}
+
+// Digest returns the sha256 digest of content.
+func Digest(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// GetManifest returns the manifest ref points to, its raw content and its
+// descriptor.
+func (c *Client) GetManifest(ctx context.Context, ref Reference) (Manifest, []byte, Descriptor, error) {
+	header := http.Header{"Accept": {strings.Join(manifestMediaTypes, ", ")}}
+	response, err := c.do(ctx, http.MethodGet, ref, "/manifests/"+ref.Identifier(), header, nil)
+	if err != nil {
+		return Manifest{}, nil, Descriptor{}, err
+	}
+	defer response.Body.Close()
+
+	content, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return Manifest{}, nil, Descriptor{}, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return Manifest{}, nil, Descriptor{}, fmt.Errorf("parsing manifest of %s: %s", ref, err)
+	}
+
+	mediaType := manifest.MediaType
+	if mediaType == "" {
+		mediaType = strings.TrimSpace(strings.Split(response.Header.Get("Content-Type"), ";")[0])
+		manifest.MediaType = mediaType
+	}
+
+	descriptor := Descriptor{MediaType: mediaType, Digest: Digest(content), Size: int64(len(content))}
+	if ref.Digest != "" && ref.Digest != descriptor.Digest {
+		return Manifest{}, nil, Descriptor{}, fmt.Errorf("manifest of %s has digest %s", ref, descriptor.Digest)
+	}
+
+	return manifest, content, descriptor, nil
+}
+
+// PutManifest uploads content as the manifest ref points to and returns its
+// digest.
+func (c *Client) PutManifest(ctx context.Context, ref Reference, mediaType string, content []byte) (string, error) {
+	header := http.Header{"Content-Type": {mediaType}}
+	response, err := c.do(ctx, http.MethodPut, ref, "/manifests/"+ref.Identifier(), header, bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	response.Body.Close()
+
+	return Digest(content), nil
+}
+
+// GetBlob returns the blob with digest from ref's repository, verifying its
+// content.
+func (c *Client) GetBlob(ctx context.Context, ref Reference, digest string) ([]byte, error) {
+	var content bytes.Buffer
+	if err := c.FetchBlob(ctx, ref, digest, &content); err != nil {
+		return nil, err
+	}
+
+	return content.Bytes(), nil
+}
+
+// FetchBlob streams the blob with digest from ref's repository to w, for
+// blobs too large to hold in memory. It fails once the blob is written if
+// its content doesn't match digest.
+func (c *Client) FetchBlob(ctx context.Context, ref Reference, digest string, w io.Writer) error {
+	response, err := c.do(ctx, http.MethodGet, ref, "/blobs/"+digest, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), response.Body); err != nil {
+		return err
+	}
+
+	if actual := fmt.Sprintf("sha256:%x", hash.Sum(nil)); actual != digest {
+		return fmt.Errorf("blob %s of %s has digest %s", digest, ref, actual)
+	}
+
+	return nil
+}
+
+// PutBlob uploads content to ref's repository in a single request unless
+// the registry already has it, and returns its descriptor.
+func (c *Client) PutBlob(ctx context.Context, ref Reference, mediaType string, content []byte) (Descriptor, error) {
+	descriptor := Descriptor{MediaType: mediaType, Digest: Digest(content), Size: int64(len(content))}
+
+	return descriptor, c.UploadBlob(ctx, ref, descriptor, bytes.NewReader(content))
+}
+
+// UploadBlob uploads the blob descriptor describes from content, such as an
+// open file, in a single request unless the registry already has it.
+func (c *Client) UploadBlob(ctx context.Context, ref Reference, descriptor Descriptor, content io.ReadSeeker) error {
+	if response, err := c.do(ctx, http.MethodHead, ref, "/blobs/"+descriptor.Digest, nil, nil); err == nil {
+		response.Body.Close()
+		return nil
+	}
+
+	response, err := c.do(ctx, http.MethodPost, ref, "/blobs/uploads/", nil, nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	location, err := response.Request.URL.Parse(response.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("invalid upload location %q: %s", response.Header.Get("Location"), err)
+	}
+
+	query := location.Query()
+	query.Set("digest", descriptor.Digest)
+	location.RawQuery = query.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	response, err = c.doURL(ctx, http.MethodPut, ref, location, header, content)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+// Error is a registry response with an unexpected status.
+type Error struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s returned %d: %s", e.Method, e.URL, e.StatusCode, e.Body)
+}
+
+func (c *Client) do(ctx context.Context, method string, ref Reference, path string, header http.Header, body io.ReadSeeker) (*http.Response, error) {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+
+	target := &url.URL{Scheme: scheme, Host: ref.apiHost(), Path: "/v2/" + ref.Repository + path}
+	return c.doURL(ctx, method, ref, target, header, body)
+}
+
+// doURL sends a request, answering an authentication challenge once, and
+// fails on any status other than 2xx.
+func (c *Client) doURL(ctx context.Context, method string, ref Reference, target *url.URL, header http.Header, body io.ReadSeeker) (*http.Response, error) {
+	response, err := c.send(ctx, method, ref, target, header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode == http.StatusUnauthorized {
+		challenge := response.Header.Get("WWW-Authenticate")
+		response.Body.Close()
+
+		if err := c.authenticate(ctx, ref, challenge); err != nil {
+			return nil, err
+		}
+
+		response, err = c.send(ctx, method, ref, target, header, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		defer response.Body.Close()
+		message, _ := ioutil.ReadAll(io.LimitReader(response.Body, 4096))
+		return nil, &Error{Method: method, URL: target.String(), StatusCode: response.StatusCode, Body: strings.TrimSpace(string(message))}
+	}
+
+	return response, nil
+}
+
+func (c *Client) send(ctx context.Context, method string, ref Reference, target *url.URL, header http.Header, body io.ReadSeeker) (*http.Response, error) {
+	var (
+		reader io.Reader
+		size   int64
+	)
+	if body != nil {
+		// Rewind the body, which is sent again after an authentication
+		// challenge, and measure it so it isn't sent chunked.
+		var err error
+		if size, err = body.Seek(0, io.SeekEnd); err == nil {
+			_, err = body.Seek(0, io.SeekStart)
+		}
+		if err != nil {
+			return nil, err
+		}
+		reader = body
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = size
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	c.mutex.Lock()
+	authorization := c.tokens[ref.Host+"/"+ref.Repository]
+	c.mutex.Unlock()
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(request)
+}
+
+// authenticate answers a WWW-Authenticate challenge, caching the resulting
+// Authorization header for the repository.
+func (c *Client) authenticate(ctx context.Context, ref Reference, challenge string) error {
+	var credentials Credentials
+	if c.Credentials != nil {
+		credentials = c.Credentials(ref.Host)
+	}
+
+	scheme, params := parseChallenge(challenge)
+
+	var authorization string
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credentials.Username == "" {
+			return fmt.Errorf("%s requires credentials", ref.Host)
+		}
+		request := &http.Request{Header: http.Header{}}
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+		authorization = request.Header.Get("Authorization")
+	case "bearer":
+		token, err := c.fetchToken(ctx, ref, params, credentials)
+		if err != nil {
+			return err
+		}
+		authorization = "Bearer " + token
+	default:
+		return fmt.Errorf("%s: unsupported authentication challenge %q", ref.Host, challenge)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]string{}
+	}
+	c.tokens[ref.Host+"/"+ref.Repository] = authorization
+
+	return nil
+}
+
+func (c *Client) fetchToken(ctx context.Context, ref Reference, params map[string]string, credentials Credentials) (string, error) {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("%s: invalid token realm %q", ref.Host, params["realm"])
+	}
+
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull,push", ref.Repository))
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if credentials.Username != "" {
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching a token for %s from %s returned %s", ref.Repository, realm.Host, response.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return "", err
+	}
+
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.Token == "" {
+		return "", fmt.Errorf("%s returned no token", realm.Host)
+	}
+
+	return token.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as
+// `Bearer realm="https://auth.docker.io/token",service="registry.docker.io"`.
+// Quoted values may contain commas.
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	if len(parts) < 2 {
+		return parts[0], params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		equals := strings.Index(rest, "=")
+		if equals < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(strings.TrimLeft(rest[:equals], ", ")))
+		rest = rest[equals+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if comma := strings.Index(rest, ","); comma >= 0 {
+			value, rest = rest[:comma], rest[comma:]
+		} else {
+			value, rest = rest, ""
+		}
+
+		params[key] = value
+	}
+
+	return parts[0], params
+}
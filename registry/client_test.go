@@ -0,0 +1,73 @@
+package registry_test
+
+import (
+	"context"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server *registrytest.Server
+		client *registry.Client
+		ref    registry.Reference
+	)
+
+	BeforeEach(func() {
+		server = registrytest.NewServer()
+		server.Token, server.Username, server.Password = "secret-token", "user", "pass"
+
+		client = &registry.Client{
+			PlainHTTP:   true,
+			Credentials: func(string) registry.Credentials { return registry.Credentials{Username: "user", Password: "pass"} },
+		}
+
+		var err error
+		ref, err = registry.ParseReference(server.Host() + "/cloudfoundry/windows2016fs:2019")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("uploads blobs and manifests with a bearer token and reads them back", func() {
+		config, err := client.PutBlob(context.Background(), ref, "application/vnd.docker.container.image.v1+json", []byte(`{"os":"windows"}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		manifest := []byte(`{"schemaVersion":2,"mediaType":"` + registry.MediaTypeDockerManifest + `","config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"` + config.Digest + `","size":16}}`)
+		digest, err := client.PutManifest(context.Background(), ref, registry.MediaTypeDockerManifest, manifest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(digest).To(Equal(registry.Digest(manifest)))
+
+		parsed, content, descriptor, err := client.GetManifest(context.Background(), ref.WithDigest(digest))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(content).To(Equal(manifest))
+		Expect(descriptor).To(Equal(registry.Descriptor{MediaType: registry.MediaTypeDockerManifest, Digest: digest, Size: int64(len(manifest))}))
+		Expect(parsed.Config.Digest).To(Equal(config.Digest))
+
+		blob, err := client.GetBlob(context.Background(), ref, config.Digest)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(blob)).To(Equal(`{"os":"windows"}`))
+	})
+
+	It("fails without valid credentials", func() {
+		client.Credentials = nil
+
+		_, _, _, err := client.GetManifest(context.Background(), ref)
+		Expect(err).To(MatchError(ContainSubstring("fetching a token")))
+	})
+
+	It("reports missing manifests", func() {
+		server.Token = ""
+
+		_, _, _, err := client.GetManifest(context.Background(), ref)
+		var registryErr *registry.Error
+		Expect(err).To(BeAssignableToTypeOf(registryErr))
+		Expect(err.(*registry.Error).StatusCode).To(Equal(404))
+	})
+})
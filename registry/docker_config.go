@@ -0,0 +1,135 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerHubKey is the key docker login stores Docker Hub credentials under.
+const dockerHubKey = "https://index.docker.io/v1/"
+
+// DockerConfig holds the credentials of a docker CLI configuration file, as
+// written by docker login.
+type DockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// DockerConfigPath returns the docker CLI configuration file: config.json in
+// $DOCKER_CONFIG, or in ~/.docker.
+func DockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// LoadDockerConfig reads a docker CLI configuration file. A missing file
+// holds no credentials.
+func LoadDockerConfig(path string) (*DockerConfig, error) {
+	config := &DockerConfig{}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return config, nil
+}
+
+// Credentials returns the credentials stored for host, asking the
+// credential helper configured for it, or the default credential store,
+// when there is one.
+func (c *DockerConfig) Credentials(host string) (Credentials, error) {
+	key := host
+	if host == "docker.io" {
+		key = dockerHubKey
+	}
+
+	helper := c.CredHelpers[host]
+	if helper == "" {
+		helper = c.CredsStore
+	}
+	if helper != "" {
+		return helperCredentials(helper, key)
+	}
+
+	for _, candidate := range []string{key, "https://" + key, "http://" + key} {
+		auth, ok := c.Auths[candidate]
+		if !ok {
+			continue
+		}
+
+		if auth.Auth == "" {
+			return Credentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("invalid auth for %s: %s", host, err)
+		}
+
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return Credentials{}, fmt.Errorf("invalid auth for %s: expected username:password", host)
+		}
+
+		return Credentials{Username: parts[0], Password: parts[1]}, nil
+	}
+
+	return Credentials{}, nil
+}
+
+// helperCredentials asks docker-credential-<helper> for the credentials of
+// key. Hosts the helper doesn't know have no credentials.
+func helperCredentials(helper, key string) (Credentials, error) {
+	command := exec.Command("docker-credential-"+helper, "get")
+	command.Stdin = strings.NewReader(key)
+
+	var stdout, stderr bytes.Buffer
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	if err := command.Run(); err != nil {
+		if strings.Contains(stdout.String()+stderr.String(), "credentials not found") {
+			return Credentials{}, nil
+		}
+
+		return Credentials{}, fmt.Errorf("docker-credential-%s get %s failed: %s: %s", helper, key, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var credentials struct {
+		Username string
+		Secret   string
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credentials); err != nil {
+		return Credentials{}, fmt.Errorf("parsing the output of docker-credential-%s: %s", helper, err)
+	}
+
+	return Credentials{Username: credentials.Username, Password: credentials.Secret}, nil
+}
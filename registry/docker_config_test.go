@@ -0,0 +1,77 @@
+package registry_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DockerConfig", func() {
+	var dir string
+
+	load := func(content string) *registry.DockerConfig {
+		path := filepath.Join(dir, "config.json")
+		Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(Succeed())
+
+		config, err := registry.LoadDockerConfig(path)
+		Expect(err).ToNot(HaveOccurred())
+
+		return config
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "docker-config")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("decodes the credentials docker login stored", func() {
+		// "dXNlcjpwYXNzOndvcmQ=" is "user:pass:word".
+		config := load(`{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNzOndvcmQ="}}}`)
+
+		credentials, err := config.Credentials("registry.example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials).To(Equal(registry.Credentials{Username: "user", Password: "pass:word"}))
+	})
+
+	It("finds Docker Hub credentials under the index URL", func() {
+		config := load(`{"auths": {"https://index.docker.io/v1/": {"username": "hub", "password": "secret"}}}`)
+
+		credentials, err := config.Credentials("docker.io")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials).To(Equal(registry.Credentials{Username: "hub", Password: "secret"}))
+	})
+
+	It("has no credentials for other hosts", func() {
+		config := load(`{"auths": {"registry.example.com": {"auth": "dXNlcjpwYXNzOndvcmQ="}}}`)
+
+		credentials, err := config.Credentials("other.example.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials).To(BeZero())
+	})
+
+	It("treats a missing file as holding no credentials", func() {
+		config, err := registry.LoadDockerConfig(filepath.Join(dir, "missing.json"))
+		Expect(err).ToNot(HaveOccurred())
+
+		credentials, err := config.Credentials("docker.io")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(credentials).To(BeZero())
+	})
+
+	It("fails on invalid auth", func() {
+		config := load(`{"auths": {"registry.example.com": {"auth": "!"}}}`)
+
+		_, err := config.Credentials("registry.example.com")
+		Expect(err).To(MatchError(ContainSubstring("invalid auth for registry.example.com")))
+	})
+})
@@ -0,0 +1,112 @@
+package registry
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dockerHub    = "docker.io"
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// Reference is a parsed image reference, e.g.
+// registry.example.com/cloudfoundry/windows2016fs:2019.
+type Reference struct {
+	// Host is the registry host, docker.io for Docker Hub.
+	Host string
+
+	// Repository is the repository within the registry, with Docker Hub's
+	// implicit library/ prefix for single-component names.
+	Repository string
+
+	// Tag and Digest identify the manifest; at most one is set.
+	Tag    string
+	Digest string
+}
+
+// ParseReference parses ref, defaulting to Docker Hub and the latest tag.
+func ParseReference(ref string) (Reference, error) {
+	var parsed Reference
+
+	name := ref
+	if at := strings.Index(name, "@"); at >= 0 {
+		parsed.Digest = name[at+1:]
+		name = name[:at]
+		if !strings.HasPrefix(parsed.Digest, "sha256:") {
+			return Reference{}, fmt.Errorf("%s: unsupported digest", ref)
+		}
+
+		// A tag next to a digest, as in name:tag@digest, is informational.
+		if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+			name = name[:colon]
+		}
+	} else if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		parsed.Tag = name[colon+1:]
+		name = name[:colon]
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		parsed.Host, parsed.Repository = parts[0], parts[1]
+	} else {
+		parsed.Host, parsed.Repository = dockerHub, name
+	}
+
+	if parsed.Host == dockerHub && !strings.Contains(parsed.Repository, "/") {
+		parsed.Repository = "library/" + parsed.Repository
+	}
+
+	if parsed.Repository == "" || parsed.Repository != strings.ToLower(parsed.Repository) {
+		return Reference{}, fmt.Errorf("%s: invalid repository name", ref)
+	}
+
+	if parsed.Tag == "" && parsed.Digest == "" {
+		parsed.Tag = "latest"
+	}
+
+	return parsed, nil
+}
+
+// Identifier returns the tag or digest of the reference, as used in manifest
+// URLs.
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+// WithTag returns the reference to tag in the same repository.
+func (r Reference) WithTag(tag string) Reference {
+	return Reference{Host: r.Host, Repository: r.Repository, Tag: tag}
+}
+
+// WithDigest returns the reference to digest in the same repository.
+func (r Reference) WithDigest(digest string) Reference {
+	return Reference{Host: r.Host, Repository: r.Repository, Digest: digest}
+}
+
+// SameRepository reports whether both references name the same repository.
+func (r Reference) SameRepository(other Reference) bool {
+	return r.Host == other.Host && r.Repository == other.Repository
+}
+
+func (r Reference) String() string {
+	name := r.Host + "/" + r.Repository
+	if r.Digest != "" {
+		return name + "@" + r.Digest
+	}
+
+	return name + ":" + r.Tag
+}
+
+// apiHost returns the host serving the registry API.
+func (r Reference) apiHost() string {
+	if r.Host == dockerHub {
+		return dockerHubAPI
+	}
+
+	return r.Host
+}
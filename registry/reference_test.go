@@ -0,0 +1,36 @@
+package registry_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/registry"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseReference", func() {
+	DescribeTable("parses references",
+		func(ref string, expected registry.Reference) {
+			parsed, err := registry.ParseReference(ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(parsed).To(Equal(expected))
+		},
+		Entry("Docker Hub with an organisation", "cloudfoundry/windows2016fs:2019.12", registry.Reference{Host: "docker.io", Repository: "cloudfoundry/windows2016fs", Tag: "2019.12"}),
+		Entry("Docker Hub official image", "busybox", registry.Reference{Host: "docker.io", Repository: "library/busybox", Tag: "latest"}),
+		Entry("a registry with a port", "localhost:5000/windows2016fs:2019", registry.Reference{Host: "localhost:5000", Repository: "windows2016fs", Tag: "2019"}),
+		Entry("a digest", "registry.example.com/cf/windows2016fs@sha256:abc", registry.Reference{Host: "registry.example.com", Repository: "cf/windows2016fs", Digest: "sha256:abc"}),
+		Entry("a tag and a digest", "mcr.microsoft.com/windows/servercore:1809@sha256:abc", registry.Reference{Host: "mcr.microsoft.com", Repository: "windows/servercore", Digest: "sha256:abc"}),
+	)
+
+	It("rejects uppercase repositories", func() {
+		_, err := registry.ParseReference("CloudFoundry/windows2016fs")
+		Expect(err).To(MatchError(ContainSubstring("invalid repository name")))
+	})
+
+	It("formats references in full", func() {
+		parsed, err := registry.ParseReference("cloudfoundry/windows2016fs:2019")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(parsed.String()).To(Equal("docker.io/cloudfoundry/windows2016fs:2019"))
+		Expect(parsed.WithDigest("sha256:abc").String()).To(Equal("docker.io/cloudfoundry/windows2016fs@sha256:abc"))
+	})
+})
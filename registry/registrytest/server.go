@@ -0,0 +1,210 @@
+// Package registrytest provides an in-memory registry for testing code that
+// uses the registry package.
+package registrytest
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Server is an in-memory registry serving the parts of the Registry HTTP API
+// V2 used by the registry package.
+type Server struct {
+	*httptest.Server
+
+	// Token, when set, makes the server demand a bearer token, which its
+	// /token endpoint hands out to Username and Password.
+	Token    string
+	Username string
+	Password string
+
+	// FailUploads is how many blob uploads to fail with a 503 before
+	// accepting them.
+	FailUploads int
+
+	mutex     sync.Mutex
+	manifests map[string]stored
+	blobs     map[string][]byte
+	uploads   int
+}
+
+type stored struct {
+	mediaType string
+	content   []byte
+}
+
+// NewServer starts a registry; callers must Close it.
+func NewServer() *Server {
+	server := &Server{manifests: map[string]stored{}, blobs: map[string][]byte{}}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+
+	return server
+}
+
+// Host returns the host:port to use in references to the server.
+func (s *Server) Host() string {
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+// Manifest returns the manifest stored under repository and a tag or digest.
+func (s *Server) Manifest(repository, identifier string) (string, []byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	manifest, ok := s.manifests[repository+"/"+identifier]
+	return manifest.mediaType, manifest.content, ok
+}
+
+// PutManifest stores a manifest under repository and tag, and its digest.
+func (s *Server) PutManifest(repository, tag, mediaType string, content []byte) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	digest := digestOf(content)
+	s.manifests[repository+"/"+tag] = stored{mediaType, content}
+	s.manifests[repository+"/"+digest] = stored{mediaType, content}
+
+	return digest
+}
+
+// PutBlob stores a blob and returns its digest.
+func (s *Server) PutBlob(content []byte) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	digest := digestOf(content)
+	s.blobs[digest] = content
+
+	return digest
+}
+
+// Blob returns a stored blob.
+func (s *Server) Blob(digest string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	content, ok := s.blobs[digest]
+	return content, ok
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		if username, password, _ := r.BasicAuth(); username != s.Username || password != s.Password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"token": %q}`, s.Token)
+		return
+	}
+
+	if s.Token != "" && r.Header.Get("Authorization") != "Bearer "+s.Token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registrytest"`, s.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/manifests/"):
+		s.serveManifest(w, r, path)
+	case strings.HasSuffix(path, "/blobs/uploads/"):
+		s.startUpload(w, r, path)
+	case strings.Contains(path, "/blobs/uploads/"):
+		s.finishUpload(w, r)
+	case strings.Contains(path, "/blobs/"):
+		s.serveBlob(w, r, path)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveManifest(w http.ResponseWriter, r *http.Request, path string) {
+	parts := strings.SplitN(path, "/manifests/", 2)
+	repository, identifier := parts[0], parts[1]
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		mediaType, content, ok := s.Manifest(repository, identifier)
+		if !ok {
+			http.Error(w, `{"errors":[{"code":"MANIFEST_UNKNOWN"}]}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", mediaType)
+		w.Header().Set("Docker-Content-Digest", digestOf(content))
+		w.Write(content)
+	case http.MethodPut:
+		content, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		digest := s.PutManifest(repository, identifier, r.Header.Get("Content-Type"), content)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) serveBlob(w http.ResponseWriter, r *http.Request, path string) {
+	digest := path[strings.LastIndex(path, "/")+1:]
+	content, ok := s.Blob(digest)
+	if !ok {
+		http.Error(w, `{"errors":[{"code":"BLOB_UNKNOWN"}]}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	if r.Method != http.MethodHead {
+		w.Write(content)
+	}
+}
+
+func (s *Server) startUpload(w http.ResponseWriter, r *http.Request, path string) {
+	s.mutex.Lock()
+	s.uploads++
+	id := s.uploads
+	s.mutex.Unlock()
+
+	w.Header().Set("Location", fmt.Sprintf("/v2/%s%d", path, id))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) finishUpload(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	fail := s.FailUploads > 0
+	if fail {
+		s.FailUploads--
+	}
+	s.mutex.Unlock()
+
+	if fail {
+		http.Error(w, `{"errors":[{"code":"UNAVAILABLE"}]}`, http.StatusServiceUnavailable)
+		return
+	}
+
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query, _ := url.ParseQuery(r.URL.RawQuery)
+	if digest := digestOf(content); query.Get("digest") != digest {
+		http.Error(w, `{"errors":[{"code":"DIGEST_INVALID"}]}`, http.StatusBadRequest)
+		return
+	}
+
+	s.PutBlob(content)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func digestOf(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
@@ -0,0 +1,56 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+const bytesPerMB = 1 << 20
+
+// registryHiveSizes returns the size in bytes of each hive file in
+// C:\Windows\System32\config, keyed by hive name.
+func registryHiveSizes(image string) (map[string]int64, error) {
+	output, err := powershellIn(image, `Get-ChildItem C:\Windows\System32\config -File | Where-Object { $_.Extension -eq '' } | ForEach-Object { "$($_.Name) $($_.Length)" }`)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := map[string]int64{}
+	for _, line := range validation.NonEmptyLines(output) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected hive line %q", line)
+		}
+
+		size, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected hive size in %q", line)
+		}
+
+		sizes[strings.ToUpper(fields[0])] = size
+	}
+
+	return sizes, nil
+}
+
+// oversizedHives describes every hive in ceilingsMB that is missing from
+// sizes or larger than its ceiling.
+func oversizedHives(ceilingsMB map[string]int64, sizes map[string]int64) []string {
+	var oversized []string
+	for hive, ceiling := range ceilingsMB {
+		size, ok := sizes[strings.ToUpper(hive)]
+		switch {
+		case !ok:
+			oversized = append(oversized, fmt.Sprintf("%s: missing", hive))
+		case size > ceiling*bytesPerMB:
+			oversized = append(oversized, fmt.Sprintf("%s: %.1f MB exceeds the %d MB ceiling", hive, float64(size)/bytesPerMB, ceiling))
+		}
+	}
+	sort.Strings(oversized)
+
+	return oversized
+}
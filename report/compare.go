@@ -0,0 +1,177 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Outcomes of a spec in a run.
+const (
+	Passed = "passed"
+	Failed = "failed"
+	NotRun = "not run"
+)
+
+// Run is a report of the suite under one configuration, such as an isolation
+// mode, named after it.
+type Run struct {
+	Name   string
+	Report Report
+
+	// Error is why the run's command failed, when it did. A run can fail
+	// without any spec failing, e.g. when BeforeSuite does.
+	Error string
+}
+
+// Divergence is a spec whose outcome isn't the same in every run.
+type Divergence struct {
+	Spec string
+
+	// Outcomes and Messages hold, by run name, the spec's outcome and its
+	// failure message.
+	Outcomes map[string]string
+	Messages map[string]string
+}
+
+// Diverging returns the specs whose outcomes differ between runs, in the
+// order they first ran. Specs that were skipped everywhere aren't reported.
+func Diverging(runs []Run) []Divergence {
+	var specs []string
+	outcomes := map[string]map[string]string{}
+	messages := map[string]map[string]string{}
+
+	for _, run := range runs {
+		for _, result := range run.Report.Results {
+			if outcomes[result.Name] == nil {
+				specs = append(specs, result.Name)
+				outcomes[result.Name] = map[string]string{}
+				messages[result.Name] = map[string]string{}
+			}
+
+			outcomes[result.Name][run.Name] = Passed
+			if !result.Passed {
+				outcomes[result.Name][run.Name] = Failed
+				messages[result.Name][run.Name] = result.Message
+			}
+		}
+	}
+
+	var divergences []Divergence
+	for _, spec := range specs {
+		for _, run := range runs {
+			if _, ok := outcomes[spec][run.Name]; !ok {
+				outcomes[spec][run.Name] = NotRun
+			}
+		}
+
+		if !sameOutcome(outcomes[spec]) {
+			divergences = append(divergences, Divergence{Spec: spec, Outcomes: outcomes[spec], Messages: messages[spec]})
+		}
+	}
+
+	return divergences
+}
+
+func sameOutcome(outcomes map[string]string) bool {
+	first := ""
+	for _, outcome := range outcomes {
+		if first == "" {
+			first = outcome
+		} else if outcome != first {
+			return false
+		}
+	}
+
+	return true
+}
+
+type jsonComparison struct {
+	Runs      []jsonRun        `json:"runs"`
+	Diverging []jsonDivergence `json:"diverging"`
+}
+
+type jsonRun struct {
+	Name   string `json:"name"`
+	Image  string `json:"image"`
+	Tag    string `json:"tag"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+	Error  string `json:"error,omitempty"`
+}
+
+type jsonDivergence struct {
+	Spec            string            `json:"spec"`
+	Outcomes        map[string]string `json:"outcomes"`
+	FailureMessages map[string]string `json:"failure_messages,omitempty"`
+}
+
+// WriteComparison writes runs to w as a JSON summary of each run and the
+// specs that diverged between them.
+func WriteComparison(runs []Run, w io.Writer) error {
+	comparison := jsonComparison{Runs: []jsonRun{}, Diverging: []jsonDivergence{}}
+
+	for _, run := range runs {
+		failed := run.Report.Failed()
+		comparison.Runs = append(comparison.Runs, jsonRun{
+			Name:   run.Name,
+			Image:  run.Report.Image,
+			Tag:    run.Report.Tag,
+			Passed: len(run.Report.Results) - failed,
+			Failed: failed,
+			Error:  run.Error,
+		})
+	}
+
+	for _, divergence := range Diverging(runs) {
+		d := jsonDivergence{Spec: divergence.Spec, Outcomes: divergence.Outcomes}
+		if len(divergence.Messages) > 0 {
+			d.FailureMessages = divergence.Messages
+		}
+
+		comparison.Diverging = append(comparison.Diverging, d)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(comparison)
+}
+
+// PrintComparison writes a summary of runs and the specs that diverged
+// between them to w, e.g. for a pipeline log.
+func PrintComparison(runs []Run, w io.Writer) error {
+	var b strings.Builder
+
+	for _, run := range runs {
+		failed := run.Report.Failed()
+		fmt.Fprintf(&b, "%s: %d passed, %d failed", run.Name, len(run.Report.Results)-failed, failed)
+		if run.Error != "" {
+			fmt.Fprintf(&b, " (%s)", run.Error)
+		}
+		b.WriteString("\n")
+	}
+
+	divergences := Diverging(runs)
+	if len(divergences) == 0 {
+		b.WriteString("no spec diverged\n")
+	} else {
+		fmt.Fprintf(&b, "%d specs diverged:\n", len(divergences))
+		for _, divergence := range divergences {
+			outcomes := make([]string, len(runs))
+			for i, run := range runs {
+				outcomes[i] = fmt.Sprintf("%s %s", run.Name, divergence.Outcomes[run.Name])
+			}
+
+			fmt.Fprintf(&b, "  %s: %s\n", divergence.Spec, strings.Join(outcomes, ", "))
+			for _, run := range runs {
+				if message := divergence.Messages[run.Name]; message != "" {
+					fmt.Fprintf(&b, "    %s: %s\n", run.Name, firstLine(message))
+				}
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
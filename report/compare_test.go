@@ -0,0 +1,91 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/report"
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Comparison", func() {
+	var runs []report.Run
+
+	BeforeEach(func() {
+		runs = []report.Run{
+			{Name: "process", Report: report.Report{Tag: "2019", Isolation: "process", Results: []validation.CheckResult{
+				{Name: "can mount SMB shares", Passed: true},
+				{Name: "has the expected fonts", Passed: true},
+				{Name: "can access the GPU", Passed: true},
+			}}},
+			{Name: "hyperv", Report: report.Report{Tag: "2019", Isolation: "hyperv", Results: []validation.CheckResult{
+				{Name: "can mount SMB shares", Passed: false, Message: "New-SmbGlobalMapping failed\nstack"},
+				{Name: "has the expected fonts", Passed: true},
+			}}, Error: "exit status 1"},
+		}
+	})
+
+	It("finds the specs whose outcome differs between runs", func() {
+		Expect(report.Diverging(runs)).To(Equal([]report.Divergence{
+			{
+				Spec:     "can mount SMB shares",
+				Outcomes: map[string]string{"process": report.Passed, "hyperv": report.Failed},
+				Messages: map[string]string{"hyperv": "New-SmbGlobalMapping failed\nstack"},
+			},
+			{
+				Spec:     "can access the GPU",
+				Outcomes: map[string]string{"process": report.Passed, "hyperv": report.NotRun},
+				Messages: map[string]string{},
+			},
+		}))
+	})
+
+	It("writes a JSON summary of the runs and their divergences", func() {
+		var buf bytes.Buffer
+		Expect(report.WriteComparison(runs, &buf)).To(Succeed())
+
+		var parsed map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &parsed)).To(Succeed())
+
+		Expect(parsed["runs"]).To(Equal([]interface{}{
+			map[string]interface{}{"name": "process", "image": "", "tag": "2019", "passed": 3.0, "failed": 0.0},
+			map[string]interface{}{"name": "hyperv", "image": "", "tag": "2019", "passed": 1.0, "failed": 1.0, "error": "exit status 1"},
+		}))
+		Expect(parsed["diverging"]).To(HaveLen(2))
+		Expect(parsed["diverging"].([]interface{})[0]).To(HaveKeyWithValue("failure_messages", map[string]interface{}{"hyperv": "New-SmbGlobalMapping failed\nstack"}))
+	})
+
+	It("prints the runs and their divergences", func() {
+		var buf bytes.Buffer
+		Expect(report.PrintComparison(runs, &buf)).To(Succeed())
+
+		Expect(buf.String()).To(Equal(`process: 3 passed, 0 failed
+hyperv: 1 passed, 1 failed (exit status 1)
+2 specs diverged:
+  can mount SMB shares: process passed, hyperv failed
+    hyperv: New-SmbGlobalMapping failed
+  can access the GPU: process passed, hyperv not run
+`))
+	})
+
+	It("reads back the reports written to a directory", func() {
+		dir, err := ioutil.TempDir("", "report")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		written := runs[1].Report
+		written.Started = time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+		written.Duration = 90 * time.Second
+		_, err = written.Write(dir)
+		Expect(err).ToNot(HaveOccurred())
+
+		reports, err := report.Read(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reports).To(Equal([]report.Report{written}))
+	})
+})
@@ -0,0 +1,317 @@
+// Package report writes the results of a validation run as JUnit XML and as
+// a JSON summary, for CI systems and dashboards to ingest.
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Report is a validation run of an image.
+type Report struct {
+	// Suite names the run, e.g. "Windows2016fs Suite".
+	Suite string
+
+	// Image is the image under test and Tag its version, e.g. 2019.
+	Image string
+	Tag   string
+
+	// Isolation is the isolation the run's containers had, when it was
+	// selected rather than left to the daemon.
+	Isolation string
+
+	Started  time.Time
+	Duration time.Duration
+
+	Results []validation.CheckResult
+}
+
+// Failed returns how many results didn't pass.
+func (r Report) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if !result.Passed {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr,omitempty"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes r to w as JUnit XML, with a test case per result. The
+// image is recorded as a property of the test suite and each result's
+// metadata as the system output of its test case.
+func WriteJUnit(r Report, w io.Writer) error {
+	suite := junitTestSuite{
+		Name:     r.Suite,
+		Tests:    len(r.Results),
+		Failures: r.Failed(),
+		Time:     seconds(r.Duration),
+		Properties: []junitProperty{
+			{Name: "image", Value: r.Image},
+			{Name: "tag", Value: r.Tag},
+		},
+	}
+	if r.Isolation != "" {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "isolation", Value: r.Isolation})
+	}
+	if !r.Started.IsZero() {
+		suite.Timestamp = r.Started.UTC().Format("2006-01-02T15:04:05")
+	}
+
+	for _, result := range r.Results {
+		testCase := junitTestCase{
+			Name:      result.Name,
+			ClassName: r.Suite,
+			Time:      seconds(result.Duration),
+			SystemOut: formatMetadata(result.Metadata),
+		}
+		if !result.Passed {
+			testCase.Failure = &junitFailure{Message: firstLine(result.Message), Type: "failure", Text: result.Message}
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+	}
+
+	content, err := xml.MarshalIndent(junitTestSuites{
+		Name:     r.Suite,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Time:     suite.Time,
+		Suites:   []junitTestSuite{suite},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", content)
+	return err
+}
+
+type jsonReport struct {
+	Suite     string     `json:"suite"`
+	Image     string     `json:"image"`
+	Tag       string     `json:"tag"`
+	Isolation string     `json:"isolation,omitempty"`
+	Started   *time.Time `json:"started,omitempty"`
+	Duration  float64    `json:"duration_seconds"`
+	Passed    int        `json:"passed"`
+	Failed    int        `json:"failed"`
+	Specs     []jsonSpec `json:"specs"`
+}
+
+type jsonSpec struct {
+	Name           string            `json:"name"`
+	Passed         bool              `json:"passed"`
+	Duration       float64           `json:"duration_seconds"`
+	FailureMessage string            `json:"failure_message,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+}
+
+// WriteJSON writes r to w as a JSON summary with the image under test, the
+// number of specs that passed and failed, and each spec's outcome.
+func WriteJSON(r Report, w io.Writer) error {
+	summary := jsonReport{
+		Suite:     r.Suite,
+		Image:     r.Image,
+		Tag:       r.Tag,
+		Isolation: r.Isolation,
+		Duration:  r.Duration.Seconds(),
+		Failed:    r.Failed(),
+		Specs:     []jsonSpec{},
+	}
+	summary.Passed = len(r.Results) - summary.Failed
+	if !r.Started.IsZero() {
+		started := r.Started.UTC()
+		summary.Started = &started
+	}
+
+	for _, result := range r.Results {
+		spec := jsonSpec{
+			Name:     result.Name,
+			Passed:   result.Passed,
+			Duration: result.Duration.Seconds(),
+			Metadata: result.Metadata,
+		}
+		if !result.Passed {
+			spec.FailureMessage = result.Message
+		}
+
+		summary.Specs = append(summary.Specs, spec)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(summary)
+}
+
+// Write stores r in dir as report-<tag>.xml and report-<tag>.json and
+// returns their paths.
+func (r Report) Write(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	formats := []struct {
+		extension string
+		write     func(Report, io.Writer) error
+	}{
+		{".xml", WriteJUnit},
+		{".json", WriteJSON},
+	}
+
+	var paths []string
+	for _, format := range formats {
+		var content bytes.Buffer
+		if err := format.write(r, &content); err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("report-%s%s", r.Tag, format.extension))
+		if err := ioutil.WriteFile(path, content.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// Read returns the reports whose JSON summaries were written to dir, in the
+// order of their tags.
+func Read(dir string) ([]Report, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "report-*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var reports []Report
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var summary jsonReport
+		if err := json.Unmarshal(content, &summary); err != nil {
+			return nil, fmt.Errorf("parsing %s: %s", path, err)
+		}
+
+		r := Report{
+			Suite:     summary.Suite,
+			Image:     summary.Image,
+			Tag:       summary.Tag,
+			Isolation: summary.Isolation,
+			Duration:  time.Duration(summary.Duration * float64(time.Second)),
+		}
+		if summary.Started != nil {
+			r.Started = *summary.Started
+		}
+		for _, spec := range summary.Specs {
+			r.Results = append(r.Results, validation.CheckResult{
+				Name:     spec.Name,
+				Passed:   spec.Passed,
+				Message:  spec.FailureMessage,
+				Duration: time.Duration(spec.Duration * float64(time.Second)),
+				Metadata: spec.Metadata,
+			})
+		}
+
+		reports = append(reports, r)
+	}
+
+	return reports, nil
+}
+
+// Failures returns the number of failed specs recorded in the JSON reports
+// in dir, e.g. for deciding whether to keep a run's images for debugging.
+func Failures(dir string) (int, error) {
+	reports, err := Read(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	failed := 0
+	for _, r := range reports {
+		failed += r.Failed()
+	}
+
+	return failed, nil
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(message string) string {
+	return strings.TrimSpace(strings.SplitN(message, "\n", 2)[0])
+}
+
+func formatMetadata(metadata map[string]string) string {
+	var keys []string
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = fmt.Sprintf("%s=%s", key, metadata[key])
+	}
+
+	return strings.Join(lines, "\n")
+}
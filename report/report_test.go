@@ -0,0 +1,140 @@
+package report_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/report"
+	"github.com/cloudfoundry/windows2016fs/validation"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Report", func() {
+	var r report.Report
+
+	BeforeEach(func() {
+		r = report.Report{
+			Suite:    "Windows2016fs Suite",
+			Image:    "windows2016fs-candidate:2019",
+			Tag:      "2019",
+			Started:  time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC),
+			Duration: 90 * time.Second,
+			Results: []validation.CheckResult{
+				{Name: "has the expected .NET Framework", Passed: true, Duration: 1500 * time.Millisecond, Metadata: map[string]string{"release": "528049"}},
+				{Name: "can mount SMB shares", Passed: false, Duration: 2 * time.Second, Message: "Expected\n    <int>: 1\nto equal\n    <int>: 0"},
+			},
+		}
+	})
+
+	It("writes JUnit XML with a test case per result", func() {
+		var buf bytes.Buffer
+		Expect(report.WriteJUnit(r, &buf)).To(Succeed())
+
+		var parsed struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Suites   []struct {
+				Time       string `xml:"time,attr"`
+				Timestamp  string `xml:"timestamp,attr"`
+				Properties []struct {
+					Name  string `xml:"name,attr"`
+					Value string `xml:"value,attr"`
+				} `xml:"properties>property"`
+				Cases []struct {
+					Name      string `xml:"name,attr"`
+					Time      string `xml:"time,attr"`
+					SystemOut string `xml:"system-out"`
+					Failure   *struct {
+						Message string `xml:"message,attr"`
+						Text    string `xml:",chardata"`
+					} `xml:"failure"`
+				} `xml:"testcase"`
+			} `xml:"testsuite"`
+		}
+		Expect(xml.Unmarshal(buf.Bytes(), &parsed)).To(Succeed())
+
+		Expect(parsed.Tests).To(Equal(2))
+		Expect(parsed.Failures).To(Equal(1))
+		Expect(parsed.Suites).To(HaveLen(1))
+
+		suite := parsed.Suites[0]
+		Expect(suite.Time).To(Equal("90.000"))
+		Expect(suite.Timestamp).To(Equal("2024-05-02T10:00:00"))
+		Expect(suite.Properties[0].Name).To(Equal("image"))
+		Expect(suite.Properties[0].Value).To(Equal("windows2016fs-candidate:2019"))
+
+		Expect(suite.Cases).To(HaveLen(2))
+		Expect(suite.Cases[0].Name).To(Equal("has the expected .NET Framework"))
+		Expect(suite.Cases[0].Time).To(Equal("1.500"))
+		Expect(suite.Cases[0].SystemOut).To(Equal("release=528049"))
+		Expect(suite.Cases[0].Failure).To(BeNil())
+		Expect(suite.Cases[1].Failure.Message).To(Equal("Expected"))
+		Expect(suite.Cases[1].Failure.Text).To(ContainSubstring("to equal"))
+	})
+
+	It("writes a JSON summary", func() {
+		var buf bytes.Buffer
+		Expect(report.WriteJSON(r, &buf)).To(Succeed())
+
+		var parsed map[string]interface{}
+		Expect(json.Unmarshal(buf.Bytes(), &parsed)).To(Succeed())
+
+		Expect(parsed).To(HaveKeyWithValue("image", "windows2016fs-candidate:2019"))
+		Expect(parsed).To(HaveKeyWithValue("started", "2024-05-02T10:00:00Z"))
+		Expect(parsed).To(HaveKeyWithValue("duration_seconds", 90.0))
+		Expect(parsed).To(HaveKeyWithValue("passed", 1.0))
+		Expect(parsed).To(HaveKeyWithValue("failed", 1.0))
+
+		specs := parsed["specs"].([]interface{})
+		Expect(specs).To(HaveLen(2))
+		Expect(specs[0]).To(Equal(map[string]interface{}{
+			"name":             "has the expected .NET Framework",
+			"passed":           true,
+			"duration_seconds": 1.5,
+			"metadata":         map[string]interface{}{"release": "528049"},
+		}))
+		Expect(specs[1]).To(HaveKeyWithValue("failure_message", "Expected\n    <int>: 1\nto equal\n    <int>: 0"))
+	})
+
+	It("writes both reports to a directory", func() {
+		dir, err := ioutil.TempDir("", "report")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		paths, err := r.Write(filepath.Join(dir, "reports"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).To(Equal([]string{
+			filepath.Join(dir, "reports", "report-2019.xml"),
+			filepath.Join(dir, "reports", "report-2019.json"),
+		}))
+		for _, path := range paths {
+			Expect(path).To(BeARegularFile())
+		}
+	})
+
+	It("counts the failures recorded in a directory's reports", func() {
+		dir, err := ioutil.TempDir("", "report")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		failed, err := report.Failures(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failed).To(Equal(0))
+
+		_, err = r.Write(dir)
+		Expect(err).ToNot(HaveOccurred())
+		r.Tag = "2022"
+		_, err = r.Write(dir)
+		Expect(err).ToNot(HaveOccurred())
+
+		failed, err = report.Failures(dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(failed).To(Equal(2))
+	})
+})
@@ -0,0 +1,198 @@
+package windows2016fs_test
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// buildTwiceAndCompare builds the Dockerfile of tag's variant under test with
+// the dependencies in depDir twice, without the build cache, and reports whether the resulting
+// layers match and, if not, which layers differ.
+//
+// Windows layers record when each file was written, so the digests of layers
+// produced by the same instructions almost always differ. Layers whose tar
+// entries have the same names, types and contents are therefore treated as
+// matching. Registry hives embed timestamps in their contents, so a layer
+// that changes the registry only matches if its hives come out identical.
+func buildTwiceAndCompare(tag, depDir string) (bool, []string, error) {
+	contextDir, err := ioutil.TempDir("", "reproducible")
+	if err != nil {
+		return false, nil, err
+	}
+	defer os.RemoveAll(contextDir)
+
+	opts, err := builder.ForVariant(tag, imageVariant, depDir)
+	if err != nil {
+		return false, nil, err
+	}
+	opts.ContextDir = contextDir
+	opts.Stdout = GinkgoWriter
+
+	err = timedCheck("command", func(ctx context.Context) error {
+		return builder.Stage(ctx, opts)
+	})
+	if err != nil {
+		return false, nil, fmt.Errorf("staging the build context failed: %s", err)
+	}
+
+	var (
+		builds  = []string{fmt.Sprintf("windows2016fs-reproducible:%s-1", tag), fmt.Sprintf("windows2016fs-reproducible:%s-2", tag)}
+		digests [][]string
+	)
+	for _, image := range builds {
+		err := timedCheck("build", func(ctx context.Context) error {
+			command := validation.Runtime.Command(ctx, "build", "--no-cache", "--platform", targetPlatform, "--tag", image, contextDir)
+			command.Stdout = GinkgoWriter
+			command.Stderr = GinkgoWriter
+			return cmdlog.Run(command)
+		})
+		if err != nil {
+			return false, nil, fmt.Errorf("building %s failed: %s", image, err)
+		}
+		defer cmdlog.Run(validation.Runtime.Command(context.Background(), "image", "rm", "--force", image))
+
+		layers, err := validation.ImageLayers(image)
+		if err != nil {
+			return false, nil, err
+		}
+		digests = append(digests, layers)
+	}
+
+	if len(digests[0]) != len(digests[1]) {
+		return false, layerDifferences(digests[0], digests[1]), nil
+	}
+
+	var differing []int
+	for i := range digests[0] {
+		if digests[0][i] != digests[1][i] {
+			differing = append(differing, i)
+		}
+	}
+	if len(differing) == 0 {
+		return true, nil, nil
+	}
+
+	var archives []string
+	for _, image := range builds {
+		archive := filepath.Join(contextDir, filepath.Base(image)+".tar")
+		if err := cmdlog.Run(validation.Runtime.Command(context.Background(), "save", "--output", archive, image)); err != nil {
+			return false, nil, fmt.Errorf("docker save %s failed: %s", image, err)
+		}
+		archives = append(archives, archive)
+	}
+
+	var differences []string
+	for _, i := range differing {
+		first, err := layerContents(archives[0], i)
+		if err != nil {
+			return false, nil, err
+		}
+		second, err := layerContents(archives[1], i)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if changed := contentDifferences(first, second); len(changed) > 0 {
+			differences = append(differences, fmt.Sprintf("layer %d (%s, %s) differs in %d entries, e.g. %s", i, digests[0][i], digests[1][i], len(changed), changed[0]))
+		}
+	}
+
+	return len(differences) == 0, differences, nil
+}
+
+// layerContents returns a digest of the type, size and contents of every
+// entry in the index-th layer of a `docker save` archive, keyed by name.
+func layerContents(archive string, index int) (map[string]string, error) {
+	var manifest []struct {
+		Layers []string
+	}
+	if err := readSavedFile(archive, "manifest.json", func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(&manifest)
+	}); err != nil {
+		return nil, err
+	}
+
+	if len(manifest) != 1 || index >= len(manifest[0].Layers) {
+		return nil, fmt.Errorf("%s has no layer %d", archive, index)
+	}
+
+	contents := map[string]string{}
+	err := readSavedFile(archive, manifest[0].Layers[index], func(r io.Reader) error {
+		layer := tar.NewReader(r)
+		for {
+			header, err := layer.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			hash := sha256.New()
+			if _, err := io.Copy(hash, layer); err != nil {
+				return err
+			}
+
+			contents[header.Name] = fmt.Sprintf("%c %d %s", header.Typeflag, header.Size, hex.EncodeToString(hash.Sum(nil)))
+		}
+	})
+
+	return contents, err
+}
+
+// readSavedFile calls read with the contents of name in archive.
+func readSavedFile(archive, name string, read func(io.Reader) error) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	saved := tar.NewReader(file)
+	for {
+		header, err := saved.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s does not contain %s", archive, name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if header.Name == name {
+			return read(saved)
+		}
+	}
+}
+
+// contentDifferences lists the entries that differ between two layers.
+func contentDifferences(first, second map[string]string) []string {
+	var differences []string
+	for name, content := range first {
+		if second[name] != content {
+			differences = append(differences, name)
+		}
+	}
+	for name := range second {
+		if _, ok := first[name]; !ok {
+			differences = append(differences, name)
+		}
+	}
+	sort.Strings(differences)
+
+	return differences
+}
@@ -0,0 +1,21 @@
+package windows2016fs_test
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// lookupExtraRunArgs parses and validates EXTRA_RUN_ARGS.
+func lookupExtraRunArgs(value string) ([]string, error) {
+	args, err := validation.SplitArgs(value)
+	if err != nil {
+		return nil, fmt.Errorf("EXTRA_RUN_ARGS is invalid: %s", err)
+	}
+
+	if err := validation.ValidateExtraRunArgs(args); err != nil {
+		return nil, fmt.Errorf("EXTRA_RUN_ARGS is invalid: %s", err)
+	}
+
+	return args, nil
+}
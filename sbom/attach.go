@@ -0,0 +1,94 @@
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// mediaTypeEmpty is the OCI empty descriptor's media type, the configuration
+// of artifacts that have none.
+const mediaTypeEmpty = "application/vnd.oci.empty.v1+json"
+
+// AttachedTag returns the tag an image's SBOM in format is attached under,
+// e.g. sha256-<hex>.cdx.sbom, so that it can be found on registries without
+// the referrers API.
+func AttachedTag(digest string, format Format) string {
+	return fmt.Sprintf("%s.%s.sbom", strings.Replace(digest, ":", "-", 1), strings.TrimSuffix(strings.TrimPrefix(format.Extension, "."), ".json"))
+}
+
+// Attach pushes d in every format as an OCI artifact whose subject is the
+// image ref points to, tagged as AttachedTag. It returns the digests of the
+// artifact manifests.
+func Attach(ctx context.Context, client *registry.Client, ref registry.Reference, d Document) ([]string, error) {
+	_, _, subject, err := client.GetManifest(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	empty, err := client.PutBlob(ctx, ref, mediaTypeEmpty, []byte("{}"))
+	if err != nil {
+		return nil, err
+	}
+
+	var digests []string
+	for _, format := range Formats {
+		content, err := format.Encode(d)
+		if err != nil {
+			return nil, err
+		}
+
+		layer, err := client.PutBlob(ctx, ref, format.MediaType, content)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest, err := json.Marshal(registry.Manifest{
+			SchemaVersion: 2,
+			MediaType:     registry.MediaTypeOCIManifest,
+			ArtifactType:  format.MediaType,
+			Config:        &empty,
+			Layers:        []registry.Descriptor{layer},
+			Subject:       &subject,
+			Annotations:   map[string]string{"org.opencontainers.image.created": d.Created.Format(time.RFC3339)},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		digest, err := client.PutManifest(ctx, ref.WithTag(AttachedTag(subject.Digest, format)), registry.MediaTypeOCIManifest, manifest)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, digest)
+	}
+
+	return digests, nil
+}
+
+// Fetch returns the SBOM attached to the image ref points to in format.
+func Fetch(ctx context.Context, client *registry.Client, ref registry.Reference, format Format) (Document, error) {
+	_, _, subject, err := client.GetManifest(ctx, ref)
+	if err != nil {
+		return Document{}, err
+	}
+
+	artifact, _, _, err := client.GetManifest(ctx, ref.WithTag(AttachedTag(subject.Digest, format)))
+	if err != nil {
+		return Document{}, fmt.Errorf("fetching the SBOM of %s: %s", ref, err)
+	}
+	if len(artifact.Layers) != 1 || artifact.Layers[0].MediaType != format.MediaType {
+		return Document{}, fmt.Errorf("the SBOM attached to %s isn't a %s document", ref, format.Name)
+	}
+
+	content, err := client.GetBlob(ctx, ref, artifact.Layers[0].Digest)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return format.Decode(content)
+}
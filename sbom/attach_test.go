@@ -0,0 +1,69 @@
+package sbom_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/registry/registrytest"
+	"github.com/cloudfoundry/windows2016fs/sbom"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Attach", func() {
+	var (
+		server *registrytest.Server
+		client *registry.Client
+		ref    registry.Reference
+		digest string
+	)
+
+	BeforeEach(func() {
+		server = registrytest.NewServer()
+		client = &registry.Client{PlainHTTP: true}
+
+		manifest := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.docker.container.image.v1+json","digest":"sha256:config","size":1}}`, registry.MediaTypeDockerManifest)
+		digest = server.PutManifest("cloudfoundry/windows2016fs", "2019.12", registry.MediaTypeDockerManifest, []byte(manifest))
+
+		var err error
+		ref, err = registry.ParseReference(server.Host() + "/cloudfoundry/windows2016fs:2019.12")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("attaches every format as an artifact referring to the image", func() {
+		doc, err := sbom.Assemble(inputs())
+		Expect(err).ToNot(HaveOccurred())
+		doc.Created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		digests, err := sbom.Attach(context.Background(), client, ref, doc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(digests).To(HaveLen(len(sbom.Formats)))
+
+		_, content, ok := server.Manifest("cloudfoundry/windows2016fs", sbom.AttachedTag(digest, sbom.Formats[0]))
+		Expect(ok).To(BeTrue())
+
+		var artifact registry.Manifest
+		Expect(json.Unmarshal(content, &artifact)).To(Succeed())
+		Expect(artifact.ArtifactType).To(Equal("application/vnd.cyclonedx+json"))
+		Expect(artifact.Subject.Digest).To(Equal(digest))
+
+		for _, format := range sbom.Formats {
+			fetched, err := sbom.Fetch(context.Background(), client, ref, format)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fetched).To(Equal(doc))
+		}
+	})
+
+	It("tags artifacts after the image digest", func() {
+		Expect(sbom.AttachedTag("sha256:abc", sbom.Formats[0])).To(Equal("sha256-abc.cdx.sbom"))
+		Expect(sbom.AttachedTag("sha256:abc", sbom.Formats[1])).To(Equal("sha256-abc.spdx.sbom"))
+	})
+})
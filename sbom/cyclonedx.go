@@ -0,0 +1,154 @@
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// layerProperty is the CycloneDX property recording a component's layer.
+const layerProperty = "windows2016fs:layer"
+
+type cycloneDXDocument struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cycloneDXTool    `json:"tools"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXTool struct {
+	Vendor string `json:"vendor"`
+	Name   string `json:"name"`
+}
+
+type cycloneDXComponent struct {
+	Type               string              `json:"type"`
+	BOMRef             string              `json:"bom-ref,omitempty"`
+	Name               string              `json:"name"`
+	Version            string              `json:"version,omitempty"`
+	Hashes             []cycloneDXHash     `json:"hashes,omitempty"`
+	ExternalReferences []cycloneDXExternal `json:"externalReferences,omitempty"`
+	Properties         []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Algorithm string `json:"alg"`
+	Content   string `json:"content"`
+}
+
+type cycloneDXExternal struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CycloneDX encodes d as a CycloneDX 1.5 JSON document.
+func CycloneDX(d Document) ([]byte, error) {
+	doc := cycloneDXDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + documentUUID(d),
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: d.Created.Format(time.RFC3339),
+			Tools:     []cycloneDXTool{{Vendor: "Cloud Foundry", Name: "imagebuilder"}},
+			Component: cycloneDXComponent{Type: TypeContainer, BOMRef: "image", Name: d.Image, Version: d.Tag},
+		},
+		Components: []cycloneDXComponent{},
+	}
+	if d.ImageID != "" {
+		doc.Metadata.Component.Hashes = []cycloneDXHash{{Algorithm: "SHA-256", Content: trimDigest(d.ImageID)}}
+	}
+
+	for i, component := range d.Components {
+		encoded := cycloneDXComponent{
+			Type:    component.Type,
+			BOMRef:  fmt.Sprintf("component-%d", i+1),
+			Name:    component.Name,
+			Version: component.Version,
+		}
+		if component.SHA256 != "" {
+			encoded.Hashes = []cycloneDXHash{{Algorithm: "SHA-256", Content: component.SHA256}}
+		}
+		if component.URL != "" {
+			encoded.ExternalReferences = []cycloneDXExternal{{Type: "distribution", URL: component.URL}}
+		}
+		if component.Layer != "" {
+			encoded.Properties = []cycloneDXProperty{{Name: layerProperty, Value: component.Layer}}
+		}
+
+		doc.Components = append(doc.Components, encoded)
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// ParseCycloneDX decodes a document written by CycloneDX.
+func ParseCycloneDX(content []byte) (Document, error) {
+	var doc cycloneDXDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return Document{}, err
+	}
+	if doc.BOMFormat != "CycloneDX" {
+		return Document{}, fmt.Errorf("not a CycloneDX document")
+	}
+
+	d := Document{Image: doc.Metadata.Component.Name, Tag: doc.Metadata.Component.Version}
+	d.Created, _ = time.Parse(time.RFC3339, doc.Metadata.Timestamp)
+	for _, hash := range doc.Metadata.Component.Hashes {
+		d.ImageID = "sha256:" + hash.Content
+	}
+
+	for _, encoded := range doc.Components {
+		component := Component{Type: encoded.Type, Name: encoded.Name, Version: encoded.Version}
+		for _, hash := range encoded.Hashes {
+			if hash.Algorithm == "SHA-256" {
+				component.SHA256 = hash.Content
+			}
+		}
+		for _, reference := range encoded.ExternalReferences {
+			if reference.Type == "distribution" {
+				component.URL = reference.URL
+			}
+		}
+		for _, property := range encoded.Properties {
+			if property.Name == layerProperty {
+				component.Layer = property.Value
+			}
+		}
+
+		d.Components = append(d.Components, component)
+	}
+
+	return d, nil
+}
+
+// documentUUID derives a stable UUID for d from its image and creation time.
+func documentUUID(d Document) string {
+	sum := sha256.Sum256([]byte(d.Image + "\x00" + d.ImageID + "\x00" + d.Created.Format(time.RFC3339Nano)))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func trimDigest(digest string) string {
+	if len(digest) > len("sha256:") && digest[:len("sha256:")] == "sha256:" {
+		return digest[len("sha256:"):]
+	}
+
+	return digest
+}
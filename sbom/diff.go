@@ -0,0 +1,146 @@
+package sbom
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff lists the components added, removed and upgraded from one document
+// to another.
+type Diff struct {
+	Previous string
+	Current  string
+
+	Added    []Component
+	Removed  []Component
+	Upgraded []Upgrade
+}
+
+// Upgrade is a component whose version, or for files content, changed.
+type Upgrade struct {
+	Previous Component
+	Current  Component
+}
+
+// Compare returns the changes from previous to current. Components are
+// matched by type and name; components listed more than once, such as
+// programs registered as both 32- and 64-bit, are compared by all their
+// versions.
+func Compare(previous, current Document) Diff {
+	diff := Diff{Previous: previous.Image, Current: current.Image}
+
+	before, after := byKey(previous.Components), byKey(current.Components)
+
+	for _, key := range sortedKeys(after) {
+		component := after[key]
+		earlier, ok := before[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, component)
+		case earlier.Version != component.Version || earlier.SHA256 != component.SHA256:
+			diff.Upgraded = append(diff.Upgraded, Upgrade{Previous: earlier, Current: component})
+		}
+	}
+
+	for _, key := range sortedKeys(before) {
+		if _, ok := after[key]; !ok {
+			diff.Removed = append(diff.Removed, before[key])
+		}
+	}
+
+	return diff
+}
+
+// Empty reports whether nothing changed.
+func (d Diff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Upgraded) == 0
+}
+
+// String renders the diff for changelogs.
+func (d Diff) String() string {
+	var report strings.Builder
+
+	fmt.Fprintf(&report, "SBOM changes from %s to %s\n", d.Previous, d.Current)
+
+	var added, removed, upgraded []string
+	for _, component := range d.Added {
+		added = append(added, describe(component))
+	}
+	for _, component := range d.Removed {
+		removed = append(removed, describe(component))
+	}
+	for _, upgrade := range d.Upgraded {
+		upgraded = append(upgraded, fmt.Sprintf("%s %s %s -> %s", upgrade.Current.Type, upgrade.Current.Name, revision(upgrade.Previous), revision(upgrade.Current)))
+	}
+
+	writeList(&report, "added", added)
+	writeList(&report, "removed", removed)
+	writeList(&report, "upgraded", upgraded)
+
+	return report.String()
+}
+
+func writeList(report *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		fmt.Fprintf(report, "  %s: none\n", title)
+		return
+	}
+
+	fmt.Fprintf(report, "  %s:\n", title)
+	for _, item := range items {
+		fmt.Fprintf(report, "    %s\n", item)
+	}
+}
+
+func describe(c Component) string {
+	if r := revision(c); r != "" {
+		return fmt.Sprintf("%s %s %s", c.Type, c.Name, r)
+	}
+
+	return c.Type + " " + c.Name
+}
+
+// revision is the version of a component or, for unversioned files, the
+// start of their SHA256.
+func revision(c Component) string {
+	switch {
+	case c.Version != "":
+		return c.Version
+	case len(c.SHA256) > 12:
+		return "sha256:" + c.SHA256[:12]
+	default:
+		return c.SHA256
+	}
+}
+
+func byKey(components []Component) map[string]Component {
+	versions := map[string][]string{}
+	indexed := map[string]Component{}
+	for _, component := range components {
+		key := component.Key()
+		versions[key] = append(versions[key], component.Version)
+		indexed[key] = component
+	}
+
+	for key, all := range versions {
+		if len(all) > 1 {
+			sort.Strings(all)
+			component := indexed[key]
+			component.Version = strings.Join(all, ", ")
+			indexed[key] = component
+		}
+	}
+
+	return indexed
+}
+
+func sortedKeys(components map[string]Component) []string {
+	var keys []string
+	for key := range components {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
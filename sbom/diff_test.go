@@ -0,0 +1,61 @@
+package sbom_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/sbom"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compare", func() {
+	previous := sbom.Document{Image: "cloudfoundry/windows2016fs:2019.11", Components: []sbom.Component{
+		{Type: sbom.TypeContainer, Name: "mcr.microsoft.com/windows/servercore", Version: "1809"},
+		{Type: sbom.TypeFile, Name: "Git-2.43.0-64-bit.exe", SHA256: "aaaaaaaaaaaaaaaa"},
+		{Type: sbom.TypeFile, Name: "dotnet-48-installer.exe", SHA256: "bbbbbbbbbbbbbbbb"},
+		{Type: sbom.TypeApplication, Name: "IIS URL Rewrite Module 2", Version: "7.2.1952"},
+		{Type: sbom.TypeApplication, Name: "Microsoft Visual C++ 2010 x64 Redistributable", Version: "10.0.40219"},
+	}}
+
+	current := sbom.Document{Image: "windows2016fs-candidate:2019", Components: []sbom.Component{
+		{Type: sbom.TypeContainer, Name: "mcr.microsoft.com/windows/servercore", Version: "1809"},
+		{Type: sbom.TypeFile, Name: "Git-2.44.0-64-bit.exe", SHA256: "cccccccccccccccc"},
+		{Type: sbom.TypeFile, Name: "dotnet-48-installer.exe", SHA256: "dddddddddddddddd"},
+		{Type: sbom.TypeApplication, Name: "IIS URL Rewrite Module 2", Version: "7.2.1993"},
+		{Type: sbom.TypeApplication, Name: "Microsoft Visual C++ 2010 x64 Redistributable", Version: "10.0.40219"},
+	}}
+
+	It("lists added, removed and upgraded components", func() {
+		diff := sbom.Compare(previous, current)
+
+		Expect(diff.Added).To(Equal([]sbom.Component{current.Components[1]}))
+		Expect(diff.Removed).To(Equal([]sbom.Component{previous.Components[1]}))
+		Expect(diff.Upgraded).To(Equal([]sbom.Upgrade{
+			{Previous: previous.Components[3], Current: current.Components[3]},
+			{Previous: previous.Components[2], Current: current.Components[2]},
+		}))
+	})
+
+	It("renders a report for changelogs", func() {
+		Expect(sbom.Compare(previous, current).String()).To(Equal(`SBOM changes from cloudfoundry/windows2016fs:2019.11 to windows2016fs-candidate:2019
+  added:
+    file Git-2.44.0-64-bit.exe sha256:cccccccccccc
+  removed:
+    file Git-2.43.0-64-bit.exe sha256:aaaaaaaaaaaa
+  upgraded:
+    application IIS URL Rewrite Module 2 7.2.1952 -> 7.2.1993
+    file dotnet-48-installer.exe sha256:bbbbbbbbbbbb -> sha256:dddddddddddd
+`))
+	})
+
+	It("compares every version of components listed more than once", func() {
+		twice := func(version string) sbom.Document {
+			return sbom.Document{Components: []sbom.Component{
+				{Type: sbom.TypeApplication, Name: "Microsoft Visual C++ 2015-2022 Redistributable", Version: "14.38.33130"},
+				{Type: sbom.TypeApplication, Name: "Microsoft Visual C++ 2015-2022 Redistributable", Version: version},
+			}}
+		}
+
+		Expect(sbom.Compare(twice("14.38.33130"), twice("14.38.33130")).Empty()).To(BeTrue())
+		Expect(sbom.Compare(twice("14.38.33130"), twice("14.40.33810")).Upgraded).To(HaveLen(1))
+	})
+})
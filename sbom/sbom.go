@@ -0,0 +1,243 @@
+// Package sbom describes the software baked into a candidate image, as
+// CycloneDX and SPDX documents.
+package sbom
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// Component types.
+const (
+	// TypeContainer is the base image.
+	TypeContainer = "container"
+
+	// TypeFile is an artifact the Dockerfile copies into the image, such as
+	// an installer.
+	TypeFile = "file"
+
+	// TypeApplication is a program registered in Programs and Features.
+	TypeApplication = "application"
+)
+
+// Component is a piece of software in the image.
+type Component struct {
+	Type    string
+	Name    string
+	Version string
+
+	// SHA256 and URL identify files from the dependency manifest.
+	SHA256 string
+	URL    string
+
+	// Layer is the diff ID of the layer that added the component, when
+	// known.
+	Layer string
+}
+
+// Key identifies a component across documents, regardless of its version.
+func (c Component) Key() string {
+	return c.Type + "/" + c.Name
+}
+
+// Document is the software bill of materials of an image.
+type Document struct {
+	// Image is the reference the document describes, ImageID its image ID
+	// and Tag its version, e.g. 2019.
+	Image   string
+	ImageID string
+	Tag     string
+	Created time.Time
+
+	Components []Component
+}
+
+// Inputs are what a document is assembled from.
+type Inputs struct {
+	Image   string
+	ImageID string
+	Tag     string
+
+	// Instructions are the Dockerfile the image was built from.
+	Instructions []builder.Instruction
+
+	// Dependencies are the artifacts of the image's dependency manifest.
+	Dependencies []builder.Dependency
+
+	// Layers are the image's diff IDs and BaseLayers how many of them come
+	// from the base image. Components aren't attributed to layers when the
+	// Dockerfile doesn't account for every layer.
+	Layers     []string
+	BaseLayers int
+
+	Programs []validation.Program
+}
+
+// Assemble builds the document of an image: its base image, the
+// dependencies the Dockerfile copies in, with the layer each was added by,
+// and its installed programs.
+func Assemble(inputs Inputs) (Document, error) {
+	if len(inputs.Instructions) == 0 || inputs.Instructions[0].Command != "FROM" || len(inputs.Instructions[0].Args) == 0 {
+		return Document{}, fmt.Errorf("the Dockerfile doesn't start with FROM")
+	}
+
+	doc := Document{Image: inputs.Image, ImageID: inputs.ImageID, Tag: inputs.Tag, Created: time.Now().UTC()}
+
+	base := inputs.Instructions[0].Args[len(inputs.Instructions[0].Args)-1]
+	baseName, baseVersion := base, ""
+	if i := strings.LastIndex(base, ":"); i > strings.LastIndex(base, "/") {
+		baseName, baseVersion = base[:i], base[i+1:]
+	}
+	doc.Components = append(doc.Components, Component{Type: TypeContainer, Name: baseName, Version: baseVersion})
+
+	layers := instructionLayers(inputs)
+
+	copied := map[string]Component{}
+	for i, instruction := range inputs.Instructions {
+		if instruction.Command != "COPY" && instruction.Command != "ADD" {
+			continue
+		}
+
+		for _, source := range instruction.Args[:len(instruction.Args)-1] {
+			for _, dependency := range inputs.Dependencies {
+				matched, err := path.Match(source, dependency.Name)
+				if err != nil {
+					return Document{}, fmt.Errorf("Dockerfile line %d: %s", instruction.Line, err)
+				}
+				if !matched {
+					continue
+				}
+
+				copied[dependency.Name] = Component{
+					Type:   TypeFile,
+					Name:   dependency.Name,
+					SHA256: dependency.SHA256,
+					URL:    dependency.URL,
+					Layer:  layers[i],
+				}
+			}
+		}
+	}
+
+	var names []string
+	for name := range copied {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		doc.Components = append(doc.Components, copied[name])
+	}
+
+	for _, program := range inputs.Programs {
+		doc.Components = append(doc.Components, Component{Type: TypeApplication, Name: program.Name, Version: program.Version})
+	}
+
+	return doc, nil
+}
+
+// instructionLayers maps the index of each instruction that adds a layer to
+// the layer's diff ID, or returns nothing when the layers don't line up
+// with the Dockerfile.
+func instructionLayers(inputs Inputs) map[int]string {
+	var adding []int
+	for i, instruction := range inputs.Instructions {
+		switch instruction.Command {
+		case "RUN", "COPY", "ADD":
+			adding = append(adding, i)
+		}
+	}
+
+	if inputs.BaseLayers == 0 || inputs.BaseLayers+len(adding) != len(inputs.Layers) {
+		return nil
+	}
+
+	layers := map[int]string{}
+	for n, i := range adding {
+		layers[i] = inputs.Layers[inputs.BaseLayers+n]
+	}
+
+	return layers
+}
+
+// Collect assembles the document of a local image built from dockerfile,
+// reading its layers and installed programs with docker. Dependencies
+// aren't attributed to layers when the base image isn't available locally.
+func Collect(image, tag, dockerfile string, manifest builder.Manifest) (Document, error) {
+	file, err := os.Open(dockerfile)
+	if err != nil {
+		return Document{}, err
+	}
+	instructions, err := builder.ParseDockerfile(file)
+	file.Close()
+	if err != nil {
+		return Document{}, err
+	}
+
+	inputs := Inputs{Image: image, Tag: tag, Instructions: instructions, Dependencies: manifest.Dependencies}
+
+	if inputs.ImageID, err = validation.ImageID(image); err != nil {
+		return Document{}, err
+	}
+	if inputs.Layers, err = validation.ImageLayers(image); err != nil {
+		return Document{}, err
+	}
+	if inputs.Programs, err = validation.InstalledPrograms(image); err != nil {
+		return Document{}, err
+	}
+
+	if len(instructions) > 0 && len(instructions[0].Args) > 0 {
+		if baseLayers, err := validation.ImageLayers(instructions[0].Args[len(instructions[0].Args)-1]); err == nil {
+			inputs.BaseLayers = len(baseLayers)
+		}
+	}
+
+	return Assemble(inputs)
+}
+
+// Write stores the CycloneDX and SPDX documents in dir as
+// sbom-<tag>.cdx.json and sbom-<tag>.spdx.json and returns their paths.
+func (d Document) Write(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, format := range Formats {
+		content, err := format.Encode(d)
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("sbom-%s%s", d.Tag, format.Extension))
+		if err := ioutil.WriteFile(path, content, 0644); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// Format is a document format.
+type Format struct {
+	Name      string
+	MediaType string
+	Extension string
+	Encode    func(Document) ([]byte, error)
+	Decode    func([]byte) (Document, error)
+}
+
+// Formats lists the formats documents are written and attached in.
+var Formats = []Format{
+	{Name: "cyclonedx", MediaType: "application/vnd.cyclonedx+json", Extension: ".cdx.json", Encode: CycloneDX, Decode: ParseCycloneDX},
+	{Name: "spdx", MediaType: "application/spdx+json", Extension: ".spdx.json", Encode: SPDX, Decode: ParseSPDX},
+}
@@ -0,0 +1,13 @@
+package sbom_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestSBOM(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "SBOM Suite")
+}
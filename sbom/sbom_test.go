@@ -0,0 +1,109 @@
+package sbom_test
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/sbom"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const dockerfile = `FROM mcr.microsoft.com/windows/servercore:1809
+RUN cmd.exe /C net users /ADD vcap
+COPY rewrite*.msi /Windows/rewrite.msi
+RUN msiexec /i C:\Windows\rewrite.msi /qn /quiet
+ENV GIT=1
+COPY vcredist-2010.x64.exe /vcredist-2010.x64.exe
+`
+
+func inputs() sbom.Inputs {
+	instructions, err := builder.ParseDockerfile(strings.NewReader(dockerfile))
+	Expect(err).ToNot(HaveOccurred())
+
+	return sbom.Inputs{
+		Image:        "windows2016fs-candidate:2019",
+		ImageID:      "sha256:image",
+		Tag:          "2019",
+		Instructions: instructions,
+		Dependencies: []builder.Dependency{
+			{Name: "rewrite_amd64_en-US.msi", URL: "https://download.microsoft.com/rewrite_amd64_en-US.msi", SHA256: "aaaa"},
+			{Name: "vcredist-2010.x64.exe", URL: "https://download.microsoft.com/vcredist_x64.exe", SHA256: "bbbb"},
+			{Name: "unused.exe"},
+		},
+		Layers:     []string{"sha256:base1", "sha256:base2", "sha256:users", "sha256:rewrite", "sha256:msiexec", "sha256:vcredist"},
+		BaseLayers: 2,
+		Programs:   []validation.Program{{Name: "IIS URL Rewrite Module 2", Version: "7.2.1993"}},
+	}
+}
+
+var _ = Describe("Assemble", func() {
+	It("lists the base image, the copied dependencies with their layers and the installed programs", func() {
+		doc, err := sbom.Assemble(inputs())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(doc.Components).To(Equal([]sbom.Component{
+			{Type: sbom.TypeContainer, Name: "mcr.microsoft.com/windows/servercore", Version: "1809"},
+			{Type: sbom.TypeFile, Name: "rewrite_amd64_en-US.msi", URL: "https://download.microsoft.com/rewrite_amd64_en-US.msi", SHA256: "aaaa", Layer: "sha256:rewrite"},
+			{Type: sbom.TypeFile, Name: "vcredist-2010.x64.exe", URL: "https://download.microsoft.com/vcredist_x64.exe", SHA256: "bbbb", Layer: "sha256:vcredist"},
+			{Type: sbom.TypeApplication, Name: "IIS URL Rewrite Module 2", Version: "7.2.1993"},
+		}))
+	})
+
+	It("doesn't attribute layers that don't line up with the Dockerfile", func() {
+		in := inputs()
+		in.Layers = in.Layers[:5]
+
+		doc, err := sbom.Assemble(in)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, component := range doc.Components {
+			Expect(component.Layer).To(BeEmpty())
+		}
+	})
+})
+
+var _ = Describe("Formats", func() {
+	for _, format := range sbom.Formats {
+		format := format
+
+		It("round-trips documents as "+format.Name, func() {
+			doc, err := sbom.Assemble(inputs())
+			Expect(err).ToNot(HaveOccurred())
+			doc.Created = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+			content, err := format.Encode(doc)
+			Expect(err).ToNot(HaveOccurred())
+
+			decoded, err := format.Decode(content)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(decoded).To(Equal(doc))
+		})
+	}
+
+	It("writes CycloneDX with hashes, distribution URLs and layers", func() {
+		doc, err := sbom.Assemble(inputs())
+		Expect(err).ToNot(HaveOccurred())
+
+		content, err := sbom.CycloneDX(doc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring(`"bomFormat": "CycloneDX"`))
+		Expect(string(content)).To(ContainSubstring(`"alg": "SHA-256"`))
+		Expect(string(content)).To(ContainSubstring(`"url": "https://download.microsoft.com/vcredist_x64.exe"`))
+		Expect(string(content)).To(ContainSubstring(`"value": "sha256:vcredist"`))
+	})
+
+	It("writes SPDX packages the image contains", func() {
+		doc, err := sbom.Assemble(inputs())
+		Expect(err).ToNot(HaveOccurred())
+
+		content, err := sbom.SPDX(doc)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(ContainSubstring(`"spdxVersion": "SPDX-2.3"`))
+		Expect(string(content)).To(ContainSubstring(`"relationshipType": "CONTAINS"`))
+		Expect(string(content)).To(ContainSubstring(`"primaryPackagePurpose": "APPLICATION"`))
+	})
+})
@@ -0,0 +1,154 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// spdxPurposes maps component types to SPDX primary package purposes.
+var spdxPurposes = map[string]string{
+	TypeContainer:   "CONTAINER",
+	TypeFile:        "FILE",
+	TypeApplication: "APPLICATION",
+}
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string         `json:"name"`
+	SPDXID                string         `json:"SPDXID"`
+	VersionInfo           string         `json:"versionInfo,omitempty"`
+	DownloadLocation      string         `json:"downloadLocation"`
+	FilesAnalyzed         bool           `json:"filesAnalyzed"`
+	Checksums             []spdxChecksum `json:"checksums,omitempty"`
+	PrimaryPackagePurpose string         `json:"primaryPackagePurpose,omitempty"`
+	Comment               string         `json:"comment,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm string `json:"algorithm"`
+	Value     string `json:"checksumValue"`
+}
+
+type spdxRelationship struct {
+	Element string `json:"spdxElementId"`
+	Type    string `json:"relationshipType"`
+	Related string `json:"relatedSpdxElement"`
+}
+
+// spdxLayerComment prefixes the comment recording a package's layer.
+const spdxLayerComment = "added by layer "
+
+// SPDX encodes d as an SPDX 2.3 JSON document.
+func SPDX(d Document) ([]byte, error) {
+	image := spdxPackage{
+		Name:                  d.Image,
+		SPDXID:                "SPDXRef-Image",
+		VersionInfo:           d.Tag,
+		DownloadLocation:      "NOASSERTION",
+		PrimaryPackagePurpose: "CONTAINER",
+	}
+	if d.ImageID != "" {
+		image.Checksums = []spdxChecksum{{Algorithm: "SHA256", Value: trimDigest(d.ImageID)}}
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              d.Image,
+		DocumentNamespace: "https://github.com/cloudfoundry/windows2016fs/spdx/" + documentUUID(d),
+		CreationInfo: spdxCreationInfo{
+			Created:  d.Created.Format(time.RFC3339),
+			Creators: []string{"Organization: Cloud Foundry", "Tool: imagebuilder"},
+		},
+		Packages:      []spdxPackage{image},
+		Relationships: []spdxRelationship{{Element: "SPDXRef-DOCUMENT", Type: "DESCRIBES", Related: "SPDXRef-Image"}},
+	}
+
+	for i, component := range d.Components {
+		encoded := spdxPackage{
+			Name:                  component.Name,
+			SPDXID:                fmt.Sprintf("SPDXRef-Package-%d", i+1),
+			VersionInfo:           component.Version,
+			DownloadLocation:      "NOASSERTION",
+			PrimaryPackagePurpose: spdxPurposes[component.Type],
+		}
+		if component.URL != "" {
+			encoded.DownloadLocation = component.URL
+		}
+		if component.SHA256 != "" {
+			encoded.Checksums = []spdxChecksum{{Algorithm: "SHA256", Value: component.SHA256}}
+		}
+		if component.Layer != "" {
+			encoded.Comment = spdxLayerComment + component.Layer
+		}
+
+		doc.Packages = append(doc.Packages, encoded)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{Element: "SPDXRef-Image", Type: "CONTAINS", Related: encoded.SPDXID})
+	}
+
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// ParseSPDX decodes a document written by SPDX.
+func ParseSPDX(content []byte) (Document, error) {
+	var doc spdxDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return Document{}, err
+	}
+	if !strings.HasPrefix(doc.SPDXVersion, "SPDX-") {
+		return Document{}, fmt.Errorf("not an SPDX document")
+	}
+
+	d := Document{Image: doc.Name}
+	d.Created, _ = time.Parse(time.RFC3339, doc.CreationInfo.Created)
+
+	types := map[string]string{}
+	for componentType, purpose := range spdxPurposes {
+		types[purpose] = componentType
+	}
+
+	for _, pkg := range doc.Packages {
+		if pkg.SPDXID == "SPDXRef-Image" {
+			d.Tag = pkg.VersionInfo
+			for _, checksum := range pkg.Checksums {
+				d.ImageID = "sha256:" + checksum.Value
+			}
+			continue
+		}
+
+		component := Component{Type: types[pkg.PrimaryPackagePurpose], Name: pkg.Name, Version: pkg.VersionInfo}
+		if pkg.DownloadLocation != "NOASSERTION" {
+			component.URL = pkg.DownloadLocation
+		}
+		for _, checksum := range pkg.Checksums {
+			if checksum.Algorithm == "SHA256" {
+				component.SHA256 = checksum.Value
+			}
+		}
+		if strings.HasPrefix(pkg.Comment, spdxLayerComment) {
+			component.Layer = strings.TrimPrefix(pkg.Comment, spdxLayerComment)
+		}
+
+		d.Components = append(d.Components, component)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,43 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"os"
+
+	"github.com/cloudfoundry/windows2016fs/publish"
+	"github.com/cloudfoundry/windows2016fs/registry"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// signPushed signs target@digest with cosign when SIGN_IMAGES is set, with
+// COSIGN_KEY or, when it is unset, keylessly.
+func signPushed(target, digest string) error {
+	if os.Getenv("SIGN_IMAGES") == "" {
+		return nil
+	}
+
+	ref, err := registry.ParseReference(target)
+	if err != nil {
+		return err
+	}
+
+	signer := publish.Signer{Key: os.Getenv("COSIGN_KEY"), Stdout: GinkgoWriter, Stderr: GinkgoWriter}
+	return signer.Sign(context.Background(), ref.WithDigest(digest))
+}
+
+// signatureVerifier returns the verifier for published images: with
+// COSIGN_PUBLIC_KEY, or with COSIGN_CERTIFICATE_IDENTITY and
+// COSIGN_CERTIFICATE_OIDC_ISSUER for keyless signatures. It reports false
+// when neither is configured.
+func signatureVerifier() (publish.Verifier, bool) {
+	verifier := publish.Verifier{
+		Key:                   os.Getenv("COSIGN_PUBLIC_KEY"),
+		CertificateIdentity:   os.Getenv("COSIGN_CERTIFICATE_IDENTITY"),
+		CertificateOIDCIssuer: os.Getenv("COSIGN_CERTIFICATE_OIDC_ISSUER"),
+		Stdout:                GinkgoWriter,
+		Stderr:                GinkgoWriter,
+	}
+
+	return verifier, verifier.Key != "" || verifier.CertificateIdentity != ""
+}
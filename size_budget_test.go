@@ -0,0 +1,13 @@
+package windows2016fs_test
+
+import "github.com/cloudfoundry/windows2016fs/validation"
+
+// sizeBudget returns the largest the candidate for tag may be: the
+// configured size budget, or else that of its tag and variant.
+func sizeBudget(tag string) (int64, error) {
+	if suiteConfig.SizeBudget != "" {
+		return validation.ParseSize(suiteConfig.SizeBudget)
+	}
+
+	return validation.SizeBudget(tag, imageVariant)
+}
@@ -0,0 +1,65 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+const (
+	smokeAppPort        = "8080"
+	smokeAppPollTimeout = 2 * time.Minute
+)
+
+// waitForHTTP polls url until it responds with 200 OK or timeout elapses.
+func waitForHTTP(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	deadline := time.Now().Add(timeout)
+
+	var lastErr error
+	for time.Now().Before(deadline) {
+		response, err := client.Get(url)
+		if err == nil {
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK {
+				return nil
+			}
+			err = fmt.Errorf("status %d", response.StatusCode)
+		}
+		lastErr = err
+
+		time.Sleep(time.Second)
+	}
+
+	return fmt.Errorf("%s did not return 200 within %s: %s", url, timeout, lastErr)
+}
+
+// startSmokeApp runs appImage detached with the app port published on the
+// host and returns the URL it can be reached at.
+func startSmokeApp(appImage string) (string, error) {
+	name := newContainerName()
+
+	args := append([]string{"run", "--detach", "--name", name, "--publish", smokeAppPort}, runFlags()...)
+	output, err := cmdlog.CombinedOutput(validation.Runtime.Command(context.Background(), append(args, appImage)...))
+	if err != nil {
+		return "", fmt.Errorf("starting %s failed: %s: %s", appImage, err, output)
+	}
+
+	output, err = cmdlog.Output(validation.Runtime.Command(context.Background(), "port", name, smokeAppPort))
+	if err != nil {
+		return "", fmt.Errorf("docker port %s failed: %s", name, err)
+	}
+
+	hostPort := strings.Fields(string(output))
+	if len(hostPort) == 0 {
+		return "", fmt.Errorf("port %s of %s is not published", smokeAppPort, name)
+	}
+
+	address := strings.Replace(hostPort[0], "0.0.0.0", "127.0.0.1", 1)
+	return fmt.Sprintf("http://%s/", address), nil
+}
@@ -0,0 +1,125 @@
+package windows2016fs_test
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/report"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	"github.com/onsi/ginkgo/config"
+	"github.com/onsi/ginkgo/types"
+)
+
+var suiteResults = &specResults{}
+
+// specResults is a ginkgo reporter that records the outcome and duration of
+// every spec that ran on this node, so that AfterSuite steps can be limited
+// to fully successful runs and summarize the run. Skipped specs aren't
+// recorded.
+type specResults struct {
+	sync.Mutex
+	results  []validation.CheckResult
+	metadata map[string]string
+
+	suite   string
+	started time.Time
+}
+
+// annotate attaches the measurements of a check to the running spec's result.
+func (r *specResults) annotate(metadata map[string]string) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.metadata == nil {
+		r.metadata = map[string]string{}
+	}
+	for key, value := range metadata {
+		r.metadata[key] = value
+	}
+}
+
+func (r *specResults) SpecDidComplete(summary *types.SpecSummary) {
+	r.Lock()
+	defer r.Unlock()
+
+	metadata := r.metadata
+	r.metadata = nil
+
+	if !summary.Passed() && !summary.HasFailureState() {
+		return
+	}
+
+	result := validation.CheckResult{
+		Name:     summary.ComponentTexts[len(summary.ComponentTexts)-1],
+		Passed:   summary.Passed(),
+		Duration: summary.RunTime,
+		Metadata: metadata,
+	}
+	if summary.HasFailureState() {
+		result.Message = summary.Failure.Message
+	}
+
+	r.results = append(r.results, result)
+}
+
+func (r *specResults) SpecSuiteWillBegin(_ config.GinkgoConfigType, summary *types.SuiteSummary) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.suite = summary.SuiteDescription
+	r.started = time.Now()
+}
+
+func (r *specResults) BeforeSuiteDidRun(*types.SetupSummary) {}
+func (r *specResults) SpecWillRun(*types.SpecSummary)        {}
+func (r *specResults) AfterSuiteDidRun(*types.SetupSummary)  {}
+func (r *specResults) SpecSuiteDidEnd(*types.SuiteSummary)   {}
+
+// summary returns the recorded results in the order the specs ran.
+func (r *specResults) summary() []validation.CheckResult {
+	r.Lock()
+	defer r.Unlock()
+
+	return append([]validation.CheckResult{}, r.results...)
+}
+
+// report returns the recorded results of the run against image, the
+// candidate for tag.
+func (r *specResults) report(image, tag string) report.Report {
+	r.Lock()
+	defer r.Unlock()
+
+	return report.Report{
+		Suite:    r.suite,
+		Image:    image,
+		Tag:      tag,
+		Started:  r.started,
+		Duration: time.Since(r.started),
+		Results:  append([]validation.CheckResult{}, r.results...),
+	}
+}
+
+func (r *specResults) allPassed() bool {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, result := range r.results {
+		if !result.Passed {
+			return false
+		}
+	}
+
+	return len(r.results) > 0
+}
+
+// reportDir returns where the run's reports are written: dir, or else
+// ARTIFACTS_DIR. No reports are written when both are empty.
+func reportDir(dir string) string {
+	if dir != "" {
+		return dir
+	}
+
+	return os.Getenv("ARTIFACTS_DIR")
+}
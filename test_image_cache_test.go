@@ -0,0 +1,49 @@
+package windows2016fs_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+const testImageCacheLabel = "org.cloudfoundry.windows2016fs.test-cache-key"
+
+// testImageCacheKey identifies the inputs of the test image: the candidate
+// image it is built from and the contents of the fixtures directory.
+func testImageCacheKey(imageNameAndTag string) (string, error) {
+	var candidate struct {
+		Id string
+	}
+	if err := validation.InspectImage(imageNameAndTag, &candidate); err != nil {
+		return "", err
+	}
+
+	fixtures, err := fixturesDigest("fixtures")
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(candidate.Id+"\n"+fixtures))), nil
+}
+
+// testImageUpToDate reports whether testImageNameAndTag exists and was built
+// from inputs matching cacheKey.
+func testImageUpToDate(testImageNameAndTag, cacheKey string) bool {
+	var testImage struct {
+		Config struct {
+			Labels map[string]string
+		}
+	}
+	if err := validation.InspectImage(testImageNameAndTag, &testImage); err != nil {
+		return false
+	}
+
+	return testImage.Config.Labels[testImageCacheLabel] == cacheKey
+}
+
+// fixturesDigest returns the digest of the fixtures in dir; see
+// validation.FixturesDigest.
+func fixturesDigest(dir string) (string, error) {
+	return validation.FixturesDigest(dir)
+}
@@ -0,0 +1,45 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/config"
+
+	. "github.com/onsi/ginkgo"
+)
+
+// checkTimeouts bounds each kind of check, from the timeouts of the suite
+// configuration.
+var checkTimeouts = config.DefaultTimeouts.ByCheck()
+
+// checkTimeout returns the timeout of the named check, falling back to the
+// command timeout for checks without their own entry.
+func checkTimeout(name string) time.Duration {
+	if timeout, ok := checkTimeouts[name]; ok {
+		return timeout
+	}
+
+	return checkTimeouts["command"]
+}
+
+// timedCheck runs check with a context bounded by checkTimeout(name) and
+// reports how long it took. Running out of time is reported with the check's
+// name and how long it waited, rather than as whatever error the cancelled
+// command returned.
+func timedCheck(name string, check func(ctx context.Context) error) error {
+	timeout := checkTimeout(name)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	fmt.Fprintf(GinkgoWriter, "%s check took %s\n", name, time.Since(start).Round(time.Millisecond))
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s check timed out after waiting %s", name, timeout)
+	}
+
+	return err
+}
@@ -0,0 +1,206 @@
+// Package upload archives the artifacts of a run, such as the exported
+// image, the build report, the SBOMs and the test reports, in an S3, GCS or
+// Azure Blob Storage bucket, through the aws, gcloud and az CLIs and their
+// ambient credentials.
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// The CLIs artifacts are uploaded with.
+var (
+	AWS    = "aws"
+	GCloud = "gcloud"
+	AZ     = "az"
+)
+
+// Patterns match the artifacts Collect picks from a directory.
+var Patterns = []string{
+	"build-report.json",
+	"report-*.json",
+	"report-*.xml",
+	"isolation-report.json",
+	"sbom-*.json",
+	"*.tar",
+	"*.tar.sha256",
+}
+
+// Target is a bucket, and the prefix under it, artifacts are uploaded to.
+type Target struct {
+	// Scheme is s3, gs or az.
+	Scheme string
+
+	// Account is the Azure storage account; Bucket is its container.
+	Account string
+	Bucket  string
+	Prefix  string
+}
+
+// ParseTarget parses s3://<bucket>[/<prefix>], gs://<bucket>[/<prefix>] or
+// az://<account>/<container>[/<prefix>].
+func ParseTarget(value string) (Target, error) {
+	u, err := url.Parse(value)
+	if err != nil {
+		return Target{}, err
+	}
+
+	t := Target{Scheme: u.Scheme, Bucket: u.Host}
+	rest := strings.Trim(u.Path, "/")
+
+	switch u.Scheme {
+	case "s3", "gs":
+	case "az":
+		t.Account = u.Host
+		parts := strings.SplitN(rest, "/", 2)
+		t.Bucket, rest = parts[0], ""
+		if len(parts) == 2 {
+			rest = parts[1]
+		}
+	default:
+		return Target{}, fmt.Errorf("%q is not an s3://, gs:// or az:// bucket", value)
+	}
+
+	if t.Bucket == "" {
+		return Target{}, fmt.Errorf("%q names no bucket", value)
+	}
+	t.Prefix = rest
+
+	return t, nil
+}
+
+func (t Target) String() string {
+	bucket := t.Bucket
+	if t.Scheme == "az" {
+		bucket = t.Account + "/" + t.Bucket
+	}
+
+	return strings.TrimSuffix(fmt.Sprintf("%s://%s/%s", t.Scheme, bucket, t.Prefix), "/")
+}
+
+// Key returns where the artifact with digest and file name is stored:
+// <prefix>/sha256/<hex>/<name>, so that an artifact is never overwritten by
+// different content and uploading it again changes nothing.
+func (t Target) Key(digest, name string) string {
+	return path.Join(t.Prefix, strings.Replace(digest, ":", "/", 1), name)
+}
+
+// Artifact is a file and where it was uploaded to.
+type Artifact struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+	Size   int64  `json:"size"`
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+}
+
+// Describe hashes the file at path and names the URL it is uploaded to.
+func (t Target) Describe(path string) (Artifact, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	digest := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	key := t.Key(digest, filepath.Base(path))
+	url := fmt.Sprintf("%s://%s/%s", t.Scheme, t.Bucket, key)
+	if t.Scheme == "az" {
+		url = fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", t.Account, t.Bucket, key)
+	}
+
+	return Artifact{Path: path, Digest: digest, Size: size, Key: key, URL: url}, nil
+}
+
+// Args returns the CLI and its arguments that upload a.
+func (t Target) Args(a Artifact) (string, []string) {
+	switch t.Scheme {
+	case "s3":
+		return AWS, []string{"s3", "cp", "--only-show-errors", a.Path, a.URL}
+	case "gs":
+		return GCloud, []string{"storage", "cp", a.Path, a.URL}
+	default:
+		return AZ, []string{"storage", "blob", "upload", "--only-show-errors", "--overwrite",
+			"--account-name", t.Account, "--container-name", t.Bucket, "--name", a.Key, "--file", a.Path}
+	}
+}
+
+// Collect returns the files in dirs that match Patterns, sorted. Empty and
+// missing directories hold no artifacts.
+func Collect(dirs ...string) ([]string, error) {
+	seen := map[string]bool{}
+	var paths []string
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		for _, pattern := range Patterns {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern))
+			if err != nil {
+				return nil, err
+			}
+
+			for _, match := range matches {
+				if info, err := os.Stat(match); err == nil && info.Mode().IsRegular() && !seen[match] {
+					seen[match] = true
+					paths = append(paths, match)
+				}
+			}
+		}
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// Uploader uploads artifacts to Target.
+type Uploader struct {
+	Target Target
+
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Upload uploads the files at paths and returns them with their URLs. It
+// stops at the first upload that fails.
+func (u Uploader) Upload(ctx context.Context, paths []string) ([]Artifact, error) {
+	var artifacts []Artifact
+	for _, path := range paths {
+		artifact, err := u.Target.Describe(path)
+		if err != nil {
+			return nil, err
+		}
+
+		name, args := u.Target.Args(artifact)
+		command := exec.CommandContext(ctx, name, args...)
+		command.Stdout = u.Stdout
+		command.Stderr = u.Stderr
+		if err := cmdlog.Run(command); err != nil {
+			return nil, fmt.Errorf("uploading %s to %s failed: %s", path, artifact.URL, err)
+		}
+
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts, nil
+}
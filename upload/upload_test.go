@@ -0,0 +1,114 @@
+package upload_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/upload"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseTarget", func() {
+	DescribeTable("parses buckets",
+		func(value string, expected upload.Target) {
+			target, err := upload.ParseTarget(value)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(target).To(Equal(expected))
+			Expect(target.String()).To(Equal(value))
+		},
+		Entry("s3", "s3://releases/windows2016fs", upload.Target{Scheme: "s3", Bucket: "releases", Prefix: "windows2016fs"}),
+		Entry("gcs", "gs://releases", upload.Target{Scheme: "gs", Bucket: "releases"}),
+		Entry("azure", "az://cfreleases/artifacts/windows2016fs/2019", upload.Target{Scheme: "az", Account: "cfreleases", Bucket: "artifacts", Prefix: "windows2016fs/2019"}),
+	)
+
+	DescribeTable("rejects other URLs",
+		func(value, message string) {
+			_, err := upload.ParseTarget(value)
+			Expect(err).To(MatchError(ContainSubstring(message)))
+		},
+		Entry("https", "https://releases.example.com", "is not an s3://, gs:// or az:// bucket"),
+		Entry("no bucket", "s3:///windows2016fs", "names no bucket"),
+		Entry("no container", "az://cfreleases", "names no bucket"),
+	)
+})
+
+var _ = Describe("Target", func() {
+	var (
+		dir    string
+		path   string
+		digest string
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "upload")
+		Expect(err).ToNot(HaveOccurred())
+
+		path = filepath.Join(dir, "report-2019.json")
+		Expect(ioutil.WriteFile(path, []byte("{}"), 0644)).To(Succeed())
+		digest = fmt.Sprintf("%x", sha256.Sum256([]byte("{}")))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("keys artifacts by their digest", func() {
+		target, err := upload.ParseTarget("s3://releases/windows2016fs")
+		Expect(err).ToNot(HaveOccurred())
+
+		artifact, err := target.Describe(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(artifact).To(Equal(upload.Artifact{
+			Path:   path,
+			Digest: "sha256:" + digest,
+			Size:   2,
+			Key:    "windows2016fs/sha256/" + digest + "/report-2019.json",
+			URL:    "s3://releases/windows2016fs/sha256/" + digest + "/report-2019.json",
+		}))
+
+		name, args := target.Args(artifact)
+		Expect(name).To(Equal("aws"))
+		Expect(args).To(Equal([]string{"s3", "cp", "--only-show-errors", path, artifact.URL}))
+	})
+
+	It("uploads to Azure blob containers by account and name", func() {
+		target, err := upload.ParseTarget("az://cfreleases/artifacts")
+		Expect(err).ToNot(HaveOccurred())
+
+		artifact, err := target.Describe(path)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(artifact.URL).To(Equal("https://cfreleases.blob.core.windows.net/artifacts/sha256/" + digest + "/report-2019.json"))
+
+		name, args := target.Args(artifact)
+		Expect(name).To(Equal("az"))
+		Expect(args).To(ContainElements("--account-name", "cfreleases", "--container-name", "artifacts", "--name", "sha256/"+digest+"/report-2019.json"))
+	})
+})
+
+var _ = Describe("Collect", func() {
+	It("picks the artifacts of a run from its directories", func() {
+		dir, err := ioutil.TempDir("", "collect")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		for _, name := range []string{"report-2019.json", "report-2019.xml", "sbom-2019.cdx.json", "windows2016fs-2019.tar", "windows2016fs-2019.tar.sha256", "commands.jsonl"} {
+			Expect(ioutil.WriteFile(filepath.Join(dir, name), nil, 0644)).To(Succeed())
+		}
+
+		paths, err := upload.Collect(dir, filepath.Join(dir, "missing"), "")
+		Expect(err).ToNot(HaveOccurred())
+
+		var names []string
+		for _, path := range paths {
+			names = append(names, filepath.Base(path))
+		}
+		Expect(names).To(Equal([]string{"report-2019.json", "report-2019.xml", "sbom-2019.cdx.json", "windows2016fs-2019.tar", "windows2016fs-2019.tar.sha256"}))
+	})
+})
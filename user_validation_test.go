@@ -0,0 +1,62 @@
+package windows2016fs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// runUserValidation copies the PowerShell script at scriptPath into a new
+// container of image and runs it there. A non-zero exit fails the check with
+// the script's stderr; an error means the script could not be run at all.
+func runUserValidation(image, scriptPath string) (validation.CheckResult, error) {
+	skipUnderCtr()
+
+	name := "user-validation " + filepath.Base(scriptPath)
+	container := newContainerName()
+	containerScript := `C:\` + filepath.Base(scriptPath)
+
+	args := append([]string{"create", "--name", container}, runFlags()...)
+	args = append(args, image, "powershell", "-NoProfile", "-ExecutionPolicy", "Bypass", "-File", containerScript)
+	create := validation.Runtime.Command(context.Background(), args...)
+	if output, err := cmdlog.CombinedOutput(create); err != nil {
+		return validation.CheckResult{}, fmt.Errorf("docker create failed: %s: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	if output, err := cmdlog.CombinedOutput(validation.Runtime.Command(context.Background(), "cp", scriptPath, container+":"+containerScript)); err != nil {
+		return validation.CheckResult{}, fmt.Errorf("docker cp %s failed: %s: %s", scriptPath, err, strings.TrimSpace(string(output)))
+	}
+
+	var stdout, stderr bytes.Buffer
+	err := timedCheck("run", func(ctx context.Context) error {
+		command := validation.Runtime.Command(ctx, "start", "--attach", container)
+		command.Stdout = &stdout
+		command.Stderr = &stderr
+		return cmdlog.Run(command)
+	})
+
+	metadata := map[string]string{"script": scriptPath, "stdout": strings.TrimSpace(stdout.String())}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		metadata["exitCode"] = fmt.Sprint(exitErr.ExitCode())
+		return validation.CheckResult{
+			Name:     name,
+			Message:  fmt.Sprintf("%s exited %d: %s", scriptPath, exitErr.ExitCode(), strings.TrimSpace(stderr.String())),
+			Metadata: metadata,
+		}, nil
+	}
+	if err != nil {
+		return validation.CheckResult{}, err
+	}
+
+	metadata["exitCode"] = "0"
+	return validation.CheckResult{Name: name, Passed: true, Metadata: metadata}, nil
+}
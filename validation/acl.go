@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"strconv"
+	"strings"
+)
+
+var (
+	broadIdentities = []string{"Everyone", `BUILTIN\Users`}
+	writeRights     = []string{"FullControl", "Modify", "Write", "WriteData", "AppendData", "ChangePermissions", "TakeOwnership"}
+)
+
+// writeRightsMask holds the access mask bits that let an identity modify a
+// file: FILE_WRITE_DATA, FILE_APPEND_DATA, WRITE_DAC, WRITE_OWNER,
+// GENERIC_WRITE and GENERIC_ALL. FileSystemRights prints generic rights,
+// which have no name in the enumeration, as a number.
+const writeRightsMask = 0x2 | 0x4 | 0x40000 | 0x80000 | 0x40000000 | 0x10000000
+
+// AccessRule is a single entry of a file's access control list, with the
+// FileSystemRights and AccessControlType of Get-Acl as strings.
+type AccessRule struct {
+	Identity string
+	Rights   string
+	Type     string
+}
+
+// Permissive reports whether the rule lets everyone, or every user, modify
+// the file.
+func (r AccessRule) Permissive() bool {
+	if r.Type != "Allow" || !ContainsFold(broadIdentities, r.Identity) {
+		return false
+	}
+
+	for _, right := range strings.Split(r.Rights, ",") {
+		right = strings.TrimSpace(right)
+		if ContainsFold(writeRights, right) {
+			return true
+		}
+
+		if mask, err := strconv.ParseInt(right, 10, 64); err == nil && uint32(mask)&writeRightsMask != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsFold reports whether values contains value, ignoring case as
+// Windows does for paths, identities and names.
+func ContainsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
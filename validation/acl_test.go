@@ -0,0 +1,25 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AccessRule", func() {
+	DescribeTable("Permissive",
+		func(rule validation.AccessRule, permissive bool) {
+			Expect(rule.Permissive()).To(Equal(permissive))
+		},
+		Entry("full control for everyone", validation.AccessRule{Identity: "Everyone", Rights: "FullControl", Type: "Allow"}, true),
+		Entry("write among other rights for users", validation.AccessRule{Identity: `BUILTIN\Users`, Rights: "ReadAndExecute, Write, Synchronize", Type: "Allow"}, true),
+		Entry("GENERIC_ALL for everyone", validation.AccessRule{Identity: "Everyone", Rights: "268435456", Type: "Allow"}, true),
+		Entry("GENERIC_WRITE for users", validation.AccessRule{Identity: `BUILTIN\Users`, Rights: "1073741824", Type: "Allow"}, true),
+		Entry("GENERIC_READ and GENERIC_EXECUTE for users", validation.AccessRule{Identity: `BUILTIN\Users`, Rights: "-1610612736", Type: "Allow"}, false),
+		Entry("read for everyone", validation.AccessRule{Identity: "Everyone", Rights: "ReadAndExecute, Synchronize", Type: "Allow"}, false),
+		Entry("denied write for everyone", validation.AccessRule{Identity: "Everyone", Rights: "Write", Type: "Deny"}, false),
+		Entry("GENERIC_ALL for administrators", validation.AccessRule{Identity: `BUILTIN\Administrators`, Rights: "268435456", Type: "Allow"}, false),
+	)
+})
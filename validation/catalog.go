@@ -0,0 +1,33 @@
+// Package validation holds the image checks exercised by the windows2016fs
+// suite in a form that can be used without Ginkgo.
+package validation
+
+import "sync"
+
+// ImageCatalog maps version tags to the image references built or selected
+// for them. It is safe for concurrent use; the zero value is empty and ready
+// to use.
+type ImageCatalog struct {
+	mutex  sync.RWMutex
+	images map[string]string
+}
+
+// Get returns the image reference registered for tag.
+func (c *ImageCatalog) Get(tag string) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	ref, ok := c.images[tag]
+	return ref, ok
+}
+
+// Set registers ref as the image for tag, replacing any previous reference.
+func (c *ImageCatalog) Set(tag, ref string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.images == nil {
+		c.images = map[string]string{}
+	}
+	c.images[tag] = ref
+}
@@ -0,0 +1,61 @@
+package validation_test
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ImageCatalog", func() {
+	var catalog *validation.ImageCatalog
+
+	BeforeEach(func() {
+		catalog = &validation.ImageCatalog{}
+	})
+
+	It("returns false for unknown tags", func() {
+		_, ok := catalog.Get("2019")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("returns the most recently set reference", func() {
+		catalog.Set("2019", "windows2016fs-candidate:2019")
+		catalog.Set("2019", "cloudfoundry/windows2016fs:2019")
+
+		ref, ok := catalog.Get("2019")
+		Expect(ok).To(BeTrue())
+		Expect(ref).To(Equal("cloudfoundry/windows2016fs:2019"))
+	})
+
+	It("supports concurrent Set and Get", func() {
+		wg := new(sync.WaitGroup)
+
+		for i := 0; i < 50; i++ {
+			tag := fmt.Sprintf("tag-%d", i)
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+				catalog.Set(tag, "image:"+tag)
+			}()
+
+			go func() {
+				defer wg.Done()
+				catalog.Get(tag)
+			}()
+		}
+
+		wg.Wait()
+
+		for i := 0; i < 50; i++ {
+			tag := fmt.Sprintf("tag-%d", i)
+			ref, ok := catalog.Get(tag)
+			Expect(ok).To(BeTrue())
+			Expect(ref).To(Equal("image:" + tag))
+		}
+	})
+})
@@ -0,0 +1,187 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CertificateStores are the LocalMachine stores the certificates baseline
+// covers: the trusted roots and the intermediate authorities.
+var CertificateStores = []string{"Root", "CA"}
+
+// DefaultCertificateExpiryWarning is how long before a baseline certificate
+// expires that it is reported.
+const DefaultCertificateExpiryWarning = 90 * 24 * time.Hour
+
+// Certificate is a certificate in one of CertificateStores.
+type Certificate struct {
+	Store      string    `json:"store"`
+	Thumbprint string    `json:"thumbprint"`
+	Subject    string    `json:"subject"`
+	NotAfter   time.Time `json:"not_after"`
+}
+
+func (c Certificate) String() string {
+	return fmt.Sprintf(`LocalMachine\%s %s (%s)`, c.Store, c.Thumbprint, c.Subject)
+}
+
+// certificatesScript lists the certificates of the stores in $stores.
+const certificatesScript = `$certificates = foreach ($store in $stores) {
+  Get-ChildItem "Cert:\LocalMachine\$store" | ForEach-Object {
+    [pscustomobject]@{ Store = $store; Thumbprint = $_.Thumbprint; Subject = $_.Subject; NotAfter = $_.NotAfter.ToUniversalTime().ToString('o') }
+  }
+}
+ConvertTo-Json -Compress -InputObject @($certificates)`
+
+// CertificatesBaselinePath is the certificates baseline of tag in
+// FixturesDir.
+func CertificatesBaselinePath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("expected-certificates-%s.json", tag))
+}
+
+// ReadCertificatesBaseline reads the certificates baseline at path.
+func ReadCertificatesBaseline(path string) ([]Certificate, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline []Certificate
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return baseline, nil
+}
+
+// WriteCertificatesBaseline writes certificates as indented JSON, sorted by
+// store and thumbprint.
+func WriteCertificatesBaseline(certificates []Certificate, w io.Writer) error {
+	sorted := append([]Certificate{}, certificates...)
+	sortCertificates(sorted)
+
+	content, err := json.MarshalIndent(sorted, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// Certificates returns the certificates in image's CertificateStores.
+func Certificates(image string) ([]Certificate, error) {
+	var quoted []string
+	for _, store := range CertificateStores {
+		quoted = append(quoted, "'"+store+"'")
+	}
+
+	output, err := powershell(image, fmt.Sprintf("$stores = @(%s)\n%s", strings.Join(quoted, ", "), certificatesScript))
+	if err != nil {
+		return nil, err
+	}
+
+	var certificates []Certificate
+	if err := unmarshalPSJSON([]byte(output), &certificates); err != nil {
+		return nil, fmt.Errorf("parsing the certificates of %s: %s", image, err)
+	}
+
+	sortCertificates(certificates)
+	return certificates, nil
+}
+
+func sortCertificates(certificates []Certificate) {
+	sort.Slice(certificates, func(i, j int) bool {
+		if certificates[i].Store != certificates[j].Store {
+			return certificates[i].Store < certificates[j].Store
+		}
+		return certificates[i].Thumbprint < certificates[j].Thumbprint
+	})
+}
+
+// CertificateDiff is how the certificates of an image differ from a
+// baseline.
+type CertificateDiff struct {
+	// Missing are baseline certificates the image lacks, such as
+	// enterprise roots a build dropped.
+	Missing []Certificate
+
+	// Unexpected are certificates the baseline doesn't list, such as ones
+	// a dependency installed.
+	Unexpected []Certificate
+}
+
+// Empty reports whether the certificates match the baseline.
+func (d CertificateDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0
+}
+
+// String lists the differences, one per line.
+func (d CertificateDiff) String() string {
+	var lines []string
+	for _, certificate := range d.Missing {
+		lines = append(lines, "missing: "+certificate.String())
+	}
+	for _, certificate := range d.Unexpected {
+		lines = append(lines, "unexpected: "+certificate.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// DiffCertificates compares certificates with baseline by store and
+// thumbprint.
+func DiffCertificates(baseline, certificates []Certificate) CertificateDiff {
+	key := func(c Certificate) string {
+		return strings.ToLower(c.Store) + `\` + strings.ToUpper(c.Thumbprint)
+	}
+
+	expected := map[string]bool{}
+	for _, certificate := range baseline {
+		expected[key(certificate)] = true
+	}
+	actual := map[string]bool{}
+	for _, certificate := range certificates {
+		actual[key(certificate)] = true
+	}
+
+	var diff CertificateDiff
+	for _, certificate := range baseline {
+		if !actual[key(certificate)] {
+			diff.Missing = append(diff.Missing, certificate)
+		}
+	}
+	for _, certificate := range certificates {
+		if !expected[key(certificate)] {
+			diff.Unexpected = append(diff.Unexpected, certificate)
+		}
+	}
+	sortCertificates(diff.Missing)
+	sortCertificates(diff.Unexpected)
+
+	return diff
+}
+
+// ExpiringCertificates describes the certificates that expired, or expire
+// within window of now. Root stores keep some expired certificates on
+// purpose, so these are warnings rather than failures.
+func ExpiringCertificates(certificates []Certificate, now time.Time, window time.Duration) []string {
+	var expiring []string
+	for _, certificate := range certificates {
+		switch {
+		case certificate.NotAfter.IsZero():
+		case !certificate.NotAfter.After(now):
+			expiring = append(expiring, fmt.Sprintf("%s expired on %s", certificate, certificate.NotAfter.Format("2006-01-02")))
+		case certificate.NotAfter.Before(now.Add(window)):
+			expiring = append(expiring, fmt.Sprintf("%s expires on %s", certificate, certificate.NotAfter.Format("2006-01-02")))
+		}
+	}
+
+	return expiring
+}
@@ -0,0 +1,72 @@
+package validation_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("certificates", func() {
+	var (
+		now        = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+		root       = validation.Certificate{Store: "Root", Thumbprint: "CDD4EEAE6000AC7F40C3802C171E30148030C072", Subject: "CN=Microsoft Root Certificate Authority", NotAfter: time.Date(2021, 5, 9, 23, 28, 13, 0, time.UTC)}
+		enterprise = validation.Certificate{Store: "Root", Thumbprint: "0123456789ABCDEF0123456789ABCDEF01234567", Subject: "CN=Example Corp Root CA", NotAfter: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)}
+		injected   = validation.Certificate{Store: "CA", Thumbprint: "89ABCDEF0123456789ABCDEF0123456789ABCDEF", Subject: "CN=Intercepting Proxy", NotAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	)
+
+	Describe("DiffCertificates", func() {
+		It("matches certificates by store and thumbprint, ignoring case", func() {
+			lower := root
+			lower.Thumbprint = "cdd4eeae6000ac7f40c3802c171e30148030c072"
+
+			Expect(validation.DiffCertificates([]validation.Certificate{root}, []validation.Certificate{lower}).Empty()).To(BeTrue())
+		})
+
+		It("lists missing and unexpected certificates", func() {
+			diff := validation.DiffCertificates([]validation.Certificate{root, enterprise}, []validation.Certificate{root, injected})
+
+			Expect(diff.String()).To(Equal(`missing: LocalMachine\Root 0123456789ABCDEF0123456789ABCDEF01234567 (CN=Example Corp Root CA)
+unexpected: LocalMachine\CA 89ABCDEF0123456789ABCDEF0123456789ABCDEF (CN=Intercepting Proxy)`))
+		})
+
+		It("treats the same certificate in another store as unexpected", func() {
+			moved := enterprise
+			moved.Store = "CA"
+
+			diff := validation.DiffCertificates([]validation.Certificate{enterprise}, []validation.Certificate{moved})
+			Expect(diff.Missing).To(Equal([]validation.Certificate{enterprise}))
+			Expect(diff.Unexpected).To(Equal([]validation.Certificate{moved}))
+		})
+	})
+
+	Describe("ExpiringCertificates", func() {
+		It("warns about expired certificates and those expiring within the window", func() {
+			Expect(validation.ExpiringCertificates([]validation.Certificate{root, enterprise, injected}, now, 90*24*time.Hour)).To(Equal([]string{
+				`LocalMachine\Root CDD4EEAE6000AC7F40C3802C171E30148030C072 (CN=Microsoft Root Certificate Authority) expired on 2021-05-09`,
+				`LocalMachine\Root 0123456789ABCDEF0123456789ABCDEF01234567 (CN=Example Corp Root CA) expires on 2021-07-01`,
+			}))
+		})
+	})
+
+	Describe("WriteCertificatesBaseline", func() {
+		It("writes the certificates sorted by store and thumbprint", func() {
+			var buf bytes.Buffer
+			Expect(validation.WriteCertificatesBaseline([]validation.Certificate{root, injected, enterprise}, &buf)).To(Succeed())
+
+			Expect(buf.String()).To(HavePrefix(`[
+    {
+        "store": "CA",
+        "thumbprint": "89ABCDEF0123456789ABCDEF0123456789ABCDEF",
+        "subject": "CN=Intercepting Proxy",
+        "not_after": "2030-01-01T00:00:00Z"
+    },
+    {
+        "store": "Root",
+        "thumbprint": "0123456789ABCDEF0123456789ABCDEF01234567",`))
+		})
+	})
+})
@@ -0,0 +1,154 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Platform and Isolation, when set, are passed to `docker run` as
+// --platform and --isolation by the checks.
+var (
+	Platform  string
+	Isolation string
+)
+
+// IsolationModes are the isolation modes Windows containers can run with.
+var IsolationModes = []string{"process", "hyperv"}
+
+// ValidateIsolation fails on modes outside IsolationModes. An empty mode,
+// the daemon's default, is valid.
+func ValidateIsolation(mode string) error {
+	if mode == "" {
+		return nil
+	}
+
+	for _, supported := range IsolationModes {
+		if mode == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported isolation %q; supported isolation modes are %s", mode, strings.Join(IsolationModes, ", "))
+}
+
+// FixturesDir holds the per-tag baselines that checks compare images
+// against.
+var FixturesDir = "fixtures"
+
+// CheckResult is the outcome of a single check against an image.
+type CheckResult struct {
+	Name     string
+	Passed   bool
+	Message  string
+	Duration time.Duration
+
+	// Metadata holds the values the check measured, such as the installed
+	// .NET Framework release.
+	Metadata map[string]string
+}
+
+// Candidate is an image to validate and the version tag and variant whose
+// expectations apply to it.
+type Candidate struct {
+	Image   string
+	Tag     string
+	Variant string
+}
+
+// NewCandidate returns image as a candidate of tag and variant. An empty tag
+// is taken from the image reference without any release or variant suffix,
+// e.g. "2019" for "cloudfoundry/windows2016fs:2019.12" or
+// "windows2016fs:2019-nanoserver".
+func NewCandidate(image, tag, variant string) Candidate {
+	if tag == "" {
+		tag = imageTag(image)
+	}
+
+	return Candidate{Image: image, Tag: tag, Variant: variant}
+}
+
+// VariantTag returns the tag of the candidate's baselines, e.g.
+// "2019-nanoserver".
+func (c Candidate) VariantTag() string {
+	return VariantTag(c.Tag, c.Variant)
+}
+
+// Profile returns the profile of the candidate's version tag.
+func (c Candidate) Profile() (Profile, error) {
+	return ProfileFor(c.Tag)
+}
+
+// Check validates a candidate. A failed expectation is reported as a
+// CheckResult that didn't pass; an error means the check could not be
+// carried out.
+type Check func(candidate Candidate) (CheckResult, error)
+
+var checks = map[string]Check{
+	"dotnet":   CheckDotNet,
+	"os-build": CheckOSBuild,
+	"services": CheckServices,
+	"smb":      CheckSMB,
+	"vcredist": CheckVCRedist,
+}
+
+// CheckNames returns the names accepted by RunChecks, sorted.
+func CheckNames() []string {
+	var names []string
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// RunChecks runs the named checks against candidate in order, or every check
+// when names is empty. It stops at the first check that can't be carried out
+// and returns the results gathered so far.
+func RunChecks(candidate Candidate, names []string) ([]CheckResult, error) {
+	if len(names) == 0 {
+		names = CheckNames()
+	}
+
+	for _, name := range names {
+		if _, ok := checks[name]; !ok {
+			return nil, fmt.Errorf("unknown check %q; known checks are %s", name, strings.Join(CheckNames(), ", "))
+		}
+	}
+
+	var results []CheckResult
+	for _, name := range names {
+		start := time.Now()
+		result, err := checks[name](candidate)
+		if err != nil {
+			return results, fmt.Errorf("%s: %s", name, err)
+		}
+		result.Duration = time.Since(start)
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func passed(name string, metadata map[string]string) CheckResult {
+	return CheckResult{Name: name, Passed: true, Metadata: metadata}
+}
+
+func failed(name string, metadata map[string]string, format string, args ...interface{}) CheckResult {
+	return CheckResult{Name: name, Message: fmt.Sprintf(format, args...), Metadata: metadata}
+}
+
+// imageTag returns the tag of the image reference without any release or
+// variant suffix, e.g. "2019" for "cloudfoundry/windows2016fs:2019.12" or
+// "windows2016fs:2019-nanoserver".
+func imageTag(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag := strings.SplitN(image[i+1:], ".", 2)[0]
+		return strings.SplitN(tag, "-", 2)[0]
+	}
+
+	return "latest"
+}
@@ -0,0 +1,21 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewCandidate", func() {
+	It("takes the tag from the image reference", func() {
+		Expect(NewCandidate("windows2016fs-candidate:2019", "", "").Tag).To(Equal("2019"))
+		Expect(NewCandidate("cloudfoundry/windows2016fs:2019.12", "", "").Tag).To(Equal("2019"))
+		Expect(NewCandidate("windows2016fs-candidate:2019-nanoserver", "", "nanoserver").Tag).To(Equal("2019"))
+		Expect(NewCandidate("localhost:5000/windows2016fs", "", "").Tag).To(Equal("latest"))
+	})
+
+	It("prefers the given tag to the image reference", func() {
+		candidate := NewCandidate("repo/x:1.2.3", "2019", "nanoserver")
+		Expect(candidate).To(Equal(Candidate{Image: "repo/x:1.2.3", Tag: "2019", Variant: "nanoserver"}))
+		Expect(candidate.VariantTag()).To(Equal("2019-nanoserver"))
+	})
+})
@@ -0,0 +1,40 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checks", func() {
+	It("lists the available checks in order", func() {
+		Expect(validation.CheckNames()).To(Equal([]string{"dotnet", "os-build", "services", "smb", "vcredist"}))
+	})
+
+	It("rejects unknown checks before running any", func() {
+		results, err := validation.RunChecks(validation.NewCandidate("windows2016fs-candidate:2019", "", ""), []string{"dotnet", "bogus"})
+		Expect(err).To(MatchError(ContainSubstring(`unknown check "bogus"`)))
+		Expect(results).To(BeEmpty())
+	})
+
+	It("rejects unknown tags", func() {
+		_, err := validation.ProfileFor("1803")
+		Expect(err).To(MatchError(ContainSubstring(`unknown tag "1803"`)))
+	})
+
+	It("reads the baselines of the candidate's variant", func() {
+		candidate := validation.NewCandidate("windows2016fs-candidate:2019-nanoserver", "2019", "nanoserver")
+
+		_, err := validation.CheckServices(candidate)
+		Expect(err).To(MatchError(ContainSubstring("expected-baseline-services-2019-nanoserver.json")))
+
+		_, err = validation.CheckRegistry(candidate)
+		Expect(err).To(MatchError(ContainSubstring("expected-registry-2019-nanoserver.json")))
+	})
+
+	It("extracts the skip reason printed by container-test.ps1", func() {
+		Expect(validation.SkipReason("resolving\r\nSKIP: QUIC is unsupported\r\n")).To(Equal("QUIC is unsupported"))
+		Expect(validation.SkipReason("T: \\\\10.0.0.1\\share")).To(BeEmpty())
+	})
+})
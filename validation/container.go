@@ -0,0 +1,204 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// removeTimeout bounds the cleanup of a container once its run has finished
+// or been cancelled.
+const removeTimeout = time.Minute
+
+// ContainerSpec describes a single `docker run` invocation.
+type ContainerSpec struct {
+	Image string
+	Cmd   []string
+	Env   map[string]string
+
+	// Name defaults to a random w2016fs-run-<hex> name.
+	Name     string
+	User     string
+	Platform string
+
+	// Isolation is the --isolation mode, one of IsolationModes. The daemon's
+	// default applies when empty.
+	Isolation string
+
+	// CredentialSpec is the gMSA credential spec, e.g. file://webapp.json,
+	// whose identity the container's Network Service and Local System
+	// accounts authenticate on the network with.
+	CredentialSpec string
+
+	// Volumes are bind mounts in docker's host:container form. Windows only
+	// supports mounting directories.
+	Volumes []string
+
+	// ExtraArgs are additional `docker run` flags, such as --dns or --memory,
+	// passed before the image.
+	ExtraArgs []string
+
+	// KeepContainer leaves the container in place after it exits so that the
+	// caller can inspect it; the caller is then responsible for removing it.
+	KeepContainer bool
+
+	// Stdout and Stderr, when set, receive the container's output as it is
+	// produced in addition to it being captured in the ContainerRun.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ContainerRun is the outcome of a container that ran to completion.
+type ContainerRun struct {
+	Name     string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Duration time.Duration
+}
+
+// Args returns the arguments of the Runtime CLI that run the container.
+func (s ContainerSpec) Args() []string {
+	return Runtime.RunArgs(s)
+}
+
+// dockerArgs returns the docker run arguments of the container, which
+// podman and nerdctl take too, with runtime's isolation flags.
+func (s ContainerSpec) dockerArgs(runtime ContainerRuntime) []string {
+	args := []string{"run", "--name", s.Name}
+
+	if s.Platform != "" {
+		args = append(args, "--platform", s.Platform)
+	}
+	if s.Isolation != "" {
+		args = append(args, runtime.IsolationArgs(s.Isolation)...)
+	}
+	if s.User != "" {
+		args = append(args, "--user", s.User)
+	}
+	if s.CredentialSpec != "" {
+		args = append(args, "--security-opt", "credentialspec="+s.CredentialSpec)
+	}
+
+	var keys []string
+	for key := range s.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--env", fmt.Sprintf("%s=%s", key, s.Env[key]))
+	}
+
+	for _, volume := range s.Volumes {
+		args = append(args, "--volume", volume)
+	}
+
+	args = append(args, s.ExtraArgs...)
+	args = append(args, s.Image)
+	return append(args, s.Cmd...)
+}
+
+// RunContainerAndWait runs cmd in a new container of image with env set and
+// waits for it to exit. The container is always removed, including when ctx
+// is cancelled.
+func RunContainerAndWait(ctx context.Context, image string, cmd []string, env map[string]string) (ContainerRun, error) {
+	return RunContainer(ctx, ContainerSpec{Image: image, Cmd: cmd, Env: env})
+}
+
+// RunContainer runs spec and waits for the container to exit. A non-zero exit
+// code is reported in the ContainerRun rather than as an error; an error means
+// the container could not be run to completion. Containers docker fails to
+// start with a transient error are run again according to DockerRetry.
+func RunContainer(ctx context.Context, spec ContainerSpec) (ContainerRun, error) {
+	if spec.Name == "" {
+		name, err := randomContainerName()
+		if err != nil {
+			return ContainerRun{}, err
+		}
+		spec.Name = name
+	}
+
+	if !spec.KeepContainer {
+		defer removeContainer(spec.Name)
+	}
+
+	for attempt := 1; ; attempt++ {
+		run, err := runContainerOnce(ctx, spec)
+		if err != nil || run.ExitCode != dockerRunErrorExitCode || !DockerRetry.ShouldRetry(attempt, run.Stderr) {
+			return run, err
+		}
+
+		if spec.Stderr != nil {
+			fmt.Fprintf(spec.Stderr, "docker couldn't start %s, retrying (attempt %d of %d)\n", spec.Name, attempt+1, DockerRetry.Attempts)
+		}
+		removeContainer(spec.Name)
+
+		if err := DockerRetry.Wait(ctx, attempt); err != nil {
+			return run, fmt.Errorf("container %s did not finish: %s", spec.Name, err)
+		}
+	}
+}
+
+func runContainerOnce(ctx context.Context, spec ContainerSpec) (ContainerRun, error) {
+	var stdout, stderr bytes.Buffer
+	command := Runtime.Command(ctx, spec.Args()...)
+	command.Stdout = teeTo(&stdout, spec.Stdout)
+	command.Stderr = teeTo(&stderr, spec.Stderr)
+
+	start := time.Now()
+	err := cmdlog.Run(command)
+
+	run := ContainerRun{
+		Name:     spec.Name,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+
+	if ctx.Err() != nil {
+		return run, fmt.Errorf("container %s did not finish: %s", spec.Name, ctx.Err())
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		run.ExitCode = exitErr.ExitCode()
+		return run, nil
+	}
+
+	return run, err
+}
+
+func removeContainer(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), removeTimeout)
+	defer cancel()
+
+	for _, args := range Runtime.RemoveArgs(name) {
+		cmdlog.Run(Runtime.Command(ctx, args...))
+	}
+}
+
+func randomContainerName() (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	return "w2016fs-run-" + hex.EncodeToString(suffix), nil
+}
+
+func teeTo(capture *bytes.Buffer, stream io.Writer) io.Writer {
+	if stream == nil {
+		return capture
+	}
+
+	return io.MultiWriter(capture, stream)
+}
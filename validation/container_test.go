@@ -0,0 +1,119 @@
+package validation_test
+
+import (
+	"context"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ContainerSpec", func() {
+	It("builds docker run arguments with sorted environment variables", func() {
+		spec := validation.ContainerSpec{
+			Image:     "windows2016fs-test:2019",
+			Cmd:       []string{"powershell", `.\container-test.ps1`},
+			Env:       map[string]string{"SHARE_UNC": `\\host\share`, "SHARE_PASSWORD": "secret"},
+			Name:      "w2016fs-mount",
+			User:      "vcap",
+			Platform:  "windows/amd64",
+			Isolation: "hyperv",
+			Volumes:   []string{`C:\creds:C:\creds`},
+			ExtraArgs: []string{"--dns", "10.0.0.2"},
+		}
+
+		Expect(spec.Args()).To(Equal([]string{
+			"run",
+			"--name", "w2016fs-mount",
+			"--platform", "windows/amd64",
+			"--isolation", "hyperv",
+			"--user", "vcap",
+			"--env", "SHARE_PASSWORD=secret",
+			"--env", `SHARE_UNC=\\host\share`,
+			"--volume", `C:\creds:C:\creds`,
+			"--dns", "10.0.0.2",
+			"windows2016fs-test:2019",
+			"powershell", `.\container-test.ps1`,
+		}))
+	})
+
+	It("passes a gMSA credential spec as a security option", func() {
+		spec := validation.ContainerSpec{Image: "image", Name: "name", CredentialSpec: "file://webapp.json"}
+
+		Expect(spec.Args()).To(Equal([]string{"run", "--name", "name", "--security-opt", "credentialspec=file://webapp.json", "image"}))
+	})
+
+	It("omits optional flags that aren't set", func() {
+		spec := validation.ContainerSpec{Image: "image", Name: "name"}
+
+		Expect(spec.Args()).To(Equal([]string{"run", "--name", "name", "image"}))
+	})
+})
+
+// recordingRuntime records the commands it is asked for, running sleep for
+// `run` and true for everything else.
+type recordingRuntime struct {
+	validation.ContainerRuntime
+
+	mutex    sync.Mutex
+	commands [][]string
+}
+
+func (r *recordingRuntime) Command(ctx context.Context, args ...string) *exec.Cmd {
+	r.mutex.Lock()
+	r.commands = append(r.commands, args)
+	r.mutex.Unlock()
+
+	if args[0] == "run" {
+		return exec.CommandContext(ctx, "sleep", "10")
+	}
+
+	return exec.CommandContext(ctx, "true")
+}
+
+func (r *recordingRuntime) recorded() [][]string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([][]string{}, r.commands...)
+}
+
+var _ = Describe("RunContainer", func() {
+	AfterEach(func() {
+		validation.Runtime = validation.Docker{}
+	})
+
+	cancelMidRun := func(runtime *recordingRuntime) error {
+		validation.Runtime = runtime
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := validation.RunContainer(ctx, validation.ContainerSpec{Name: "w2016fs-run-cancelled", Image: "image"})
+		return err
+	}
+
+	It("removes the container when the run is cancelled", func() {
+		runtime := &recordingRuntime{ContainerRuntime: validation.Docker{}}
+
+		Expect(cancelMidRun(runtime)).To(MatchError(ContainSubstring("container w2016fs-run-cancelled did not finish")))
+		Expect(runtime.recorded()).To(Equal([][]string{
+			{"run", "--name", "w2016fs-run-cancelled", "image"},
+			{"rm", "--force", "w2016fs-run-cancelled"},
+		}))
+	})
+
+	It("deletes the task of a cancelled ctr run before the container", func() {
+		runtime := &recordingRuntime{ContainerRuntime: validation.Ctr{}}
+
+		Expect(cancelMidRun(runtime)).To(MatchError(ContainSubstring("did not finish")))
+		Expect(runtime.recorded()[1:]).To(Equal([][]string{
+			{"task", "delete", "--force", "w2016fs-run-cancelled"},
+			{"container", "rm", "w2016fs-run-cancelled"},
+		}))
+	})
+})
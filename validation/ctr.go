@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ctr is the ctr CLI of containerd, for workers without a Docker daemon,
+// such as Kubernetes Windows nodes. It imports images from OCI image
+// layouts and runs containers through hcsshim, but can't build or inspect
+// images. The namespace is ctr's, from CONTAINERD_NAMESPACE.
+type Ctr struct{}
+
+func (Ctr) Name() string { return "ctr" }
+
+func (c Ctr) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, c.Name(), args...)
+}
+
+// IsolationArgs runs hyperv containers as utility VMs; process isolation
+// is ctr's default on Windows.
+func (Ctr) IsolationArgs(isolation string) []string {
+	if isolation == "hyperv" {
+		return []string{"--isolated"}
+	}
+
+	return nil
+}
+
+// RunArgs translates spec into `ctr run`, which names the container after
+// the image. Volumes become bind mounts, and ExtraArgs are passed as they
+// are, so they must be ctr run flags. ctr can't run containers with a gMSA
+// CredentialSpec.
+func (c Ctr) RunArgs(spec ContainerSpec) []string {
+	args := []string{"run"}
+
+	if spec.Platform != "" {
+		args = append(args, "--platform", spec.Platform)
+	}
+	if spec.Isolation != "" {
+		args = append(args, c.IsolationArgs(spec.Isolation)...)
+	}
+	if spec.User != "" {
+		args = append(args, "--user", spec.User)
+	}
+
+	var keys []string
+	for key := range spec.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--env", fmt.Sprintf("%s=%s", key, spec.Env[key]))
+	}
+
+	for _, volume := range spec.Volumes {
+		host, container := splitVolume(volume)
+		args = append(args, "--mount", fmt.Sprintf("type=bind,src=%s,dst=%s,options=rbind:rw", host, container))
+	}
+
+	args = append(args, spec.ExtraArgs...)
+	args = append(args, spec.Image, spec.Name)
+	return append(args, spec.Cmd...)
+}
+
+// ExecArgs runs cmd as a new process of the container's task.
+func (Ctr) ExecArgs(container string, cmd []string) []string {
+	execID := fmt.Sprintf("exec-%d", time.Now().UnixNano())
+	return append([]string{"task", "exec", "--exec-id", execID, container}, cmd...)
+}
+
+// RemoveArgs kills and deletes the container's task, which outlives a
+// cancelled `ctr run`, before removing the container.
+func (Ctr) RemoveArgs(container string) [][]string {
+	return [][]string{
+		{"task", "delete", "--force", container},
+		{"container", "rm", container},
+	}
+}
+
+// ImportArgs names the archive's index image, since ctr only keeps the
+// names of fully qualified references.
+func (Ctr) ImportArgs(archive, image string) []string {
+	return []string{"images", "import", "--index-name", image, archive}
+}
+
+// splitVolume splits a volume in docker's host:container form, where
+// either side may start with a Windows drive letter.
+func splitVolume(volume string) (string, string) {
+	start := 0
+	if len(volume) > 1 && volume[1] == ':' {
+		start = 2
+	}
+
+	i := strings.Index(volume[start:], ":")
+	if i < 0 {
+		return volume, volume
+	}
+
+	return volume[:start+i], volume[start+i+1:]
+}
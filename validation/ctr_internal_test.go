@@ -0,0 +1,52 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ctr", func() {
+	It("runs a container spec with ctr run", func() {
+		spec := ContainerSpec{
+			Name:      "w2016fs-run-1",
+			Image:     "windows2016fs-candidate:2019",
+			Cmd:       []string{"powershell", "-Command", "exit 0"},
+			Env:       map[string]string{"B": "2", "A": "1"},
+			User:      "vcap",
+			Platform:  "windows/amd64",
+			Isolation: "hyperv",
+			Volumes:   []string{`C:\fixtures:C:\fixtures`},
+		}
+
+		Expect(Ctr{}.RunArgs(spec)).To(Equal([]string{
+			"run",
+			"--platform", "windows/amd64",
+			"--isolated",
+			"--user", "vcap",
+			"--env", "A=1",
+			"--env", "B=2",
+			"--mount", `type=bind,src=C:\fixtures,dst=C:\fixtures,options=rbind:rw`,
+			"windows2016fs-candidate:2019", "w2016fs-run-1",
+			"powershell", "-Command", "exit 0",
+		}))
+	})
+
+	It("deletes the task before removing the container", func() {
+		Expect(Ctr{}.RemoveArgs("w2016fs-run-1")).To(Equal([][]string{
+			{"task", "delete", "--force", "w2016fs-run-1"},
+			{"container", "rm", "w2016fs-run-1"},
+		}))
+	})
+
+	DescribeTable("splitVolume",
+		func(volume, host, container string) {
+			h, c := splitVolume(volume)
+			Expect(h).To(Equal(host))
+			Expect(c).To(Equal(container))
+		},
+		Entry("drive letters on both sides", `C:\fixtures:C:\fixtures`, `C:\fixtures`, `C:\fixtures`),
+		Entry("relative host path", `fixtures:C:\fixtures`, `fixtures`, `C:\fixtures`),
+		Entry("no container path", `C:\fixtures`, `C:\fixtures`, `C:\fixtures`),
+	)
+})
@@ -0,0 +1,17 @@
+//go:build !windows
+// +build !windows
+
+package validation
+
+import "syscall"
+
+// freeSpace returns how many bytes unprivileged users can write to the file
+// system of path.
+func freeSpace(path string) (int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
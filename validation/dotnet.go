@@ -0,0 +1,28 @@
+package validation
+
+import (
+	"strings"
+)
+
+// CheckDotNet checks that candidate has the .NET Framework release of its
+// tag's profile installed.
+func CheckDotNet(candidate Candidate) (CheckResult, error) {
+	profile, err := candidate.Profile()
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	output, err := powershell(candidate.Image, `Get-ChildItem 'HKLM:\SOFTWARE\Microsoft\NET Framework Setup\NDP\v4\Full\' | Get-ItemPropertyValue -Name Release`)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	release := strings.TrimSpace(output)
+	metadata := map[string]string{"release": release}
+
+	if release != profile.FrameworkRelease {
+		return failed("dotnet", metadata, "expected .NET Framework release %s, got %s", profile.FrameworkRelease, release), nil
+	}
+
+	return passed("dotnet", metadata), nil
+}
@@ -0,0 +1,132 @@
+package validation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// engineClient is a minimal Docker Engine API client for the operations the
+// docker CLI can't report on in a structured way. It honours DOCKER_HOST for
+// unix://, npipe:// and tcp:// hosts; TLS is not supported.
+type engineClient struct {
+	http *http.Client
+	base string
+}
+
+func newEngineClient() (*engineClient, error) {
+	host := os.Getenv("DOCKER_HOST")
+	if host == "" {
+		host = defaultDockerHost
+	}
+
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("DOCKER_HOST %q is invalid: %s", host, err)
+	}
+
+	transport := &http.Transport{}
+	base := "http://docker"
+
+	switch hostURL.Scheme {
+	case "unix":
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", hostURL.Path)
+		}
+	case "npipe":
+		pipe := strings.ReplaceAll(hostURL.Path, "/", `\`)
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialPipe(ctx, `\\`+strings.TrimLeft(hostURL.Host+pipe, `\`))
+		}
+	case "tcp", "http":
+		base = "http://" + hostURL.Host
+	default:
+		return nil, fmt.Errorf("DOCKER_HOST scheme %q is not supported", hostURL.Scheme)
+	}
+
+	return &engineClient{http: &http.Client{Transport: transport}, base: base}, nil
+}
+
+// do sends a request to the Engine API and fails on any non-2xx status,
+// including the daemon's error message.
+func (c *engineClient) do(ctx context.Context, method, path string, query url.Values, header http.Header) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.base+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+
+	response, err := c.http.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode/100 != 2 {
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		return nil, &engineError{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+
+	return response, nil
+}
+
+type engineError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *engineError) Error() string {
+	return fmt.Sprintf("docker engine returned %d: %s", e.StatusCode, e.Message)
+}
+
+// jsonMessage is one line of a streamed Engine API progress response.
+type jsonMessage struct {
+	Status string          `json:"status"`
+	Error  string          `json:"error"`
+	Aux    json.RawMessage `json:"aux"`
+}
+
+// readJSONMessages consumes a progress stream, calling onAux for every aux
+// payload and failing on the first error message.
+func readJSONMessages(stream io.Reader, onAux func(json.RawMessage) error) error {
+	decoder := json.NewDecoder(stream)
+	for {
+		var message jsonMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if message.Error != "" {
+			return fmt.Errorf("%s", message.Error)
+		}
+
+		if len(message.Aux) > 0 && onAux != nil {
+			if err := onAux(message.Aux); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// registryAuthHeader encodes credentials in the X-Registry-Auth format.
+func registryAuthHeader(auth RegistryAuth) (string, error) {
+	encoded, err := json.Marshal(auth)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
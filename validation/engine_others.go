@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package validation
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+const defaultDockerHost = "unix:///var/run/docker.sock"
+
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
@@ -0,0 +1,14 @@
+package validation
+
+import (
+	"context"
+	"net"
+
+	"github.com/Microsoft/go-winio"
+)
+
+const defaultDockerHost = "npipe:////./pipe/docker_engine"
+
+func dialPipe(ctx context.Context, path string) (net.Conn, error) {
+	return winio.DialPipeContext(ctx, path)
+}
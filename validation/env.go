@@ -0,0 +1,165 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultEnvVary are the variables a new environment baseline expects to
+// vary: they describe the container's name and the host's processors.
+var DefaultEnvVary = []string{
+	"COMPUTERNAME",
+	"USERDOMAIN",
+	"USERDOMAIN_ROAMINGPROFILE",
+	"NUMBER_OF_PROCESSORS",
+	"PROCESSOR_IDENTIFIER",
+	"PROCESSOR_LEVEL",
+	"PROCESSOR_REVISION",
+}
+
+// EnvBaseline is the default environment of a container of an image.
+type EnvBaseline struct {
+	// Env maps each variable to its value.
+	Env map[string]string `json:"env"`
+
+	// Vary are path.Match patterns of the variables whose values may differ
+	// from Env; they must still be set. Variable names are compared
+	// case-insensitively, as Windows does.
+	Vary []string `json:"vary"`
+}
+
+// EnvBaselinePath is the environment baseline of tag, with its variant, in
+// FixturesDir.
+func EnvBaselinePath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("expected-container-env-%s.json", tag))
+}
+
+// ReadEnvBaseline reads the environment baseline at path.
+func ReadEnvBaseline(path string) (EnvBaseline, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return EnvBaseline{}, err
+	}
+
+	var baseline EnvBaseline
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return EnvBaseline{}, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return baseline, nil
+}
+
+// WriteEnvBaseline writes baseline as indented JSON, its variables sorted.
+func WriteEnvBaseline(baseline EnvBaseline, w io.Writer) error {
+	content, err := json.MarshalIndent(baseline, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// ContainerEnv returns the environment a new container of image starts
+// PowerShell with.
+func ContainerEnv(image string) (map[string]string, error) {
+	output, err := powershell(image, "ConvertTo-Json -Compress -InputObject @(Get-ChildItem env: | Select-Object Name, Value)")
+	if err != nil {
+		return nil, err
+	}
+
+	var variables []struct {
+		Name  string
+		Value string
+	}
+	if err := unmarshalPSJSON([]byte(output), &variables); err != nil {
+		return nil, fmt.Errorf("parsing the environment of %s: %s", image, err)
+	}
+
+	env := map[string]string{}
+	for _, variable := range variables {
+		env[variable.Name] = variable.Value
+	}
+
+	return env, nil
+}
+
+// EnvChange is a variable whose value differs from the baseline.
+type EnvChange struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+// EnvDiff is how an environment differs from a baseline.
+type EnvDiff struct {
+	Missing    []string
+	Unexpected []string
+	Changed    []EnvChange
+}
+
+// Empty reports whether the environment matches the baseline.
+func (d EnvDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 && len(d.Changed) == 0
+}
+
+// String lists the differences, one per line.
+func (d EnvDiff) String() string {
+	var lines []string
+	for _, name := range d.Missing {
+		lines = append(lines, "missing: "+name)
+	}
+	for _, name := range d.Unexpected {
+		lines = append(lines, "unexpected: "+name)
+	}
+	for _, change := range d.Changed {
+		lines = append(lines, fmt.Sprintf("changed: %s=%q, not %q", change.Name, change.Actual, change.Expected))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares env with the baseline.
+func (b EnvBaseline) Diff(env map[string]string) (EnvDiff, error) {
+	actual := map[string]string{}
+	for name, value := range env {
+		actual[strings.ToUpper(name)] = value
+	}
+
+	var diff EnvDiff
+	expected := map[string]bool{}
+	for name, value := range b.Env {
+		expected[strings.ToUpper(name)] = true
+
+		actualValue, ok := actual[strings.ToUpper(name)]
+		if !ok {
+			diff.Missing = append(diff.Missing, name)
+			continue
+		}
+
+		varies, err := matchesAnyFold(b.Vary, name)
+		if err != nil {
+			return EnvDiff{}, fmt.Errorf("vary %s", err)
+		}
+		if !varies && actualValue != value {
+			diff.Changed = append(diff.Changed, EnvChange{Name: name, Expected: value, Actual: actualValue})
+		}
+	}
+
+	for name := range env {
+		if !expected[strings.ToUpper(name)] {
+			diff.Unexpected = append(diff.Unexpected, name)
+		}
+	}
+
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Unexpected)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Name < diff.Changed[j].Name })
+
+	return diff, nil
+}
@@ -0,0 +1,49 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EnvBaseline", func() {
+	baseline := validation.EnvBaseline{
+		Env: map[string]string{
+			"Path":                 `C:\Windows\system32;C:\Windows`,
+			"TEMP":                 `C:\Users\ContainerAdministrator\AppData\Local\Temp`,
+			"PROCESSOR_IDENTIFIER": "Intel64 Family 6 Model 85 Stepping 7, GenuineIntel",
+			"COMPUTERNAME":         "4C2A1B3D5E6F",
+		},
+		Vary: []string{"PROCESSOR_*", "computername"},
+	}
+
+	It("compares names case-insensitively and lets allowed values vary", func() {
+		diff, err := baseline.Diff(map[string]string{
+			"PATH":                 `C:\Windows\system32;C:\Windows`,
+			"TEMP":                 `C:\Users\ContainerAdministrator\AppData\Local\Temp`,
+			"PROCESSOR_IDENTIFIER": "AMD64 Family 23 Model 49 Stepping 0, AuthenticAMD",
+			"COMPUTERNAME":         "9F8E7D6C5B4A",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.Empty()).To(BeTrue(), diff.String())
+	})
+
+	It("lists missing, unexpected and changed variables", func() {
+		diff, err := baseline.Diff(map[string]string{
+			"Path":                 `C:\Windows\system32`,
+			"PROCESSOR_IDENTIFIER": "AMD64 Family 23 Model 49 Stepping 0, AuthenticAMD",
+			"COMPUTERNAME":         "9F8E7D6C5B4A",
+			"DOTNET_ROOT":          `C:\dotnet`,
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.String()).To(Equal(`missing: TEMP
+unexpected: DOTNET_ROOT
+changed: Path="C:\\Windows\\system32", not "C:\\Windows\\system32;C:\\Windows"`))
+	})
+
+	It("fails on invalid patterns", func() {
+		_, err := validation.EnvBaseline{Env: map[string]string{"TEMP": "x"}, Vary: []string{"["}}.Diff(map[string]string{"TEMP": "x"})
+		Expect(err).To(MatchError(ContainSubstring(`vary pattern "["`)))
+	})
+})
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// featuresScript prints "<name> <enabled>" for every Windows feature. Server
+// SKUs expose Get-WindowsFeature; client SKUs only Get-WindowsOptionalFeature,
+// which reads the same state as dism /online /get-features.
+const featuresScript = `
+if (Get-Command Get-WindowsFeature -ErrorAction SilentlyContinue) {
+    Get-WindowsFeature | ForEach-Object { "$($_.Name) $($_.Installed)" }
+} else {
+    Get-WindowsOptionalFeature -Online | ForEach-Object { "$($_.FeatureName) $($_.State -eq 'Enabled')" }
+}
+`
+
+// FeaturesBaselinePath is the list of the features enabled in tag's image,
+// in FixturesDir.
+func FeaturesBaselinePath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("enabled-features-%s.json", tag))
+}
+
+// WindowsFeatures returns every Windows feature known to image and whether
+// it is enabled.
+func WindowsFeatures(image string) (map[string]bool, error) {
+	output, err := powershell(image, featuresScript)
+	if err != nil {
+		return nil, err
+	}
+
+	features := map[string]bool{}
+	for _, line := range NonEmptyLines(output) {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected feature line %q", line)
+		}
+
+		features[fields[0]] = strings.EqualFold(fields[1], "True")
+	}
+
+	return features, nil
+}
+
+// EnabledFeatures returns the names of the enabled features, sorted, as a
+// features baseline lists them.
+func EnabledFeatures(features map[string]bool) []string {
+	var enabled []string
+	for name, on := range features {
+		if on {
+			enabled = append(enabled, name)
+		}
+	}
+	sort.Strings(enabled)
+
+	return enabled
+}
+
+// FeatureChanges lists the features enabled although baseline doesn't list
+// them, and those baseline lists that are no longer enabled, sorted by name.
+func FeatureChanges(baseline []string, features map[string]bool) []string {
+	listed := map[string]bool{}
+	var changes []string
+	for _, name := range baseline {
+		listed[name] = true
+		if !features[name] {
+			changes = append(changes, name+": no longer enabled")
+		}
+	}
+
+	for _, name := range EnabledFeatures(features) {
+		if !listed[name] {
+			changes = append(changes, name+": enabled, but not in the baseline")
+		}
+	}
+	sort.Strings(changes)
+
+	return changes
+}
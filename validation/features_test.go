@@ -0,0 +1,31 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FeatureChanges", func() {
+	It("lists features enabled outside of the baseline and those no longer enabled", func() {
+		features := map[string]bool{
+			"Web-Webserver":  true,
+			"Web-WebSockets": false,
+			"Telnet-Client":  true,
+			"PowerShellRoot": true,
+			"Hyper-V":        false,
+		}
+
+		Expect(validation.FeatureChanges([]string{"PowerShellRoot", "Web-WebSockets", "Web-Webserver"}, features)).To(Equal([]string{
+			"Telnet-Client: enabled, but not in the baseline",
+			"Web-WebSockets: no longer enabled",
+		}))
+	})
+
+	It("finds nothing to report when the features match", func() {
+		features := map[string]bool{"Web-Webserver": true, "Hyper-V": false}
+
+		Expect(validation.FeatureChanges(validation.EnabledFeatures(features), features)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,61 @@
+package validation
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FixturesDigestFile is the file, relative to the fixtures directory, that
+// records the reviewed digest of the fixtures. It is excluded from the
+// digest itself.
+const FixturesDigestFile = ".digest"
+
+// FixturesDigest returns a SHA256 over the relative paths and contents of
+// every file under dir other than FixturesDigestFile, visited in a stable
+// order.
+func FixturesDigest(dir string) (string, error) {
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() && path != filepath.Join(dir, FixturesDigestFile) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	hash := sha256.New()
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00", filepath.ToSlash(rel))
+
+		if err := hashFile(hash, path); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func hashFile(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,53 @@
+package validation_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FixturesDigest", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "fixtures")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, "smoke-app"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "odbc.reg"), []byte("REGEDIT4"), 0644)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "smoke-app", "app.ps1"), []byte("exit 0"), 0644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	digest := func() string {
+		d, err := validation.FixturesDigest(dir)
+		Expect(err).ToNot(HaveOccurred())
+		return d
+	}
+
+	It("is stable and ignores the recorded digest", func() {
+		before := digest()
+		Expect(ioutil.WriteFile(filepath.Join(dir, validation.FixturesDigestFile), []byte(before), 0644)).To(Succeed())
+		Expect(digest()).To(Equal(before))
+	})
+
+	It("changes when a fixture is edited or renamed", func() {
+		before := digest()
+
+		Expect(ioutil.WriteFile(filepath.Join(dir, "odbc.reg"), []byte("REGEDIT5"), 0644)).To(Succeed())
+		edited := digest()
+		Expect(edited).ToNot(Equal(before))
+
+		Expect(os.Rename(filepath.Join(dir, "odbc.reg"), filepath.Join(dir, "odbc2.reg"))).To(Succeed())
+		Expect(digest()).ToNot(Equal(edited))
+	})
+})
@@ -0,0 +1,203 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// OperationTimeout bounds each docker invocation made by the package's
+// exported helpers.
+var OperationTimeout = 10 * time.Minute
+
+// InspectImage unmarshals the output of `docker image inspect image` into v,
+// which should be a struct describing the fields of interest.
+func InspectImage(image string, v interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	output, err := cmdlog.Output(Runtime.Command(ctx, "image", "inspect", image))
+	if err != nil {
+		return fmt.Errorf("%s image inspect %s failed: %s", Runtime.Name(), image, err)
+	}
+
+	var inspections []json.RawMessage
+	if err := json.Unmarshal(output, &inspections); err != nil {
+		return err
+	}
+
+	if len(inspections) != 1 {
+		return fmt.Errorf("expected one image for %s, got %d", image, len(inspections))
+	}
+
+	return json.Unmarshal(inspections[0], v)
+}
+
+// powershell runs script in image, as described by RunIn, and returns its
+// stdout, failing on a non-zero exit code.
+func powershell(image, script string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	run, err := RunIn(ctx, image, []string{"powershell", "-Command", script})
+	if err != nil {
+		return "", err
+	}
+
+	if run.ExitCode != 0 {
+		return run.Stdout, fmt.Errorf("powershell in %s exited %d: %s", image, run.ExitCode, strings.TrimSpace(run.Stderr))
+	}
+
+	return run.Stdout, nil
+}
+
+// NonEmptyLines returns the lines of output with surrounding whitespace
+// trimmed, dropping blank ones.
+func NonEmptyLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// ImageID returns the ID of a local image, the digest of its configuration.
+func ImageID(image string) (string, error) {
+	var inspection struct {
+		ID string `json:"Id"`
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return "", err
+	}
+
+	return inspection.ID, nil
+}
+
+// ImageLayers returns the diff IDs of image's layers, base first.
+func ImageLayers(image string) ([]string, error) {
+	var inspection struct {
+		RootFS struct {
+			Layers []string
+		}
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return nil, err
+	}
+
+	return inspection.RootFS.Layers, nil
+}
+
+// ImageLabels returns the labels of image's configuration.
+func ImageLabels(image string) (map[string]string, error) {
+	var inspection struct {
+		Config struct {
+			Labels map[string]string
+		}
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return nil, err
+	}
+
+	return inspection.Config.Labels, nil
+}
+
+// ImageSize returns the size of a local image in bytes, including its base
+// layers.
+func ImageSize(image string) (int64, error) {
+	var inspection struct {
+		Size int64
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return 0, err
+	}
+
+	return inspection.Size, nil
+}
+
+// ImageOSBuild returns the Windows build image was made from, as its
+// configuration records it, e.g. 17763 for an os.version of 10.0.17763.1879.
+func ImageOSBuild(image string) (int, error) {
+	var inspection struct {
+		OsVersion string
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return 0, err
+	}
+
+	parts := strings.Split(inspection.OsVersion, ".")
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("%s has no Windows OS version, got %q", image, inspection.OsVersion)
+	}
+
+	return strconv.Atoi(parts[2])
+}
+
+// ImageRepoDigests returns the repository digests of a local image, e.g.
+// cloudfoundry/windows2016fs@sha256:..., which it only has once it was
+// pushed or pulled.
+func ImageRepoDigests(image string) ([]string, error) {
+	var inspection struct {
+		RepoDigests []string
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return nil, err
+	}
+
+	return inspection.RepoDigests, nil
+}
+
+// HistoryEntry is a step of an image's history: the instruction it was
+// created by and the size of the layer it added, if any.
+type HistoryEntry struct {
+	CreatedBy string
+	Size      int64
+}
+
+// ImageHistory returns the history of a local image, base first.
+func ImageHistory(image string) ([]HistoryEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	output, err := cmdlog.Output(Runtime.Command(ctx, "image", "history", "--no-trunc", "--human=false", "--format", "{{.Size}}\t{{json .CreatedBy}}", image))
+	if err != nil {
+		return nil, fmt.Errorf("docker image history %s failed: %s", image, err)
+	}
+
+	return parseHistory(string(output))
+}
+
+// parseHistory parses the lines of docker image history, which lists the
+// newest step first.
+func parseHistory(output string) ([]HistoryEntry, error) {
+	lines := NonEmptyLines(output)
+	history := make([]HistoryEntry, len(lines))
+	for i, line := range lines {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("unexpected docker image history line %q", line)
+		}
+
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected docker image history size %q", fields[0])
+		}
+
+		var entry HistoryEntry
+		if err := json.Unmarshal([]byte(fields[1]), &entry.CreatedBy); err != nil {
+			return nil, fmt.Errorf("unexpected docker image history line %q: %s", line, err)
+		}
+		entry.Size = size
+
+		history[len(lines)-1-i] = entry
+	}
+
+	return history, nil
+}
@@ -0,0 +1,23 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("parseHistory", func() {
+	It("lists the steps base first", func() {
+		history, err := parseHistory("0\t\"cmd /S /C #(nop)  USER vcap\"\n300\t\"cmd /S /C #(nop) COPY file:abc in C:\\\\Windows \"\n4000\t\"Apply image 10.0.17763.1\"\n")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(history).To(Equal([]HistoryEntry{
+			{CreatedBy: "Apply image 10.0.17763.1", Size: 4000},
+			{CreatedBy: `cmd /S /C #(nop) COPY file:abc in C:\Windows `, Size: 300},
+			{CreatedBy: "cmd /S /C #(nop)  USER vcap"},
+		}))
+	})
+
+	It("fails on unexpected lines", func() {
+		_, err := parseHistory("4.2GB\t\"Apply image\"\n")
+		Expect(err).To(MatchError(`unexpected docker image history size "4.2GB"`))
+	})
+})
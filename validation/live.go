@@ -0,0 +1,67 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+var (
+	liveMutex      sync.RWMutex
+	liveContainers = map[string]string{}
+)
+
+// UseLiveContainer makes checks against image run their commands with
+// `docker exec` in container, which must be a running container of image,
+// instead of starting a new container per command.
+func UseLiveContainer(image, container string) {
+	liveMutex.Lock()
+	defer liveMutex.Unlock()
+
+	liveContainers[image] = container
+}
+
+func liveContainer(image string) (string, bool) {
+	liveMutex.RLock()
+	defer liveMutex.RUnlock()
+
+	container, ok := liveContainers[image]
+	return container, ok
+}
+
+// RunIn runs cmd in the live container registered for image or, failing
+// that, in a new, automatically removed container of image.
+func RunIn(ctx context.Context, image string, cmd []string) (ContainerRun, error) {
+	container, ok := liveContainer(image)
+	if !ok {
+		return RunContainer(ctx, ContainerSpec{Image: image, Cmd: cmd, Platform: Platform, Isolation: Isolation})
+	}
+
+	var stdout, stderr bytes.Buffer
+	command := Runtime.Command(ctx, Runtime.ExecArgs(container, cmd)...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+
+	start := time.Now()
+	err := cmdlog.Run(command)
+
+	run := ContainerRun{
+		Name:     container,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		Duration: time.Since(start),
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		run.ExitCode = exitErr.ExitCode()
+		return run, nil
+	}
+
+	return run, err
+}
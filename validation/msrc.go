@@ -0,0 +1,268 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MSRCURL is the base URL of the Microsoft Security Response Center's
+// security update API.
+var MSRCURL = "https://api.msrc.microsoft.com/cvrf/v2.0"
+
+var msrcClient = &http.Client{
+	Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+}
+
+// patchLevelScript prints the full OS build, e.g. "build 10.0.17763.1999",
+// and a "hotfix KB5003646" line for each installed update.
+const patchLevelScript = `
+$version = Get-ItemProperty 'HKLM:\SOFTWARE\Microsoft\Windows NT\CurrentVersion'
+"build $($version.CurrentMajorVersionNumber).$($version.CurrentMinorVersionNumber).$($version.CurrentBuildNumber).$($version.UBR)"
+Get-HotFix | ForEach-Object { "hotfix $($_.HotFixID)" }
+`
+
+// CumulativeUpdate is the monthly security update released for a product.
+type CumulativeUpdate struct {
+	// KB is the number of the update's knowledge base article, e.g.
+	// 5003646.
+	KB string
+
+	// Month is the MSRC release the update is part of, e.g. 2021-Jun.
+	Month    string
+	Released time.Time
+
+	// FixedBuild is the OS build the update brings the product to, e.g.
+	// 10.0.17763.1999.
+	FixedBuild string
+
+	// Behind is how many monthly releases are newer than Month, including
+	// those without an update for the product; 0 for the latest release.
+	Behind int
+}
+
+// CumulativeUpdates returns the security updates of product, as MSRC names
+// it (e.g. "Windows Server 2019 (Server Core installation)"), from the last
+// months monthly releases, newest first. Releases without an update for
+// product are skipped.
+func CumulativeUpdates(ctx context.Context, product string, months int) ([]CumulativeUpdate, error) {
+	var index struct {
+		Value []struct {
+			ID                 string
+			InitialReleaseDate time.Time
+		} `json:"value"`
+	}
+	if err := getMSRC(ctx, "/updates", &index); err != nil {
+		return nil, err
+	}
+
+	releases := index.Value
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].InitialReleaseDate.After(releases[j].InitialReleaseDate)
+	})
+	if len(releases) > months {
+		releases = releases[:months]
+	}
+
+	var updates []CumulativeUpdate
+	for i, release := range releases {
+		var document cvrfDocument
+		if err := getMSRC(ctx, "/cvrf/"+release.ID, &document); err != nil {
+			return nil, err
+		}
+
+		update, ok := document.cumulativeUpdate(product)
+		if !ok {
+			continue
+		}
+		update.Month = release.ID
+		update.Released = release.InitialReleaseDate
+		update.Behind = i
+
+		updates = append(updates, update)
+	}
+
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("MSRC lists no updates for %q in the last %d releases", product, months)
+	}
+
+	return updates, nil
+}
+
+func getMSRC(ctx context.Context, path string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, MSRCURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := msrcClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", request.URL, response.Status)
+	}
+
+	if err := json.NewDecoder(response.Body).Decode(v); err != nil {
+		return fmt.Errorf("parsing %s: %s", request.URL, err)
+	}
+
+	return nil
+}
+
+// cvrfDocument is the part of a monthly MSRC release that lists the
+// updates fixing each vulnerability.
+type cvrfDocument struct {
+	ProductTree struct {
+		FullProductName []struct {
+			ProductID string
+			Value     string
+		}
+	}
+	Vulnerability []struct {
+		Remediations []struct {
+			Description struct {
+				Value string
+			}
+			ProductID  []string
+			SubType    string
+			FixedBuild string
+		}
+	}
+}
+
+// cumulativeUpdate returns the security update of product that brings it
+// to the highest build.
+func (d cvrfDocument) cumulativeUpdate(product string) (CumulativeUpdate, bool) {
+	ids := map[string]bool{}
+	for _, name := range d.ProductTree.FullProductName {
+		if name.Value == product {
+			ids[name.ProductID] = true
+		}
+	}
+
+	var latest CumulativeUpdate
+	for _, vulnerability := range d.Vulnerability {
+		for _, remediation := range vulnerability.Remediations {
+			if remediation.SubType != "Security Update" || remediation.FixedBuild == "" {
+				continue
+			}
+
+			applies := false
+			for _, id := range remediation.ProductID {
+				applies = applies || ids[id]
+			}
+			if !applies {
+				continue
+			}
+
+			if latest.FixedBuild == "" || compareBuilds(remediation.FixedBuild, latest.FixedBuild) > 0 {
+				latest = CumulativeUpdate{KB: strings.TrimPrefix(remediation.Description.Value, "KB"), FixedBuild: remediation.FixedBuild}
+			}
+		}
+	}
+
+	return latest, latest.FixedBuild != ""
+}
+
+// PatchLevel is the servicing state of an image.
+type PatchLevel struct {
+	// Build is the full OS build, e.g. 10.0.17763.1999.
+	Build string
+
+	// Hotfixes are the IDs of the installed updates, e.g. KB5003646.
+	Hotfixes []string
+}
+
+// ImagePatchLevel reads the full OS build and the installed hotfixes of
+// image.
+func ImagePatchLevel(image string) (PatchLevel, error) {
+	output, err := powershell(image, patchLevelScript)
+	if err != nil {
+		return PatchLevel{}, err
+	}
+
+	var level PatchLevel
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 2 && fields[0] == "build":
+			level.Build = fields[1]
+		case len(fields) == 2 && fields[0] == "hotfix":
+			level.Hotfixes = append(level.Hotfixes, fields[1])
+		}
+	}
+
+	if level.Build == "" {
+		return PatchLevel{}, fmt.Errorf("no OS build in the output of %s: %q", image, output)
+	}
+
+	return level, nil
+}
+
+// Includes reports whether update is installed, either as a hotfix or
+// because the image's build is at least the one update brings.
+func (l PatchLevel) Includes(update CumulativeUpdate) bool {
+	for _, hotfix := range l.Hotfixes {
+		if strings.EqualFold(hotfix, "KB"+update.KB) {
+			return true
+		}
+	}
+
+	return sameBase(l.Build, update.FixedBuild) && compareBuilds(l.Build, update.FixedBuild) >= 0
+}
+
+// MonthsBehind returns how many monthly releases are newer than the latest
+// of updates, newest first, that level includes. It fails when level
+// includes none of them, since it is then behind by more releases than were
+// fetched.
+func MonthsBehind(level PatchLevel, updates []CumulativeUpdate) (int, error) {
+	for _, update := range updates {
+		if level.Includes(update) {
+			return update.Behind, nil
+		}
+	}
+
+	if len(updates) == 0 {
+		return 0, fmt.Errorf("no updates to compare build %s with", level.Build)
+	}
+
+	oldest := updates[len(updates)-1]
+	return 0, fmt.Errorf("build %s includes none of the cumulative updates since KB%s (%s)", level.Build, oldest.KB, oldest.Month)
+}
+
+// sameBase reports whether two builds differ only in their revision.
+func sameBase(a, b string) bool {
+	return a[:strings.LastIndex(a, ".")+1] == b[:strings.LastIndex(b, ".")+1]
+}
+
+// compareBuilds compares dotted build numbers part by part.
+func compareBuilds(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
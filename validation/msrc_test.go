@@ -0,0 +1,140 @@
+package validation_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CumulativeUpdates", func() {
+	var (
+		server  *httptest.Server
+		msrcURL string
+	)
+
+	BeforeEach(func() {
+		documents := map[string]string{
+			"/updates": `{"value": [
+				{"ID": "2021-Apr", "InitialReleaseDate": "2021-04-13T07:00:00Z"},
+				{"ID": "2021-Jul", "InitialReleaseDate": "2021-07-13T07:00:00Z"},
+				{"ID": "2021-Jun", "InitialReleaseDate": "2021-06-08T07:00:00Z"},
+				{"ID": "2021-May", "InitialReleaseDate": "2021-05-11T07:00:00Z"}
+			]}`,
+			"/cvrf/2021-Jul": `{
+				"ProductTree": {"FullProductName": [{"ProductID": "11572", "Value": "Windows 10 Version 1809 for x64-based Systems"}]},
+				"Vulnerability": [{"Remediations": [
+					{"Description": {"Value": "5004244"}, "ProductID": ["11572"], "SubType": "Security Update", "FixedBuild": "10.0.17763.2061"}
+				]}]
+			}`,
+			"/cvrf/2021-Jun": `{
+				"ProductTree": {"FullProductName": [
+					{"ProductID": "11571", "Value": "Windows Server 2019 (Server Core installation)"},
+					{"ProductID": "11572", "Value": "Windows 10 Version 1809 for x64-based Systems"}
+				]},
+				"Vulnerability": [
+					{"Remediations": [
+						{"Description": {"Value": "5003646"}, "ProductID": ["11571", "11572"], "SubType": "Security Update", "FixedBuild": "10.0.17763.1999"},
+						{"Description": {"Value": "5003711"}, "ProductID": ["11571"], "SubType": "Servicing Stack Update"}
+					]},
+					{"Remediations": [
+						{"Description": {"Value": "5003243"}, "ProductID": ["11571"], "SubType": "Security Update", "FixedBuild": "10.0.17763.1971"}
+					]}
+				]
+			}`,
+			"/cvrf/2021-May": `{
+				"ProductTree": {"FullProductName": [{"ProductID": "11571", "Value": "Windows Server 2019 (Server Core installation)"}]},
+				"Vulnerability": [{"Remediations": [
+					{"Description": {"Value": "KB5003171"}, "ProductID": ["11571"], "SubType": "Security Update", "FixedBuild": "10.0.17763.1935"}
+				]}]
+			}`,
+		}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			document, ok := documents[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write([]byte(document))
+		}))
+
+		msrcURL = validation.MSRCURL
+		validation.MSRCURL = server.URL
+	})
+
+	AfterEach(func() {
+		validation.MSRCURL = msrcURL
+		server.Close()
+	})
+
+	It("returns the latest security update of each recent release, newest first", func() {
+		updates, err := validation.CumulativeUpdates(context.Background(), "Windows Server 2019 (Server Core installation)", 3)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(updates).To(HaveLen(2))
+		Expect(updates[0].Month).To(Equal("2021-Jun"))
+		Expect(updates[0].KB).To(Equal("5003646"))
+		Expect(updates[0].FixedBuild).To(Equal("10.0.17763.1999"))
+		Expect(updates[1].KB).To(Equal("5003171"))
+	})
+
+	It("counts the releases without an update for the product as newer releases", func() {
+		updates, err := validation.CumulativeUpdates(context.Background(), "Windows Server 2019 (Server Core installation)", 3)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(updates[0].Behind).To(Equal(1))
+		Expect(updates[1].Behind).To(Equal(2))
+	})
+
+	It("fails when no release has an update for the product", func() {
+		_, err := validation.CumulativeUpdates(context.Background(), "Windows Server 2022 (Server Core installation)", 2)
+		Expect(err).To(MatchError(`MSRC lists no updates for "Windows Server 2022 (Server Core installation)" in the last 2 releases`))
+	})
+
+	It("fails when a release can't be fetched", func() {
+		_, err := validation.CumulativeUpdates(context.Background(), "Windows Server 2019 (Server Core installation)", 4)
+		Expect(err).To(MatchError(ContainSubstring("/cvrf/2021-Apr: 404 Not Found")))
+	})
+})
+
+var _ = Describe("MonthsBehind", func() {
+	updates := []validation.CumulativeUpdate{
+		{KB: "5003646", Month: "2021-Jun", FixedBuild: "10.0.17763.1999", Behind: 0},
+		{KB: "5003171", Month: "2021-May", FixedBuild: "10.0.17763.1935", Behind: 1},
+		{KB: "5001342", Month: "2021-Apr", FixedBuild: "10.0.17763.1879", Behind: 2},
+	}
+
+	It("counts the releases newer than the latest installed one", func() {
+		Expect(validation.MonthsBehind(validation.PatchLevel{Build: "10.0.17763.1999"}, updates)).To(Equal(0))
+		Expect(validation.MonthsBehind(validation.PatchLevel{Build: "10.0.17763.1970"}, updates)).To(Equal(1))
+	})
+
+	It("counts updates installed as hotfixes", func() {
+		level := validation.PatchLevel{Build: "10.0.17763.1800", Hotfixes: []string{"KB4589208", "kb5001342"}}
+		Expect(validation.MonthsBehind(level, updates)).To(Equal(2))
+	})
+
+	It("counts releases that were skipped for having no update for the product", func() {
+		skipped := []validation.CumulativeUpdate{
+			{KB: "5003171", Month: "2021-May", FixedBuild: "10.0.17763.1935", Behind: 1},
+			{KB: "5001342", Month: "2021-Apr", FixedBuild: "10.0.17763.1879", Behind: 2},
+		}
+
+		Expect(validation.MonthsBehind(validation.PatchLevel{Build: "10.0.17763.1935"}, skipped)).To(Equal(1))
+	})
+
+	It("fails when none of the updates is installed", func() {
+		_, err := validation.MonthsBehind(validation.PatchLevel{Build: "10.0.17763.1800"}, updates)
+		Expect(err).To(MatchError("build 10.0.17763.1800 includes none of the cumulative updates since KB5001342 (2021-Apr)"))
+	})
+
+	It("doesn't compare revisions of different builds", func() {
+		_, err := validation.MonthsBehind(validation.PatchLevel{Build: "10.0.20348.2000"}, updates)
+		Expect(err).To(HaveOccurred())
+	})
+})
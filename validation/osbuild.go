@@ -0,0 +1,105 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CheckOSBuild checks that the Windows build reported inside candidate lies
+// within the bounds of its tag's profile.
+func CheckOSBuild(candidate Candidate) (CheckResult, error) {
+	profile, err := candidate.Profile()
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	build, err := OSBuild(candidate.Image)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	metadata := map[string]string{"build": strconv.Itoa(build)}
+
+	if build < profile.MinOSBuild || build > profile.MaxOSBuild {
+		if channel := ChannelOfBuild(build); channel != "" {
+			return failed("os-build", metadata, "expected a %s build between %d and %d, got %d, a %s build; the base image tag is probably wrong", profile.Channel, profile.MinOSBuild, profile.MaxOSBuild, build, channel), nil
+		}
+
+		return failed("os-build", metadata, "expected a %s build between %d and %d, got %d", profile.Channel, profile.MinOSBuild, profile.MaxOSBuild, build), nil
+	}
+
+	metadata["channel"] = profile.Channel
+	return passed("os-build", metadata), nil
+}
+
+// ChannelOfBuild returns the LTSC release of the profile whose builds
+// include build, or "" when no profile's do.
+func ChannelOfBuild(build int) string {
+	for _, tag := range KnownTags() {
+		if profile := Profiles[tag]; build >= profile.MinOSBuild && build <= profile.MaxOSBuild {
+			return profile.Channel
+		}
+	}
+
+	return ""
+}
+
+// VerifyBaseImageTag checks that image, the base a Dockerfile of tag is
+// built FROM, is tagged for tag's LTSC release, e.g. 1809 or ltsc2019 for
+// 2019.
+func VerifyBaseImageTag(tag, image string) error {
+	profile, err := ProfileFor(tag)
+	if err != nil {
+		return err
+	}
+
+	name := image
+	if at := strings.Index(name, "@"); at >= 0 {
+		name = name[:at]
+	}
+	colon := strings.LastIndex(name, ":")
+	if colon < strings.LastIndex(name, "/") {
+		return fmt.Errorf("base image %s has no tag; expected one of the %s tags (%s)", image, profile.Channel, strings.Join(profile.BaseTags, ", "))
+	}
+
+	baseTag := name[colon+1:]
+	for _, prefix := range profile.BaseTags {
+		if baseTag == prefix || strings.HasPrefix(baseTag, prefix+"-") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("base image %s isn't tagged for %s; expected one of %s", image, profile.Channel, strings.Join(profile.BaseTags, ", "))
+}
+
+// OSBuild returns the build number reported by `cmd /c ver` in image, e.g.
+// 17763 for "Microsoft Windows [Version 10.0.17763.1879]".
+func OSBuild(image string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	run, err := RunIn(ctx, image, []string{"cmd", "/c", "ver"})
+	if err != nil {
+		return 0, err
+	}
+
+	if run.ExitCode != 0 {
+		return 0, fmt.Errorf("ver in %s exited %d: %s", image, run.ExitCode, strings.TrimSpace(run.Stderr))
+	}
+
+	output := run.Stdout
+	start := strings.Index(output, "[Version ")
+	end := strings.Index(output, "]")
+	if start < 0 || end < start {
+		return 0, fmt.Errorf("unexpected ver output %q", output)
+	}
+
+	parts := strings.Split(output[start+len("[Version "):end], ".")
+	if len(parts) < 3 {
+		return 0, fmt.Errorf("unexpected ver output %q", output)
+	}
+
+	return strconv.Atoi(parts[2])
+}
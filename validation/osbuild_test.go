@@ -0,0 +1,32 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LTSC channels", func() {
+	It("maps builds to the release of their profile", func() {
+		Expect(validation.ChannelOfBuild(17763)).To(Equal("ltsc2019"))
+		Expect(validation.ChannelOfBuild(20348)).To(Equal("ltsc2022"))
+		Expect(validation.ChannelOfBuild(14393)).To(BeEmpty())
+	})
+
+	DescribeTable("accepts base images tagged for the tag's release",
+		func(tag, image string) {
+			Expect(validation.VerifyBaseImageTag(tag, image)).To(Succeed())
+		},
+		Entry("1809", "2019", "mcr.microsoft.com/windows/servercore:1809"),
+		Entry("ltsc2019 for a platform", "2019", "mcr.microsoft.com/windows/nanoserver:ltsc2019-amd64"),
+		Entry("a pinned digest", "2022", "mcr.microsoft.com/windows/servercore:ltsc2022@sha256:abc"),
+	)
+
+	It("rejects base images tagged for another release", func() {
+		Expect(validation.VerifyBaseImageTag("2022", "mcr.microsoft.com/windows/servercore:1809")).To(MatchError("base image mcr.microsoft.com/windows/servercore:1809 isn't tagged for ltsc2022; expected one of ltsc2022"))
+		Expect(validation.VerifyBaseImageTag("2019", "mcr.microsoft.com/windows/servercore:18090")).To(HaveOccurred())
+		Expect(validation.VerifyBaseImageTag("2019", "mcr.microsoft.com/windows/servercore")).To(MatchError(ContainSubstring("has no tag")))
+	})
+})
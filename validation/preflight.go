@@ -0,0 +1,190 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// Host is what the preflight checks know about the machine the suite runs
+// containers on.
+type Host struct {
+	// OSType is the kind of containers the daemon runs, windows or linux.
+	OSType string
+
+	// Build is the Windows build of the host, e.g. 17763.
+	Build int
+
+	// DefaultIsolation is the isolation the daemon applies when none is
+	// given: process on Windows Server, hyperv on Windows 10 and 11.
+	DefaultIsolation string
+
+	// HypervisorPresent is nil when it couldn't be determined, because the
+	// checks don't run on Windows.
+	HypervisorPresent *bool
+
+	// RootDir is where the daemon stores images and FreeSpace how many
+	// bytes are available there.
+	RootDir   string
+	FreeSpace int64
+}
+
+// daemonInfo holds the fields of `docker info` the preflight checks read.
+// nerdctl reports them too.
+type daemonInfo struct {
+	OSType        string
+	OSVersion     string
+	KernelVersion string
+	Isolation     string
+	DockerRootDir string
+}
+
+// InspectHost asks the container runtime about its host and measures the
+// free space of its root directory, which must be local.
+func InspectHost() (Host, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	output, err := cmdlog.Output(Runtime.Command(ctx, "info", "--format", "{{json .}}"))
+	if err != nil {
+		return Host{}, fmt.Errorf("%s info failed: %s; is the daemon running?", Runtime.Name(), err)
+	}
+
+	var info daemonInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		return Host{}, fmt.Errorf("parsing %s info: %s", Runtime.Name(), err)
+	}
+
+	host := Host{OSType: info.OSType, DefaultIsolation: info.Isolation, RootDir: info.DockerRootDir}
+	if host.Build, err = hostBuild(info); err != nil {
+		return Host{}, err
+	}
+
+	if host.FreeSpace, err = freeSpace(host.RootDir); err != nil {
+		return Host{}, fmt.Errorf("measuring the free space of %s: %s", host.RootDir, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		present, err := hypervisorPresent(ctx)
+		if err != nil {
+			return Host{}, err
+		}
+		host.HypervisorPresent = &present
+	}
+
+	return host, nil
+}
+
+// hostBuild reads the host's build from the kernel version the daemon
+// reports on Windows, e.g. "10.0 17763 (17763.1.amd64fre.rs5_release...)",
+// or else from its OS version, e.g. "10.0.17763".
+func hostBuild(info daemonInfo) (int, error) {
+	if fields := strings.Fields(info.KernelVersion); len(fields) > 1 {
+		if build, err := strconv.Atoi(fields[1]); err == nil {
+			return build, nil
+		}
+	}
+
+	if parts := strings.Split(info.OSVersion, "."); len(parts) > 2 {
+		if build, err := strconv.Atoi(parts[2]); err == nil {
+			return build, nil
+		}
+	}
+
+	return 0, fmt.Errorf("can't tell the host's Windows build from kernel version %q and OS version %q", info.KernelVersion, info.OSVersion)
+}
+
+func hypervisorPresent(ctx context.Context) (bool, error) {
+	command := exec.CommandContext(ctx, "powershell", "-NoProfile", "-Command", "(Get-CimInstance Win32_ComputerSystem).HypervisorPresent")
+	output, err := cmdlog.Output(command)
+	if err != nil {
+		return false, fmt.Errorf("checking for a hypervisor failed: %s", err)
+	}
+
+	return strings.EqualFold(strings.TrimSpace(string(output)), "true"), nil
+}
+
+// PreflightOptions describe the run the host is checked for.
+type PreflightOptions struct {
+	// ImageBuild is the Windows build of the image the suite would run:
+	// the candidate's when it exists, else the one its tag's profile expects.
+	ImageBuild int
+
+	// Isolation is the isolation the suite would run containers with. The
+	// host's default applies when it is empty.
+	Isolation string
+
+	// MinFreeSpace is how many bytes a build and run need.
+	MinFreeSpace int64
+}
+
+// CheckHost reports whether the suite can run on host: the daemon runs
+// Windows containers, the host isn't older than the image, the isolation
+// can run the image, and there is enough free space.
+func CheckHost(host Host, opts PreflightOptions) []CheckResult {
+	var results []CheckResult
+
+	if host.OSType != "windows" {
+		results = append(results, failed("daemon", map[string]string{"os": host.OSType}, "the daemon runs %s containers; switch it to Windows containers", host.OSType))
+	} else {
+		results = append(results, passed("daemon", map[string]string{"os": host.OSType}))
+	}
+
+	builds := map[string]string{"host": strconv.Itoa(host.Build), "image": strconv.Itoa(opts.ImageBuild)}
+	if host.Build < opts.ImageBuild {
+		results = append(results, failed("host-build", builds, "the host's build %d is older than the image's %d; Windows can't run images of newer builds", host.Build, opts.ImageBuild))
+	} else {
+		results = append(results, passed("host-build", builds))
+	}
+
+	results = append(results, checkIsolation(host, opts))
+
+	space := map[string]string{"dir": host.RootDir, "free": FormatSize(host.FreeSpace), "needed": FormatSize(opts.MinFreeSpace)}
+	if host.FreeSpace < opts.MinFreeSpace {
+		results = append(results, failed("disk-space", space, "%s has %s free, less than the %s a run needs", host.RootDir, FormatSize(host.FreeSpace), FormatSize(opts.MinFreeSpace)))
+	} else {
+		results = append(results, passed("disk-space", space))
+	}
+
+	return results
+}
+
+func checkIsolation(host Host, opts PreflightOptions) CheckResult {
+	isolation := opts.Isolation
+	if isolation == "" {
+		isolation = host.DefaultIsolation
+	}
+	if isolation == "" {
+		isolation = "process"
+	}
+
+	metadata := map[string]string{"isolation": isolation}
+	if host.HypervisorPresent != nil {
+		metadata["hypervisor"] = strconv.FormatBool(*host.HypervisorPresent)
+	}
+
+	switch {
+	case isolation == "process" && host.Build != opts.ImageBuild:
+		return failed("isolation", metadata, "process isolation needs the host's build %d to match the image's %d; run with hyperv isolation or on a %s host", host.Build, opts.ImageBuild, channelName(opts.ImageBuild))
+	case isolation == "hyperv" && host.HypervisorPresent != nil && !*host.HypervisorPresent:
+		return failed("isolation", metadata, "hyperv isolation needs a hypervisor, and the host has none; enable the Hyper-V feature or run with process isolation")
+	}
+
+	return passed("isolation", metadata)
+}
+
+// channelName names the release of build, e.g. ltsc2019, or the build
+// itself when no profile has it.
+func channelName(build int) string {
+	if channel := ChannelOfBuild(build); channel != "" {
+		return channel
+	}
+
+	return fmt.Sprintf("build %d", build)
+}
@@ -0,0 +1,88 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Preflight", func() {
+	var (
+		host validation.Host
+		opts validation.PreflightOptions
+	)
+
+	BeforeEach(func() {
+		present := true
+		host = validation.Host{
+			OSType:            "windows",
+			Build:             17763,
+			DefaultIsolation:  "process",
+			HypervisorPresent: &present,
+			RootDir:           `C:\ProgramData\docker`,
+			FreeSpace:         40 * validation.GiB,
+		}
+		opts = validation.PreflightOptions{ImageBuild: 17763, MinFreeSpace: 25 * validation.GiB}
+	})
+
+	failures := func(results []validation.CheckResult) map[string]string {
+		messages := map[string]string{}
+		for _, result := range results {
+			if !result.Passed {
+				messages[result.Name] = result.Message
+			}
+		}
+		return messages
+	}
+
+	It("passes a host that matches the image", func() {
+		results := validation.CheckHost(host, opts)
+		Expect(results).To(HaveLen(4))
+		Expect(failures(results)).To(BeEmpty())
+	})
+
+	It("fails a daemon running Linux containers", func() {
+		host.OSType = "linux"
+		Expect(failures(validation.CheckHost(host, opts))).To(HaveKeyWithValue("daemon", "the daemon runs linux containers; switch it to Windows containers"))
+	})
+
+	It("fails hosts older than the image", func() {
+		opts.ImageBuild = 20348
+		opts.Isolation = "hyperv"
+
+		Expect(failures(validation.CheckHost(host, opts))).To(Equal(map[string]string{
+			"host-build": "the host's build 17763 is older than the image's 20348; Windows can't run images of newer builds",
+		}))
+	})
+
+	It("needs matching builds for process isolation", func() {
+		host.Build = 20348
+
+		Expect(failures(validation.CheckHost(host, opts))).To(Equal(map[string]string{
+			"isolation": "process isolation needs the host's build 20348 to match the image's 17763; run with hyperv isolation or on a ltsc2019 host",
+		}))
+
+		opts.Isolation = "hyperv"
+		Expect(failures(validation.CheckHost(host, opts))).To(BeEmpty())
+	})
+
+	It("needs a hypervisor for hyperv isolation", func() {
+		absent := false
+		host.HypervisorPresent = &absent
+		host.DefaultIsolation = "hyperv"
+
+		Expect(failures(validation.CheckHost(host, opts))).To(HaveKey("isolation"))
+
+		host.HypervisorPresent = nil
+		Expect(failures(validation.CheckHost(host, opts))).To(BeEmpty())
+	})
+
+	It("fails when the daemon's root directory is short of space", func() {
+		host.FreeSpace = 10 * validation.GiB
+
+		Expect(failures(validation.CheckHost(host, opts))).To(Equal(map[string]string{
+			"disk-space": `C:\ProgramData\docker has 10.00GiB free, less than the 25.00GiB a run needs`,
+		}))
+	})
+})
@@ -0,0 +1,92 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Profile holds everything that differs between version tags. Supporting a
+// new Windows release should only need a new entry in Profiles.
+type Profile struct {
+	// https://docs.microsoft.com/en-us/dotnet/framework/migration-guide/release-keys-and-os-versions
+	FrameworkRelease string
+
+	// MinOSBuild and MaxOSBuild bound the Windows build number the image's
+	// base is expected to report.
+	MinOSBuild int
+	MaxOSBuild int
+
+	// Channel is the LTSC release of those builds, e.g. ltsc2019, and
+	// BaseTags the prefixes of the base image tags that track it.
+	Channel  string
+	BaseTags []string
+
+	// MSRCProduct is the product MSRC lists the base's security updates
+	// under.
+	MSRCProduct string
+
+	// VCRedistDLLs maps each Visual C++ redistributable to a DLL it installs.
+	VCRedistDLLs map[string]string
+
+	// SizeBudgets map each variant to the largest its image may be, in
+	// bytes, as docker image inspect reports it.
+	SizeBudgets map[string]int64
+}
+
+// Profiles maps each supported version tag to its expectations.
+var Profiles = map[string]Profile{
+	"2019": {
+		FrameworkRelease: "528049", //Framework version 4.8
+		MinOSBuild:       17763,
+		MaxOSBuild:       17763,
+		Channel:          "ltsc2019",
+		BaseTags:         []string{"1809", "ltsc2019"},
+		MSRCProduct:      "Windows Server 2019 (Server Core installation)",
+		VCRedistDLLs: map[string]string{
+			"2010":  `C:\Windows\System32\msvcr100.dll`,
+			"2015+": `C:\Windows\System32\vcruntime140.dll`,
+		},
+		SizeBudgets: map[string]int64{
+			DefaultVariant: 6656 * MiB,
+			"nanoserver":   384 * MiB,
+		},
+	},
+	"2022": {
+		FrameworkRelease: "528449", //Framework version 4.8, as shipped with ltsc2022
+		MinOSBuild:       20348,
+		MaxOSBuild:       20348,
+		Channel:          "ltsc2022",
+		BaseTags:         []string{"ltsc2022"},
+		MSRCProduct:      "Windows Server 2022 (Server Core installation)",
+		VCRedistDLLs: map[string]string{
+			"2010":  `C:\Windows\System32\msvcr100.dll`,
+			"2015+": `C:\Windows\System32\vcruntime140.dll`,
+		},
+		SizeBudgets: map[string]int64{
+			DefaultVariant: 6144 * MiB,
+			"nanoserver":   448 * MiB,
+		},
+	},
+}
+
+// KnownTags returns the tags in Profiles, sorted.
+func KnownTags() []string {
+	var tags []string
+	for tag := range Profiles {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// ProfileFor returns the profile of tag.
+func ProfileFor(tag string) (Profile, error) {
+	profile, ok := Profiles[tag]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown tag %q; known tags are %s", tag, strings.Join(KnownTags(), ", "))
+	}
+
+	return profile, nil
+}
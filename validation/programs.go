@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// programsScript prints the display name and version of every 64- and 32-bit
+// program registered under the uninstall keys as JSON. Keys without a display
+// name are updates and components that aren't programs in their own right.
+const programsScript = `ConvertTo-Json -InputObject @(Get-ItemProperty HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall\*, HKLM:\SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall\* | Where-Object { $_.DisplayName } | Select-Object @{Name='Name'; Expression={$_.DisplayName}}, @{Name='Version'; Expression={$_.DisplayVersion}})`
+
+// Program is an installed program as listed in Programs and Features.
+type Program struct {
+	Name    string
+	Version string
+}
+
+func (p Program) String() string {
+	if p.Version == "" {
+		return p.Name
+	}
+
+	return fmt.Sprintf("%s (%s)", p.Name, p.Version)
+}
+
+// InstalledPrograms returns the programs installed in image, sorted by name.
+func InstalledPrograms(image string) ([]Program, error) {
+	output, err := powershell(image, programsScript)
+	if err != nil {
+		return nil, err
+	}
+
+	var programs []Program
+	if err := json.Unmarshal([]byte(output), &programs); err != nil {
+		return nil, fmt.Errorf("parsing uninstall key output: %s", err)
+	}
+
+	sort.Slice(programs, func(i, j int) bool {
+		return programs[i].Name < programs[j].Name
+	})
+
+	return programs, nil
+}
@@ -0,0 +1,27 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// unmarshalPSJSON unmarshals ConvertTo-Json output into v. PowerShell writes
+// a single object rather than a one-element array when a pipeline yields one
+// item, and nothing at all when it yields none, so for slice targets both are
+// normalized to arrays first.
+func unmarshalPSJSON(data []byte, v interface{}) error {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+
+	target := reflect.TypeOf(v)
+	if target != nil && target.Kind() == reflect.Ptr && target.Elem().Kind() == reflect.Slice {
+		switch {
+		case len(data) == 0:
+			data = []byte("[]")
+		case data[0] == '{':
+			data = append(append([]byte("["), data...), ']')
+		}
+	}
+
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,32 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("unmarshalPSJSON", func() {
+	DescribeTable("unmarshals into slices",
+		func(output string, expected []ServiceState) {
+			var services []ServiceState
+			Expect(unmarshalPSJSON([]byte(output), &services)).To(Succeed())
+			Expect(services).To(Equal(expected))
+		},
+		Entry("an array",
+			`[{"Name": "Dhcp", "StartType": 2, "Status": 4}, {"Name": "Dnscache", "StartType": 2, "Status": 4}]`,
+			[]ServiceState{{Name: "Dhcp", StartType: 2, Status: 4}, {Name: "Dnscache", StartType: 2, Status: 4}},
+		),
+		Entry("a single object",
+			"\r\n{\r\n    \"Name\": \"Dhcp\",\r\n    \"StartType\": 2,\r\n    \"Status\": 4\r\n}\r\n",
+			[]ServiceState{{Name: "Dhcp", StartType: 2, Status: 4}},
+		),
+		Entry("no output", "\r\n", []ServiceState{}),
+	)
+
+	It("leaves objects alone for struct targets", func() {
+		var service ServiceState
+		Expect(unmarshalPSJSON([]byte("\xef\xbb\xbf{\"Name\": \"Dhcp\"}"), &service)).To(Succeed())
+		Expect(service.Name).To(Equal("Dhcp"))
+	})
+})
@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// RegistryAuth holds the credentials sent to the daemon for a push.
+type RegistryAuth struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+}
+
+// PushImage tags localRef as remoteRef and pushes it through the Docker
+// Engine API, retrying transient failures. It returns the digest of the
+// pushed manifest. Credentials are read from REGISTRY_USERNAME and
+// REGISTRY_PASSWORD when set.
+func PushImage(localRef, remoteRef string) (string, error) {
+	return PushImageWithAuth(context.Background(), localRef, remoteRef, RegistryAuth{
+		Username: os.Getenv("REGISTRY_USERNAME"),
+		Password: os.Getenv("REGISTRY_PASSWORD"),
+	}, PushRetry)
+}
+
+// PushImageWithAuth is PushImage with the given credentials and retry
+// policy, giving up when ctx is done. The server address defaults to the
+// registry of remoteRef.
+func PushImageWithAuth(ctx context.Context, localRef, remoteRef string, credentials RegistryAuth, retry RetryPolicy) (string, error) {
+	client, err := newEngineClient()
+	if err != nil {
+		return "", err
+	}
+
+	repository, tag, err := splitReference(remoteRef)
+	if err != nil {
+		return "", err
+	}
+
+	if credentials.ServerAddress == "" {
+		credentials.ServerAddress = registryHost(repository)
+	}
+
+	auth, err := registryAuthHeader(credentials)
+	if err != nil {
+		return "", err
+	}
+
+	tagCtx, cancel := context.WithTimeout(ctx, OperationTimeout)
+	defer cancel()
+
+	response, err := client.do(tagCtx, "POST", "/images/"+localRef+"/tag", url.Values{"repo": {repository}, "tag": {tag}}, nil)
+	if err != nil {
+		return "", fmt.Errorf("tagging %s as %s failed: %s", localRef, remoteRef, err)
+	}
+	response.Body.Close()
+
+	var digest string
+	err = retry.Do(ctx, nil, "pushing "+remoteRef, func() error {
+		var err error
+		digest, err = pushOnce(ctx, client, repository, tag, auth)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("pushing %s failed: %s", remoteRef, err)
+	}
+
+	return digest, nil
+}
+
+func pushOnce(ctx context.Context, client *engineClient, repository, tag, auth string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, OperationTimeout)
+	defer cancel()
+
+	response, err := client.do(ctx, "POST", "/images/"+repository+"/push", url.Values{"tag": {tag}}, map[string][]string{"X-Registry-Auth": {auth}})
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var digest string
+	err = readJSONMessages(response.Body, func(aux json.RawMessage) error {
+		var pushResult struct {
+			Digest string
+		}
+		if err := json.Unmarshal(aux, &pushResult); err != nil {
+			return err
+		}
+		if pushResult.Digest != "" {
+			digest = pushResult.Digest
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if digest == "" {
+		return "", errors.New("the daemon did not report a digest")
+	}
+
+	return digest, nil
+}
+
+// splitReference splits a name:tag reference, defaulting the tag to latest.
+func splitReference(ref string) (string, string, error) {
+	if strings.Contains(ref, "@") {
+		return "", "", fmt.Errorf("%s: digest references can't be pushed to", ref)
+	}
+
+	lastSlash := strings.LastIndex(ref, "/")
+	if colon := strings.LastIndex(ref, ":"); colon > lastSlash {
+		return ref[:colon], ref[colon+1:], nil
+	}
+
+	return ref, "latest", nil
+}
+
+// registryHost returns the registry part of a repository, defaulting to
+// Docker Hub.
+func registryHost(repository string) string {
+	parts := strings.SplitN(repository, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+
+	return "docker.io"
+}
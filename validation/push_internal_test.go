@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("push helpers", func() {
+	DescribeTable("splitReference",
+		func(ref, repository, tag string) {
+			actualRepository, actualTag, err := splitReference(ref)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actualRepository).To(Equal(repository))
+			Expect(actualTag).To(Equal(tag))
+		},
+		Entry("tagged", "cloudfoundry/windows2016fs:2019", "cloudfoundry/windows2016fs", "2019"),
+		Entry("untagged", "cloudfoundry/windows2016fs", "cloudfoundry/windows2016fs", "latest"),
+		Entry("registry with port", "registry.local:5000/windows2016fs:2019.0.91", "registry.local:5000/windows2016fs", "2019.0.91"),
+		Entry("registry with port, untagged", "registry.local:5000/windows2016fs", "registry.local:5000/windows2016fs", "latest"),
+	)
+
+	It("rejects digest references", func() {
+		_, _, err := splitReference("cloudfoundry/windows2016fs@sha256:abc")
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("registryHost",
+		func(repository, host string) {
+			Expect(registryHost(repository)).To(Equal(host))
+		},
+		Entry("docker hub", "cloudfoundry/windows2016fs", "docker.io"),
+		Entry("private registry", "registry.local:5000/windows2016fs", "registry.local:5000"),
+		Entry("localhost", "localhost/windows2016fs", "localhost"),
+	)
+
+	It("treats server errors as transient and client errors as permanent", func() {
+		Expect(IsTransient(&engineError{StatusCode: 503})).To(BeTrue())
+		Expect(IsTransient(&engineError{StatusCode: 404})).To(BeFalse())
+		Expect(IsTransient(errors.New("received unexpected HTTP status: 502 Bad Gateway"))).To(BeTrue())
+		Expect(IsTransient(errors.New("denied: requested access to the resource is denied"))).To(BeFalse())
+	})
+
+	It("reads the digest from a push progress stream", func() {
+		stream := strings.NewReader(`{"status":"Pushed"}
+{"status":"2019: digest: sha256:abc size: 1234"}
+{"aux":{"Tag":"2019","Digest":"sha256:abc","Size":1234}}
+`)
+		var digests []string
+		Expect(readJSONMessages(stream, func(aux json.RawMessage) error {
+			var result struct{ Digest string }
+			Expect(json.Unmarshal(aux, &result)).To(Succeed())
+			digests = append(digests, result.Digest)
+			return nil
+		})).To(Succeed())
+		Expect(digests).To(Equal([]string{"sha256:abc"}))
+	})
+
+	It("fails on an error message in the stream", func() {
+		stream := strings.NewReader(`{"status":"Preparing"}
+{"error":"unauthorized: authentication required"}
+`)
+		Expect(readJSONMessages(stream, nil)).To(MatchError("unauthorized: authentication required"))
+	})
+})
@@ -0,0 +1,295 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultRegistryKeys are the keys a new registry baseline covers: the
+// configuration of the .NET Framework, PowerShell and IIS the image ships,
+// policies, and the SMB client and TLS settings the share mounts rely on.
+var DefaultRegistryKeys = []string{
+	`HKLM\SOFTWARE\Microsoft\NET Framework Setup\NDP`,
+	`HKLM\SOFTWARE\Microsoft\PowerShell\3\PowerShellEngine`,
+	`HKLM\SOFTWARE\Microsoft\InetStp`,
+	`HKLM\SOFTWARE\Policies`,
+	`HKLM\SYSTEM\CurrentControlSet\Services\LanmanWorkstation\Parameters`,
+	`HKLM\SYSTEM\CurrentControlSet\Control\SecurityProviders\SCHANNEL`,
+}
+
+// DefaultRegistryIgnore are the values a new registry baseline ignores
+// because they record when something was installed rather than how.
+var DefaultRegistryIgnore = []string{
+	`*\InstallDate`,
+	`*\InstallTime`,
+}
+
+// RegistryBaseline is the expected content of the registry keys of an image.
+type RegistryBaseline struct {
+	// Keys are exported with all their subkeys, e.g. HKLM\SOFTWARE\Policies.
+	Keys []string `json:"keys"`
+
+	// Ignore are patterns of volatile values, or keys whose values are all
+	// volatile, that may change freely. * matches any run of characters,
+	// key separators included, and case is ignored.
+	Ignore []string `json:"ignore"`
+
+	// Values maps the path of each value, <key>\<name>, to its data. The
+	// unnamed default value of a key is <key>\(default).
+	Values map[string]string `json:"values"`
+}
+
+// RegistryBaselinePath is the registry baseline of tag in FixturesDir.
+func RegistryBaselinePath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("expected-registry-%s.json", tag))
+}
+
+// ReadRegistryBaseline reads the registry baseline at path.
+func ReadRegistryBaseline(path string) (RegistryBaseline, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RegistryBaseline{}, err
+	}
+
+	var baseline RegistryBaseline
+	if err := json.Unmarshal(content, &baseline); err != nil {
+		return RegistryBaseline{}, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return baseline, nil
+}
+
+// WriteRegistryBaseline writes baseline as indented JSON, its values sorted
+// by path.
+func WriteRegistryBaseline(baseline RegistryBaseline, w io.Writer) error {
+	content, err := json.MarshalIndent(baseline, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// registryScript exports the values under the keys in $keys as a JSON array
+// of {Path, Value}. Expandable strings are kept unexpanded and other data is
+// formatted as PowerShell formats it; keys that can't be read are left out.
+const registryScript = `$ErrorActionPreference = 'Stop'
+$values = foreach ($root in $keys) {
+  $key = Get-Item -LiteralPath "Registry::$root" -ErrorAction SilentlyContinue
+  if (-not $key) { continue }
+  @($key) + @(Get-ChildItem -LiteralPath "Registry::$root" -Recurse -ErrorAction SilentlyContinue) | ForEach-Object {
+    $k = $_
+    foreach ($name in $k.GetValueNames()) {
+      $data = $k.GetValue($name, $null, 'DoNotExpandEnvironmentNames')
+      if ($data -is [array]) { $data = $data -join ' ' }
+      [pscustomobject]@{ Path = $k.Name + '\' + $(if ($name) { $name } else { '(default)' }); Value = [string]$data }
+    }
+  }
+}
+ConvertTo-Json -Compress -InputObject @($values)`
+
+// registryRoots maps the abbreviations of the registry roots to the names
+// .NET reports keys with.
+var registryRoots = map[string]string{
+	"HKLM": "HKEY_LOCAL_MACHINE",
+	"HKCU": "HKEY_CURRENT_USER",
+	"HKCR": "HKEY_CLASSES_ROOT",
+	"HKU":  "HKEY_USERS",
+}
+
+// RegistryValues exports the values under keys, and all their subkeys, from
+// image, keyed by their HKLM\...-style paths.
+func RegistryValues(image string, keys []string) (map[string]string, error) {
+	var quoted []string
+	for _, key := range keys {
+		quoted = append(quoted, "'"+strings.ReplaceAll(expandRegistryPath(key), "'", "''")+"'")
+	}
+
+	output, err := powershell(image, fmt.Sprintf("$keys = @(%s)\n%s", strings.Join(quoted, ", "), registryScript))
+	if err != nil {
+		return nil, err
+	}
+
+	var exported []struct {
+		Path  string
+		Value string
+	}
+	if err := unmarshalPSJSON([]byte(output), &exported); err != nil {
+		return nil, fmt.Errorf("parsing the registry of %s: %s", image, err)
+	}
+
+	values := map[string]string{}
+	for _, value := range exported {
+		values[abbreviateRegistryPath(value.Path)] = value.Value
+	}
+
+	return values, nil
+}
+
+func expandRegistryPath(path string) string {
+	for short, long := range registryRoots {
+		if strings.EqualFold(path, short) || strings.HasPrefix(strings.ToUpper(path), short+`\`) {
+			return long + path[len(short):]
+		}
+	}
+
+	return path
+}
+
+func abbreviateRegistryPath(path string) string {
+	for short, long := range registryRoots {
+		if strings.HasPrefix(path, long+`\`) {
+			return short + strings.TrimPrefix(path, long)
+		}
+	}
+
+	return path
+}
+
+// Snapshot returns b with Values replaced by values, leaving out the
+// ignored ones.
+func (b RegistryBaseline) Snapshot(values map[string]string) (RegistryBaseline, error) {
+	ignored, err := registryPatterns(b.Ignore)
+	if err != nil {
+		return RegistryBaseline{}, err
+	}
+
+	b.Values = map[string]string{}
+	for path, value := range values {
+		if !ignored(path) {
+			b.Values[path] = value
+		}
+	}
+
+	return b, nil
+}
+
+// RegistryChange is a value whose data differs from the baseline.
+type RegistryChange struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+// RegistryDiff is how the registry of an image differs from a baseline.
+type RegistryDiff struct {
+	Added   []string
+	Removed []string
+	Changed []RegistryChange
+}
+
+// Empty reports whether the registry matches the baseline.
+func (d RegistryDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String lists the differences, one per line.
+func (d RegistryDiff) String() string {
+	var lines []string
+	for _, path := range d.Removed {
+		lines = append(lines, "removed: "+path)
+	}
+	for _, path := range d.Added {
+		lines = append(lines, "added: "+path)
+	}
+	for _, change := range d.Changed {
+		lines = append(lines, fmt.Sprintf("changed: %s: %q, not %q", change.Path, change.Actual, change.Expected))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Diff compares values, as RegistryValues exports them for b.Keys, with
+// b.Values, leaving out the values b.Ignore matches.
+func (b RegistryBaseline) Diff(values map[string]string) (RegistryDiff, error) {
+	ignored, err := registryPatterns(b.Ignore)
+	if err != nil {
+		return RegistryDiff{}, err
+	}
+
+	var diff RegistryDiff
+	for path, value := range values {
+		if ignored(path) {
+			continue
+		}
+
+		expected, ok := b.Values[path]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, path)
+		case value != expected:
+			diff.Changed = append(diff.Changed, RegistryChange{Path: path, Expected: expected, Actual: value})
+		}
+	}
+
+	for path := range b.Values {
+		if _, ok := values[path]; !ok && !ignored(path) {
+			diff.Removed = append(diff.Removed, path)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Path < diff.Changed[j].Path })
+
+	return diff, nil
+}
+
+// registryPatterns returns whether a path is ignored: whether one of
+// patterns, compared case-insensitively, matches it or one of its keys.
+func registryPatterns(patterns []string) (func(path string) bool, error) {
+	if len(patterns) == 0 {
+		return func(string) bool { return false }, nil
+	}
+
+	var alternatives []string
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return nil, fmt.Errorf("empty registry ignore pattern")
+		}
+		alternatives = append(alternatives, strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, `.*`))
+	}
+
+	expression, err := regexp.Compile(`(?i)^(?:` + strings.Join(alternatives, "|") + `)(?:\\.*)?$`)
+	if err != nil {
+		return nil, err
+	}
+
+	return expression.MatchString, nil
+}
+
+// CheckRegistry compares the values under the keys of
+// FixturesDir/expected-registry-<tag>.json, which `imagebuilder snapshot
+// registry` writes, with those of candidate. The tag is the candidate's
+// VariantTag.
+func CheckRegistry(candidate Candidate) (CheckResult, error) {
+	baseline, err := ReadRegistryBaseline(RegistryBaselinePath(candidate.VariantTag()))
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	values, err := RegistryValues(candidate.Image, baseline.Keys)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	diff, err := baseline.Diff(values)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	metadata := map[string]string{"registry-values": fmt.Sprint(len(values))}
+
+	if !diff.Empty() {
+		return failed("registry", metadata, "the registry differs from its baseline of %d values:\n%s", len(baseline.Values), diff), nil
+	}
+
+	return passed("registry", metadata), nil
+}
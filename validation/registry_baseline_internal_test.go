@@ -0,0 +1,24 @@
+package validation
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("registry paths", func() {
+	DescribeTable("expands abbreviated roots for the registry provider",
+		func(path, expected string) {
+			Expect(expandRegistryPath(path)).To(Equal(expected))
+		},
+		Entry("HKLM", `HKLM\SOFTWARE\Policies`, `HKEY_LOCAL_MACHINE\SOFTWARE\Policies`),
+		Entry("lower case", `hklm\SOFTWARE`, `HKEY_LOCAL_MACHINE\SOFTWARE`),
+		Entry("HKU", `HKU\.DEFAULT`, `HKEY_USERS\.DEFAULT`),
+		Entry("a root alone", `HKLM`, `HKEY_LOCAL_MACHINE`),
+		Entry("full names", `HKEY_LOCAL_MACHINE\SOFTWARE`, `HKEY_LOCAL_MACHINE\SOFTWARE`),
+	)
+
+	It("abbreviates the key names .NET reports", func() {
+		Expect(abbreviateRegistryPath(`HKEY_LOCAL_MACHINE\SOFTWARE\Policies\Value`)).To(Equal(`HKLM\SOFTWARE\Policies\Value`))
+	})
+})
@@ -0,0 +1,61 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RegistryBaseline", func() {
+	baseline := validation.RegistryBaseline{
+		Keys:   []string{`HKLM\SOFTWARE\Microsoft\InetStp`},
+		Ignore: []string{`*\InstallDate`, `HKLM\SOFTWARE\Microsoft\InetStp\Components`},
+		Values: map[string]string{
+			`HKLM\SOFTWARE\Microsoft\InetStp\MajorVersion`: "10",
+			`HKLM\SOFTWARE\Microsoft\InetStp\PathWWWRoot`:  `%SystemDrive%\inetpub\wwwroot`,
+			`HKLM\SOFTWARE\Microsoft\InetStp\SetupString`:  "IIS 10.0",
+		},
+	}
+
+	It("matches the values it records, leaving out ignored ones", func() {
+		diff, err := baseline.Diff(map[string]string{
+			`HKLM\SOFTWARE\Microsoft\InetStp\MajorVersion`:         "10",
+			`HKLM\SOFTWARE\Microsoft\InetStp\PathWWWRoot`:          `%SystemDrive%\inetpub\wwwroot`,
+			`HKLM\SOFTWARE\Microsoft\InetStp\SetupString`:          "IIS 10.0",
+			`HKLM\SOFTWARE\Microsoft\InetStp\installdate`:          "1612345678",
+			`HKLM\SOFTWARE\Microsoft\InetStp\Components\W3SVC`:     "1",
+			`HKLM\SOFTWARE\Microsoft\InetStp\Components\Sub\Value`: "1",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.Empty()).To(BeTrue(), diff.String())
+	})
+
+	It("lists added, removed and changed values", func() {
+		diff, err := baseline.Diff(map[string]string{
+			`HKLM\SOFTWARE\Microsoft\InetStp\MajorVersion`: "11",
+			`HKLM\SOFTWARE\Microsoft\InetStp\SetupString`:  "IIS 10.0",
+			`HKLM\SOFTWARE\Microsoft\InetStp\UpdateHost`:   "attacker.example.com",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.String()).To(Equal(`removed: HKLM\SOFTWARE\Microsoft\InetStp\PathWWWRoot
+added: HKLM\SOFTWARE\Microsoft\InetStp\UpdateHost
+changed: HKLM\SOFTWARE\Microsoft\InetStp\MajorVersion: "11", not "10"`))
+	})
+
+	It("takes a snapshot without the ignored values", func() {
+		snapshot, err := baseline.Snapshot(map[string]string{
+			`HKLM\SOFTWARE\Microsoft\InetStp\MajorVersion`: "10",
+			`HKLM\SOFTWARE\Microsoft\InetStp\InstallDate`:  "1612345678",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(snapshot.Keys).To(Equal(baseline.Keys))
+		Expect(snapshot.Ignore).To(Equal(baseline.Ignore))
+		Expect(snapshot.Values).To(Equal(map[string]string{`HKLM\SOFTWARE\Microsoft\InetStp\MajorVersion`: "10"}))
+	})
+
+	It("rejects empty ignore patterns", func() {
+		_, err := validation.RegistryBaseline{Ignore: []string{""}}.Diff(nil)
+		Expect(err).To(MatchError("empty registry ignore pattern"))
+	})
+})
@@ -0,0 +1,189 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	frameworkReleaseScript = `Get-ItemPropertyValue 'HKLM:\SOFTWARE\Microsoft\NET Framework Setup\NDP\v4\Full\' -Name Release`
+	serviceNamesScript     = `Get-Service | ForEach-Object { $_.Name }`
+
+	// vcRedistScript prints "<version>|<name>" for each installed Visual C++
+	// redistributable, from both the 64-bit and 32-bit uninstall keys.
+	vcRedistScript = `
+Get-ItemProperty 'HKLM:\SOFTWARE\Microsoft\Windows\CurrentVersion\Uninstall\*', 'HKLM:\SOFTWARE\WOW6432Node\Microsoft\Windows\CurrentVersion\Uninstall\*' -ErrorAction SilentlyContinue |
+    Where-Object { $_.DisplayName -like 'Microsoft Visual C++*' } |
+    ForEach-Object { "$($_.DisplayVersion)|$($_.DisplayName)" }
+`
+)
+
+// ReleaseSummary is the release-relevant state of a single image.
+type ReleaseSummary struct {
+	Image            string
+	Size             int64
+	LayerCount       int
+	FrameworkRelease string
+	Services         []string
+	VCRedist         map[string]string
+}
+
+// ReleaseDiff compares a candidate image against the published release it
+// would replace.
+type ReleaseDiff struct {
+	Candidate ReleaseSummary
+	Published ReleaseSummary
+
+	ServicesAdded   []string
+	ServicesRemoved []string
+
+	// VCRedistChanges describes every redistributable that was added,
+	// removed or changed version.
+	VCRedistChanges []string
+}
+
+// CompareReleases summarises candidate and published and the differences
+// between them.
+func CompareReleases(candidate, published string) (ReleaseDiff, error) {
+	candidateSummary, err := summarizeRelease(candidate)
+	if err != nil {
+		return ReleaseDiff{}, err
+	}
+
+	publishedSummary, err := summarizeRelease(published)
+	if err != nil {
+		return ReleaseDiff{}, err
+	}
+
+	return ReleaseDiff{
+		Candidate:       candidateSummary,
+		Published:       publishedSummary,
+		ServicesAdded:   difference(candidateSummary.Services, publishedSummary.Services),
+		ServicesRemoved: difference(publishedSummary.Services, candidateSummary.Services),
+		VCRedistChanges: versionChanges(publishedSummary.VCRedist, candidateSummary.VCRedist),
+	}, nil
+}
+
+func summarizeRelease(image string) (ReleaseSummary, error) {
+	var inspection struct {
+		Size   int64
+		RootFS struct {
+			Layers []string
+		}
+	}
+	if err := InspectImage(image, &inspection); err != nil {
+		return ReleaseSummary{}, err
+	}
+
+	release, err := powershell(image, frameworkReleaseScript)
+	if err != nil {
+		return ReleaseSummary{}, err
+	}
+
+	services, err := powershell(image, serviceNamesScript)
+	if err != nil {
+		return ReleaseSummary{}, err
+	}
+
+	vcRedistOutput, err := powershell(image, vcRedistScript)
+	if err != nil {
+		return ReleaseSummary{}, err
+	}
+
+	vcRedist := map[string]string{}
+	for _, line := range NonEmptyLines(vcRedistOutput) {
+		versionAndName := strings.SplitN(line, "|", 2)
+		if len(versionAndName) != 2 {
+			return ReleaseSummary{}, fmt.Errorf("unexpected redistributable line %q", line)
+		}
+		vcRedist[versionAndName[1]] = versionAndName[0]
+	}
+
+	serviceNames := NonEmptyLines(services)
+	sort.Strings(serviceNames)
+
+	return ReleaseSummary{
+		Image:            image,
+		Size:             inspection.Size,
+		LayerCount:       len(inspection.RootFS.Layers),
+		FrameworkRelease: strings.TrimSpace(release),
+		Services:         serviceNames,
+		VCRedist:         vcRedist,
+	}, nil
+}
+
+// String renders the diff as a report for release reviewers.
+func (d ReleaseDiff) String() string {
+	var report strings.Builder
+
+	fmt.Fprintf(&report, "Changes from %s to %s\n", d.Published.Image, d.Candidate.Image)
+	fmt.Fprintf(&report, "  size:           %s -> %s (%+.1f MB)\n", megabytes(d.Published.Size), megabytes(d.Candidate.Size), float64(d.Candidate.Size-d.Published.Size)/(1<<20))
+	fmt.Fprintf(&report, "  layers:         %d -> %d\n", d.Published.LayerCount, d.Candidate.LayerCount)
+	fmt.Fprintf(&report, "  .NET Framework: %s -> %s\n", d.Published.FrameworkRelease, d.Candidate.FrameworkRelease)
+
+	writeList(&report, "services added", d.ServicesAdded)
+	writeList(&report, "services removed", d.ServicesRemoved)
+	writeList(&report, "Visual C++ redistributables", d.VCRedistChanges)
+
+	return report.String()
+}
+
+func writeList(report *strings.Builder, title string, items []string) {
+	if len(items) == 0 {
+		fmt.Fprintf(report, "  %s: none\n", title)
+		return
+	}
+
+	fmt.Fprintf(report, "  %s:\n", title)
+	for _, item := range items {
+		fmt.Fprintf(report, "    %s\n", item)
+	}
+}
+
+func megabytes(bytes int64) string {
+	return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20))
+}
+
+// difference returns the sorted items of a that are not in b.
+func difference(a, b []string) []string {
+	inB := map[string]bool{}
+	for _, item := range b {
+		inB[item] = true
+	}
+
+	var result []string
+	for _, item := range a {
+		if !inB[item] {
+			result = append(result, item)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// versionChanges describes how the name -> version map changed from before
+// to after.
+func versionChanges(before, after map[string]string) []string {
+	var changes []string
+
+	for name, version := range after {
+		previous, ok := before[name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("added %s %s", name, version))
+		case previous != version:
+			changes = append(changes, fmt.Sprintf("upgraded %s %s -> %s", name, previous, version))
+		}
+	}
+
+	for name, version := range before {
+		if _, ok := after[name]; !ok {
+			changes = append(changes, fmt.Sprintf("removed %s %s", name, version))
+		}
+	}
+	sort.Strings(changes)
+
+	return changes
+}
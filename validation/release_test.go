@@ -0,0 +1,37 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReleaseDiff", func() {
+	It("renders size, layer, service and redistributable changes", func() {
+		diff := validation.ReleaseDiff{
+			Published: validation.ReleaseSummary{
+				Image:            "cloudfoundry/windows2016fs:2019",
+				Size:             5 << 30,
+				LayerCount:       20,
+				FrameworkRelease: "528049",
+			},
+			Candidate: validation.ReleaseSummary{
+				Image:            "windows2016fs-candidate:2019",
+				Size:             5<<30 + 10<<20,
+				LayerCount:       21,
+				FrameworkRelease: "528049",
+			},
+			ServicesAdded:   []string{"WaaSMedicSvc"},
+			VCRedistChanges: []string{"upgraded Microsoft Visual C++ 2015-2019 Redistributable (x64) 14.28.29913 -> 14.29.30133"},
+		}
+
+		report := diff.String()
+		Expect(report).To(ContainSubstring("Changes from cloudfoundry/windows2016fs:2019 to windows2016fs-candidate:2019"))
+		Expect(report).To(ContainSubstring("(+10.0 MB)"))
+		Expect(report).To(ContainSubstring("layers:         20 -> 21"))
+		Expect(report).To(ContainSubstring("services added:\n    WaaSMedicSvc"))
+		Expect(report).To(ContainSubstring("services removed: none"))
+		Expect(report).To(ContainSubstring("14.28.29913 -> 14.29.30133"))
+	})
+})
@@ -0,0 +1,159 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+)
+
+// dockerRunErrorExitCode is the exit code of docker run when the container
+// couldn't be started, rather than its command failing.
+const dockerRunErrorExitCode = 125
+
+// RetryableDockerErrors match the output of docker failures caused by a
+// registry or the host compute service being briefly unavailable.
+var RetryableDockerErrors = []string{
+	`TLS handshake timeout`,
+	`i/o timeout`,
+	`connection reset by peer`,
+	`unexpected EOF`,
+	`net/http: request canceled`,
+	`toomanyrequests`,
+	`(502 Bad Gateway|503 Service Unavailable|504 Gateway Timeout)`,
+	`hcsshim::(PrepareLayer|ActivateLayer|CreateComputeSystem|Start)`,
+	`The process cannot access the file because it is being used by another process`,
+	`failed to register layer`,
+	`re-exec error: exit status 1: output: hcsshim`,
+}
+
+// RetryPolicy decides whether a failed operation, such as a docker command
+// or a registry upload, is run again, and after how long.
+type RetryPolicy struct {
+	// Attempts is how many times an operation runs at most.
+	Attempts int
+
+	// Backoff is the delay before the first retry; it doubles after each
+	// further failure.
+	Backoff time.Duration
+
+	// Retryable match the output of failures worth retrying.
+	Retryable []*regexp.Regexp
+
+	// Transient classifies the errors Do retries, IsTransient when nil.
+	Transient func(error) bool
+}
+
+// NewRetryPolicy returns a policy retrying failures whose output matches one
+// of patterns.
+func NewRetryPolicy(attempts int, backoff time.Duration, patterns []string) (RetryPolicy, error) {
+	policy := RetryPolicy{Attempts: attempts, Backoff: backoff}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("invalid retryable error %q: %s", pattern, err)
+		}
+		policy.Retryable = append(policy.Retryable, re)
+	}
+
+	return policy, nil
+}
+
+// DockerRetry is the policy docker operations run with.
+var DockerRetry, _ = NewRetryPolicy(3, 10*time.Second, RetryableDockerErrors)
+
+// PushRetry is the policy pushes to a registry run with by default.
+var PushRetry = RetryPolicy{Attempts: 3, Backoff: 10 * time.Second}
+
+// ShouldRetry reports whether an operation that failed on attempt, counting
+// from 1, with output should be run again.
+func (p RetryPolicy) ShouldRetry(attempt int, output string) bool {
+	if attempt >= p.Attempts {
+		return false
+	}
+
+	for _, re := range p.Retryable {
+		if re.MatchString(output) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Wait sleeps for the backoff of the retry after attempt, or until ctx is
+// done.
+func (p RetryPolicy) Wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.Backoff << (attempt - 1))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Do calls f until it succeeds, fails with an error the policy doesn't
+// consider transient, or has run Attempts times, reporting each retry of
+// what to log. It returns the last error of f, or that of ctx when it is
+// done while waiting.
+func (p RetryPolicy) Do(ctx context.Context, log io.Writer, what string, f func() error) error {
+	if log == nil {
+		log = ioutil.Discard
+	}
+	transient := p.Transient
+	if transient == nil {
+		transient = IsTransient
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= p.Attempts || ctx.Err() != nil || !transient(err) {
+			return err
+		}
+
+		fmt.Fprintf(log, "%s: attempt %d of %d failed, retrying in %s: %s\n", what, attempt, p.Attempts, p.Backoff<<(attempt-1), err)
+		if err := p.Wait(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+// IsTransient reports whether err is a network failure, a server-side error
+// or rate limiting, or a registry losing track of a blob upload, whether it
+// came from a registry or from the Docker daemon.
+func IsTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var registryErr *registry.Error
+	if errors.As(err, &registryErr) {
+		return registryErr.StatusCode >= 500 || registryErr.StatusCode == 429 ||
+			strings.Contains(registryErr.Body, "BLOB_UPLOAD_UNKNOWN") || strings.Contains(registryErr.Body, "BLOB_UPLOAD_INVALID")
+	}
+
+	var engineErr *engineError
+	if errors.As(err, &engineErr) {
+		return engineErr.StatusCode >= 500
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, transient := range []string{"timeout", "connection reset", "eof", "503", "502", "500 internal server error", "blob upload unknown"} {
+		if strings.Contains(message, transient) {
+			return true
+		}
+	}
+
+	return false
+}
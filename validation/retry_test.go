@@ -0,0 +1,89 @@
+package validation_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	It("retries failures matching a retryable error until the attempts run out", func() {
+		policy, err := validation.NewRetryPolicy(3, time.Second, validation.RetryableDockerErrors)
+		Expect(err).ToNot(HaveOccurred())
+
+		output := "Error response from daemon: Get https://mcr.microsoft.com/v2/: net/http: TLS handshake timeout"
+		Expect(policy.ShouldRetry(1, output)).To(BeTrue())
+		Expect(policy.ShouldRetry(2, output)).To(BeTrue())
+		Expect(policy.ShouldRetry(3, output)).To(BeFalse())
+	})
+
+	It("retries hcs failures but not failing commands", func() {
+		Expect(validation.DockerRetry.ShouldRetry(1, "docker: Error response from daemon: container 1a2b encountered an error during hcsshim::System::CreateProcess: hcsshim::PrepareLayer failed in Win32: The process cannot access the file")).To(BeTrue())
+		Expect(validation.DockerRetry.ShouldRetry(1, "The command 'cmd /S /C msiexec /i rewrite.msi' returned a non-zero code: 1603")).To(BeFalse())
+	})
+
+	It("rejects invalid patterns", func() {
+		_, err := validation.NewRetryPolicy(3, time.Second, []string{"("})
+		Expect(err).To(MatchError(ContainSubstring(`invalid retryable error "("`)))
+	})
+
+	It("doubles the backoff and stops waiting when cancelled", func() {
+		policy := validation.RetryPolicy{Attempts: 3, Backoff: 10 * time.Millisecond}
+
+		start := time.Now()
+		Expect(policy.Wait(context.Background(), 2)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Expect(policy.Wait(ctx, 10)).To(MatchError(context.Canceled))
+	})
+
+	It("retries transient errors until the attempts run out, logging each retry", func() {
+		policy := validation.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+		var log strings.Builder
+
+		calls := 0
+		err := policy.Do(context.Background(), &log, "uploading", func() error {
+			calls++
+			return &registry.Error{StatusCode: 503}
+		})
+		Expect(err).To(MatchError(ContainSubstring("503")))
+		Expect(calls).To(Equal(3))
+		Expect(log.String()).To(ContainSubstring("uploading: attempt 2 of 3 failed, retrying in 2ms"))
+	})
+
+	It("classifies errors with the policy's Transient when it is set", func() {
+		permanent := errors.New("permanent")
+		policy := validation.RetryPolicy{Attempts: 3, Backoff: time.Millisecond, Transient: func(err error) bool { return err != permanent }}
+
+		calls := 0
+		err := policy.Do(context.Background(), nil, "downloading", func() error {
+			calls++
+			if calls == 1 {
+				return errors.New("flaky")
+			}
+			return permanent
+		})
+		Expect(err).To(Equal(permanent))
+		Expect(calls).To(Equal(2))
+	})
+})
+
+var _ = Describe("IsTransient", func() {
+	It("retries server errors, rate limiting and lost uploads but not client errors", func() {
+		Expect(validation.IsTransient(&registry.Error{StatusCode: 502})).To(BeTrue())
+		Expect(validation.IsTransient(&registry.Error{StatusCode: 429})).To(BeTrue())
+		Expect(validation.IsTransient(&registry.Error{StatusCode: 404, Body: `{"errors":[{"code":"BLOB_UPLOAD_UNKNOWN"}]}`})).To(BeTrue())
+		Expect(validation.IsTransient(&registry.Error{StatusCode: 401})).To(BeFalse())
+		Expect(validation.IsTransient(json.Unmarshal([]byte("{"), &struct{}{}))).To(BeFalse())
+	})
+})
@@ -0,0 +1,97 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// mandatoryRunFlags are set by the suite itself on the mount container and
+// must not be overridden by extra run arguments.
+var mandatoryRunFlags = []string{"--name", "--platform", "--user", "-u", "--rm", "--env-file"}
+
+// SplitArgs splits s on whitespace, keeping single- or double-quoted
+// sections together. Backslashes are literal so Windows paths need no
+// escaping.
+func SplitArgs(s string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		quote   rune
+		inArg   bool
+	)
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// ValidateExtraRunArgs rejects flags that would override the mandatory
+// settings of the mount container, including its SHARE_* variables, in any
+// of the forms the docker CLI accepts: --env X, --env=X, -e X, -eX and -e=X.
+func ValidateExtraRunArgs(args []string) error {
+	for i, arg := range args {
+		flag, value, attached := splitFlag(arg)
+
+		for _, mandatory := range mandatoryRunFlags {
+			if flag == mandatory {
+				return fmt.Errorf("%s is set by the suite and can't be overridden", flag)
+			}
+		}
+
+		if flag == "--env" || flag == "-e" {
+			if !attached && i+1 < len(args) {
+				value = args[i+1]
+			}
+
+			if strings.HasPrefix(strings.ToUpper(value), "SHARE_") {
+				return fmt.Errorf("%s %s is set by the suite and can't be overridden", flag, value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// splitFlag splits a flag from a value attached to it, as in --env=X, -eX or
+// -e=X. Arguments that aren't flags are returned as they are.
+func splitFlag(arg string) (string, string, bool) {
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+			return parts[0], parts[1], true
+		}
+	case strings.HasPrefix(arg, "-") && len(arg) > 2:
+		return arg[:2], strings.TrimPrefix(arg[2:], "="), true
+	}
+
+	return arg, "", false
+}
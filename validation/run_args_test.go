@@ -0,0 +1,49 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("extra run arguments", func() {
+	DescribeTable("SplitArgs",
+		func(s string, expected []string) {
+			Expect(validation.SplitArgs(s)).To(Equal(expected))
+		},
+		Entry("whitespace", "  --memory 2g\t--cpus 2 ", []string{"--memory", "2g", "--cpus", "2"}),
+		Entry("quoted sections", `--label "team=windows rootfs" -v 'C:\my dir:C:\data'`, []string{"--label", "team=windows rootfs", "-v", `C:\my dir:C:\data`}),
+		Entry("nothing", "", []string(nil)),
+	)
+
+	It("rejects unterminated quotes", func() {
+		_, err := validation.SplitArgs(`--label "team`)
+		Expect(err).To(MatchError(ContainSubstring(`unterminated " quote`)))
+	})
+
+	DescribeTable("ValidateExtraRunArgs accepts",
+		func(args ...string) {
+			Expect(validation.ValidateExtraRunArgs(args)).To(Succeed())
+		},
+		Entry("resource limits", "--memory", "2g", "--cpus=2"),
+		Entry("other variables", "-e", "PROXY=http://proxy", "--env=NO_PROXY=.internal", "-eLANG=en-US"),
+		Entry("SHARE_ as the value of another flag", "--label", "SHARE_UNC=x"),
+	)
+
+	DescribeTable("ValidateExtraRunArgs rejects",
+		func(message string, args ...string) {
+			Expect(validation.ValidateExtraRunArgs(args)).To(MatchError(message))
+		},
+		Entry("--env X", "--env SHARE_PASSWORD=x is set by the suite and can't be overridden", "--env", "SHARE_PASSWORD=x"),
+		Entry("--env=X", "--env SHARE_UNC=x is set by the suite and can't be overridden", "--env=SHARE_UNC=x"),
+		Entry("-e X", "-e share_username=x is set by the suite and can't be overridden", "-e", "share_username=x"),
+		Entry("-eX", "-e SHARE_PASSWORD=x is set by the suite and can't be overridden", "-eSHARE_PASSWORD=x"),
+		Entry("-e=X", "-e SHARE_PASSWORD is set by the suite and can't be overridden", "-e=SHARE_PASSWORD"),
+		Entry("--user=X", "--user is set by the suite and can't be overridden", "--user=Administrator"),
+		Entry("-uX", "-u is set by the suite and can't be overridden", "-uAdministrator"),
+		Entry("--rm", "--rm is set by the suite and can't be overridden", "--memory", "2g", "--rm"),
+		Entry("--env-file", "--env-file is set by the suite and can't be overridden", "--env-file=share.env"),
+	)
+})
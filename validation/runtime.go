@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// ContainerRuntime is the CLI images are built and inspected with and
+// containers run with. Docker, podman and nerdctl take the same commands
+// and differ in the run flags they support; ctr only imports images and
+// runs containers.
+type ContainerRuntime interface {
+	// Name is the runtime's executable, and how the configuration selects
+	// it.
+	Name() string
+
+	// Command returns the command running the runtime's CLI with args.
+	Command(ctx context.Context, args ...string) *exec.Cmd
+
+	// IsolationArgs returns the run flags selecting isolation, e.g.
+	// process or hyperv, or nil when the runtime can't select it.
+	IsolationArgs(isolation string) []string
+
+	// RunArgs returns the arguments that run spec and wait for it to exit.
+	RunArgs(spec ContainerSpec) []string
+
+	// ExecArgs returns the arguments that run cmd in the running
+	// container.
+	ExecArgs(container string, cmd []string) []string
+
+	// RemoveArgs returns the commands, in order, that stop and remove the
+	// container.
+	RemoveArgs(container string) [][]string
+
+	// ImportArgs returns the arguments that import the OCI image archive
+	// holding image.
+	ImportArgs(archive, image string) []string
+}
+
+// dockerCLI implements the commands docker, podman and nerdctl share.
+type dockerCLI struct{}
+
+func (dockerCLI) ExecArgs(container string, cmd []string) []string {
+	return append([]string{"exec", container}, cmd...)
+}
+
+func (dockerCLI) RemoveArgs(container string) [][]string {
+	return [][]string{{"rm", "--force", container}}
+}
+
+func (dockerCLI) ImportArgs(archive, image string) []string {
+	return []string{"load", "--input", archive}
+}
+
+// Docker is the docker CLI.
+type Docker struct{ dockerCLI }
+
+func (Docker) Name() string { return "docker" }
+
+func (d Docker) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, d.Name(), args...)
+}
+
+func (Docker) IsolationArgs(isolation string) []string {
+	return []string{"--isolation", isolation}
+}
+
+func (d Docker) RunArgs(spec ContainerSpec) []string {
+	return spec.dockerArgs(d)
+}
+
+// Podman is the podman CLI. Its --isolation selects how builds are
+// isolated rather than a Windows isolation mode, so the runtime's default
+// always applies.
+type Podman struct{ dockerCLI }
+
+func (Podman) Name() string { return "podman" }
+
+func (p Podman) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, p.Name(), args...)
+}
+
+func (Podman) IsolationArgs(string) []string {
+	return nil
+}
+
+func (p Podman) RunArgs(spec ContainerSpec) []string {
+	return spec.dockerArgs(p)
+}
+
+// Nerdctl is the nerdctl CLI of containerd.
+type Nerdctl struct{ dockerCLI }
+
+func (Nerdctl) Name() string { return "nerdctl" }
+
+func (n Nerdctl) Command(ctx context.Context, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, n.Name(), args...)
+}
+
+func (Nerdctl) IsolationArgs(isolation string) []string {
+	return []string{"--isolation", isolation}
+}
+
+func (n Nerdctl) RunArgs(spec ContainerSpec) []string {
+	return spec.dockerArgs(n)
+}
+
+// Runtimes are the supported container runtimes by name.
+var Runtimes = map[string]ContainerRuntime{
+	"docker":  Docker{},
+	"podman":  Podman{},
+	"nerdctl": Nerdctl{},
+	"ctr":     Ctr{},
+}
+
+// Runtime is the container runtime the package, the builder and the suite
+// drive. Pushing always goes through the Docker Engine API.
+var Runtime ContainerRuntime = Docker{}
+
+// RuntimeFor returns the runtime named name, docker when it is empty.
+func RuntimeFor(name string) (ContainerRuntime, error) {
+	if name == "" {
+		return Docker{}, nil
+	}
+
+	runtime, ok := Runtimes[name]
+	if !ok {
+		var names []string
+		for known := range Runtimes {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("unknown container runtime %q; supported runtimes are %s", name, strings.Join(names, ", "))
+	}
+
+	return runtime, nil
+}
@@ -0,0 +1,42 @@
+package validation_test
+
+import (
+	"context"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RuntimeFor", func() {
+	AfterEach(func() {
+		validation.Runtime = validation.Docker{}
+	})
+
+	It("defaults to docker", func() {
+		runtime, err := validation.RuntimeFor("")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime).To(Equal(validation.Docker{}))
+	})
+
+	It("runs the selected runtime's CLI", func() {
+		runtime, err := validation.RuntimeFor("nerdctl")
+		Expect(err).ToNot(HaveOccurred())
+
+		command := runtime.Command(context.Background(), "image", "inspect", "windows2016fs-candidate:2019")
+		Expect(command.Args).To(Equal([]string{"nerdctl", "image", "inspect", "windows2016fs-candidate:2019"}))
+	})
+
+	It("leaves out isolation for runtimes that can't select it", func() {
+		validation.Runtime = validation.Podman{}
+
+		spec := validation.ContainerSpec{Name: "c", Image: "image", Isolation: "hyperv"}
+		Expect(spec.Args()).To(Equal([]string{"run", "--name", "c", "image"}))
+	})
+
+	It("rejects unknown runtimes", func() {
+		_, err := validation.RuntimeFor("lxc")
+		Expect(err).To(MatchError("unknown container runtime \"lxc\"; supported runtimes are ctr, docker, nerdctl, podman"))
+	})
+})
@@ -0,0 +1,225 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// ErrNoScanner is returned by ScanImage when neither trivy nor grype is on
+// the PATH.
+var ErrNoScanner = errors.New("no vulnerability scanner (trivy or grype) is installed")
+
+// severities orders the severities reported by the scanners, lowest first.
+var severities = []string{"UNKNOWN", "NEGLIGIBLE", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+// Finding is a single vulnerability reported for a package in an image.
+type Finding struct {
+	ID               string
+	Package          string
+	InstalledVersion string
+	FixedVersion     string
+	Severity         string
+}
+
+// ScanReport holds the vulnerabilities a scanner found in an image.
+type ScanReport struct {
+	Scanner  string
+	Counts   map[string]int
+	Findings []Finding
+}
+
+// AtOrAbove returns the findings whose severity is at least severity.
+func (r ScanReport) AtOrAbove(severity string) []Finding {
+	threshold := severityRank(severity)
+
+	var findings []Finding
+	for _, finding := range r.Findings {
+		if severityRank(finding.Severity) >= threshold {
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// AcceptedVulnerability is a vulnerability reviewed and accepted for an
+// image, such as one without a fix or in a component the image doesn't use.
+type AcceptedVulnerability struct {
+	ID string `json:"id"`
+
+	// Package restricts the acceptance to findings in that package.
+	Package string `json:"package,omitempty"`
+
+	Reason string `json:"reason"`
+
+	// Expires is the date, e.g. 2021-12-31, after which the vulnerability
+	// must be reviewed again.
+	Expires string `json:"expires,omitempty"`
+}
+
+// Unaccepted returns the findings no unexpired acceptance covers. Every
+// acceptance must give a reason.
+func Unaccepted(findings []Finding, accepted []AcceptedVulnerability, now time.Time) ([]Finding, error) {
+	var current []AcceptedVulnerability
+	for _, acceptance := range accepted {
+		if acceptance.ID == "" || strings.TrimSpace(acceptance.Reason) == "" {
+			return nil, fmt.Errorf("accepted vulnerability %q has no reason", acceptance.ID)
+		}
+
+		if acceptance.Expires != "" {
+			expires, err := time.Parse("2006-01-02", acceptance.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("accepted vulnerability %s: invalid expiry date %q", acceptance.ID, acceptance.Expires)
+			}
+			if !now.Before(expires.AddDate(0, 0, 1)) {
+				continue
+			}
+		}
+
+		current = append(current, acceptance)
+	}
+
+	var unaccepted []Finding
+	for _, finding := range findings {
+		if !isAccepted(finding, current) {
+			unaccepted = append(unaccepted, finding)
+		}
+	}
+
+	return unaccepted, nil
+}
+
+func isAccepted(finding Finding, accepted []AcceptedVulnerability) bool {
+	for _, acceptance := range accepted {
+		if strings.EqualFold(acceptance.ID, finding.ID) && (acceptance.Package == "" || acceptance.Package == finding.Package) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidSeverity reports whether severity is one the scanners report.
+func ValidSeverity(severity string) bool {
+	return severityRank(severity) >= 0
+}
+
+func severityRank(severity string) int {
+	for i, known := range severities {
+		if strings.EqualFold(known, severity) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ScanImage scans image with trivy or, failing that, grype.
+func ScanImage(image string) (ScanReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	if _, err := exec.LookPath("trivy"); err == nil {
+		output, err := cmdlog.Output(exec.CommandContext(ctx, "trivy", "image", "--quiet", "--format", "json", image))
+		if err != nil {
+			return ScanReport{}, fmt.Errorf("trivy image %s failed: %s", image, err)
+		}
+
+		return parseTrivy(output)
+	}
+
+	if _, err := exec.LookPath("grype"); err == nil {
+		output, err := cmdlog.Output(exec.CommandContext(ctx, "grype", image, "--output", "json"))
+		if err != nil {
+			return ScanReport{}, fmt.Errorf("grype %s failed: %s", image, err)
+		}
+
+		return parseGrype(output)
+	}
+
+	return ScanReport{}, ErrNoScanner
+}
+
+func parseTrivy(output []byte) (ScanReport, error) {
+	var trivy struct {
+		Results []struct {
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+			}
+		}
+	}
+	if err := json.Unmarshal(output, &trivy); err != nil {
+		return ScanReport{}, fmt.Errorf("parsing trivy report: %s", err)
+	}
+
+	report := ScanReport{Scanner: "trivy", Counts: map[string]int{}}
+	for _, result := range trivy.Results {
+		for _, vulnerability := range result.Vulnerabilities {
+			report.add(Finding{
+				ID:               vulnerability.VulnerabilityID,
+				Package:          vulnerability.PkgName,
+				InstalledVersion: vulnerability.InstalledVersion,
+				FixedVersion:     vulnerability.FixedVersion,
+				Severity:         vulnerability.Severity,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func parseGrype(output []byte) (ScanReport, error) {
+	var grype struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID       string
+				Severity string
+				Fix      struct {
+					Versions []string
+				}
+			}
+			Artifact struct {
+				Name    string
+				Version string
+			}
+		}
+	}
+	if err := json.Unmarshal(output, &grype); err != nil {
+		return ScanReport{}, fmt.Errorf("parsing grype report: %s", err)
+	}
+
+	report := ScanReport{Scanner: "grype", Counts: map[string]int{}}
+	for _, match := range grype.Matches {
+		report.add(Finding{
+			ID:               match.Vulnerability.ID,
+			Package:          match.Artifact.Name,
+			InstalledVersion: match.Artifact.Version,
+			FixedVersion:     strings.Join(match.Vulnerability.Fix.Versions, ", "),
+			Severity:         match.Vulnerability.Severity,
+		})
+	}
+
+	return report, nil
+}
+
+// add records finding, normalizing its severity to upper case.
+func (r *ScanReport) add(finding Finding) {
+	finding.Severity = strings.ToUpper(finding.Severity)
+	if !ValidSeverity(finding.Severity) {
+		finding.Severity = "UNKNOWN"
+	}
+
+	r.Counts[finding.Severity]++
+	r.Findings = append(r.Findings, finding)
+}
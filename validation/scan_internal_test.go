@@ -0,0 +1,85 @@
+package validation
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scan reports", func() {
+	It("parses trivy reports", func() {
+		report, err := parseTrivy([]byte(`{
+			"Results": [{
+				"Vulnerabilities": [
+					{"VulnerabilityID": "CVE-2021-1", "PkgName": "git", "InstalledVersion": "2.31.1", "FixedVersion": "2.32.0", "Severity": "CRITICAL"},
+					{"VulnerabilityID": "CVE-2021-2", "PkgName": "git", "InstalledVersion": "2.31.1", "Severity": "LOW"}
+				]
+			}, {}]
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Scanner).To(Equal("trivy"))
+		Expect(report.Counts).To(Equal(map[string]int{"CRITICAL": 1, "LOW": 1}))
+		Expect(report.Findings[0]).To(Equal(Finding{ID: "CVE-2021-1", Package: "git", InstalledVersion: "2.31.1", FixedVersion: "2.32.0", Severity: "CRITICAL"}))
+	})
+
+	It("parses grype reports and normalizes severities", func() {
+		report, err := parseGrype([]byte(`{
+			"matches": [
+				{"vulnerability": {"id": "CVE-2021-3", "severity": "High", "fix": {"versions": ["1.2", "1.3"]}}, "artifact": {"name": "openssl", "version": "1.1"}},
+				{"vulnerability": {"id": "CVE-2021-4", "severity": "Bogus"}, "artifact": {"name": "zlib", "version": "1.2.11"}}
+			]
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(report.Counts).To(Equal(map[string]int{"HIGH": 1, "UNKNOWN": 1}))
+		Expect(report.Findings[0].FixedVersion).To(Equal("1.2, 1.3"))
+	})
+
+	It("selects findings at or above a severity", func() {
+		report := ScanReport{Findings: []Finding{
+			{ID: "a", Severity: "MEDIUM"},
+			{ID: "b", Severity: "HIGH"},
+			{ID: "c", Severity: "CRITICAL"},
+		}}
+
+		Expect(report.AtOrAbove("high")).To(Equal([]Finding{{ID: "b", Severity: "HIGH"}, {ID: "c", Severity: "CRITICAL"}}))
+	})
+
+	Describe("Unaccepted", func() {
+		now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+		findings := []Finding{
+			{ID: "CVE-2021-1", Package: "git", Severity: "CRITICAL"},
+			{ID: "CVE-2021-2", Package: "openssl", Severity: "HIGH"},
+			{ID: "CVE-2021-2", Package: "curl", Severity: "HIGH"},
+		}
+
+		It("drops the findings that are accepted, optionally only in one package", func() {
+			unaccepted, err := Unaccepted(findings, []AcceptedVulnerability{
+				{ID: "cve-2021-1", Reason: "no fix available"},
+				{ID: "CVE-2021-2", Package: "openssl", Reason: "the vulnerable API isn't reachable"},
+			}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unaccepted).To(Equal([]Finding{findings[2]}))
+		})
+
+		It("ignores acceptances that expired", func() {
+			unaccepted, err := Unaccepted(findings[:1], []AcceptedVulnerability{{ID: "CVE-2021-1", Reason: "no fix available", Expires: "2021-06-14"}}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unaccepted).To(HaveLen(1))
+
+			unaccepted, err = Unaccepted(findings[:1], []AcceptedVulnerability{{ID: "CVE-2021-1", Reason: "no fix available", Expires: "2021-06-15"}}, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(unaccepted).To(BeEmpty())
+		})
+
+		It("requires a reason and a valid expiry date", func() {
+			_, err := Unaccepted(findings, []AcceptedVulnerability{{ID: "CVE-2021-1"}}, now)
+			Expect(err).To(MatchError(`accepted vulnerability "CVE-2021-1" has no reason`))
+
+			_, err = Unaccepted(findings, []AcceptedVulnerability{{ID: "CVE-2021-1", Reason: "no fix available", Expires: "June"}}, now)
+			Expect(err).To(MatchError(`accepted vulnerability CVE-2021-1: invalid expiry date "June"`))
+		})
+	})
+})
@@ -0,0 +1,240 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ServiceState is the subset of Get-Service output compared against the
+// baseline.
+type ServiceState struct {
+	Name      string
+	StartType int
+	Status    int
+}
+
+// servicesScript lists the services of an image with the fields of
+// ServiceState. ConvertTo-JSON writes StartType and Status as numbers.
+const servicesScript = "Get-Service | Select-Object Name, StartType, Status | ConvertTo-JSON"
+
+// ServicesBaselinePath is the services baseline of tag in FixturesDir.
+func ServicesBaselinePath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("expected-baseline-services-%s.json", tag))
+}
+
+// Services returns the services of image, sorted by name.
+func Services(image string) ([]ServiceState, error) {
+	output, err := powershell(image, servicesScript)
+	if err != nil {
+		return nil, err
+	}
+
+	var services []ServiceState
+	if err := unmarshalPSJSON([]byte(output), &services); err != nil {
+		return nil, err
+	}
+
+	sortServices(services)
+	return services, nil
+}
+
+func sortServices(services []ServiceState) {
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+}
+
+// WriteServicesBaseline writes services as a baseline CheckServices reads,
+// one indented object per service.
+func WriteServicesBaseline(services []ServiceState, w io.Writer) error {
+	content, err := json.MarshalIndent(services, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// VolatileServicesPath lists the services of tag whose StartType and Status
+// may differ from the baseline, as path.Match patterns.
+func VolatileServicesPath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("volatile-services-%s.json", tag))
+}
+
+var (
+	startTypes = []string{"Boot", "System", "Automatic", "Manual", "Disabled"}
+	statuses   = []string{"", "Stopped", "StartPending", "StopPending", "Running", "ContinuePending", "PausePending", "Paused"}
+)
+
+// ServiceChange is a field of a service that differs from the baseline.
+type ServiceChange struct {
+	Name     string
+	Field    string
+	Expected int
+	Actual   int
+}
+
+func (c ServiceChange) String() string {
+	names := startTypes
+	if c.Field == "Status" {
+		names = statuses
+	}
+
+	name := func(value int) string {
+		if value >= 0 && value < len(names) && names[value] != "" {
+			return fmt.Sprintf("%s (%d)", names[value], value)
+		}
+		return fmt.Sprint(value)
+	}
+
+	return fmt.Sprintf("%s %s: %s, not %s", c.Name, c.Field, name(c.Actual), name(c.Expected))
+}
+
+// ServiceDiff is how services differ from a baseline.
+type ServiceDiff struct {
+	Missing    []string
+	Unexpected []string
+	Changed    []ServiceChange
+}
+
+// Empty reports whether the services match the baseline.
+func (d ServiceDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 && len(d.Changed) == 0
+}
+
+// String lists the differences, one per line.
+func (d ServiceDiff) String() string {
+	var lines []string
+	for _, name := range d.Missing {
+		lines = append(lines, "missing: "+name)
+	}
+	for _, name := range d.Unexpected {
+		lines = append(lines, "unexpected: "+name)
+	}
+	for _, change := range d.Changed {
+		lines = append(lines, "changed: "+change.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// DiffServices compares actual with baseline regardless of their order.
+// Services matching a volatile pattern, compared case-insensitively, may
+// have any StartType and Status but must still be present.
+func DiffServices(baseline, actual []ServiceState, volatile []string) (ServiceDiff, error) {
+	expected := map[string]ServiceState{}
+	for _, service := range baseline {
+		expected[service.Name] = service
+	}
+
+	var diff ServiceDiff
+	found := map[string]bool{}
+	for _, service := range actual {
+		found[service.Name] = true
+
+		want, ok := expected[service.Name]
+		if !ok {
+			diff.Unexpected = append(diff.Unexpected, service.Name)
+			continue
+		}
+
+		tolerated, err := matchesAnyFold(volatile, service.Name)
+		if err != nil {
+			return ServiceDiff{}, fmt.Errorf("volatile service %s", err)
+		}
+		if tolerated {
+			continue
+		}
+
+		if service.StartType != want.StartType {
+			diff.Changed = append(diff.Changed, ServiceChange{Name: service.Name, Field: "StartType", Expected: want.StartType, Actual: service.StartType})
+		}
+		if service.Status != want.Status {
+			diff.Changed = append(diff.Changed, ServiceChange{Name: service.Name, Field: "Status", Expected: want.Status, Actual: service.Status})
+		}
+	}
+
+	for _, service := range baseline {
+		if !found[service.Name] {
+			diff.Missing = append(diff.Missing, service.Name)
+		}
+	}
+
+	sort.Strings(diff.Missing)
+	sort.Strings(diff.Unexpected)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		if diff.Changed[i].Name != diff.Changed[j].Name {
+			return diff.Changed[i].Name < diff.Changed[j].Name
+		}
+		return diff.Changed[i].Field < diff.Changed[j].Field
+	})
+
+	return diff, nil
+}
+
+// matchesAnyFold reports whether one of the path.Match patterns matches
+// name, ignoring case.
+func matchesAnyFold(patterns []string, name string) (bool, error) {
+	for _, pattern := range patterns {
+		matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+		if err != nil {
+			return false, fmt.Errorf("pattern %q: %s", pattern, err)
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CheckServices compares the services of candidate against
+// FixturesDir/expected-baseline-services-<tag>.json, which `imagebuilder
+// snapshot services` writes, tolerating the changes that
+// FixturesDir/volatile-services-<tag>.json allows, if it exists. The tag is
+// the candidate's VariantTag.
+func CheckServices(candidate Candidate) (CheckResult, error) {
+	tag := candidate.VariantTag()
+	jsonData, err := ioutil.ReadFile(ServicesBaselinePath(tag))
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	var baseline []ServiceState
+	if err := unmarshalPSJSON(jsonData, &baseline); err != nil {
+		return CheckResult{}, err
+	}
+
+	var volatile []string
+	if content, err := ioutil.ReadFile(VolatileServicesPath(tag)); err == nil {
+		if err := json.Unmarshal(content, &volatile); err != nil {
+			return CheckResult{}, fmt.Errorf("parsing %s: %s", VolatileServicesPath(tag), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return CheckResult{}, err
+	}
+
+	actual, err := Services(candidate.Image)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	diff, err := DiffServices(baseline, actual, volatile)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	metadata := map[string]string{"services": fmt.Sprint(len(actual))}
+
+	if !diff.Empty() {
+		return failed("services", metadata, "services differ from the baseline of %d services:\n%s", len(baseline), diff), nil
+	}
+
+	return passed("services", metadata), nil
+}
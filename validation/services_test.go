@@ -0,0 +1,123 @@
+package validation_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteServicesBaseline", func() {
+	It("writes one indented object per service with only the compared fields", func() {
+		var buf bytes.Buffer
+		Expect(validation.WriteServicesBaseline([]validation.ServiceState{{Name: "Dhcp", StartType: 2, Status: 4}}, &buf)).To(Succeed())
+
+		Expect(buf.String()).To(Equal(`[
+    {
+        "Name": "Dhcp",
+        "StartType": 2,
+        "Status": 4
+    }
+]
+`))
+	})
+})
+
+var _ = Describe("DiffServices", func() {
+	baseline := []validation.ServiceState{
+		{Name: "Dhcp", StartType: 2, Status: 4},
+		{Name: "Dnscache", StartType: 2, Status: 4},
+		{Name: "wuauserv", StartType: 3, Status: 1},
+	}
+
+	It("ignores the order of the services", func() {
+		actual := []validation.ServiceState{baseline[2], baseline[0], baseline[1]}
+
+		diff, err := validation.DiffServices(baseline, actual, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.Empty()).To(BeTrue())
+	})
+
+	It("lists missing, unexpected and changed services", func() {
+		actual := []validation.ServiceState{
+			{Name: "Dhcp", StartType: 4, Status: 1},
+			{Name: "wuauserv", StartType: 3, Status: 1},
+			{Name: "WaaSMedicSvc", StartType: 3, Status: 1},
+		}
+
+		diff, err := validation.DiffServices(baseline, actual, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.String()).To(Equal(`missing: Dnscache
+unexpected: WaaSMedicSvc
+changed: Dhcp StartType: Disabled (4), not Automatic (2)
+changed: Dhcp Status: Stopped (1), not Running (4)`))
+	})
+
+	It("tolerates state changes of volatile services, but not their removal", func() {
+		actual := []validation.ServiceState{
+			{Name: "Dhcp", StartType: 2, Status: 4},
+			{Name: "wuauserv", StartType: 2, Status: 4},
+		}
+
+		diff, err := validation.DiffServices(baseline, actual, []string{"WUAU*", "Dns*"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff).To(Equal(validation.ServiceDiff{Missing: []string{"Dnscache"}}))
+	})
+
+	It("fails on invalid patterns", func() {
+		_, err := validation.DiffServices(baseline, baseline, []string{"["})
+		Expect(err).To(MatchError(ContainSubstring(`volatile service pattern "["`)))
+	})
+})
+
+var _ = Describe("the volatile services fixtures", func() {
+	// hardened matches the services a Dockerfile sets the start type of.
+	hardened := []*regexp.Regexp{
+		regexp.MustCompile(`\$svs=\(([^)]*)\)`),
+		regexp.MustCompile(`Set-Service -Name (\S+)`),
+		regexp.MustCompile(`Services\\(\w+)'? -Name Start`),
+	}
+
+	It("leave out the services the Dockerfile hardens", func() {
+		for _, tag := range validation.KnownTags() {
+			content, err := ioutil.ReadFile(filepath.Join("..", validation.VolatileServicesPath(tag)))
+			if os.IsNotExist(err) {
+				continue
+			}
+			Expect(err).ToNot(HaveOccurred())
+
+			var volatile []string
+			Expect(json.Unmarshal(content, &volatile)).To(Succeed())
+
+			dockerfile, err := ioutil.ReadFile(filepath.Join("..", tag, "Dockerfile"))
+			Expect(err).ToNot(HaveOccurred())
+
+			var services []string
+			for _, expression := range hardened {
+				for _, match := range expression.FindAllStringSubmatch(string(dockerfile), -1) {
+					for _, name := range strings.Split(match[1], ",") {
+						services = append(services, strings.Trim(name, " '\""))
+					}
+				}
+			}
+			Expect(services).ToNot(BeEmpty())
+
+			for _, service := range services {
+				for _, pattern := range volatile {
+					matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(service))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(matched).To(BeFalse(), "%s tolerates %s, which %s/Dockerfile hardens", validation.VolatileServicesPath(tag), service, tag)
+				}
+			}
+		}
+	})
+})
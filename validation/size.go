@@ -0,0 +1,87 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Size units, in bytes.
+const (
+	KiB int64 = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+)
+
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GiB", GiB},
+	{"MiB", MiB},
+	{"KiB", KiB},
+	{"GB", 1000 * 1000 * 1000},
+	{"MB", 1000 * 1000},
+	{"KB", 1000},
+	{"B", 1},
+}
+
+// ParseSize parses a size such as 6.5GiB, 700MB or 1024, in bytes.
+func ParseSize(size string) (int64, error) {
+	number, unit := strings.TrimSpace(size), int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, u.suffix)), u.bytes
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%q is not a size such as 6.5GiB", size)
+	}
+
+	return int64(value * float64(unit)), nil
+}
+
+// FormatSize formats bytes in the largest binary unit it has at least one
+// of, e.g. 6.53GiB.
+func FormatSize(bytes int64) string {
+	for _, u := range sizeUnits[:3] {
+		if bytes >= u.bytes {
+			return strconv.FormatFloat(float64(bytes)/float64(u.bytes), 'f', 2, 64) + u.suffix
+		}
+	}
+
+	return strconv.FormatInt(bytes, 10) + "B"
+}
+
+// SizeBudget returns the largest the image of variant for tag may be, or 0
+// when it has no budget.
+func SizeBudget(tag, variant string) (int64, error) {
+	profile, err := ProfileFor(tag)
+	if err != nil {
+		return 0, err
+	}
+	if variant == "" {
+		variant = DefaultVariant
+	}
+
+	return profile.SizeBudgets[variant], nil
+}
+
+// CheckImageSize checks that the local image is no larger than budget
+// bytes.
+func CheckImageSize(image string, budget int64) (CheckResult, error) {
+	size, err := ImageSize(image)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	metadata := map[string]string{"size": FormatSize(size), "budget": FormatSize(budget)}
+	if size > budget {
+		return failed("size", metadata, "%s is %s, %s over its budget of %s", image, FormatSize(size), FormatSize(size-budget), FormatSize(budget)), nil
+	}
+
+	return passed("size", metadata), nil
+}
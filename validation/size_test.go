@@ -0,0 +1,45 @@
+package validation_test
+
+import (
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("sizes", func() {
+	DescribeTable("ParseSize",
+		func(size string, expected int64) {
+			Expect(validation.ParseSize(size)).To(Equal(expected))
+		},
+		Entry("bytes", "1024", int64(1024)),
+		Entry("binary units", "6.5GiB", int64(6656)*validation.MiB),
+		Entry("decimal units", "700MB", int64(700000000)),
+		Entry("spaced units", "384 MiB", 384*validation.MiB),
+	)
+
+	It("rejects sizes that aren't numbers", func() {
+		_, err := validation.ParseSize("big")
+		Expect(err).To(MatchError(`"big" is not a size such as 6.5GiB`))
+
+		_, err = validation.ParseSize("-1GiB")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("formats sizes in binary units", func() {
+		Expect(validation.FormatSize(6656 * validation.MiB)).To(Equal("6.50GiB"))
+		Expect(validation.FormatSize(1536)).To(Equal("1.50KiB"))
+		Expect(validation.FormatSize(12)).To(Equal("12B"))
+	})
+
+	It("budgets every variant of every tag", func() {
+		for _, tag := range validation.KnownTags() {
+			for variant := range validation.Variants {
+				budget, err := validation.SizeBudget(tag, variant)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(budget).To(BeNumerically(">", 0), "%s %s has no size budget", tag, variant)
+			}
+		}
+	})
+})
@@ -0,0 +1,173 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ShareResolutionExitCode is the exit code of container-test.ps1 when the
+// share host could not be resolved, as opposed to the mount itself failing.
+const ShareResolutionExitCode = 3
+
+// SMBMountSpec describes a container that runs container-test.ps1, which
+// image must contain, against shareUnc.
+func SMBMountSpec(image, shareUnc, username, password string) ContainerSpec {
+	return ContainerSpec{
+		Image: image,
+		Cmd:   []string{"powershell", `.\container-test.ps1`},
+		Env: map[string]string{
+			"SHARE_UNC":      shareUnc,
+			"SHARE_USERNAME": username,
+			"SHARE_PASSWORD": password,
+		},
+		User:      "vcap",
+		Platform:  Platform,
+		Isolation: Isolation,
+	}
+}
+
+// GMSAUser is the account mount containers run as with a gMSA: Network
+// Service authenticates on the network as the gMSA.
+const GMSAUser = `NT AUTHORITY\NETWORK SERVICE`
+
+// GMSAMountSpec describes a container that runs container-test.ps1 against
+// shareUnc as the gMSA of credentialSpec, e.g. file://webapp.json, rather
+// than with a username and password. Kerberos needs the share's FQDN.
+func GMSAMountSpec(image, shareUnc, credentialSpec string) ContainerSpec {
+	return ContainerSpec{
+		Image: image,
+		Cmd:   []string{"powershell", `.\container-test.ps1`},
+		Env: map[string]string{
+			"SHARE_UNC":  shareUnc,
+			"SHARE_GMSA": "1",
+		},
+		User:           GMSAUser,
+		CredentialSpec: credentialSpec,
+		Platform:       Platform,
+		Isolation:      Isolation,
+	}
+}
+
+// MountSMB runs the mount container described by spec and returns its
+// output. A failed mount is reported as an error that distinguishes DNS
+// resolution failures from SMB failures.
+func MountSMB(ctx context.Context, spec ContainerSpec) (string, error) {
+	run, err := RunContainer(ctx, spec)
+	if err != nil {
+		return "", err
+	}
+
+	if run.ExitCode != 0 {
+		return run.Stdout, errors.New(describeMountFailure(run))
+	}
+
+	return run.Stdout, nil
+}
+
+func describeMountFailure(run ContainerRun) string {
+	if run.ExitCode == ShareResolutionExitCode {
+		return fmt.Sprintf("DNS resolution of the share host failed inside the container:\n%s", run.Stdout)
+	}
+
+	return fmt.Sprintf("mounting the share failed:\n%s\n%s", run.Stdout, run.Stderr)
+}
+
+// SkipReason returns the reason printed by container-test.ps1 when the image
+// can't exercise the requested mount mode, or "" if the mount was attempted.
+func SkipReason(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "SKIP: ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "SKIP: "))
+		}
+	}
+
+	return ""
+}
+
+// CheckSMB mounts the share at \\SHARE_IP\SHARE_NAME with SHARE_USERNAME and
+// SHARE_PASSWORD from a container of candidate, which must contain
+// container-test.ps1.
+func CheckSMB(candidate Candidate) (CheckResult, error) {
+	var missing []string
+	for _, name := range []string{"SHARE_IP", "SHARE_NAME", "SHARE_USERNAME", "SHARE_PASSWORD"} {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return CheckResult{}, fmt.Errorf("%s must be set", strings.Join(missing, ", "))
+	}
+
+	shareUnc := fmt.Sprintf(`\\%s\%s`, os.Getenv("SHARE_IP"), os.Getenv("SHARE_NAME"))
+	spec := SMBMountSpec(candidate.Image, shareUnc, os.Getenv("SHARE_USERNAME"), os.Getenv("SHARE_PASSWORD"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), OperationTimeout)
+	defer cancel()
+
+	run, err := RunContainer(ctx, spec)
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	return MountResult("smb", run, shareUnc), nil
+}
+
+// MountResult reports whether run, a container that ran container-test.ps1,
+// mapped shareUnc to T:, as the check named name.
+func MountResult(name string, run ContainerRun, shareUnc string) CheckResult {
+	metadata := map[string]string{"share": shareUnc, "exitCode": fmt.Sprint(run.ExitCode)}
+
+	if run.ExitCode != 0 {
+		return failed(name, metadata, "%s", describeMountFailure(run))
+	}
+
+	if !strings.Contains(run.Stdout, "T:") || !strings.Contains(run.Stdout, shareUnc) {
+		return failed(name, metadata, "%s is not mapped to T: in the container:\n%s", shareUnc, run.Stdout)
+	}
+
+	return passed(name, metadata)
+}
+
+// MountAttempt is the outcome of one of several concurrent mounts.
+type MountAttempt struct {
+	Worker int
+	Output string
+	Err    error
+}
+
+// Problem describes why the attempt didn't map shareUnc to T:, or is empty
+// when it did.
+func (a MountAttempt) Problem(shareUnc string) string {
+	switch {
+	case a.Err != nil:
+		return fmt.Sprintf("worker %d failed: %s", a.Worker, a.Err)
+	case !strings.Contains(a.Output, "T:") || !strings.Contains(a.Output, shareUnc):
+		return fmt.Sprintf("worker %d didn't map %s to T:\n%s", a.Worker, shareUnc, a.Output)
+	}
+
+	return ""
+}
+
+// MountConcurrently calls mount from workers goroutines at once and returns
+// their attempts, ordered by worker, once all of them have finished.
+func MountConcurrently(workers int, mount func(worker int) (string, error)) []MountAttempt {
+	attempts := make([]MountAttempt, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func(worker int) {
+			defer wg.Done()
+
+			output, err := mount(worker)
+			attempts[worker] = MountAttempt{Worker: worker, Output: output, Err: err}
+		}(i)
+	}
+
+	wg.Wait()
+	return attempts
+}
@@ -0,0 +1,45 @@
+package validation_test
+
+import (
+	"errors"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MountConcurrently", func() {
+	const shareUnc = `\\10.0.0.1\share`
+
+	It("attributes a failing worker's error to that worker", func() {
+		attempts := validation.MountConcurrently(5, func(worker int) (string, error) {
+			if worker == 3 {
+				return "", errors.New("forced failure")
+			}
+			return "T: " + shareUnc, nil
+		})
+		Expect(attempts).To(HaveLen(5))
+
+		var problems []string
+		for i, attempt := range attempts {
+			Expect(attempt.Worker).To(Equal(i))
+			if problem := attempt.Problem(shareUnc); problem != "" {
+				problems = append(problems, problem)
+			}
+		}
+		Expect(problems).To(Equal([]string{"worker 3 failed: forced failure"}))
+	})
+
+	It("reports workers that didn't map the share", func() {
+		attempts := validation.MountConcurrently(2, func(worker int) (string, error) {
+			if worker == 1 {
+				return `T: \\10.0.0.2\other`, nil
+			}
+			return "T: " + shareUnc, nil
+		})
+
+		Expect(attempts[0].Problem(shareUnc)).To(BeEmpty())
+		Expect(attempts[1].Problem(shareUnc)).To(HavePrefix(`worker 1 didn't map \\10.0.0.1\share to T:`))
+	})
+})
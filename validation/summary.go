@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	colorGreen = "\x1b[32m"
+	colorRed   = "\x1b[31m"
+	colorReset = "\x1b[0m"
+)
+
+// PrintSummary writes an aligned table of results, with each check's status,
+// duration and metadata, followed by the number of checks that passed and
+// failed. Statuses are colored when w is a terminal.
+func PrintSummary(results []CheckResult, w io.Writer) error {
+	color := isTerminal(w)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "CHECK\tSTATUS\tDURATION\tMETADATA")
+
+	var passedCount, failedCount int
+	for _, result := range results {
+		status, code := "PASS", colorGreen
+		if result.Passed {
+			passedCount++
+		} else {
+			status, code = "FAIL", colorRed
+			failedCount++
+		}
+		if color {
+			status = code + status + colorReset
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", result.Name, status, result.Duration.Round(time.Millisecond), formatMetadata(result.Metadata))
+	}
+
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d passed, %d failed\n", passedCount, failedCount)
+	return err
+}
+
+func formatMetadata(metadata map[string]string) string {
+	var keys []string
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = fmt.Sprintf("%s=%s", key, metadata[key])
+	}
+
+	return strings.Join(pairs, " ")
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,30 @@
+package validation_test
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrintSummary", func() {
+	It("renders an aligned, uncolored table and totals for non-terminals", func() {
+		results := []validation.CheckResult{
+			{Name: "dotnet", Passed: true, Duration: 1500 * time.Millisecond, Metadata: map[string]string{"release": "528049"}},
+			{Name: "os-build", Duration: 2 * time.Second, Metadata: map[string]string{"build": "17134"}},
+		}
+
+		var out bytes.Buffer
+		Expect(validation.PrintSummary(results, &out)).To(Succeed())
+
+		Expect(out.String()).To(Equal(`CHECK     STATUS  DURATION  METADATA
+dotnet    PASS    1.5s      release=528049
+os-build  FAIL    2s        build=17134
+
+1 passed, 1 failed
+`))
+	})
+})
@@ -0,0 +1,49 @@
+package validation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// WriteTAP writes results to w as a TAP version 13 stream with one test point
+// per result, in the order given. Failed results carry a YAML diagnostic
+// block with the check's message and measured metadata.
+func WriteTAP(results []CheckResult, w io.Writer) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintln(out, "TAP version 13")
+	fmt.Fprintf(out, "1..%d\n", len(results))
+
+	for i, result := range results {
+		status := "ok"
+		if !result.Passed {
+			status = "not ok"
+		}
+		fmt.Fprintf(out, "%s %d - %s\n", status, i+1, result.Name)
+
+		if result.Passed {
+			continue
+		}
+
+		fmt.Fprintln(out, "  ---")
+		fmt.Fprintf(out, "  message: %s\n", strconv.Quote(result.Message))
+		if len(result.Metadata) > 0 {
+			var keys []string
+			for key := range result.Metadata {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			fmt.Fprintln(out, "  metadata:")
+			for _, key := range keys {
+				fmt.Fprintf(out, "    %s: %s\n", strconv.Quote(key), strconv.Quote(result.Metadata[key]))
+			}
+		}
+		fmt.Fprintln(out, "  ...")
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,44 @@
+package validation_test
+
+import (
+	"bytes"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteTAP", func() {
+	It("writes a test point per result with diagnostics for failures", func() {
+		results := []validation.CheckResult{
+			{Name: "dotnet", Passed: true, Metadata: map[string]string{"release": "528049"}},
+			{
+				Name:     "vcredist",
+				Message:  `missing Visual C++ redistributables: 2010 (C:\Windows\System32\msvcr100.dll)`,
+				Metadata: map[string]string{"2015+": "true", "2010": "false"},
+			},
+		}
+
+		var out bytes.Buffer
+		Expect(validation.WriteTAP(results, &out)).To(Succeed())
+
+		Expect(out.String()).To(Equal(`TAP version 13
+1..2
+ok 1 - dotnet
+not ok 2 - vcredist
+  ---
+  message: "missing Visual C++ redistributables: 2010 (C:\\Windows\\System32\\msvcr100.dll)"
+  metadata:
+    "2010": "false"
+    "2015+": "true"
+  ...
+`))
+	})
+
+	It("writes an empty plan when there are no results", func() {
+		var out bytes.Buffer
+		Expect(validation.WriteTAP(nil, &out)).To(Succeed())
+		Expect(out.String()).To(Equal("TAP version 13\n1..0\n"))
+	})
+})
@@ -0,0 +1,125 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+)
+
+// tasksScript lists every scheduled task with the fields of TaskState. Run
+// history lives in Get-ScheduledTaskInfo and is never compared.
+const tasksScript = "ConvertTo-Json -Compress -InputObject @(Get-ScheduledTask | Select-Object TaskPath, TaskName, State)"
+
+// Values of the ScheduledTask State enumeration.
+const (
+	TaskStateReady   = 3
+	TaskStateRunning = 4
+)
+
+// TaskState is the part of a scheduled task compared against the baseline.
+type TaskState struct {
+	TaskPath string
+	TaskName string
+	State    int
+}
+
+func (t TaskState) key() string {
+	return t.TaskPath + t.TaskName
+}
+
+// TasksBaselinePath is the scheduled tasks baseline of tag in FixturesDir.
+func TasksBaselinePath(tag string) string {
+	return filepath.Join(FixturesDir, fmt.Sprintf("expected-tasks-%s.json", tag))
+}
+
+// ReadTasksBaseline reads the scheduled tasks baseline at path.
+func ReadTasksBaseline(path string) ([]TaskState, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseline []TaskState
+	if err := unmarshalPSJSON(content, &baseline); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", path, err)
+	}
+
+	return baseline, nil
+}
+
+// WriteTasksBaseline writes tasks as indented JSON, one object per task,
+// sorted by path and name.
+func WriteTasksBaseline(tasks []TaskState, w io.Writer) error {
+	sorted := append([]TaskState{}, tasks...)
+	sortTasks(sorted)
+
+	content, err := json.MarshalIndent(sorted, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(content, '\n'))
+	return err
+}
+
+// ScheduledTasks returns the scheduled tasks of a new container of image,
+// sorted by path and name.
+func ScheduledTasks(image string) ([]TaskState, error) {
+	output, err := powershell(image, tasksScript)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []TaskState
+	if err := unmarshalPSJSON([]byte(output), &tasks); err != nil {
+		return nil, fmt.Errorf("parsing the scheduled tasks of %s: %s", image, err)
+	}
+
+	sortTasks(tasks)
+	return tasks, nil
+}
+
+func sortTasks(tasks []TaskState) {
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].key() < tasks[j].key() })
+}
+
+// DiffTasks describes the tasks added to, removed from and changed in
+// actual relative to baseline. A task that happens to be running counts as
+// ready.
+func DiffTasks(baseline, actual []TaskState) []string {
+	expected := map[string]TaskState{}
+	for _, task := range baseline {
+		expected[task.key()] = task
+	}
+
+	var differences []string
+	for _, task := range actual {
+		baselineTask, ok := expected[task.key()]
+		delete(expected, task.key())
+
+		switch {
+		case !ok:
+			differences = append(differences, fmt.Sprintf("added: %s (state %d)", task.key(), task.State))
+		case stableTaskState(task.State) != stableTaskState(baselineTask.State):
+			differences = append(differences, fmt.Sprintf("changed: %s state %d -> %d", task.key(), baselineTask.State, task.State))
+		}
+	}
+
+	for key := range expected {
+		differences = append(differences, fmt.Sprintf("removed: %s", key))
+	}
+	sort.Strings(differences)
+
+	return differences
+}
+
+func stableTaskState(state int) int {
+	if state == TaskStateRunning {
+		return TaskStateReady
+	}
+
+	return state
+}
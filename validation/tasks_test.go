@@ -0,0 +1,76 @@
+package validation_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("scheduled tasks", func() {
+	var (
+		cleanup = validation.TaskState{TaskPath: `\Microsoft\Windows\Server Manager\`, TaskName: "CleanupOldPerfLogs", State: validation.TaskStateReady}
+		sihost  = validation.TaskState{TaskPath: `\Microsoft\Windows\Shell\`, TaskName: "CreateObjectTask", State: validation.TaskStateReady}
+	)
+
+	Describe("DiffTasks", func() {
+		It("lists added, removed and changed tasks", func() {
+			disabled := cleanup
+			disabled.State = 1
+			added := validation.TaskState{TaskPath: `\`, TaskName: "Updater", State: validation.TaskStateReady}
+
+			Expect(validation.DiffTasks([]validation.TaskState{cleanup, sihost}, []validation.TaskState{disabled, added})).To(Equal([]string{
+				`added: \Updater (state 3)`,
+				`changed: \Microsoft\Windows\Server Manager\CleanupOldPerfLogs state 3 -> 1`,
+				`removed: \Microsoft\Windows\Shell\CreateObjectTask`,
+			}))
+		})
+
+		It("treats a running task as ready", func() {
+			running := sihost
+			running.State = validation.TaskStateRunning
+
+			Expect(validation.DiffTasks([]validation.TaskState{sihost}, []validation.TaskState{running})).To(BeEmpty())
+		})
+	})
+
+	Describe("WriteTasksBaseline", func() {
+		var fixturesDir string
+
+		BeforeEach(func() {
+			var err error
+			fixturesDir, err = ioutil.TempDir("", "fixtures")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(fixturesDir)
+		})
+
+		It("writes a sorted baseline that ReadTasksBaseline reads back", func() {
+			var buf bytes.Buffer
+			Expect(validation.WriteTasksBaseline([]validation.TaskState{sihost, cleanup}, &buf)).To(Succeed())
+
+			path := filepath.Join(fixturesDir, "expected-tasks-2019.json")
+			Expect(ioutil.WriteFile(path, buf.Bytes(), 0644)).To(Succeed())
+
+			Expect(validation.ReadTasksBaseline(path)).To(Equal([]validation.TaskState{cleanup, sihost}))
+		})
+
+		It("reads a baseline of one task that PowerShell wrote as an object", func() {
+			path := filepath.Join(fixturesDir, "expected-tasks-2022.json")
+			Expect(ioutil.WriteFile(path, []byte("\xef\xbb\xbf"+`{"TaskPath":"\\Microsoft\\Windows\\Shell\\","TaskName":"CreateObjectTask","State":3}`), 0644)).To(Succeed())
+
+			Expect(validation.ReadTasksBaseline(path)).To(Equal([]validation.TaskState{sihost}))
+		})
+	})
+
+	It("keeps baselines per tag in FixturesDir", func() {
+		Expect(validation.TasksBaselinePath("2019-nanoserver")).To(Equal(filepath.Join(validation.FixturesDir, "expected-tasks-2019-nanoserver.json")))
+	})
+})
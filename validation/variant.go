@@ -0,0 +1,103 @@
+package validation
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultVariant is the servercore image built from <tag>/Dockerfile.
+const DefaultVariant = "servercore"
+
+// Variant is a flavour of the image built from its own Dockerfile in
+// <tag>/<variant>, and validated with the checks and specs that apply to it.
+type Variant struct {
+	// Checks are the names of the checks in CheckNames that apply to the
+	// variant; nil means all of them.
+	Checks []string
+
+	// Specs are the texts of the windows2016fs suite specs that apply to the
+	// variant; nil means all of them.
+	Specs []string
+}
+
+// Variants maps each supported variant to what is validated for it.
+var Variants = map[string]Variant{
+	DefaultVariant: {},
+
+	// nanoserver has neither PowerShell nor the installers of servercore, so
+	// only the specs that inspect it from the outside or with cmd apply.
+	"nanoserver": {
+		Checks: []string{"os-build"},
+		Specs: []string{
+			"runs the expected Windows build",
+			"is built FROM a base image of its LTSC release",
+			"matches golden layer digests",
+			"has the expected image config",
+			"builds reproducibly",
+			"has no critical vulnerabilities",
+			"was built from a recent base image",
+			"was built from the pinned base image",
+			"is labelled with its build metadata",
+			"fixtures match the recorded digest",
+			"stays within its image size budget",
+			"runs under groot-windows and winc",
+		},
+	},
+}
+
+// VariantFor returns the variant called name, or DefaultVariant when name is
+// empty.
+func VariantFor(name string) (Variant, error) {
+	if name == "" {
+		name = DefaultVariant
+	}
+
+	variant, ok := Variants[name]
+	if !ok {
+		var names []string
+		for known := range Variants {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+
+		return Variant{}, fmt.Errorf("unknown variant %q; known variants are %s", name, strings.Join(names, ", "))
+	}
+
+	return variant, nil
+}
+
+// VariantDir returns the directory holding the Dockerfile and dependency
+// manifest of variant for tag.
+func VariantDir(tag, variant string) string {
+	if variant == "" || variant == DefaultVariant {
+		return tag
+	}
+
+	return filepath.Join(tag, variant)
+}
+
+// VariantTag returns the image tag of variant for tag, e.g. "2019-nanoserver".
+func VariantTag(tag, variant string) string {
+	if variant == "" || variant == DefaultVariant {
+		return tag
+	}
+
+	return tag + "-" + variant
+}
+
+// AppliesTo reports whether the spec with text applies to the variant.
+func (v Variant) AppliesTo(spec string) bool {
+	if v.Specs == nil {
+		return true
+	}
+
+	for _, text := range v.Specs {
+		if text == spec {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,83 @@
+package validation_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Variants", func() {
+	It("only name known checks", func() {
+		for name, variant := range validation.Variants {
+			for _, check := range variant.Checks {
+				Expect(validation.CheckNames()).To(ContainElement(check), "variant %s", name)
+			}
+		}
+	})
+
+	It("only name specs of the windows2016fs suite", func() {
+		files, err := filepath.Glob(filepath.Join("..", "*_test.go"))
+		Expect(err).ToNot(HaveOccurred())
+
+		specs := map[string]bool{}
+		fset := token.NewFileSet()
+		for _, file := range files {
+			parsed, err := parser.ParseFile(fset, file, nil, 0)
+			Expect(err).ToNot(HaveOccurred())
+
+			ast.Inspect(parsed, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok || len(call.Args) == 0 {
+					return true
+				}
+				if name, ok := call.Fun.(*ast.Ident); !ok || name.Name != "It" {
+					return true
+				}
+				if text, ok := call.Args[0].(*ast.BasicLit); ok && text.Kind == token.STRING {
+					unquoted, err := strconv.Unquote(text.Value)
+					Expect(err).ToNot(HaveOccurred())
+					specs[unquoted] = true
+				}
+				return true
+			})
+		}
+		Expect(specs).ToNot(BeEmpty())
+
+		for name, variant := range validation.Variants {
+			for _, spec := range variant.Specs {
+				Expect(specs).To(HaveKey(spec), "variant %s", name)
+			}
+		}
+	})
+
+	It("defaults to servercore, which runs every spec from the version directory", func() {
+		variant, err := validation.VariantFor("")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(variant.AppliesTo("has expected list of services")).To(BeTrue())
+		Expect(validation.VariantDir("2019", validation.DefaultVariant)).To(Equal("2019"))
+		Expect(validation.VariantTag("2019", validation.DefaultVariant)).To(Equal("2019"))
+	})
+
+	It("limits nanoserver to its own specs and directory", func() {
+		variant, err := validation.VariantFor("nanoserver")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(variant.AppliesTo("runs the expected Windows build")).To(BeTrue())
+		Expect(variant.AppliesTo("has expected list of services")).To(BeFalse())
+		Expect(validation.VariantDir("2019", "nanoserver")).To(Equal(filepath.Join("2019", "nanoserver")))
+		Expect(validation.VariantTag("2019", "nanoserver")).To(Equal("2019-nanoserver"))
+	})
+
+	It("rejects unknown variants", func() {
+		_, err := validation.VariantFor("desktop")
+		Expect(err).To(MatchError(`unknown variant "desktop"; known variants are nanoserver, servercore`))
+	})
+})
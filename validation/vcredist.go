@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CheckVCRedist checks that candidate contains a DLL from each Visual C++
+// redistributable in its tag's profile.
+func CheckVCRedist(candidate Candidate) (CheckResult, error) {
+	profile, err := candidate.Profile()
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	var versions []string
+	for version := range profile.VCRedistDLLs {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	var script strings.Builder
+	for _, version := range versions {
+		fmt.Fprintf(&script, "Test-Path -LiteralPath '%s'\n", profile.VCRedistDLLs[version])
+	}
+
+	output, err := powershell(candidate.Image, script.String())
+	if err != nil {
+		return CheckResult{}, err
+	}
+
+	lines := NonEmptyLines(output)
+	if len(lines) != len(versions) {
+		return CheckResult{}, fmt.Errorf("unexpected Test-Path output %q", output)
+	}
+
+	metadata := map[string]string{}
+	var missing []string
+	for i, version := range versions {
+		present := strings.EqualFold(lines[i], "True")
+		metadata[version] = fmt.Sprint(present)
+		if !present {
+			missing = append(missing, fmt.Sprintf("%s (%s)", version, profile.VCRedistDLLs[version]))
+		}
+	}
+
+	if len(missing) > 0 {
+		return failed("vcredist", metadata, "missing Visual C++ redistributables: %s", strings.Join(missing, ", ")), nil
+	}
+
+	return passed("vcredist", metadata), nil
+}
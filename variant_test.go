@@ -0,0 +1,32 @@
+package windows2016fs_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/cloudfoundry/windows2016fs/validation"
+)
+
+// imageVariant is the variant of the image under test, resolved from
+// IMAGE_VARIANT in BeforeSuite, and variant holds what applies to it.
+var (
+	imageVariant = validation.DefaultVariant
+	variant      validation.Variant
+)
+
+// resolveVariant returns IMAGE_VARIANT, or the default servercore variant
+// when it is unset, failing on variants not in validation.Variants.
+func resolveVariant() (string, validation.Variant, error) {
+	name := os.Getenv("IMAGE_VARIANT")
+	if name == "" {
+		name = validation.DefaultVariant
+	}
+
+	selected, err := validation.VariantFor(name)
+	return name, selected, err
+}
+
+// dockerfilePath returns the Dockerfile of the variant under test for tag.
+func dockerfilePath(tag string) string {
+	return filepath.Join(validation.VariantDir(tag, imageVariant), "Dockerfile")
+}
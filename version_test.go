@@ -0,0 +1,45 @@
+package windows2016fs_test
+
+import (
+	"strconv"
+	"strings"
+)
+
+// compareVersions compares dotted numeric versions such as "4.8.03761",
+// returning -1, 0 or 1. Missing components count as zero and any
+// pre-release suffix (e.g. "-preview.1") is ignored.
+func compareVersions(a, b string) int {
+	aParts := versionParts(a)
+	bParts := versionParts(b)
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var x, y int
+		if i < len(aParts) {
+			x = aParts[i]
+		}
+		if i < len(bParts) {
+			y = bParts[i]
+		}
+
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+
+	return 0
+}
+
+func versionParts(version string) []int {
+	version = strings.SplitN(strings.TrimSpace(version), "-", 2)[0]
+
+	var parts []int
+	for _, field := range strings.Split(version, ".") {
+		part, _ := strconv.Atoi(field)
+		parts = append(parts, part)
+	}
+
+	return parts
+}
@@ -0,0 +1,126 @@
+package windows2016fs_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+)
+
+// wincRuntime is groot-windows, which turns an image into a container's
+// volume, and winc, which runs the container, as Cloud Foundry runs the
+// rootfs. GROOT_PATH and WINC_PATH locate their binaries.
+type wincRuntime struct {
+	groot, winc string
+
+	// driverStore is groot's store of layers and volumes.
+	driverStore string
+}
+
+// lookupWincRuntime returns the runtime of GROOT_PATH and WINC_PATH, keeping
+// groot's store in dir, and false when either is unset.
+func lookupWincRuntime(dir string) (wincRuntime, bool) {
+	groot, winc := os.Getenv("GROOT_PATH"), os.Getenv("WINC_PATH")
+	if groot == "" || winc == "" {
+		return wincRuntime{}, false
+	}
+
+	return wincRuntime{groot: groot, winc: winc, driverStore: filepath.Join(dir, "groot")}, true
+}
+
+// runInWinc saves image as an OCI image layout under dir, creates a container
+// of it with groot and winc, runs params in it and deletes it again. It
+// returns the output of params; deleting the container and its volume must
+// succeed too.
+func (r wincRuntime) runInWinc(image, dir string, params ...string) (string, error) {
+	var output string
+
+	err := timedCheck("run", func(ctx context.Context) error {
+		layout := filepath.Join(dir, "layout")
+		if err := builder.SaveLayout(ctx, image, layout); err != nil {
+			return err
+		}
+
+		var err error
+		output, err = r.run(ctx, layout, filepath.Join(dir, "bundle"), newContainerName(), params)
+		return err
+	})
+
+	return output, err
+}
+
+func (r wincRuntime) run(ctx context.Context, layout, bundle, handle string, params []string) (output string, err error) {
+	spec, err := r.command(ctx, r.groot, "--driver-store", r.driverStore, "create", "oci:///"+filepath.ToSlash(layout), handle)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if _, deleteErr := r.command(context.Background(), r.groot, "--driver-store", r.driverStore, "delete", handle); err == nil {
+			err = deleteErr
+		}
+	}()
+
+	if err := writeBundle(bundle, []byte(spec)); err != nil {
+		return "", err
+	}
+
+	if _, err := r.command(ctx, r.winc, "create", "--bundle", bundle, handle); err != nil {
+		return "", err
+	}
+	defer func() {
+		if _, deleteErr := r.command(context.Background(), r.winc, "delete", handle); err == nil {
+			err = deleteErr
+		}
+	}()
+
+	return r.command(ctx, r.winc, append([]string{"exec", handle}, params...)...)
+}
+
+// writeBundle writes the runtime spec groot created to bundle, with the
+// process winc starts the container with.
+func writeBundle(bundle string, spec []byte) error {
+	var config map[string]interface{}
+	if err := json.Unmarshal(spec, &config); err != nil {
+		return fmt.Errorf("parsing groot's runtime spec: %s", err)
+	}
+	config["process"] = map[string]interface{}{
+		"args": []string{"cmd", "/c", "ping -t localhost > NUL"},
+		"cwd":  `C:\`,
+	}
+
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(bundle, 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(bundle, "config.json"), content, 0644)
+}
+
+// command runs name with args and returns its stdout. groot and winc log to
+// stderr, which is only reported when they fail.
+func (r wincRuntime) command(ctx context.Context, name string, args ...string) (string, error) {
+	output, err := cmdlog.Output(exec.CommandContext(ctx, name, args...))
+	if err != nil {
+		var stderr []byte
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			stderr = exitErr.Stderr
+		}
+
+		return "", fmt.Errorf("%s %s failed: %s: %s", filepath.Base(name), strings.Join(args, " "), err, strings.TrimSpace(string(stderr)))
+	}
+
+	return string(output), nil
+}
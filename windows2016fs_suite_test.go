@@ -9,5 +9,5 @@ import (
 
 func TestWindows2016fs(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Windows2016fs Suite")
+	RunSpecsWithDefaultAndCustomReporters(t, "Windows2016fs Suite", []Reporter{suiteResults})
 }
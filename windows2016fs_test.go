@@ -1,30 +1,60 @@
 package windows2016fs_test
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/cloudfoundry/windows2016fs/builder"
+	"github.com/cloudfoundry/windows2016fs/cleanup"
+	"github.com/cloudfoundry/windows2016fs/config"
+	"github.com/cloudfoundry/windows2016fs/internal/cmdlog"
+	"github.com/cloudfoundry/windows2016fs/registry"
+	"github.com/cloudfoundry/windows2016fs/validation"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gexec"
 )
 
 var (
-	SESSION_TIMEOUT = 10 * time.Minute
+	images = &validation.ImageCatalog{}
+
+	// suiteConfig holds the settings loaded in BeforeSuite.
+	suiteConfig config.Config
 )
 
 func expectCommand(executable string, params ...string) {
-	command := exec.Command(executable, params...)
-	session, err := Start(command, GinkgoWriter, GinkgoWriter)
-	Expect(err).ToNot(HaveOccurred())
-	Eventually(session, SESSION_TIMEOUT).Should(Exit(0))
+	expectCheckCommand("command", executable, params...)
+}
+
+// expectCheckCommand runs executable within the timeout of the named check
+// and asserts that it succeeds. docker commands failing with a transient
+// error are run again per validation.DockerRetry.
+func expectCheckCommand(check string, executable string, params ...string) {
+	timeout := checkTimeout(check)
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		session := runSession(exec.Command(executable, params...), timeout)
+		Expect(session).To(Exit(), fmt.Sprintf("%s check timed out after waiting %s", check, timeout))
+
+		output := string(session.Out.Contents()) + string(session.Err.Contents())
+		if session.ExitCode() == 0 || executable != validation.Runtime.Name() || !validation.DockerRetry.ShouldRetry(attempt, output) {
+			Expect(session.ExitCode()).To(Equal(0), fmt.Sprintf("%s check failed", check))
+			break
+		}
+
+		fmt.Fprintf(GinkgoWriter, "%s check failed with a transient error, retrying (attempt %d of %d)\n", check, attempt+1, validation.DockerRetry.Attempts)
+		Expect(validation.DockerRetry.Wait(context.Background(), attempt)).To(Succeed())
+	}
+
+	fmt.Fprintf(GinkgoWriter, "%s check took %s\n", check, time.Since(start).Round(time.Millisecond))
 }
 
 func lookupEnv(envName string) string {
@@ -37,70 +67,123 @@ func lookupEnv(envName string) string {
 }
 
 func buildDockerImage(tempDirPath, depDir, imageNameAndTag, tag string) {
-	dockerSrcPath := filepath.Join(tag, "Dockerfile")
-	Expect(dockerSrcPath).To(BeARegularFile())
-
-	Expect(depDir).To(BeADirectory())
+	Expect(dockerfilePath(tag)).To(BeARegularFile())
 
-	expectCommand("powershell", "Copy-Item", "-Path", dockerSrcPath, "-Destination", tempDirPath)
+	opts, err := builder.ForVariant(tag, imageVariant, depDir)
+	Expect(err).ToNot(HaveOccurred())
 
-	expectCommand("powershell", "Copy-Item", "-Path", filepath.Join(depDir, "*"), "-Destination", tempDirPath)
+	opts.StrictDependencies = os.Getenv("VERIFY_DEPENDENCIES") != ""
+	opts.Reuse = os.Getenv("FORCE_CANDIDATE_REBUILD") == ""
+	opts.ContextDir = tempDirPath
+	opts.Image = imageNameAndTag
+	opts.Platform = targetPlatform
+	opts.Stdout = GinkgoWriter
+	opts.Stderr = GinkgoWriter
+	if artifactsDir := os.Getenv("ARTIFACTS_DIR"); artifactsDir != "" {
+		opts.ReportPath = filepath.Join(artifactsDir, builder.ReportName)
+	}
 
-	expectCommand(
-		"docker",
-		"build",
-		"-f", filepath.Join(tempDirPath, "Dockerfile"),
-		"--tag", imageNameAndTag,
-		"--pull",
-		tempDirPath,
-	)
+	Expect(pullAndBuild(&opts)).To(Succeed())
 }
 
+// buildTestDockerImage builds the test image on top of imageNameAndTag,
+// reusing an existing test image built from the same candidate and fixtures
+// unless FORCE_TEST_IMAGE_REBUILD is set.
 func buildTestDockerImage(imageNameAndTag, testImageNameAndTag string) {
-	expectCommand(
-		"docker",
+	skipUnderCtr()
+
+	cacheKey, err := testImageCacheKey(imageNameAndTag)
+	Expect(err).ToNot(HaveOccurred())
+
+	if os.Getenv("FORCE_TEST_IMAGE_REBUILD") == "" && testImageUpToDate(testImageNameAndTag, cacheKey) {
+		return
+	}
+
+	expectCheckCommand(
+		"build",
+		validation.Runtime.Name(),
 		"build",
 		"-f", filepath.Join("fixtures", "test.Dockerfile"),
 		"--build-arg", fmt.Sprintf("CI_IMAGE_NAME_AND_TAG=%s", imageNameAndTag),
+		"--label", fmt.Sprintf("%s=%s", testImageCacheLabel, cacheKey),
 		"--tag", testImageNameAndTag,
+		"--platform", targetPlatform,
 		"fixtures",
 	)
+	cleanups.Image(testImageNameAndTag)
 }
 
-func expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, imageNameAndTag string) {
-	command := exec.Command(
-		"docker",
-		"run",
-		"--rm",
-		"--user", "vcap",
-		"--env", fmt.Sprintf("SHARE_UNC=%s", shareUnc),
-		"--env", fmt.Sprintf("SHARE_USERNAME=%s", shareUsername),
-		"--env", fmt.Sprintf("SHARE_PASSWORD=%s", sharePassword),
-		imageNameAndTag,
-		"powershell",
-		`.\container-test.ps1`,
-	)
+// expectCommandToFail runs executable and asserts that it exits with
+// expectedCode and writes stderrSubstring to stderr.
+func expectCommandToFail(expectedCode int, stderrSubstring string, executable string, params ...string) {
+	session := runSession(exec.Command(executable, params...), checkTimeout("command"))
+	Expect(session).To(Exit(expectedCode))
+	Expect(string(session.Err.Contents())).To(ContainSubstring(stderrSubstring))
+}
+
+// mountSMBSpec describes a container that runs container-test.ps1 against
+// shareUnc. extraRunArgs are appended to the docker run flags and extraEnv
+// holds additional KEY=VALUE pairs, such as SHARE_VIA_PROXY, that select
+// alternative mount behaviour in the script. With a gMSA credential spec
+// configured the container authenticates as the gMSA instead.
+func mountSMBSpec(shareUnc, shareUsername, sharePassword, imageNameAndTag string, extraRunArgs []string, extraEnv ...string) validation.ContainerSpec {
+	spec := validation.SMBMountSpec(imageNameAndTag, shareUnc, shareUsername, sharePassword)
+	if gmsaCredentialSpec != "" {
+		spec = validation.GMSAMountSpec(imageNameAndTag, shareUnc, gmsaCredentialSpec)
+	}
+	for _, pair := range extraEnv {
+		keyValue := strings.SplitN(pair, "=", 2)
+		spec.Env[keyValue[0]] = keyValue[len(keyValue)-1]
+	}
+
+	spec.Name = newContainerName()
+	spec.ExtraArgs = extraRunArgs
+	spec.KeepContainer = true
+	spec.Stdout = GinkgoWriter
+	spec.Stderr = GinkgoWriter
+
+	return spec
+}
+
+func mountSMBArgs(shareUnc, shareUsername, sharePassword, imageNameAndTag string, extraRunArgs []string, extraEnv ...string) []string {
+	return mountSMBSpec(shareUnc, shareUsername, sharePassword, imageNameAndTag, extraRunArgs, extraEnv...).Args()
+}
 
-	session, err := Start(command, GinkgoWriter, GinkgoWriter)
+func expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, imageNameAndTag string, extraRunArgs []string, extraEnv ...string) {
+	smbMapping, err := mountSMBImage(mountSMBSpec(shareUnc, shareUsername, sharePassword, imageNameAndTag, extraRunArgs, extraEnv...))
 	Expect(err).ToNot(HaveOccurred())
 
-	Eventually(session, SESSION_TIMEOUT).Should(Exit(0))
+	if reason := validation.SkipReason(smbMapping); reason != "" {
+		Skip(reason)
+	}
 
-	smbMapping := string(session.Out.Contents())
 	Expect(smbMapping).To(ContainSubstring("T:"))
 	Expect(smbMapping).To(ContainSubstring(shareUnc))
 }
 
-type serviceState struct {
-	Name      string
-	StartType int
-	Status    int
+// readOnlyWriteDenied is printed by container-test.ps1 in SHARE_READONLY mode
+// when a write to the share was refused with access denied.
+const readOnlyWriteDenied = "READONLY: write denied"
+
+// candidateImage returns the image registered for tag in BeforeSuite.
+func candidateImage(tag string) string {
+	image, ok := images.Get(tag)
+	Expect(ok).To(BeTrue(), fmt.Sprintf("no candidate image for tag %s", tag))
+
+	return image
+}
+
+// candidate returns the image registered for tag in BeforeSuite as a
+// validation candidate of tag and the suite's variant, so that the checks
+// apply the expectations of the suite's version tag whatever the image is
+// called.
+func candidate(tag string) validation.Candidate {
+	return validation.Candidate{Image: candidateImage(tag), Tag: tag, Variant: imageVariant}
 }
 
 var _ = Describe("Windows2016fs", func() {
 	var (
 		tag                 string
-		imageNameAndTag     string
 		testImageNameAndTag string
 		tempDirPath         string
 		shareUsername       string
@@ -108,165 +191,860 @@ var _ = Describe("Windows2016fs", func() {
 		shareName           string
 		shareIP             string
 		shareFqdn           string
+		extraRunArgs        []string
+		hostSMBSnapshot     []Mapping
 		err                 error
 	)
 
 	BeforeSuite(func() {
-		tempDirPath, err = ioutil.TempDir("", "build")
+		suiteConfig, err = config.Load(os.Getenv(config.FileEnv))
+		Expect(err).NotTo(HaveOccurred())
+		checkTimeouts = suiteConfig.Timeouts.ByCheck()
+		validation.DockerRetry = suiteConfig.Retry.Policy()
+		validation.Runtime, err = validation.RuntimeFor(suiteConfig.ContainerRuntime)
 		Expect(err).NotTo(HaveOccurred())
 
-		shareName = lookupEnv("SHARE_NAME")
-		shareUsername = lookupEnv("SHARE_USERNAME")
-		sharePassword = lookupEnv("SHARE_PASSWORD")
-		shareFqdn = lookupEnv("SHARE_FQDN")
-		shareIP = lookupEnv("SHARE_IP")
-		tag = lookupEnv("VERSION_TAG")
+		cmdlog.Default, err = openCommandLog(suiteConfig.CommandLog)
+		Expect(err).NotTo(HaveOccurred())
+		cmdlog.Default.Redact(suiteConfig.Share.Password)
+
+		hostSMBSnapshot, err = snapshotHostSMB()
+		Expect(err).NotTo(HaveOccurred())
+
+		tempDirPath, err = ioutil.TempDir("", cleanup.TempDirPrefix+"build")
+		Expect(err).NotTo(HaveOccurred())
+		cleanups.Dir(tempDirPath)
+
+		shareName = suiteConfig.Share.Name
+		shareUsername = suiteConfig.Share.Username
+		sharePassword = suiteConfig.Share.Password
+		shareFqdn = suiteConfig.Share.FQDN
+		shareIP = suiteConfig.Share.IP
+		gmsaCredentialSpec = suiteConfig.Share.GMSACredentialSpec
+		tag = suiteConfig.VersionTag
 		testImageNameAndTag = fmt.Sprintf("windows2016fs-test:%s", tag)
 
-		if os.Getenv("TEST_CANDIDATE_IMAGE") == "" {
-			depDir := lookupEnv("DEPENDENCIES_DIR")
-			imageNameAndTag = fmt.Sprintf("windows2016fs-candidate:%s", tag)
-			buildDockerImage(tempDirPath, depDir, imageNameAndTag, tag)
-		} else {
-			imageNameAndTag = os.Getenv("TEST_CANDIDATE_IMAGE")
+		targetPlatform, err = resolveTargetPlatform()
+		Expect(err).ToNot(HaveOccurred())
+		validation.Platform = targetPlatform
+
+		isolation = suiteConfig.Isolation
+		validation.Isolation = isolation
+
+		imageVariant, variant, err = resolveVariant()
+		Expect(err).ToNot(HaveOccurred())
+
+		extraRunArgs, err = lookupExtraRunArgs(os.Getenv("EXTRA_RUN_ARGS"))
+		Expect(err).ToNot(HaveOccurred())
+
+		if smbSpecsSelected() {
+			probeTimeout, err := shareProbeTimeout()
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, host := range []string{shareIP, shareFqdn} {
+				Expect(probeShareReachable(host, probeTimeout)).To(Succeed())
+			}
 		}
+
+		var imageNameAndTag string
+		switch {
+		case os.Getenv("LIVE_CONTAINER") != "":
+			imageNameAndTag, err = useLiveContainer(os.Getenv("LIVE_CONTAINER"))
+			Expect(err).ToNot(HaveOccurred())
+		case suiteConfig.CandidateImage != "":
+			imageNameAndTag = suiteConfig.CandidateImage
+		default:
+			imageNameAndTag = builder.CandidateImage(validation.VariantTag(tag, imageVariant))
+			cleanups.Image(imageNameAndTag)
+
+			if tarPath := os.Getenv("BUILD_CONTEXT_TAR"); tarPath != "" {
+				Expect(buildFromTar(tarPath, dockerfilePath(tag), imageNameAndTag)).To(Succeed())
+			} else {
+				buildDockerImage(tempDirPath, suiteConfig.DependenciesDir, imageNameAndTag, tag)
+			}
+		}
+
+		images.Set(tag, imageNameAndTag)
+	})
+
+	BeforeEach(func() {
+		if spec := CurrentGinkgoTestDescription().TestText; !variant.AppliesTo(spec) {
+			Skip(fmt.Sprintf("does not apply to the %s variant", imageVariant))
+		}
+	})
+
+	AfterSuite(func() {
+		defer cmdlog.Default.Close()
+		defer cleanUpSuite()
+
+		if hostSMBSnapshot != nil {
+			Expect(restoreHostSMB(hostSMBSnapshot)).To(Succeed())
+		}
+
+		Expect(validation.PrintSummary(suiteResults.summary(), os.Stdout)).To(Succeed())
+
+		if reportDir := reportDir(suiteConfig.ReportDir); reportDir != "" {
+			image, _ := images.Get(tag)
+			run := suiteResults.report(image, validation.VariantTag(tag, imageVariant))
+			run.Isolation = isolation
+			paths, err := run.Write(reportDir)
+			Expect(err).ToNot(HaveOccurred())
+			fmt.Fprintf(GinkgoWriter, "wrote %s\n", strings.Join(paths, ", "))
+		}
+
+		if suiteConfig.ArtifactsBucket != "" && suiteResults.allPassed() {
+			Expect(uploadArtifacts(suiteConfig.ArtifactsBucket, reportDir(suiteConfig.ReportDir), os.Getenv("ARTIFACTS_DIR"))).To(Succeed())
+		}
+
+		if os.Getenv("PUSH_ON_SUCCESS") == "" {
+			return
+		}
+
+		if !suiteResults.allPassed() {
+			fmt.Fprintln(GinkgoWriter, "not pushing: not all specs passed")
+			return
+		}
+
+		pushTarget := lookupEnv("PUSH_TARGET")
+		digest, err := validation.PushImage(candidateImage(tag), pushTarget)
+		Expect(err).ToNot(HaveOccurred())
+		cleanups.Image(pushTarget)
+
+		fmt.Fprintf(GinkgoWriter, "pushed %s@%s\n", pushTarget, digest)
+		Expect(signPushed(pushTarget, digest)).To(Succeed())
+		if artifactsDir := os.Getenv("ARTIFACTS_DIR"); artifactsDir != "" {
+			Expect(os.MkdirAll(artifactsDir, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(artifactsDir, "pushed-digest"), []byte(digest), 0644)).To(Succeed())
+		}
+	})
+
+	JustAfterEach(func() {
+		collectSpecContainers()
 	})
 
 	It("can write to an IP-based smb share", func() {
 		shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, shareName)
-		buildTestDockerImage(imageNameAndTag, testImageNameAndTag)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
 
-		expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, testImageNameAndTag)
+		expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, testImageNameAndTag, extraRunArgs)
 	})
 
 	It("can write to an FQDN-based smb share", func() {
 		shareUnc := fmt.Sprintf(`\\%s\%s`, shareFqdn, shareName)
-		buildTestDockerImage(imageNameAndTag, testImageNameAndTag)
-		expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, testImageNameAndTag)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+		expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, testImageNameAndTag, extraRunArgs)
+	})
+
+	It("can write to an smb share over QUIC when a proxy is required", func() {
+		if os.Getenv("SHARE_VIA_PROXY") == "" {
+			Skip("SHARE_VIA_PROXY is not set")
+		}
+		skipWithGMSA()
+
+		shareUnc := fmt.Sprintf(`\\%s\%s`, shareFqdn, shareName)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+		expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, testImageNameAndTag, extraRunArgs, "SHARE_VIA_PROXY=1")
+	})
+
+	It("can write to an smb share using a credential file", func() {
+		if os.Getenv("USE_CREDS_FILE") == "" {
+			Skip("USE_CREDS_FILE is not set")
+		}
+		skipWithGMSA()
+
+		shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, shareName)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		spec, cleanup, err := withCredentialsFile(mountSMBSpec(shareUnc, shareUsername, sharePassword, testImageNameAndTag, extraRunArgs), shareUsername, sharePassword)
+		Expect(err).ToNot(HaveOccurred())
+		defer cleanup()
+
+		smbMapping, err := mountSMBImage(spec)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(smbMapping).To(ContainSubstring("T:"))
+		Expect(smbMapping).To(ContainSubstring(shareUnc))
+	})
+
+	It("fails to mount an smb share with a bad password", func() {
+		skipWithGMSA()
+
+		shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, shareName)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		// net use exits 2 on any failure; container-test.ps1 reports access denied on stderr
+		expectCommandToFail(
+			2,
+			"ERROR: access denied",
+			validation.Runtime.Name(),
+			mountSMBArgs(shareUnc, shareUsername, sharePassword+"-wrong", testImageNameAndTag, extraRunArgs)...,
+		)
 	})
 
 	It("can access one share multiple times on the same VM", func() {
 		shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, shareName)
-		buildTestDockerImage(imageNameAndTag, testImageNameAndTag)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
 
 		concurrentConnections := 10
-		wg := new(sync.WaitGroup)
-		wg.Add(concurrentConnections)
-
-		for i := 1; i <= concurrentConnections; i++ {
-			go func() {
-				expectMountSMBImage(shareUnc, shareUsername, sharePassword, tempDirPath, testImageNameAndTag)
-				wg.Done()
-			}()
+		specs := make([]validation.ContainerSpec, concurrentConnections)
+		for i := range specs {
+			specs[i] = mountSMBSpec(shareUnc, shareUsername, sharePassword, testImageNameAndTag, extraRunArgs)
 		}
 
-		wg.Wait()
+		results := validation.MountConcurrently(concurrentConnections, func(worker int) (string, error) {
+			return mountSMBImage(specs[worker])
+		})
+
+		expectMountResults(results, shareUnc)
 	})
 
 	It("has expected list of services", func() {
-		Skip("this test is brittle and serves little value")
+		skipWithoutServicesBaseline(tag)
 
-		//Expected baseline service generated by: `docker run cloudfoundry/windows2016fs:2019 powershell "Get-Service | ConvertTo-JSON" > .\fixtures\expected-baseline-services-2019.json`
-		jsonData, err := ioutil.ReadFile(filepath.Join("fixtures", fmt.Sprintf("expected-baseline-services-%s.json", tag)))
+		expectCheckToPass(validation.CheckServices, candidate(tag))
+	})
+
+	It("has expected version of .NET Framework", func() {
+		expectCheckToPass(validation.CheckDotNet, candidate(tag))
+	})
+
+	It("runs the expected Windows build", func() {
+		expectCheckToPass(validation.CheckOSBuild, candidate(tag))
+	})
+
+	It("is built FROM a base image of its LTSC release", func() {
+		base, err := baseImage(tag)
 		Expect(err).ToNot(HaveOccurred())
 
-		var baselineServices []serviceState
-		err = json.Unmarshal(jsonData, &baselineServices)
+		Expect(validation.VerifyBaseImageTag(tag, base)).To(Succeed())
+	})
+
+	It("has expected .NET runtimes", func() {
+		var expected []Runtime
+		Expect(loadTagFixture("expected-dotnet-runtimes", tag, &expected)).To(Succeed())
+
+		installed, err := installedDotNetRuntimes(candidateImage(tag))
 		Expect(err).ToNot(HaveOccurred())
 
-		command := exec.Command(
-			"docker",
-			"run",
-			"--rm",
-			imageNameAndTag,
-			"powershell", "Get-Service | ConvertTo-JSON",
-		)
+		Expect(missingRuntimes(expected, installed)).To(BeEmpty(), fmt.Sprintf("installed runtimes: %+v", installed))
+	})
+
+	It("matches golden layer digests", func() {
+		if os.Getenv("CHECK_GOLDEN_LAYERS") == "" {
+			Skip("CHECK_GOLDEN_LAYERS is not set")
+		}
+		skipUnderCtr()
 
-		session, err := Start(command, GinkgoWriter, GinkgoWriter)
+		// Golden layers generated by: `docker image inspect --format "{{json .RootFS.Layers}}" windows2016fs-candidate:2019 > .\fixtures\golden-layers-2019.json`
+		var golden []string
+		Expect(loadTagFixture("golden-layers", tag, &golden)).To(Succeed())
+
+		actual, err := validation.ImageLayers(candidateImage(tag))
 		Expect(err).ToNot(HaveOccurred())
-		Eventually(session, SESSION_TIMEOUT).Should(Exit(0))
 
-		actualServicesPowershellJSON := session.Out.Contents()
+		Expect(layerDifferences(golden, actual)).To(BeEmpty(), "layer digests differ from golden")
+	})
+
+	It("can import a registry file", func() {
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		args := append([]string{"run", "--rm", "--user", "vcap"}, runFlags()...)
+		command := validation.Runtime.Command(context.Background(), append(args, testImageNameAndTag, "cmd", "/c", `reg import odbc.reg`)...)
 
-		var actualServices []serviceState
-		err = json.Unmarshal(actualServicesPowershellJSON, &actualServices)
+		_, err := command.StdinPipe()
 		Expect(err).ToNot(HaveOccurred())
 
-		Expect(actualServices).To(Equal(baselineServices))
+		session := runSession(command, checkTimeout("run"))
+		Expect(session).To(Exit(0))
+
+		Expect(string(session.Err.Contents())).To(ContainSubstring("The operation completed successfully."))
 	})
 
-	It("has expected version of .NET Framework", func() {
-		command := exec.Command(
-			"docker",
-			"run",
-			"--rm",
-			imageNameAndTag,
-			"powershell", `Get-ChildItem 'HKLM:\SOFTWARE\Microsoft\NET Framework Setup\NDP\v4\Full\' | Get-ItemPropertyValue -Name Release`,
+	It("resolves the driver paths referenced by odbc.reg", func() {
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		// odbc.reg registers a driver that isn't shipped in the image; its
+		// paths are expected not to resolve.
+		var unresolved []string
+		Expect(loadTagFixture("expected-unresolved-odbc-paths", tag, &unresolved)).To(Succeed())
+
+		paths, err := odbcDriverPaths(testImageNameAndTag)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).ToNot(BeEmpty())
+
+		missing, err := missingFiles(testImageNameAndTag, paths)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(withoutValues(missing, unresolved)).To(BeEmpty(), "ODBC driver files are missing")
+	})
+
+	It("contains Visual C++ restributables", func() {
+		expectCheckToPass(validation.CheckVCRedist, candidate(tag))
+	})
+
+	It("exposes only expected listening ports", func() {
+		var allowlist listeningPortsAllowlist
+		Expect(loadTagFixture("expected-listening-ports", tag, &allowlist)).To(Succeed())
+
+		ports, err := listeningPorts(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(allowlist.unexpected(ports)).To(BeEmpty(), "unexpected listening ports")
+	})
+
+	It("has the expected image config", func() {
+		var expected ImageConfig
+		Expect(loadTagFixture("expected-image-config", tag, &expected)).To(Succeed())
+
+		actual, err := imageConfig(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(expected.diff(actual)).To(BeEmpty(), "image config differs from fixture")
+	})
+
+	It("can run a minimal HTTP app", func() {
+		if os.Getenv("SMOKE_APP") == "" {
+			Skip("SMOKE_APP is not set")
+		}
+		skipUnderCtr()
+
+		appImage := fmt.Sprintf("windows2016fs-smoke-app:%s", tag)
+		expectCheckCommand(
+			"build",
+			validation.Runtime.Name(),
+			"build",
+			"-f", filepath.Join("fixtures", "smoke-app", "Dockerfile"),
+			"--build-arg", fmt.Sprintf("CI_IMAGE_NAME_AND_TAG=%s", candidateImage(tag)),
+			"--tag", appImage,
+			"--platform", targetPlatform,
+			filepath.Join("fixtures", "smoke-app"),
 		)
+		defer cmdlog.Run(validation.Runtime.Command(context.Background(), "rmi", "--force", appImage))
 
-		session, err := Start(command, GinkgoWriter, GinkgoWriter)
+		url, err := startSmokeApp(appImage)
 		Expect(err).ToNot(HaveOccurred())
-		Eventually(session, SESSION_TIMEOUT).Should(Exit(0))
 
-		actualFrameworkRelease := strings.TrimSpace(string(session.Out.Contents()))
+		Expect(waitForHTTP(url, smokeAppPollTimeout)).To(Succeed())
+	})
+
+	It("has required directories on PATH", func() {
+		var expected pathExpectations
+		Expect(loadTagFixture("expected-path", tag, &expected)).To(Succeed())
 
-		var expectedFrameworkRelease string
+		path, err := containerPath(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
 
-		// https://docs.microsoft.com/en-us/dotnet/framework/migration-guide/release-keys-and-os-versions
-		if tag == "2019" {
-			expectedFrameworkRelease = "528049" //Framework version 4.8
-		} else {
-			Fail(fmt.Sprintf("unknown tag: %+s", tag))
+		for _, entry := range expected.unexpectedPathEntries(path) {
+			fmt.Fprintf(GinkgoWriter, "WARNING: unexpected PATH entry %s\n", entry)
 		}
 
-		Expect(actualFrameworkRelease).To(Equal(expectedFrameworkRelease))
+		Expect(expected.missingPathEntries(path)).To(BeEmpty(), "required directories are missing from PATH")
 	})
 
-	It("can import a registry file", func() {
-		buildTestDockerImage(imageNameAndTag, testImageNameAndTag)
-
-		command := exec.Command(
-			"docker",
-			"run",
-			"--rm",
-			"--user", "vcap",
-			testImageNameAndTag,
-			"cmd", "/c",
-			`reg import odbc.reg`,
-		)
+	It("has required Windows features enabled", func() {
+		var required []string
+		Expect(loadTagFixture("expected-features", tag, &required)).To(Succeed())
 
-		_, err := command.StdinPipe()
+		features, err := validation.WindowsFeatures(candidateImage(tag))
 		Expect(err).ToNot(HaveOccurred())
 
-		session, err := Start(command, GinkgoWriter, GinkgoWriter)
+		Expect(disabledFeatures(required, features)).To(BeEmpty(), "required Windows features are not enabled")
+	})
+
+	It("has the Windows features of its baseline enabled, and no others", func() {
+		fixture := validation.FeaturesBaselinePath(validation.VariantTag(tag, imageVariant))
+		if _, err := os.Stat(fixture); os.IsNotExist(err) {
+			Skip(fmt.Sprintf("%s does not exist", fixture))
+		}
+
+		var baseline []string
+		Expect(loadTagFixture("enabled-features", tag, &baseline)).To(Succeed())
+
+		features, err := validation.WindowsFeatures(candidateImage(tag))
 		Expect(err).ToNot(HaveOccurred())
 
-		Eventually(session, SESSION_TIMEOUT).Should(Exit(0))
+		Expect(validation.FeatureChanges(baseline, features)).To(BeEmpty(), "Windows features differ from %s", fixture)
+	})
 
-		Expect(string(session.Err.Contents())).To(ContainSubstring("The operation completed successfully."))
+	It("summarizes changes vs. published release", func() {
+		published := os.Getenv("PUBLISHED_IMAGE")
+		if published == "" {
+			Skip("PUBLISHED_IMAGE is not set")
+		}
+		skipUnderCtr()
+
+		expectCheckCommand("pull", validation.Runtime.Name(), "pull", published)
+
+		diff, err := validation.CompareReleases(candidateImage(tag), published)
+		Expect(err).ToNot(HaveOccurred())
+
+		fmt.Fprint(GinkgoWriter, diff)
+		if artifactsDir := os.Getenv("ARTIFACTS_DIR"); artifactsDir != "" {
+			Expect(os.MkdirAll(artifactsDir, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(artifactsDir, "release-diff.txt"), []byte(diff.String()), 0644)).To(Succeed())
+		}
 	})
 
-	It("contains Visual C++ restributable for 2010", func() {
-		buildTestDockerImage(imageNameAndTag, testImageNameAndTag)
+	It("uses the expected console code page", func() {
+		var expected EncodingInfo
+		Expect(loadTagFixture("expected-console-encoding", tag, &expected)).To(Succeed())
 
-		expectCommand(
-			"docker",
-			"run",
-			"--rm",
-			testImageNameAndTag,
-			"powershell", `Get-ChildItem C:\Windows\System32\msvcr100.dll`,
-		)
+		actual, err := consoleEncoding(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(actual).To(Equal(expected))
 	})
 
-	It("contains Visual C++ restributable for 2015+", func() {
-		buildTestDockerImage(imageNameAndTag, testImageNameAndTag)
+	It("has secure ACLs on sensitive files", func() {
+		var paths []string
+		Expect(loadTagFixture("sensitive-paths", tag, &paths)).To(Succeed())
 
-		expectCommand(
-			"docker",
-			"run",
-			"--rm",
-			testImageNameAndTag,
-			"powershell", `Get-ChildItem C:\Windows\System32\vcruntime140.dll`,
-		)
+		var offending []string
+		for _, path := range paths {
+			rules, err := fileACL(candidateImage(tag), path)
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, rule := range rules {
+				if rule.Permissive() {
+					offending = append(offending, fmt.Sprintf("%s: %s %s %s", path, rule.Type, rule.Identity, rule.Rights))
+				}
+			}
+		}
+
+		Expect(offending).To(BeEmpty(), "sensitive files grant write access too broadly")
+	})
+
+	It("has the expected PowerShell version", func() {
+		var expected PowerShellVersionExpectation
+		Expect(loadTagFixture("expected-powershell-version", tag, &expected)).To(Succeed())
+
+		actual, err := powerShellVersion(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(compareVersions(actual, expected.Minimum)).To(BeNumerically(">=", 0),
+			fmt.Sprintf("expected PowerShell %s or later, got %s", expected.Minimum, actual))
+	})
+
+	It("has expected default environment variables", func() {
+		// The fixture maps each variable CF cells rely on to its expected
+		// value. The image config sets none of them, so they are checked in
+		// the environment containers start with, which includes the config's.
+		var required map[string]string
+		Expect(loadTagFixture("expected-env", tag, &required)).To(Succeed())
+		Expect(required).ToNot(BeEmpty())
+
+		actual, err := validation.ContainerEnv(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(envDifferences(required, actual)).To(BeEmpty(), "default environment differs from fixture")
+	})
+
+	It("starts containers with the environment of its baseline", func() {
+		fixture := validation.EnvBaselinePath(validation.VariantTag(tag, imageVariant))
+		if _, err := os.Stat(fixture); os.IsNotExist(err) {
+			Skip(fmt.Sprintf("%s does not exist", fixture))
+		}
+
+		baseline, err := validation.ReadEnvBaseline(fixture)
+		Expect(err).ToNot(HaveOccurred())
+
+		env, err := validation.ContainerEnv(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		diff, err := baseline.Diff(env)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(diff.Empty()).To(BeTrue(), "the container environment differs from %s:\n%s", fixture, diff)
+	})
+
+	It("has the certificates of its baseline in the machine's Root and CA stores", func() {
+		fixture := validation.CertificatesBaselinePath(validation.VariantTag(tag, imageVariant))
+		if _, err := os.Stat(fixture); os.IsNotExist(err) {
+			Skip(fmt.Sprintf("%s does not exist", fixture))
+		}
+
+		window := validation.DefaultCertificateExpiryWarning
+		if value := os.Getenv("CERT_EXPIRY_WARNING"); value != "" {
+			var err error
+			window, err = time.ParseDuration(value)
+			Expect(err).ToNot(HaveOccurred(), "CERT_EXPIRY_WARNING")
+		}
+
+		baseline, err := validation.ReadCertificatesBaseline(fixture)
+		Expect(err).ToNot(HaveOccurred())
+
+		certificates, err := validation.Certificates(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, warning := range validation.ExpiringCertificates(certificates, time.Now(), window) {
+			fmt.Fprintf(GinkgoWriter, "WARNING: %s\n", warning)
+		}
+
+		diff := validation.DiffCertificates(baseline, certificates)
+		Expect(diff.Empty()).To(BeTrue(), "the certificate stores differ from %s:\n%s", fixture, diff)
+	})
+
+	It("has expected Defender configuration", func() {
+		var expected DefenderPrefs
+		Expect(loadTagFixture("expected-defender", tag, &expected)).To(Succeed())
+
+		actual, err := defenderConfig(candidateImage(tag))
+		if err == errDefenderUnavailable {
+			fmt.Fprintf(GinkgoWriter, "skipping Defender checks: %s\n", err)
+			Skip(err.Error())
+		}
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(expected.missingExclusions(actual)).To(BeEmpty(), "Defender exclusions are missing")
+	})
+
+	It("has expected DNS configuration", func() {
+		var expected DNSConfig
+		Expect(loadTagFixture("expected-dns", tag, &expected)).To(Succeed())
+
+		actual, err := dnsConfig(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(actual).To(Equal(expected))
+	})
+
+	It("functions under a memory limit", func() {
+		if os.Getenv("MEMORY_LIMIT_TEST") == "" {
+			Skip("MEMORY_LIMIT_TEST is not set")
+		}
+		skipUnderCtr()
+
+		limit := memoryLimit()
+		runArgs := withMemoryLimit(extraRunArgs, limit)
+
+		shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, shareName)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		spec := mountSMBSpec(shareUnc, shareUsername, sharePassword, testImageNameAndTag, runArgs)
+		smbMapping, err := mountSMBImage(spec)
+		if err != nil {
+			Fail(limitedRunError(spec.Name, limit, err).Error())
+		}
+		Expect(smbMapping).To(ContainSubstring(shareUnc))
+
+		var run validation.ContainerRun
+		err = timedCheck("run", func(ctx context.Context) error {
+			var err error
+			run, err = validation.RunContainer(ctx, validation.ContainerSpec{
+				Image:         candidateImage(tag),
+				Cmd:           []string{"powershell", "-Command", `Get-ChildItem C:\Windows | Measure-Object | Out-String`},
+				Name:          newContainerName(),
+				Platform:      targetPlatform,
+				Isolation:     isolation,
+				ExtraArgs:     runArgs,
+				KeepContainer: true,
+				Stdout:        GinkgoWriter,
+				Stderr:        GinkgoWriter,
+			})
+			return err
+		})
+		Expect(err).ToNot(HaveOccurred())
+		if run.ExitCode != 0 {
+			Fail(limitedRunError(run.Name, limit, fmt.Errorf("exited %d: %s", run.ExitCode, run.Stderr)).Error())
+		}
+	})
+
+	It("builds reproducibly", func() {
+		if os.Getenv("CHECK_REPRODUCIBLE") == "" {
+			Skip("CHECK_REPRODUCIBLE is not set")
+		}
+		skipUnderCtr()
+
+		matched, differences, err := buildTwiceAndCompare(tag, suiteConfig.DependenciesDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matched).To(BeTrue(), fmt.Sprintf("two builds from the same inputs differ:\n%s", strings.Join(differences, "\n")))
+	})
+
+	It("has expected scheduled tasks", func() {
+		// Baselines are written by: go run ./cmd/imagebuilder snapshot tasks -tag 2019 -image cloudfoundry/windows2016fs:2019.12
+		fixture := validation.TasksBaselinePath(validation.VariantTag(tag, imageVariant))
+		if _, err := os.Stat(fixture); os.IsNotExist(err) {
+			Skip(fmt.Sprintf("%s does not exist", fixture))
+		}
+
+		baseline, err := validation.ReadTasksBaseline(fixture)
+		Expect(err).ToNot(HaveOccurred())
+
+		actual, err := validation.ScheduledTasks(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(validation.DiffTasks(baseline, actual)).To(BeEmpty(), "scheduled tasks differ from the baseline")
+	})
+
+	It("has reasonably-sized registry hives", func() {
+		// Ceilings are in MB.
+		var ceilings map[string]int64
+		Expect(loadTagFixture("registry-hive-ceilings", tag, &ceilings)).To(Succeed())
+
+		sizes, err := registryHiveSizes(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(oversizedHives(ceilings, sizes)).To(BeEmpty(), "registry hives are too large")
+	})
+
+	It("has the registry values of its baseline", func() {
+		fixture := validation.RegistryBaselinePath(validation.VariantTag(tag, imageVariant))
+		if _, err := os.Stat(fixture); os.IsNotExist(err) {
+			Skip(fmt.Sprintf("%s does not exist", fixture))
+		}
+
+		expectCheckToPass(validation.CheckRegistry, candidate(tag))
+	})
+
+	It("handles a read-only share correctly", func() {
+		if os.Getenv("SHARE_READONLY") == "" {
+			Skip("SHARE_READONLY is not set")
+		}
+
+		readOnlyShareName := shareName
+		if name := os.Getenv("SHARE_READONLY_NAME"); name != "" {
+			readOnlyShareName = name
+		}
+
+		shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, readOnlyShareName)
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		output, err := mountSMBImage(mountSMBSpec(shareUnc, shareUsername, sharePassword, testImageNameAndTag, extraRunArgs, "SHARE_READONLY=1"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(ContainSubstring(readOnlyWriteDenied))
+	})
+
+	It("has no critical vulnerabilities", func() {
+		if os.Getenv("RUN_CVE_SCAN") == "" {
+			Skip("RUN_CVE_SCAN is not set")
+		}
+
+		threshold := "CRITICAL"
+		if value := os.Getenv("CVE_SEVERITY_THRESHOLD"); value != "" {
+			Expect(validation.ValidSeverity(value)).To(BeTrue(), fmt.Sprintf("unknown CVE_SEVERITY_THRESHOLD %q", value))
+			threshold = value
+		}
+
+		report, err := validation.ScanImage(candidateImage(tag))
+		if err == validation.ErrNoScanner {
+			fmt.Fprintf(GinkgoWriter, "skipping vulnerability scan: %s\n", err)
+			Skip(err.Error())
+		}
+		Expect(err).ToNot(HaveOccurred())
+
+		fmt.Fprintf(GinkgoWriter, "%s findings by severity: %v\n", report.Scanner, report.Counts)
+
+		var accepted []validation.AcceptedVulnerability
+		if err := loadTagFixture("accepted-vulnerabilities", tag, &accepted); err != nil && !os.IsNotExist(err) {
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		unaccepted, err := validation.Unaccepted(report.AtOrAbove(threshold), accepted, time.Now())
+		Expect(err).ToNot(HaveOccurred())
+
+		var findings []string
+		for _, finding := range unaccepted {
+			findings = append(findings, fmt.Sprintf("%s %s in %s %s (fixed in %q)", finding.Severity, finding.ID, finding.Package, finding.InstalledVersion, finding.FixedVersion))
+		}
+		Expect(findings).To(BeEmpty(), fmt.Sprintf("%s found vulnerabilities at or above %s that fixtures/accepted-vulnerabilities-%s.json doesn't accept", report.Scanner, threshold, validation.VariantTag(tag, imageVariant)))
+	})
+
+	It("was built from a recent base image", func() {
+		maxAge, err := maxBaseImageAge()
+		Expect(err).ToNot(HaveOccurred())
+
+		base, err := baseImage(tag)
+		Expect(err).ToNot(HaveOccurred())
+
+		created, err := baseImageCreated(base)
+		Expect(err).ToNot(HaveOccurred())
+
+		age := time.Since(created)
+		Expect(age).To(BeNumerically("<=", maxAge),
+			fmt.Sprintf("base image %s was created %s, %d days ago; the limit is %d days", base, created.Format(time.RFC3339), int(age.Hours()/24), int(maxAge.Hours()/24)))
+	})
+
+	It("passes the user-supplied validation script", func() {
+		scriptPath := os.Getenv("EXTRA_VALIDATION_SCRIPT")
+		if scriptPath == "" {
+			Skip("EXTRA_VALIDATION_SCRIPT is not set")
+		}
+
+		buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+		result, err := runUserValidation(testImageNameAndTag, scriptPath)
+		Expect(err).ToNot(HaveOccurred())
+
+		fmt.Fprintln(GinkgoWriter, result.Metadata["stdout"])
+		Expect(result.Passed).To(BeTrue(), result.Message)
+	})
+
+	It("has expected event log sources registered", func() {
+		var required []string
+		Expect(loadTagFixture("expected-event-log-sources", tag, &required)).To(Succeed())
+
+		registered, err := eventLogSources(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(missingSources(required, registered)).To(BeEmpty(), "event log sources are not registered")
+	})
+
+	It("fixtures match the recorded digest", func() {
+		if os.Getenv("CHECK_FIXTURES_DIGEST") == "" {
+			Skip("CHECK_FIXTURES_DIGEST is not set")
+		}
+
+		recorded, err := ioutil.ReadFile(filepath.Join("fixtures", validation.FixturesDigestFile))
+		Expect(err).ToNot(HaveOccurred())
+
+		actual, err := fixturesDigest("fixtures")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(actual).To(Equal(strings.TrimSpace(string(recorded))),
+			"fixtures changed since the digest was recorded; review the change and run `go run ./cmd/imagebuilder fixtures-digest -write`")
+	})
+
+	for _, mode := range validation.IsolationModes {
+		mode := mode
+
+		It(fmt.Sprintf("can write to an IP-based smb share with %s isolation", mode), func() {
+			if os.Getenv("TEST_BOTH_ISOLATIONS") == "" {
+				Skip("TEST_BOTH_ISOLATIONS is not set")
+			}
+
+			shareUnc := fmt.Sprintf(`\\%s\%s`, shareIP, shareName)
+			buildTestDockerImage(candidateImage(tag), testImageNameAndTag)
+
+			spec := mountSMBSpec(shareUnc, shareUsername, sharePassword, testImageNameAndTag, extraRunArgs)
+			spec.Isolation = mode
+
+			smbMapping, err := mountSMBImage(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(smbMapping).To(ContainSubstring("T:"))
+			Expect(smbMapping).To(ContainSubstring(shareUnc))
+		})
+
+		It(fmt.Sprintf("has expected list of services with %s isolation", mode), func() {
+			if os.Getenv("TEST_BOTH_ISOLATIONS") == "" {
+				Skip("TEST_BOTH_ISOLATIONS is not set")
+			}
+			skipWithoutServicesBaseline(tag)
+
+			defer func(previous string) { validation.Isolation = previous }(validation.Isolation)
+			validation.Isolation = mode
+
+			expectCheckToPass(validation.CheckServices, candidate(tag))
+		})
+	}
+
+	It("has required fonts installed", func() {
+		var required []string
+		Expect(loadTagFixture("expected-fonts", tag, &required)).To(Succeed())
+
+		installed, err := installedFonts(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(missingFonts(required, installed)).To(BeEmpty(), "required fonts are not installed")
+	})
+
+	It("contains only approved installed programs", func() {
+		var allowlist []string
+		Expect(loadTagFixture("allowed-programs", tag, &allowlist)).To(Succeed())
+
+		programs, err := validation.InstalledPrograms(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		unapproved, err := unapprovedPrograms(allowlist, programs)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unapproved).To(BeEmpty(), "programs are installed that aren't on the allowlist in fixtures/allowed-programs-%s.json", tag)
+	})
+
+	It("published image is signed", func() {
+		published := os.Getenv("PUBLISHED_IMAGE")
+		if published == "" {
+			Skip("PUBLISHED_IMAGE is not set")
+		}
+
+		verifier, ok := signatureVerifier()
+		if !ok {
+			Skip("neither COSIGN_PUBLIC_KEY nor COSIGN_CERTIFICATE_IDENTITY is set")
+		}
+
+		ref, err := registry.ParseReference(published)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(verifier.Verify(context.Background(), ref)).To(Succeed(), "%s has no valid signature", published)
+	})
+
+	It("is no more than MAX_PATCH_MONTHS_BEHIND cumulative updates behind", func() {
+		if os.Getenv("CHECK_PATCH_LEVEL") == "" {
+			Skip("CHECK_PATCH_LEVEL is not set")
+		}
+
+		maxBehind, err := maxPatchMonthsBehind()
+		Expect(err).ToNot(HaveOccurred())
+
+		profile, err := validation.ProfileFor(tag)
+		Expect(err).ToNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), validation.OperationTimeout)
+		defer cancel()
+
+		updates, err := validation.CumulativeUpdates(ctx, profile.MSRCProduct, maxBehind+1)
+		Expect(err).ToNot(HaveOccurred())
+
+		level, err := validation.ImagePatchLevel(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+		fmt.Fprintf(GinkgoWriter, "image build %s, latest update KB%s (%s) brings %s\n", level.Build, updates[0].KB, updates[0].Month, updates[0].FixedBuild)
+
+		behind, err := validation.MonthsBehind(level, updates)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(behind).To(BeNumerically("<=", maxBehind), fmt.Sprintf("build %s lacks the cumulative updates of the last %d releases; the latest is KB%s (%s)", level.Build, behind, updates[0].KB, updates[0].Month))
+	})
+
+	It("was built from the pinned base image", func() {
+		pinned, baseLayers, err := pinnedBaseLayers(tag)
+		Expect(err).ToNot(HaveOccurred())
+		if pinned == "" {
+			Skip("no base image digest is pinned in " + filepath.Join(validation.VariantDir(tag, imageVariant), builder.ManifestName))
+		}
+
+		layers, err := validation.ImageLayers(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(len(layers)).To(BeNumerically(">=", len(baseLayers)))
+		Expect(layers[:len(baseLayers)]).To(Equal(baseLayers), fmt.Sprintf("the base layers of %s aren't those of %s", candidateImage(tag), pinned))
+	})
+
+	It("is labelled with its build metadata", func() {
+		skipUnderCtr()
+
+		labels, err := validation.ImageLabels(candidateImage(tag))
+		Expect(err).ToNot(HaveOccurred())
+
+		problems, err := buildLabelProblems(tag, labels)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(problems).To(BeEmpty())
+	})
+
+	It("stays within its image size budget", func() {
+		budget, err := sizeBudget(tag)
+		Expect(err).ToNot(HaveOccurred())
+		if budget == 0 {
+			Skip(fmt.Sprintf("%s has no size budget", validation.VariantTag(tag, imageVariant)))
+		}
+
+		expectCheckToPass(func(candidate validation.Candidate) (validation.CheckResult, error) {
+			return validation.CheckImageSize(candidate.Image, budget)
+		}, candidate(tag))
+	})
+
+	It("runs under groot-windows and winc", func() {
+		runner, ok := lookupWincRuntime(tempDirPath)
+		if !ok {
+			Skip("GROOT_PATH and WINC_PATH are not set")
+		}
+
+		output, err := runner.runInWinc(candidateImage(tag), filepath.Join(tempDirPath, "winc"), "cmd", "/c", "ver")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(output).To(ContainSubstring("Microsoft Windows [Version"))
 	})
 })